@@ -29,6 +29,45 @@ type GophKeeperClient interface {
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResp, error)
 	GetAllDataForUser(ctx context.Context, in *GetAllDataForUserRequest, opts ...grpc.CallOption) (*GetAllDataForUserResp, error)
 	InsertSyncData(ctx context.Context, in *InsertSyncDataRequest, opts ...grpc.CallOption) (*InsertSyncDataResp, error)
+	ListExpiring(ctx context.Context, in *ListExpiringRequest, opts ...grpc.CallOption) (*ListExpiringResp, error)
+	InsertBatch(ctx context.Context, in *InsertBatchRequest, opts ...grpc.CallOption) (*InsertBatchResp, error)
+	SetVaultKey(ctx context.Context, in *SetVaultKeyRequest, opts ...grpc.CallOption) (*SetVaultKeyResp, error)
+	RotateVaultKey(ctx context.Context, in *RotateVaultKeyRequest, opts ...grpc.CallOption) (*RotateVaultKeyResp, error)
+	SetKeyPair(ctx context.Context, in *SetKeyPairRequest, opts ...grpc.CallOption) (*SetKeyPairResp, error)
+	GetPublicKey(ctx context.Context, in *GetPublicKeyRequest, opts ...grpc.CallOption) (*GetPublicKeyResp, error)
+	ShareSecret(ctx context.Context, in *ShareSecretRequest, opts ...grpc.CallOption) (*ShareSecretResp, error)
+	ListShares(ctx context.Context, in *ListSharesRequest, opts ...grpc.CallOption) (*ListSharesResp, error)
+	UpdateShare(ctx context.Context, in *UpdateShareRequest, opts ...grpc.CallOption) (*UpdateShareResp, error)
+	RevokeShare(ctx context.Context, in *RevokeShareRequest, opts ...grpc.CallOption) (*RevokeShareResp, error)
+	CreateOrg(ctx context.Context, in *CreateOrgRequest, opts ...grpc.CallOption) (*CreateOrgResp, error)
+	ListOrgs(ctx context.Context, in *ListOrgsRequest, opts ...grpc.CallOption) (*ListOrgsResp, error)
+	DeleteOrg(ctx context.Context, in *DeleteOrgRequest, opts ...grpc.CallOption) (*DeleteOrgResp, error)
+	InviteMember(ctx context.Context, in *InviteMemberRequest, opts ...grpc.CallOption) (*InviteMemberResp, error)
+	ListInvitations(ctx context.Context, in *ListInvitationsRequest, opts ...grpc.CallOption) (*ListInvitationsResp, error)
+	AnswerInvitation(ctx context.Context, in *AnswerInvitationRequest, opts ...grpc.CallOption) (*AnswerInvitationResp, error)
+	ListMembers(ctx context.Context, in *ListMembersRequest, opts ...grpc.CallOption) (*ListMembersResp, error)
+	UpdateMember(ctx context.Context, in *UpdateMemberRequest, opts ...grpc.CallOption) (*UpdateMemberResp, error)
+	RemoveMember(ctx context.Context, in *RemoveMemberRequest, opts ...grpc.CallOption) (*RemoveMemberResp, error)
+	CreateCollection(ctx context.Context, in *CreateCollectionRequest, opts ...grpc.CallOption) (*CreateCollectionResp, error)
+	ListCollections(ctx context.Context, in *ListCollectionsRequest, opts ...grpc.CallOption) (*ListCollectionsResp, error)
+	DeleteCollection(ctx context.Context, in *DeleteCollectionRequest, opts ...grpc.CallOption) (*DeleteCollectionResp, error)
+	InviteEmergencyContact(ctx context.Context, in *InviteEmergencyContactRequest, opts ...grpc.CallOption) (*InviteEmergencyContactResp, error)
+	ListEmergencyAccess(ctx context.Context, in *ListEmergencyAccessRequest, opts ...grpc.CallOption) (*ListEmergencyAccessResp, error)
+	AcceptEmergencyAccess(ctx context.Context, in *AcceptEmergencyAccessRequest, opts ...grpc.CallOption) (*AcceptEmergencyAccessResp, error)
+	ConfirmEmergencyAccess(ctx context.Context, in *ConfirmEmergencyAccessRequest, opts ...grpc.CallOption) (*ConfirmEmergencyAccessResp, error)
+	InitiateEmergencyAccess(ctx context.Context, in *InitiateEmergencyAccessRequest, opts ...grpc.CallOption) (*InitiateEmergencyAccessResp, error)
+	AnswerEmergencyAccess(ctx context.Context, in *AnswerEmergencyAccessRequest, opts ...grpc.CallOption) (*AnswerEmergencyAccessResp, error)
+	GetEmergencyData(ctx context.Context, in *GetEmergencyDataRequest, opts ...grpc.CallOption) (*GetEmergencyDataResp, error)
+	TakeoverEmergencyAccess(ctx context.Context, in *TakeoverEmergencyAccessRequest, opts ...grpc.CallOption) (*TakeoverEmergencyAccessResp, error)
+	RemoveEmergencyAccess(ctx context.Context, in *RemoveEmergencyAccessRequest, opts ...grpc.CallOption) (*RemoveEmergencyAccessResp, error)
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResp, error)
+	UnlockAccount(ctx context.Context, in *UnlockAccountRequest, opts ...grpc.CallOption) (*UnlockAccountResp, error)
+	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*ChangePasswordResp, error)
+	DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*DeleteAccountResp, error)
+	SetRecoveryKey(ctx context.Context, in *SetRecoveryKeyRequest, opts ...grpc.CallOption) (*SetRecoveryKeyResp, error)
+	GetRecoveryKey(ctx context.Context, in *GetRecoveryKeyRequest, opts ...grpc.CallOption) (*GetRecoveryKeyResp, error)
+	RecoverAccount(ctx context.Context, in *RecoverAccountRequest, opts ...grpc.CallOption) (*RecoverAccountResp, error)
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResp, error)
 }
 
 type gophKeeperClient struct {
@@ -102,6 +141,357 @@ func (c *gophKeeperClient) InsertSyncData(ctx context.Context, in *InsertSyncDat
 	return out, nil
 }
 
+func (c *gophKeeperClient) ListExpiring(ctx context.Context, in *ListExpiringRequest, opts ...grpc.CallOption) (*ListExpiringResp, error) {
+	out := new(ListExpiringResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListExpiring", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) InsertBatch(ctx context.Context, in *InsertBatchRequest, opts ...grpc.CallOption) (*InsertBatchResp, error) {
+	out := new(InsertBatchResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/InsertBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) SetVaultKey(ctx context.Context, in *SetVaultKeyRequest, opts ...grpc.CallOption) (*SetVaultKeyResp, error) {
+	out := new(SetVaultKeyResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/SetVaultKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) RotateVaultKey(ctx context.Context, in *RotateVaultKeyRequest, opts ...grpc.CallOption) (*RotateVaultKeyResp, error) {
+	out := new(RotateVaultKeyResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/RotateVaultKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) SetKeyPair(ctx context.Context, in *SetKeyPairRequest, opts ...grpc.CallOption) (*SetKeyPairResp, error) {
+	out := new(SetKeyPairResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/SetKeyPair", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) GetPublicKey(ctx context.Context, in *GetPublicKeyRequest, opts ...grpc.CallOption) (*GetPublicKeyResp, error) {
+	out := new(GetPublicKeyResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/GetPublicKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ShareSecret(ctx context.Context, in *ShareSecretRequest, opts ...grpc.CallOption) (*ShareSecretResp, error) {
+	out := new(ShareSecretResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ShareSecret", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ListShares(ctx context.Context, in *ListSharesRequest, opts ...grpc.CallOption) (*ListSharesResp, error) {
+	out := new(ListSharesResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListShares", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) UpdateShare(ctx context.Context, in *UpdateShareRequest, opts ...grpc.CallOption) (*UpdateShareResp, error) {
+	out := new(UpdateShareResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/UpdateShare", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) RevokeShare(ctx context.Context, in *RevokeShareRequest, opts ...grpc.CallOption) (*RevokeShareResp, error) {
+	out := new(RevokeShareResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/RevokeShare", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) CreateOrg(ctx context.Context, in *CreateOrgRequest, opts ...grpc.CallOption) (*CreateOrgResp, error) {
+	out := new(CreateOrgResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/CreateOrg", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ListOrgs(ctx context.Context, in *ListOrgsRequest, opts ...grpc.CallOption) (*ListOrgsResp, error) {
+	out := new(ListOrgsResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListOrgs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) DeleteOrg(ctx context.Context, in *DeleteOrgRequest, opts ...grpc.CallOption) (*DeleteOrgResp, error) {
+	out := new(DeleteOrgResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/DeleteOrg", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) InviteMember(ctx context.Context, in *InviteMemberRequest, opts ...grpc.CallOption) (*InviteMemberResp, error) {
+	out := new(InviteMemberResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/InviteMember", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ListInvitations(ctx context.Context, in *ListInvitationsRequest, opts ...grpc.CallOption) (*ListInvitationsResp, error) {
+	out := new(ListInvitationsResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListInvitations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) AnswerInvitation(ctx context.Context, in *AnswerInvitationRequest, opts ...grpc.CallOption) (*AnswerInvitationResp, error) {
+	out := new(AnswerInvitationResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/AnswerInvitation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ListMembers(ctx context.Context, in *ListMembersRequest, opts ...grpc.CallOption) (*ListMembersResp, error) {
+	out := new(ListMembersResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListMembers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) UpdateMember(ctx context.Context, in *UpdateMemberRequest, opts ...grpc.CallOption) (*UpdateMemberResp, error) {
+	out := new(UpdateMemberResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/UpdateMember", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) RemoveMember(ctx context.Context, in *RemoveMemberRequest, opts ...grpc.CallOption) (*RemoveMemberResp, error) {
+	out := new(RemoveMemberResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/RemoveMember", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) CreateCollection(ctx context.Context, in *CreateCollectionRequest, opts ...grpc.CallOption) (*CreateCollectionResp, error) {
+	out := new(CreateCollectionResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/CreateCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ListCollections(ctx context.Context, in *ListCollectionsRequest, opts ...grpc.CallOption) (*ListCollectionsResp, error) {
+	out := new(ListCollectionsResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListCollections", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) DeleteCollection(ctx context.Context, in *DeleteCollectionRequest, opts ...grpc.CallOption) (*DeleteCollectionResp, error) {
+	out := new(DeleteCollectionResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/DeleteCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) InviteEmergencyContact(ctx context.Context, in *InviteEmergencyContactRequest, opts ...grpc.CallOption) (*InviteEmergencyContactResp, error) {
+	out := new(InviteEmergencyContactResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/InviteEmergencyContact", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ListEmergencyAccess(ctx context.Context, in *ListEmergencyAccessRequest, opts ...grpc.CallOption) (*ListEmergencyAccessResp, error) {
+	out := new(ListEmergencyAccessResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListEmergencyAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) AcceptEmergencyAccess(ctx context.Context, in *AcceptEmergencyAccessRequest, opts ...grpc.CallOption) (*AcceptEmergencyAccessResp, error) {
+	out := new(AcceptEmergencyAccessResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/AcceptEmergencyAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ConfirmEmergencyAccess(ctx context.Context, in *ConfirmEmergencyAccessRequest, opts ...grpc.CallOption) (*ConfirmEmergencyAccessResp, error) {
+	out := new(ConfirmEmergencyAccessResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ConfirmEmergencyAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) InitiateEmergencyAccess(ctx context.Context, in *InitiateEmergencyAccessRequest, opts ...grpc.CallOption) (*InitiateEmergencyAccessResp, error) {
+	out := new(InitiateEmergencyAccessResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/InitiateEmergencyAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) AnswerEmergencyAccess(ctx context.Context, in *AnswerEmergencyAccessRequest, opts ...grpc.CallOption) (*AnswerEmergencyAccessResp, error) {
+	out := new(AnswerEmergencyAccessResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/AnswerEmergencyAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) GetEmergencyData(ctx context.Context, in *GetEmergencyDataRequest, opts ...grpc.CallOption) (*GetEmergencyDataResp, error) {
+	out := new(GetEmergencyDataResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/GetEmergencyData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) TakeoverEmergencyAccess(ctx context.Context, in *TakeoverEmergencyAccessRequest, opts ...grpc.CallOption) (*TakeoverEmergencyAccessResp, error) {
+	out := new(TakeoverEmergencyAccessResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/TakeoverEmergencyAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) RemoveEmergencyAccess(ctx context.Context, in *RemoveEmergencyAccessRequest, opts ...grpc.CallOption) (*RemoveEmergencyAccessResp, error) {
+	out := new(RemoveEmergencyAccessResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/RemoveEmergencyAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResp, error) {
+	out := new(ListAuditEventsResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ListAuditEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) UnlockAccount(ctx context.Context, in *UnlockAccountRequest, opts ...grpc.CallOption) (*UnlockAccountResp, error) {
+	out := new(UnlockAccountResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/UnlockAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*ChangePasswordResp, error) {
+	out := new(ChangePasswordResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/ChangePassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*DeleteAccountResp, error) {
+	out := new(DeleteAccountResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/DeleteAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) SetRecoveryKey(ctx context.Context, in *SetRecoveryKeyRequest, opts ...grpc.CallOption) (*SetRecoveryKeyResp, error) {
+	out := new(SetRecoveryKeyResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/SetRecoveryKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) GetRecoveryKey(ctx context.Context, in *GetRecoveryKeyRequest, opts ...grpc.CallOption) (*GetRecoveryKeyResp, error) {
+	out := new(GetRecoveryKeyResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/GetRecoveryKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) RecoverAccount(ctx context.Context, in *RecoverAccountRequest, opts ...grpc.CallOption) (*RecoverAccountResp, error) {
+	out := new(RecoverAccountResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/RecoverAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResp, error) {
+	out := new(UpdateResp)
+	err := c.cc.Invoke(ctx, "/proto.GophKeeper/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GophKeeperServer is the server API for GophKeeper service.
 // All implementations must embed UnimplementedGophKeeperServer
 // for forward compatibility
@@ -113,169 +503,1027 @@ type GophKeeperServer interface {
 	Delete(context.Context, *DeleteRequest) (*DeleteResp, error)
 	GetAllDataForUser(context.Context, *GetAllDataForUserRequest) (*GetAllDataForUserResp, error)
 	InsertSyncData(context.Context, *InsertSyncDataRequest) (*InsertSyncDataResp, error)
+	ListExpiring(context.Context, *ListExpiringRequest) (*ListExpiringResp, error)
+	InsertBatch(context.Context, *InsertBatchRequest) (*InsertBatchResp, error)
+	SetVaultKey(context.Context, *SetVaultKeyRequest) (*SetVaultKeyResp, error)
+	RotateVaultKey(context.Context, *RotateVaultKeyRequest) (*RotateVaultKeyResp, error)
+	SetKeyPair(context.Context, *SetKeyPairRequest) (*SetKeyPairResp, error)
+	GetPublicKey(context.Context, *GetPublicKeyRequest) (*GetPublicKeyResp, error)
+	ShareSecret(context.Context, *ShareSecretRequest) (*ShareSecretResp, error)
+	ListShares(context.Context, *ListSharesRequest) (*ListSharesResp, error)
+	UpdateShare(context.Context, *UpdateShareRequest) (*UpdateShareResp, error)
+	RevokeShare(context.Context, *RevokeShareRequest) (*RevokeShareResp, error)
+	CreateOrg(context.Context, *CreateOrgRequest) (*CreateOrgResp, error)
+	ListOrgs(context.Context, *ListOrgsRequest) (*ListOrgsResp, error)
+	DeleteOrg(context.Context, *DeleteOrgRequest) (*DeleteOrgResp, error)
+	InviteMember(context.Context, *InviteMemberRequest) (*InviteMemberResp, error)
+	ListInvitations(context.Context, *ListInvitationsRequest) (*ListInvitationsResp, error)
+	AnswerInvitation(context.Context, *AnswerInvitationRequest) (*AnswerInvitationResp, error)
+	ListMembers(context.Context, *ListMembersRequest) (*ListMembersResp, error)
+	UpdateMember(context.Context, *UpdateMemberRequest) (*UpdateMemberResp, error)
+	RemoveMember(context.Context, *RemoveMemberRequest) (*RemoveMemberResp, error)
+	CreateCollection(context.Context, *CreateCollectionRequest) (*CreateCollectionResp, error)
+	ListCollections(context.Context, *ListCollectionsRequest) (*ListCollectionsResp, error)
+	DeleteCollection(context.Context, *DeleteCollectionRequest) (*DeleteCollectionResp, error)
+	InviteEmergencyContact(context.Context, *InviteEmergencyContactRequest) (*InviteEmergencyContactResp, error)
+	ListEmergencyAccess(context.Context, *ListEmergencyAccessRequest) (*ListEmergencyAccessResp, error)
+	AcceptEmergencyAccess(context.Context, *AcceptEmergencyAccessRequest) (*AcceptEmergencyAccessResp, error)
+	ConfirmEmergencyAccess(context.Context, *ConfirmEmergencyAccessRequest) (*ConfirmEmergencyAccessResp, error)
+	InitiateEmergencyAccess(context.Context, *InitiateEmergencyAccessRequest) (*InitiateEmergencyAccessResp, error)
+	AnswerEmergencyAccess(context.Context, *AnswerEmergencyAccessRequest) (*AnswerEmergencyAccessResp, error)
+	GetEmergencyData(context.Context, *GetEmergencyDataRequest) (*GetEmergencyDataResp, error)
+	TakeoverEmergencyAccess(context.Context, *TakeoverEmergencyAccessRequest) (*TakeoverEmergencyAccessResp, error)
+	RemoveEmergencyAccess(context.Context, *RemoveEmergencyAccessRequest) (*RemoveEmergencyAccessResp, error)
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResp, error)
+	UnlockAccount(context.Context, *UnlockAccountRequest) (*UnlockAccountResp, error)
+	ChangePassword(context.Context, *ChangePasswordRequest) (*ChangePasswordResp, error)
+	DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResp, error)
+	SetRecoveryKey(context.Context, *SetRecoveryKeyRequest) (*SetRecoveryKeyResp, error)
+	GetRecoveryKey(context.Context, *GetRecoveryKeyRequest) (*GetRecoveryKeyResp, error)
+	RecoverAccount(context.Context, *RecoverAccountRequest) (*RecoverAccountResp, error)
+	Update(context.Context, *UpdateRequest) (*UpdateResp, error)
 	mustEmbedUnimplementedGophKeeperServer()
 }
 
-// UnimplementedGophKeeperServer must be embedded to have forward compatible implementations.
-type UnimplementedGophKeeperServer struct {
+// UnimplementedGophKeeperServer must be embedded to have forward compatible implementations.
+type UnimplementedGophKeeperServer struct {
+}
+
+func (UnimplementedGophKeeperServer) Register(context.Context, *RegisterRequest) (*RegisterResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedGophKeeperServer) Login(context.Context, *LoginRequest) (*LoginResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedGophKeeperServer) Insert(context.Context, *InsertRequest) (*InsertResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Insert not implemented")
+}
+func (UnimplementedGophKeeperServer) GetData(context.Context, *GetDataRequest) (*GetDataResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetData not implemented")
+}
+func (UnimplementedGophKeeperServer) Delete(context.Context, *DeleteRequest) (*DeleteResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedGophKeeperServer) GetAllDataForUser(context.Context, *GetAllDataForUserRequest) (*GetAllDataForUserResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllDataForUser not implemented")
+}
+func (UnimplementedGophKeeperServer) InsertSyncData(context.Context, *InsertSyncDataRequest) (*InsertSyncDataResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertSyncData not implemented")
+}
+func (UnimplementedGophKeeperServer) ListExpiring(context.Context, *ListExpiringRequest) (*ListExpiringResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListExpiring not implemented")
+}
+func (UnimplementedGophKeeperServer) InsertBatch(context.Context, *InsertBatchRequest) (*InsertBatchResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertBatch not implemented")
+}
+func (UnimplementedGophKeeperServer) SetVaultKey(context.Context, *SetVaultKeyRequest) (*SetVaultKeyResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetVaultKey not implemented")
+}
+func (UnimplementedGophKeeperServer) RotateVaultKey(context.Context, *RotateVaultKeyRequest) (*RotateVaultKeyResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateVaultKey not implemented")
+}
+func (UnimplementedGophKeeperServer) SetKeyPair(context.Context, *SetKeyPairRequest) (*SetKeyPairResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetKeyPair not implemented")
+}
+func (UnimplementedGophKeeperServer) GetPublicKey(context.Context, *GetPublicKeyRequest) (*GetPublicKeyResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPublicKey not implemented")
+}
+func (UnimplementedGophKeeperServer) ShareSecret(context.Context, *ShareSecretRequest) (*ShareSecretResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShareSecret not implemented")
+}
+func (UnimplementedGophKeeperServer) ListShares(context.Context, *ListSharesRequest) (*ListSharesResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListShares not implemented")
+}
+func (UnimplementedGophKeeperServer) UpdateShare(context.Context, *UpdateShareRequest) (*UpdateShareResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateShare not implemented")
+}
+func (UnimplementedGophKeeperServer) RevokeShare(context.Context, *RevokeShareRequest) (*RevokeShareResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeShare not implemented")
+}
+func (UnimplementedGophKeeperServer) CreateOrg(context.Context, *CreateOrgRequest) (*CreateOrgResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrg not implemented")
+}
+func (UnimplementedGophKeeperServer) ListOrgs(context.Context, *ListOrgsRequest) (*ListOrgsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrgs not implemented")
+}
+func (UnimplementedGophKeeperServer) DeleteOrg(context.Context, *DeleteOrgRequest) (*DeleteOrgResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteOrg not implemented")
+}
+func (UnimplementedGophKeeperServer) InviteMember(context.Context, *InviteMemberRequest) (*InviteMemberResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InviteMember not implemented")
+}
+func (UnimplementedGophKeeperServer) ListInvitations(context.Context, *ListInvitationsRequest) (*ListInvitationsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInvitations not implemented")
+}
+func (UnimplementedGophKeeperServer) AnswerInvitation(context.Context, *AnswerInvitationRequest) (*AnswerInvitationResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnswerInvitation not implemented")
+}
+func (UnimplementedGophKeeperServer) ListMembers(context.Context, *ListMembersRequest) (*ListMembersResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMembers not implemented")
+}
+func (UnimplementedGophKeeperServer) UpdateMember(context.Context, *UpdateMemberRequest) (*UpdateMemberResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateMember not implemented")
+}
+func (UnimplementedGophKeeperServer) RemoveMember(context.Context, *RemoveMemberRequest) (*RemoveMemberResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveMember not implemented")
+}
+func (UnimplementedGophKeeperServer) CreateCollection(context.Context, *CreateCollectionRequest) (*CreateCollectionResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCollection not implemented")
+}
+func (UnimplementedGophKeeperServer) ListCollections(context.Context, *ListCollectionsRequest) (*ListCollectionsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCollections not implemented")
+}
+func (UnimplementedGophKeeperServer) DeleteCollection(context.Context, *DeleteCollectionRequest) (*DeleteCollectionResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCollection not implemented")
+}
+func (UnimplementedGophKeeperServer) InviteEmergencyContact(context.Context, *InviteEmergencyContactRequest) (*InviteEmergencyContactResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InviteEmergencyContact not implemented")
+}
+func (UnimplementedGophKeeperServer) ListEmergencyAccess(context.Context, *ListEmergencyAccessRequest) (*ListEmergencyAccessResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEmergencyAccess not implemented")
+}
+func (UnimplementedGophKeeperServer) AcceptEmergencyAccess(context.Context, *AcceptEmergencyAccessRequest) (*AcceptEmergencyAccessResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptEmergencyAccess not implemented")
+}
+func (UnimplementedGophKeeperServer) ConfirmEmergencyAccess(context.Context, *ConfirmEmergencyAccessRequest) (*ConfirmEmergencyAccessResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmEmergencyAccess not implemented")
+}
+func (UnimplementedGophKeeperServer) InitiateEmergencyAccess(context.Context, *InitiateEmergencyAccessRequest) (*InitiateEmergencyAccessResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitiateEmergencyAccess not implemented")
+}
+func (UnimplementedGophKeeperServer) AnswerEmergencyAccess(context.Context, *AnswerEmergencyAccessRequest) (*AnswerEmergencyAccessResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnswerEmergencyAccess not implemented")
+}
+func (UnimplementedGophKeeperServer) GetEmergencyData(context.Context, *GetEmergencyDataRequest) (*GetEmergencyDataResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEmergencyData not implemented")
+}
+func (UnimplementedGophKeeperServer) TakeoverEmergencyAccess(context.Context, *TakeoverEmergencyAccessRequest) (*TakeoverEmergencyAccessResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TakeoverEmergencyAccess not implemented")
+}
+func (UnimplementedGophKeeperServer) RemoveEmergencyAccess(context.Context, *RemoveEmergencyAccessRequest) (*RemoveEmergencyAccessResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveEmergencyAccess not implemented")
+}
+func (UnimplementedGophKeeperServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedGophKeeperServer) UnlockAccount(context.Context, *UnlockAccountRequest) (*UnlockAccountResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockAccount not implemented")
+}
+func (UnimplementedGophKeeperServer) ChangePassword(context.Context, *ChangePasswordRequest) (*ChangePasswordResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
+}
+func (UnimplementedGophKeeperServer) DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
+}
+func (UnimplementedGophKeeperServer) SetRecoveryKey(context.Context, *SetRecoveryKeyRequest) (*SetRecoveryKeyResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRecoveryKey not implemented")
+}
+func (UnimplementedGophKeeperServer) GetRecoveryKey(context.Context, *GetRecoveryKeyRequest) (*GetRecoveryKeyResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecoveryKey not implemented")
+}
+func (UnimplementedGophKeeperServer) RecoverAccount(context.Context, *RecoverAccountRequest) (*RecoverAccountResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecoverAccount not implemented")
+}
+func (UnimplementedGophKeeperServer) Update(context.Context, *UpdateRequest) (*UpdateResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedGophKeeperServer) mustEmbedUnimplementedGophKeeperServer() {}
+
+// UnsafeGophKeeperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GophKeeperServer will
+// result in compilation errors.
+type UnsafeGophKeeperServer interface {
+	mustEmbedUnimplementedGophKeeperServer()
+}
+
+func RegisterGophKeeperServer(s grpc.ServiceRegistrar, srv GophKeeperServer) {
+	s.RegisterService(&GophKeeper_ServiceDesc, srv)
+}
+
+func _GophKeeper_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).Insert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/Insert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).Insert(ctx, req.(*InsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_GetData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).GetData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/GetData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).GetData(ctx, req.(*GetDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_GetAllDataForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllDataForUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).GetAllDataForUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/GetAllDataForUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).GetAllDataForUser(ctx, req.(*GetAllDataForUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_InsertSyncData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertSyncDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).InsertSyncData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/InsertSyncData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).InsertSyncData(ctx, req.(*InsertSyncDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_ListExpiring_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListExpiringRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListExpiring(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListExpiring",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListExpiring(ctx, req.(*ListExpiringRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_InsertBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).InsertBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/InsertBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).InsertBatch(ctx, req.(*InsertBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_SetVaultKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetVaultKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).SetVaultKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/SetVaultKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).SetVaultKey(ctx, req.(*SetVaultKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_RotateVaultKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateVaultKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).RotateVaultKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/RotateVaultKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).RotateVaultKey(ctx, req.(*RotateVaultKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_SetKeyPair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetKeyPairRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).SetKeyPair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/SetKeyPair",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).SetKeyPair(ctx, req.(*SetKeyPairRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_GetPublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPublicKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).GetPublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/GetPublicKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).GetPublicKey(ctx, req.(*GetPublicKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_ShareSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShareSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ShareSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ShareSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ShareSecret(ctx, req.(*ShareSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_ListShares_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSharesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListShares(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListShares",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListShares(ctx, req.(*ListSharesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_UpdateShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).UpdateShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/UpdateShare",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).UpdateShare(ctx, req.(*UpdateShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_RevokeShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).RevokeShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/RevokeShare",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).RevokeShare(ctx, req.(*RevokeShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_CreateOrg_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateOrgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).CreateOrg(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/CreateOrg",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).CreateOrg(ctx, req.(*CreateOrgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_ListOrgs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrgsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListOrgs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListOrgs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListOrgs(ctx, req.(*ListOrgsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_DeleteOrg_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteOrgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).DeleteOrg(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/DeleteOrg",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).DeleteOrg(ctx, req.(*DeleteOrgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_InviteMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InviteMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).InviteMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/InviteMember",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).InviteMember(ctx, req.(*InviteMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_ListInvitations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInvitationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListInvitations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListInvitations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListInvitations(ctx, req.(*ListInvitationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_AnswerInvitation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnswerInvitationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).AnswerInvitation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/AnswerInvitation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).AnswerInvitation(ctx, req.(*AnswerInvitationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_ListMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListMembers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListMembers(ctx, req.(*ListMembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_UpdateMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).UpdateMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/UpdateMember",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).UpdateMember(ctx, req.(*UpdateMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_RemoveMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).RemoveMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/RemoveMember",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).RemoveMember(ctx, req.(*RemoveMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_CreateCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).CreateCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/CreateCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).CreateCollection(ctx, req.(*CreateCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_ListCollections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCollectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListCollections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListCollections",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListCollections(ctx, req.(*ListCollectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_DeleteCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).DeleteCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/DeleteCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).DeleteCollection(ctx, req.(*DeleteCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeper_InviteEmergencyContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InviteEmergencyContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).InviteEmergencyContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/InviteEmergencyContact",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).InviteEmergencyContact(ctx, req.(*InviteEmergencyContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedGophKeeperServer) Register(context.Context, *RegisterRequest) (*RegisterResp, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+func _GophKeeper_ListEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEmergencyAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListEmergencyAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListEmergencyAccess(ctx, req.(*ListEmergencyAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedGophKeeperServer) Login(context.Context, *LoginRequest) (*LoginResp, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+
+func _GophKeeper_AcceptEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcceptEmergencyAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).AcceptEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/AcceptEmergencyAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).AcceptEmergencyAccess(ctx, req.(*AcceptEmergencyAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedGophKeeperServer) Insert(context.Context, *InsertRequest) (*InsertResp, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Insert not implemented")
+
+func _GophKeeper_ConfirmEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmEmergencyAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ConfirmEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ConfirmEmergencyAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ConfirmEmergencyAccess(ctx, req.(*ConfirmEmergencyAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedGophKeeperServer) GetData(context.Context, *GetDataRequest) (*GetDataResp, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetData not implemented")
+
+func _GophKeeper_InitiateEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitiateEmergencyAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).InitiateEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/InitiateEmergencyAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).InitiateEmergencyAccess(ctx, req.(*InitiateEmergencyAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedGophKeeperServer) Delete(context.Context, *DeleteRequest) (*DeleteResp, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+
+func _GophKeeper_AnswerEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnswerEmergencyAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).AnswerEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/AnswerEmergencyAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).AnswerEmergencyAccess(ctx, req.(*AnswerEmergencyAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedGophKeeperServer) GetAllDataForUser(context.Context, *GetAllDataForUserRequest) (*GetAllDataForUserResp, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetAllDataForUser not implemented")
+
+func _GophKeeper_GetEmergencyData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEmergencyDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).GetEmergencyData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/GetEmergencyData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).GetEmergencyData(ctx, req.(*GetEmergencyDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedGophKeeperServer) InsertSyncData(context.Context, *InsertSyncDataRequest) (*InsertSyncDataResp, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method InsertSyncData not implemented")
+
+func _GophKeeper_TakeoverEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TakeoverEmergencyAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).TakeoverEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/TakeoverEmergencyAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).TakeoverEmergencyAccess(ctx, req.(*TakeoverEmergencyAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedGophKeeperServer) mustEmbedUnimplementedGophKeeperServer() {}
 
-// UnsafeGophKeeperServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to GophKeeperServer will
-// result in compilation errors.
-type UnsafeGophKeeperServer interface {
-	mustEmbedUnimplementedGophKeeperServer()
+func _GophKeeper_RemoveEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveEmergencyAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).RemoveEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/RemoveEmergencyAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).RemoveEmergencyAccess(ctx, req.(*RemoveEmergencyAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterGophKeeperServer(s grpc.ServiceRegistrar, srv GophKeeperServer) {
-	s.RegisterService(&GophKeeper_ServiceDesc, srv)
+func _GophKeeper_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GophKeeper/ListAuditEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _GophKeeper_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RegisterRequest)
+func _GophKeeper_UnlockAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockAccountRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophKeeperServer).Register(ctx, in)
+		return srv.(GophKeeperServer).UnlockAccount(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.GophKeeper/Register",
+		FullMethod: "/proto.GophKeeper/UnlockAccount",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophKeeperServer).Register(ctx, req.(*RegisterRequest))
+		return srv.(GophKeeperServer).UnlockAccount(ctx, req.(*UnlockAccountRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _GophKeeper_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(LoginRequest)
+func _GophKeeper_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePasswordRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophKeeperServer).Login(ctx, in)
+		return srv.(GophKeeperServer).ChangePassword(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.GophKeeper/Login",
+		FullMethod: "/proto.GophKeeper/ChangePassword",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophKeeperServer).Login(ctx, req.(*LoginRequest))
+		return srv.(GophKeeperServer).ChangePassword(ctx, req.(*ChangePasswordRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _GophKeeper_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(InsertRequest)
+func _GophKeeper_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAccountRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophKeeperServer).Insert(ctx, in)
+		return srv.(GophKeeperServer).DeleteAccount(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.GophKeeper/Insert",
+		FullMethod: "/proto.GophKeeper/DeleteAccount",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophKeeperServer).Insert(ctx, req.(*InsertRequest))
+		return srv.(GophKeeperServer).DeleteAccount(ctx, req.(*DeleteAccountRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _GophKeeper_GetData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetDataRequest)
+func _GophKeeper_SetRecoveryKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRecoveryKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophKeeperServer).GetData(ctx, in)
+		return srv.(GophKeeperServer).SetRecoveryKey(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.GophKeeper/GetData",
+		FullMethod: "/proto.GophKeeper/SetRecoveryKey",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophKeeperServer).GetData(ctx, req.(*GetDataRequest))
+		return srv.(GophKeeperServer).SetRecoveryKey(ctx, req.(*SetRecoveryKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _GophKeeper_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteRequest)
+func _GophKeeper_GetRecoveryKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecoveryKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophKeeperServer).Delete(ctx, in)
+		return srv.(GophKeeperServer).GetRecoveryKey(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.GophKeeper/Delete",
+		FullMethod: "/proto.GophKeeper/GetRecoveryKey",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophKeeperServer).Delete(ctx, req.(*DeleteRequest))
+		return srv.(GophKeeperServer).GetRecoveryKey(ctx, req.(*GetRecoveryKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _GophKeeper_GetAllDataForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetAllDataForUserRequest)
+func _GophKeeper_RecoverAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecoverAccountRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophKeeperServer).GetAllDataForUser(ctx, in)
+		return srv.(GophKeeperServer).RecoverAccount(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.GophKeeper/GetAllDataForUser",
+		FullMethod: "/proto.GophKeeper/RecoverAccount",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophKeeperServer).GetAllDataForUser(ctx, req.(*GetAllDataForUserRequest))
+		return srv.(GophKeeperServer).RecoverAccount(ctx, req.(*RecoverAccountRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _GophKeeper_InsertSyncData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(InsertSyncDataRequest)
+func _GophKeeper_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophKeeperServer).InsertSyncData(ctx, in)
+		return srv.(GophKeeperServer).Update(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.GophKeeper/InsertSyncData",
+		FullMethod: "/proto.GophKeeper/Update",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophKeeperServer).InsertSyncData(ctx, req.(*InsertSyncDataRequest))
+		return srv.(GophKeeperServer).Update(ctx, req.(*UpdateRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -315,6 +1563,162 @@ var GophKeeper_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "InsertSyncData",
 			Handler:    _GophKeeper_InsertSyncData_Handler,
 		},
+		{
+			MethodName: "ListExpiring",
+			Handler:    _GophKeeper_ListExpiring_Handler,
+		},
+		{
+			MethodName: "InsertBatch",
+			Handler:    _GophKeeper_InsertBatch_Handler,
+		},
+		{
+			MethodName: "SetVaultKey",
+			Handler:    _GophKeeper_SetVaultKey_Handler,
+		},
+		{
+			MethodName: "RotateVaultKey",
+			Handler:    _GophKeeper_RotateVaultKey_Handler,
+		},
+		{
+			MethodName: "SetKeyPair",
+			Handler:    _GophKeeper_SetKeyPair_Handler,
+		},
+		{
+			MethodName: "GetPublicKey",
+			Handler:    _GophKeeper_GetPublicKey_Handler,
+		},
+		{
+			MethodName: "ShareSecret",
+			Handler:    _GophKeeper_ShareSecret_Handler,
+		},
+		{
+			MethodName: "ListShares",
+			Handler:    _GophKeeper_ListShares_Handler,
+		},
+		{
+			MethodName: "UpdateShare",
+			Handler:    _GophKeeper_UpdateShare_Handler,
+		},
+		{
+			MethodName: "RevokeShare",
+			Handler:    _GophKeeper_RevokeShare_Handler,
+		},
+		{
+			MethodName: "CreateOrg",
+			Handler:    _GophKeeper_CreateOrg_Handler,
+		},
+		{
+			MethodName: "ListOrgs",
+			Handler:    _GophKeeper_ListOrgs_Handler,
+		},
+		{
+			MethodName: "DeleteOrg",
+			Handler:    _GophKeeper_DeleteOrg_Handler,
+		},
+		{
+			MethodName: "InviteMember",
+			Handler:    _GophKeeper_InviteMember_Handler,
+		},
+		{
+			MethodName: "ListInvitations",
+			Handler:    _GophKeeper_ListInvitations_Handler,
+		},
+		{
+			MethodName: "AnswerInvitation",
+			Handler:    _GophKeeper_AnswerInvitation_Handler,
+		},
+		{
+			MethodName: "ListMembers",
+			Handler:    _GophKeeper_ListMembers_Handler,
+		},
+		{
+			MethodName: "UpdateMember",
+			Handler:    _GophKeeper_UpdateMember_Handler,
+		},
+		{
+			MethodName: "RemoveMember",
+			Handler:    _GophKeeper_RemoveMember_Handler,
+		},
+		{
+			MethodName: "CreateCollection",
+			Handler:    _GophKeeper_CreateCollection_Handler,
+		},
+		{
+			MethodName: "ListCollections",
+			Handler:    _GophKeeper_ListCollections_Handler,
+		},
+		{
+			MethodName: "DeleteCollection",
+			Handler:    _GophKeeper_DeleteCollection_Handler,
+		},
+		{
+			MethodName: "InviteEmergencyContact",
+			Handler:    _GophKeeper_InviteEmergencyContact_Handler,
+		},
+		{
+			MethodName: "ListEmergencyAccess",
+			Handler:    _GophKeeper_ListEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "AcceptEmergencyAccess",
+			Handler:    _GophKeeper_AcceptEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "ConfirmEmergencyAccess",
+			Handler:    _GophKeeper_ConfirmEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "InitiateEmergencyAccess",
+			Handler:    _GophKeeper_InitiateEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "AnswerEmergencyAccess",
+			Handler:    _GophKeeper_AnswerEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "GetEmergencyData",
+			Handler:    _GophKeeper_GetEmergencyData_Handler,
+		},
+		{
+			MethodName: "TakeoverEmergencyAccess",
+			Handler:    _GophKeeper_TakeoverEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "RemoveEmergencyAccess",
+			Handler:    _GophKeeper_RemoveEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _GophKeeper_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "UnlockAccount",
+			Handler:    _GophKeeper_UnlockAccount_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _GophKeeper_ChangePassword_Handler,
+		},
+		{
+			MethodName: "DeleteAccount",
+			Handler:    _GophKeeper_DeleteAccount_Handler,
+		},
+		{
+			MethodName: "SetRecoveryKey",
+			Handler:    _GophKeeper_SetRecoveryKey_Handler,
+		},
+		{
+			MethodName: "GetRecoveryKey",
+			Handler:    _GophKeeper_GetRecoveryKey_Handler,
+		},
+		{
+			MethodName: "RecoverAccount",
+			Handler:    _GophKeeper_RecoverAccount_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _GophKeeper_Update_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "server.proto",
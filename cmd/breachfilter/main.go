@@ -0,0 +1,48 @@
+// breachfilter builds binary fuse filter from Pwned Passwords hash file or range directory,
+// the filter is much smaller than the corpus and can be used by the client with -b flag.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/maffka123/GophKeeper/internal/breach"
+)
+
+func main() {
+	in := flag.String("i", "pwned-passwords-sha1-ordered-by-hash.txt", "hash file with HASH:COUNT lines or directory of range files")
+	out := flag.String("o", "pwned-passwords.filter", "where to write the filter")
+	flag.Parse()
+
+	var keys []uint64
+	err := breach.ReadHashes(*in, func(hash string) error {
+		key, err := breach.Key(hash)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("cannot read hashes: %v", err)
+	}
+	fmt.Printf("read %d hashes, building filter...\n", len(keys))
+
+	filter, err := breach.BuildFilter(keys)
+	if err != nil {
+		log.Fatalf("cannot build filter: %v", err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("cannot create filter file: %v", err)
+	}
+	defer f.Close()
+	size, err := filter.WriteTo(f)
+	if err != nil {
+		log.Fatalf("cannot write filter: %v", err)
+	}
+	fmt.Printf("filter written to %s, %d bytes\n", *out, size)
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockSignalList are signals that lock the vault: kill -USR1 <pid>
+func lockSignalList() []os.Signal {
+	return []os.Signal{syscall.SIGUSR1}
+}
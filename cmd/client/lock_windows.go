@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// lockSignalList is empty, there is no user signal on windows, the vault is locked with /api/vault/lock
+func lockSignalList() []os.Signal {
+	return nil
+}
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	pb "github.com/maffka123/GophKeeper/api/proto"
 	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/breach"
 	"github.com/maffka123/GophKeeper/internal/client/config"
+	"github.com/maffka123/GophKeeper/internal/clipboard"
 	basecfg "github.com/maffka123/GophKeeper/internal/config"
 	"github.com/maffka123/GophKeeper/internal/handlers"
+	"github.com/maffka123/GophKeeper/internal/localapi"
 	"github.com/maffka123/GophKeeper/internal/storage"
 	"github.com/maffka123/GophKeeper/internal/syncdb"
 	"go.uber.org/zap"
@@ -51,7 +54,9 @@ func main() {
 		logger.Fatal("Error initializing db", zap.Error(err))
 	}
 
-	conn, err := grpc.Dial(cfg.ServerEndpoint, grpc.WithInsecure())
+	// server keeps user agent as the device in the audit log
+	host, _ := os.Hostname()
+	conn, err := grpc.Dial(cfg.ServerEndpoint, grpc.WithInsecure(), grpc.WithUserAgent("gophkeeper-client/"+host))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -64,25 +69,88 @@ func main() {
 	tokenChan := make(chan string)
 	idChan := make(chan int64)
 	syncTicker := time.NewTicker(cfg.SyncInterval)
-	go syncdb.InitSync(ctx, tokenChan, idChan, db, client, logger, syncTicker.C)
+	// sync runs periodically and on demand of the api
+	syncNow := make(chan time.Time)
+	go func() {
+		for t := range syncTicker.C {
+			select {
+			case syncNow <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	syncState := syncdb.NewState()
+	go syncdb.InitSync(ctx, tokenChan, idChan, db, client, logger, syncNow, syncState)
+
+	// load breach corpus, passwords are checked locally
+	var breached breach.Checker
+	if cfg.BreachFile != "" {
+		breached, err = breach.Open(cfg.BreachFile)
+		if err != nil {
+			logger.Fatal("Error loading breach corpus", zap.Error(err))
+		}
+		defer breached.Close()
+	}
+
+	// vault is locked on idle and on signals
+	lockSignals := make(chan os.Signal, 1)
+	if sigs := lockSignalList(); len(sigs) > 0 {
+		signal.Notify(lockSignals, sigs...)
+	}
+
+	// secrets copied to the clipboard are cleared after a while, copy is disabled without clipboard
+	var copier *clipboard.Copier
+	if cb, err := clipboard.Detect(cfg.Clipboard); err != nil {
+		logger.Warn("clipboard is not available", zap.Error(err))
+	} else {
+		copier = clipboard.NewCopier(cb, cfg.ClearAfter, func(cleared bool, err error) {
+			if err != nil {
+				logger.Error("clipboard clear failed", zap.Error(err))
+				return
+			}
+			logger.Debug("clipboard clear", zap.Bool("cleared", cleared))
+		})
+	}
 
 	// prepare handles
-	r := handlers.KeeperRouter(ctx, logger, client, db, tokenChan, idChan)
+	r := handlers.KeeperRouter(ctx, logger, client, db, tokenChan, idChan, breached, cfg.LockAfter, lockSignals, syncNow, syncState, copier)
+
+	// only processes of the user that read the secret can call the api
+	secret, err := localapi.NewSecret()
+	if err != nil {
+		logger.Fatal("Error generating api secret", zap.Error(err))
+	}
+	if err := localapi.WriteSecret(cfg.SecretFile, secret); err != nil {
+		logger.Fatal("Error writing api secret", zap.Error(err))
+	}
+	endpoint := cfg.Endpoint
+	if cfg.Socket != "" {
+		endpoint = ""
+	}
+	guard := localapi.NewGuard(secret, localapi.Hosts(endpoint), cfg.Origins)
+	ln, err := localapi.Listen(cfg.Endpoint, cfg.Socket)
+	if err != nil {
+		logger.Fatal("Error listening", zap.Error(err))
+	}
 
 	// handle service stop
-	srv := &http.Server{Addr: cfg.Endpoint, Handler: r}
+	srv := &http.Server{Handler: guard.Handler(r)}
 	quit := make(chan os.Signal)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		sig := <-quit
 		logger.Info(fmt.Sprintf("caught sig: %+v", sig))
+		if copier != nil {
+			copier.Flush()
+		}
 		if err := srv.Shutdown(ctx); err != nil {
 			// Error from closing listeners, or context timeout:
 			logger.Error("HTTP server Shutdown:", zap.Error(err))
 		}
 	}()
 
-	logger.Info("Start serving on", zap.String("endpoint name", cfg.Endpoint))
-	log.Fatal(srv.ListenAndServe())
+	logger.Info("Start serving on", zap.String("endpoint name", ln.Addr().String()), zap.String("secret file", cfg.SecretFile))
+	log.Fatal(srv.Serve(ln))
 
 }
@@ -8,6 +8,8 @@ import (
 	pb "github.com/maffka123/GophKeeper/api/proto"
 	"github.com/maffka123/GophKeeper/internal/app"
 	basecfg "github.com/maffka123/GophKeeper/internal/config"
+	"github.com/maffka123/GophKeeper/internal/ratelimit"
+	"github.com/maffka123/GophKeeper/internal/reminder"
 	"github.com/maffka123/GophKeeper/internal/server"
 	"github.com/maffka123/GophKeeper/internal/server/config"
 	"github.com/maffka123/GophKeeper/internal/storage"
@@ -19,7 +21,9 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -46,6 +50,26 @@ func main() {
 	}
 
 	srv := server.New(logger, db, cfg.Key)
+	srv.SetAdmins(strings.Split(cfg.Admins, ","))
+
+	// limit login attempts, replicas share limits in postgres
+	var rateStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateStore == "postgres" {
+		rateStore = storage.NewRateStore(db)
+	}
+	srv.SetLimiter(ratelimit.New(rateStore,
+		ratelimit.Rate{PerMinute: cfg.IPRate, Burst: int(cfg.IPRate)},
+		ratelimit.Rate{PerMinute: cfg.LoginRate, Burst: int(cfg.LoginRate)},
+		ratelimit.Lockout{Threshold: cfg.LockoutAfter, Base: time.Minute, Max: 24 * time.Hour}))
+
+	// remind about expiring secrets
+	var notifier reminder.Notifier = reminder.NewLogNotifier(logger)
+	if cfg.SMTPAddress != "" {
+		notifier = reminder.MultiNotifier{notifier, reminder.NewSMTPNotifier(cfg.SMTPAddress, cfg.MailFrom, cfg.MailTo)}
+	}
+	expiryTicker := time.NewTicker(cfg.ExpiryInterval)
+	defer expiryTicker.Stop()
+	go reminder.NewJob(db, notifier, cfg.ExpiryWindow, logger).Run(ctx, expiryTicker.C)
 
 	authfunc := srv.JWTAuthFunction()
 	// run grpc server
@@ -54,7 +78,11 @@ func main() {
 		grpc.UnaryInterceptor(
 			grpc_middleware.ChainUnaryServer(
 				// auth
-				grpc_auth.UnaryServerInterceptor(authfunc))),
+				grpc_auth.UnaryServerInterceptor(authfunc),
+				// audit log
+				srv.AuditInterceptor(),
+				// login rate limits
+				srv.LimitInterceptor())),
 	)
 
 	reflection.Register(grpcServer)
@@ -15,3 +15,188 @@ CREATE TABLE IF NOT EXISTS secrets  (
     synchronized boolean,
     FOREIGN KEY(user_id) REFERENCES users(id)
 );
+
+
+-- card numbers used to be stored as numbers
+UPDATE secrets SET data = jsonb_set(data, '{BankCard,CardNumber}', to_jsonb(data #>> '{BankCard,CardNumber}'))
+WHERE jsonb_typeof(data #> '{BankCard,CardNumber}') = 'number';
+
+-- security codes of cards are not kept any more
+UPDATE secrets SET data = data #- '{BankCard,ThreeDigit}' #- '{BankCard,CVV}'
+WHERE data #> '{BankCard}' ?| array['ThreeDigit', 'CVV'];
+
+
+ALTER TABLE secrets ADD COLUMN IF NOT EXISTS expires_at timestamp;
+
+CREATE INDEX IF NOT EXISTS secrets_expires_at_idx ON secrets (expires_at) WHERE expires_at IS NOT NULL;
+
+
+-- full-text and fuzzy search over everything but passwords and card numbers
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE secrets ADD COLUMN IF NOT EXISTS search_text text GENERATED ALWAYS AS (
+    lower(coalesce(metadata, '') || ' ' ||
+        coalesce(data #>> '{AuthData,login}', '') || ' ' ||
+        coalesce(jsonb_path_query_array(data, '$.AuthData.URIs[*].URI') #>> '{}', '') || ' ' ||
+        coalesce(data #>> '{Text}', '') || ' ' ||
+        coalesce(data #>> '{BankCard,HolderName}', '') || ' ' ||
+        coalesce(data #>> '{BankCard,BankName}', ''))
+) STORED;
+
+ALTER TABLE secrets ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(metadata, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(data #>> '{AuthData,login}', '') || ' ' ||
+        coalesce(jsonb_path_query_array(data, '$.AuthData.URIs[*].URI') #>> '{}', '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(data #>> '{Text}', '') || ' ' ||
+        coalesce(data #>> '{BankCard,HolderName}', '') || ' ' ||
+        coalesce(data #>> '{BankCard,BankName}', '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS secrets_search_tsv_idx ON secrets USING gin (search_tsv);
+
+CREATE INDEX IF NOT EXISTS secrets_search_text_idx ON secrets USING gin (search_text gin_trgm_ops);
+
+
+-- vault key wrapped on the client and blind indexes of secrets computed with it
+ALTER TABLE users ADD COLUMN IF NOT EXISTS vault_key bytea;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS vault_salt bytea;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS vault_key_version bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE secrets ADD COLUMN IF NOT EXISTS blind_index text[];
+
+CREATE INDEX IF NOT EXISTS secrets_blind_index_idx ON secrets USING gin (blind_index);
+
+
+-- key pairs of users and secrets shared between them, secrets are encrypted on the client
+ALTER TABLE users ADD COLUMN IF NOT EXISTS public_key bytea;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS private_key bytea;
+
+CREATE TABLE IF NOT EXISTS shares (
+    id UUID PRIMARY KEY,
+    owner_id bigint NOT NULL,
+    ciphertext bytea NOT NULL,
+    key_version bigint NOT NULL DEFAULT 1,
+    change_date timestamp DEFAULT current_timestamp,
+    FOREIGN KEY(owner_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS share_recipients (
+    share_id UUID NOT NULL,
+    user_id bigint NOT NULL,
+    wrapped_key bytea NOT NULL,
+    permission varchar(10) NOT NULL,
+    PRIMARY KEY(share_id, user_id),
+    FOREIGN KEY(share_id) REFERENCES shares(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS share_recipients_user_id_idx ON share_recipients (user_id);
+
+
+-- organisations own collections of secrets, members access them by their role
+CREATE TABLE IF NOT EXISTS orgs (
+    id UUID PRIMARY KEY,
+    name varchar(100) NOT NULL,
+    created_at timestamp DEFAULT current_timestamp
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id UUID NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(20) NOT NULL,
+    PRIMARY KEY(org_id, user_id),
+    FOREIGN KEY(org_id) REFERENCES orgs(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS org_members_user_id_idx ON org_members (user_id);
+
+CREATE TABLE IF NOT EXISTS org_invitations (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(20) NOT NULL,
+    invited_by bigint NOT NULL,
+    created_at timestamp DEFAULT current_timestamp,
+    UNIQUE(org_id, user_id),
+    FOREIGN KEY(org_id) REFERENCES orgs(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(invited_by) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL,
+    name varchar(100) NOT NULL,
+    FOREIGN KEY(org_id) REFERENCES orgs(id) ON DELETE CASCADE
+);
+
+ALTER TABLE secrets ADD COLUMN IF NOT EXISTS collection_id UUID REFERENCES collections(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS secrets_collection_id_idx ON secrets (collection_id) WHERE collection_id IS NOT NULL;
+
+
+-- trusted contacts get access to the vault after a waiting period, vault key is sealed to the contact on the client
+CREATE TABLE IF NOT EXISTS emergency_access (
+    id UUID PRIMARY KEY,
+    grantor_id bigint NOT NULL,
+    grantee_id bigint NOT NULL,
+    access_type varchar(10) NOT NULL,
+    wait_days bigint NOT NULL,
+    status varchar(20) NOT NULL,
+    wrapped_key bytea,
+    key_version bigint NOT NULL DEFAULT 0,
+    recovery_initiated_at timestamp,
+    change_date timestamp DEFAULT current_timestamp,
+    UNIQUE(grantor_id, grantee_id),
+    FOREIGN KEY(grantor_id) REFERENCES users(id),
+    FOREIGN KEY(grantee_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS emergency_access_grantee_id_idx ON emergency_access (grantee_id);
+
+
+-- audit log of vault operations, every event is chained to the previous one by hash
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigint PRIMARY KEY,
+    user_id bigint NOT NULL DEFAULT 0,
+    login varchar(100) NOT NULL DEFAULT '',
+    method varchar(50) NOT NULL,
+    action varchar(20) NOT NULL,
+    target text NOT NULL DEFAULT '',
+    outcome varchar(30) NOT NULL,
+    ip varchar(100) NOT NULL DEFAULT '',
+    device text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL,
+    prev_hash bytea,
+    hash bytea NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON audit_log (user_id, id);
+
+
+-- token buckets and failed logins of the login rate limiter shared by server replicas
+CREATE TABLE IF NOT EXISTS rate_buckets (
+    key varchar(200) PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS login_failures (
+    login varchar(100) PRIMARY KEY,
+    failures int NOT NULL DEFAULT 0,
+    locked_until timestamp
+);
+
+
+-- vault key wrapped with the offline recovery key and hash of the verifier of the recovery key
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_key bytea;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_hash bytea;
+
+
+-- tokens carry the version they were issued for, changing the password moves it and revokes older tokens
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version bigint NOT NULL DEFAULT 0;
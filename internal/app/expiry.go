@@ -0,0 +1,39 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+)
+
+const (
+	// ExpiryLayout is the format of expiry date of a secret.
+	ExpiryLayout = "2006-01-02"
+	// DefaultExpiryDays is the window used to list expiring secrets if none is given.
+	DefaultExpiryDays = 30
+	// ChangeDateLayout is the format of change date of a secret, also used as time of the last sync.
+	ChangeDateLayout = "2006-01-02 15:04:05"
+)
+
+// ExpiryFromData gets expiry date of a secret: either explicitly set one or the one of the bank card.
+// Returns nil if secret does not expire.
+func ExpiryFromData(d *pb.Data) (*time.Time, error) {
+	if d.GetExpiresAt() != "" {
+		t, err := time.Parse(ExpiryLayout, d.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("expiry date must be in format %s: %v", ExpiryLayout, err)
+		}
+		return &t, nil
+	}
+
+	if card := d.GetData().GetBankCard(); card != nil && card.Expiry != "" {
+		t, err := bankcard.ExpiryDate(card.Expiry)
+		if err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+	return nil, nil
+}
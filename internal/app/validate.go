@@ -0,0 +1,19 @@
+package app
+
+import (
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+	"github.com/maffka123/GophKeeper/internal/urlmatch"
+)
+
+// Validate checks a secret before it is saved: bank card is normalized, expiry date and uris of the login must be valid.
+// Server runs it on every insert and sync, importer runs it to mark entries that the server would reject.
+func Validate(d *pb.Data) error {
+	if err := bankcard.Prepare(d.GetData().GetBankCard()); err != nil {
+		return err
+	}
+	if _, err := ExpiryFromData(d); err != nil {
+		return err
+	}
+	return urlmatch.Validate(d.GetData().GetAuthData())
+}
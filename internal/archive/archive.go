@@ -0,0 +1,176 @@
+// Package archive writes the whole vault to a portable file and reads it back.
+//
+// Archive is a json envelope: format name, version, key derivation parameters and nonce in clear,
+// and the vault as protobuf encrypted with AES-256-GCM under a key derived from the passphrase
+// with argon2id. The envelope header is authenticated too, so parameters cannot be tampered with.
+//
+// The vault holds secrets with their names, metadata and binary data. Folders are "/" separated prefixes of names,
+// so they need no own records. The server keeps no history of secrets, archive has only their current versions;
+// history would need a new version of the archive.
+package archive
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"golang.org/x/crypto/argon2"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Format names the file type in the envelope
+	Format = "gophkeeper-archive"
+	// Version of the archive written by Seal, Open reads all versions up to it
+	Version = 1
+	// MinPassphrase is the shortest passphrase archive can be sealed with
+	MinPassphrase = 8
+)
+
+// default argon2id parameters, recommended by RFC 9106 for memory constrained environments
+const (
+	kdfTime    = 3
+	kdfMemory  = 64 * 1024
+	kdfThreads = 4
+	keyLen     = 32
+	saltLen    = 16
+	// limits for parameters read from archive, so that a crafted file cannot exhaust memory
+	maxTime   = 16
+	maxMemory = 1024 * 1024
+)
+
+var (
+	ErrFormat     = errors.New("file is not a gophkeeper archive")
+	ErrVersion    = errors.New("archive version is not supported")
+	ErrPassphrase = errors.New("passphrase is wrong or archive is damaged")
+)
+
+// KDF is key derivation function and its parameters
+type KDF struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// header is the clear part of the envelope
+type header struct {
+	Format  string `json:"format"`
+	Version int64  `json:"version"`
+	KDF     KDF    `json:"kdf"`
+	Cipher  string `json:"cipher"`
+	Nonce   []byte `json:"nonce"`
+}
+
+type envelope struct {
+	header
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (k KDF) key(passphrase string) ([]byte, error) {
+	if k.Name != "argon2id" || k.Time == 0 || k.Time > maxTime || k.Memory == 0 || k.Memory > maxMemory ||
+		k.Threads == 0 || len(k.Salt) < saltLen {
+		return nil, fmt.Errorf("key derivation %s is not supported", k.Name)
+	}
+	return argon2.IDKey([]byte(passphrase), k.Salt, k.Time, k.Memory, k.Threads, keyLen), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// New puts data to archive of the current version, ids of data are kept so restore can tell entries apart
+func New(data []*pb.Data) *pb.VaultArchive {
+	return &pb.VaultArchive{
+		Version:   Version,
+		CreatedAt: time.Now().Format(app.ChangeDateLayout),
+		Data:      data,
+	}
+}
+
+// Seal encrypts archive with the passphrase
+func Seal(a *pb.VaultArchive, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphrase {
+		return nil, fmt.Errorf("passphrase must have at least %d characters", MinPassphrase)
+	}
+	plain, err := proto.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("archive cannot be encoded: %v", err)
+	}
+
+	h := header{
+		Format:  Format,
+		Version: Version,
+		KDF:     KDF{Name: "argon2id", Salt: make([]byte, saltLen), Time: kdfTime, Memory: kdfMemory, Threads: kdfThreads},
+		Cipher:  "aes-256-gcm",
+	}
+	if _, err := rand.Read(h.KDF.Salt); err != nil {
+		return nil, fmt.Errorf("random generator failed: %v", err)
+	}
+	key, err := h.KDF.key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(h.Nonce); err != nil {
+		return nil, fmt.Errorf("random generator failed: %v", err)
+	}
+
+	ad, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{header: h, Ciphertext: aead.Seal(nil, h.Nonce, plain, ad)})
+}
+
+// Open decrypts archive written by Seal
+func Open(file []byte, passphrase string) (*pb.VaultArchive, error) {
+	var e envelope
+	if err := json.Unmarshal(file, &e); err != nil || e.Format != Format {
+		return nil, ErrFormat
+	}
+	if e.Version < 1 || e.Version > Version || e.Cipher != "aes-256-gcm" {
+		return nil, ErrVersion
+	}
+
+	key, err := e.KDF.key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, ErrFormat
+	}
+	ad, err := json.Marshal(e.header)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, e.Nonce, e.Ciphertext, ad)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+
+	var a pb.VaultArchive
+	if err := proto.Unmarshal(plain, &a); err != nil {
+		return nil, fmt.Errorf("archive cannot be decoded: %v", err)
+	}
+	return &a, nil
+}
@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func vault() *pb.VaultArchive {
+	return New([]*pb.Data{
+		{ID: "1", Metadata: "mail", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice", Password: "pw",
+			URIs: []*pb.LoginURI{{URI: "mail.example.com"}}}}},
+		{ID: "2", Metadata: "photo", Data: &pb.KeepData{Binary: []byte{0, 1, 2, 255}}},
+		{ID: "3", ExpiresAt: "2027-10-31", Data: &pb.KeepData{BankCard: &pb.BankCard{CardNumber: "4111111111111111", Expiry: "10/27"}}},
+	})
+}
+
+func TestSealOpen(t *testing.T) {
+	a := vault()
+	file, err := Seal(a, "correct horse")
+	require.NoError(t, err)
+	assert.NotContains(t, string(file), "alice")
+
+	got, err := Open(file, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(a, got))
+
+	_, err = Open(file, "wrong horse!")
+	assert.ErrorIs(t, err, ErrPassphrase)
+
+	// header is authenticated
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(file, &e))
+	e["kdf"].(map[string]interface{})["time"] = 4
+	tampered, _ := json.Marshal(e)
+	_, err = Open(tampered, "correct horse")
+	assert.ErrorIs(t, err, ErrPassphrase)
+
+	e["version"] = Version + 1
+	tampered, _ = json.Marshal(e)
+	_, err = Open(tampered, "correct horse")
+	assert.ErrorIs(t, err, ErrVersion)
+
+	_, err = Open([]byte("name,url,username,password"), "correct horse")
+	assert.ErrorIs(t, err, ErrFormat)
+
+	_, err = Seal(a, "short")
+	assert.Error(t, err)
+}
+
+func TestPlaintext(t *testing.T) {
+	out, err := CSV(vault())
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "login,mail,,,alice,pw,mail.example.com,,,,,,,,", lines[1])
+	assert.Equal(t, "binary,photo,,,,,,,AAEC/w==,,,,,,", lines[2])
+	assert.Equal(t, "card,,2027-10-31,,,,,,,,4111111111111111,10/27,,,", lines[3])
+
+	out, err = JSON(vault())
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"alice"`)
+}
@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ConfirmPlaintext must be passed to export the vault unencrypted
+const ConfirmPlaintext = "plaintext"
+
+// csvHeader is the header of plaintext csv export
+var csvHeader = []string{"type", "metadata", "expires_at", "change_date", "login", "password", "uris",
+	"text", "binary", "holder_name", "card_number", "expiry", "brand", "bank_name", "address"}
+
+// JSON writes archive as plain json
+func JSON(a *pb.VaultArchive) ([]byte, error) {
+	return protojson.MarshalOptions{Multiline: true}.Marshal(a)
+}
+
+// CSV writes archive as plain csv, one row per secret, binary data is base64 encoded
+func CSV(a *pb.VaultArchive) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, d := range a.Data {
+		kd := d.GetData()
+		auth, card := kd.GetAuthData(), kd.GetBankCard()
+		var uris []string
+		for _, u := range auth.GetURIs() {
+			uris = append(uris, u.URI)
+		}
+
+		kind := "text"
+		switch {
+		case auth != nil:
+			kind = "login"
+		case card != nil:
+			kind = "card"
+		case len(kd.GetBinary()) > 0:
+			kind = "binary"
+		}
+
+		var binary string
+		if len(kd.GetBinary()) > 0 {
+			binary = base64.StdEncoding.EncodeToString(kd.GetBinary())
+		}
+		err := w.Write([]string{kind, d.Metadata, d.ExpiresAt, d.ChangeDate,
+			auth.GetLogin(), auth.GetPassword(), strings.Join(uris, " "),
+			kd.GetText(), binary,
+			card.GetHolderName(), card.GetCardNumber(), card.GetExpiry(), card.GetBrand(), card.GetBankName(), card.GetAddress()})
+		if err != nil {
+			return nil, fmt.Errorf("csv cannot be written: %v", err)
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,154 @@
+// Package audit keeps tamper-evident trail of vault operations.
+//
+// Every event is chained to the previous one: its hash covers all its fields and the hash of the previous event,
+// so a changed, removed or reordered event breaks the chain from that point on. The chain does not stop someone
+// who can write to the db from rewriting all of it, so hash of the last event should be saved outside from time to time,
+// verification checks the chain still leads to it.
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// Actions of events
+const (
+	ActionRegister    = "register"
+	ActionLogin       = "login"
+	ActionLoginFailed = "login_failed"
+	ActionRead        = "read"
+	ActionInsert      = "insert"
+	ActionUpdate      = "update"
+	ActionDelete      = "delete"
+	ActionShare       = "share"
+	ActionOrg         = "org"
+	ActionEmergency   = "emergency"
+	ActionUnlock      = "unlock"
+	ActionRecover     = "recover"
+)
+
+// TimeLayout is the layout of CreatedAt, time is in UTC with microseconds as postgres keeps it
+const TimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// actions are kinds of audited rpcs, listings of keys, organisations and other metadata are not audited
+var actions = map[string]string{
+	"Register":                ActionRegister,
+	"Login":                   ActionLogin,
+	"GetData":                 ActionRead,
+	"GetAllDataForUser":       ActionRead,
+	"ListExpiring":            ActionRead,
+	"ListShares":              ActionRead,
+	"GetEmergencyData":        ActionRead,
+	"Insert":                  ActionInsert,
+	"InsertBatch":             ActionInsert,
+	"InsertSyncData":          ActionInsert,
+	"Update":                  ActionUpdate,
+	"SetVaultKey":             ActionUpdate,
+	"RotateVaultKey":          ActionUpdate,
+	"SetKeyPair":              ActionUpdate,
+	"UpdateShare":             ActionUpdate,
+	"Delete":                  ActionDelete,
+	"ShareSecret":             ActionShare,
+	"RevokeShare":             ActionShare,
+	"CreateOrg":               ActionOrg,
+	"DeleteOrg":               ActionOrg,
+	"InviteMember":            ActionOrg,
+	"AnswerInvitation":        ActionOrg,
+	"UpdateMember":            ActionOrg,
+	"RemoveMember":            ActionOrg,
+	"CreateCollection":        ActionOrg,
+	"DeleteCollection":        ActionOrg,
+	"InviteEmergencyContact":  ActionEmergency,
+	"AcceptEmergencyAccess":   ActionEmergency,
+	"ConfirmEmergencyAccess":  ActionEmergency,
+	"InitiateEmergencyAccess": ActionEmergency,
+	"AnswerEmergencyAccess":   ActionEmergency,
+	"TakeoverEmergencyAccess": ActionEmergency,
+	"RemoveEmergencyAccess":   ActionEmergency,
+	"UnlockAccount":           ActionUnlock,
+	"ChangePassword":          ActionUpdate,
+	"DeleteAccount":           ActionDelete,
+	"SetRecoveryKey":          ActionUpdate,
+	"GetRecoveryKey":          ActionRecover,
+	"RecoverAccount":          ActionRecover,
+}
+
+// Method returns short name of the rpc from its full name like /proto.GophKeeper/Login
+func Method(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+// Action returns action of the rpc, false if the rpc is not audited
+func Action(method string) (string, bool) {
+	a, ok := actions[method]
+	return a, ok
+}
+
+// ValidAction reports whether events can have the action
+func ValidAction(action string) bool {
+	if action == ActionLoginFailed {
+		return true
+	}
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash computes hash of the event chained to hash of the previous event, every field is prefixed with its length,
+// so values cannot be shifted between fields
+func Hash(prev []byte, e *pb.AuditEvent) []byte {
+	h := sha256.New()
+	write := func(b []byte) {
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+	}
+	num := func(n int64) []byte {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(n))
+		return b[:]
+	}
+	write(prev)
+	write(num(e.ID))
+	write(num(e.UserID))
+	for _, s := range []string{e.Login, e.Method, e.Action, e.Target, e.Outcome, e.IP, e.Device, e.CreatedAt} {
+		write([]byte(s))
+	}
+	return h.Sum(nil)
+}
+
+// BrokenError tells which event breaks the chain and why
+type BrokenError struct {
+	ID     int64
+	Reason string
+}
+
+func (e *BrokenError) Error() string {
+	return fmt.Sprintf("audit log is broken at event %d: %s", e.ID, e.Reason)
+}
+
+// Verify checks that events ordered by id follow each other and the event before them, which has id prevID and hash prev,
+// nil prev and 0 prevID mean the beginning of the log
+func Verify(prevID int64, prev []byte, events []*pb.AuditEvent) error {
+	for _, e := range events {
+		switch {
+		case e.ID != prevID+1:
+			return &BrokenError{ID: e.ID, Reason: fmt.Sprintf("event %d is missing", prevID+1)}
+		case !bytes.Equal(e.PrevHash, prev):
+			return &BrokenError{ID: e.ID, Reason: "previous hash does not match"}
+		case !bytes.Equal(e.Hash, Hash(prev, e)):
+			return &BrokenError{ID: e.ID, Reason: "event was changed"}
+		}
+		prevID, prev = e.ID, e.Hash
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func chain(n int) []*pb.AuditEvent {
+	var out []*pb.AuditEvent
+	var prev []byte
+	for i := 1; i <= n; i++ {
+		e := &pb.AuditEvent{ID: int64(i), UserID: 11, Method: "GetData", Action: ActionRead, Target: "1", Outcome: "OK",
+			IP: "127.0.0.1", CreatedAt: "2022-10-01T10:00:00.000000Z", PrevHash: prev}
+		e.Hash = Hash(prev, e)
+		prev = e.Hash
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(events []*pb.AuditEvent) []*pb.AuditEvent
+		broken int64
+	}{
+		{name: "valid", change: func(events []*pb.AuditEvent) []*pb.AuditEvent { return events }},
+		{name: "changed", broken: 2, change: func(events []*pb.AuditEvent) []*pb.AuditEvent {
+			events[1].Target = "2"
+			return events
+		}},
+		{name: "shifted field", broken: 2, change: func(events []*pb.AuditEvent) []*pb.AuditEvent {
+			events[1].Target, events[1].Outcome = "1O", "K"
+			return events
+		}},
+		{name: "removed", broken: 3, change: func(events []*pb.AuditEvent) []*pb.AuditEvent {
+			return append(events[:1], events[2:]...)
+		}},
+		{name: "rehashed", broken: 3, change: func(events []*pb.AuditEvent) []*pb.AuditEvent {
+			events[1].Target = "2"
+			events[1].Hash = Hash(events[1].PrevHash, events[1])
+			return events
+		}},
+		{name: "reordered", broken: 2, change: func(events []*pb.AuditEvent) []*pb.AuditEvent {
+			events[1].ID, events[2].ID = 3, 2
+			events[1], events[2] = events[2], events[1]
+			return events
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(0, nil, tt.change(chain(3)))
+			if tt.broken == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var broken *BrokenError
+			if assert.True(t, errors.As(err, &broken)) {
+				assert.Equal(t, tt.broken, broken.ID)
+			}
+		})
+	}
+}
+
+func TestVerify_continued(t *testing.T) {
+	events := chain(4)
+	assert.NoError(t, Verify(2, events[1].Hash, events[2:]))
+	assert.Error(t, Verify(2, events[0].Hash, events[2:]))
+}
+
+func TestAction(t *testing.T) {
+	a, ok := Action(Method("/proto.GophKeeper/ShareSecret"))
+	assert.True(t, ok)
+	assert.Equal(t, ActionShare, a)
+
+	_, ok = Action(Method("/proto.GophKeeper/GetPublicKey"))
+	assert.False(t, ok)
+
+	assert.True(t, ValidAction(ActionLoginFailed))
+	assert.False(t, ValidAction("everything"))
+}
@@ -0,0 +1,185 @@
+// Package bankcard validates bank cards before they get to the storage and masks them on the way out.
+package bankcard
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// Card brands detected from the PAN.
+const (
+	BrandVisa       = "visa"
+	BrandMastercard = "mastercard"
+	BrandAmex       = "amex"
+	BrandDiscover   = "discover"
+	BrandJCB        = "jcb"
+	BrandDiners     = "diners"
+	BrandUnionPay   = "unionpay"
+	BrandMaestro    = "maestro"
+	BrandMir        = "mir"
+	BrandUnknown    = "unknown"
+)
+
+var (
+	ErrCardNumber = errors.New("card number is not valid")
+	ErrExpiry     = errors.New("expiry must be in format MM/YY")
+)
+
+// brandRange describes a range of PAN prefixes that belongs to a brand.
+type brandRange struct {
+	brand   string
+	from    int
+	to      int
+	lengths []int
+}
+
+// brandRanges are checked in order, more specific ranges go first.
+var brandRanges = []brandRange{
+	{BrandAmex, 34, 34, []int{15}},
+	{BrandAmex, 37, 37, []int{15}},
+	{BrandDiners, 300, 305, []int{14, 16, 17, 18, 19}},
+	{BrandDiners, 36, 36, []int{14, 16, 17, 18, 19}},
+	{BrandDiners, 38, 39, []int{14, 16, 17, 18, 19}},
+	{BrandMir, 2200, 2204, []int{16, 17, 18, 19}},
+	{BrandMastercard, 2221, 2720, []int{16}},
+	{BrandMastercard, 51, 55, []int{16}},
+	{BrandDiscover, 6011, 6011, []int{16, 17, 18, 19}},
+	{BrandDiscover, 644, 649, []int{16, 17, 18, 19}},
+	{BrandDiscover, 65, 65, []int{16, 17, 18, 19}},
+	{BrandUnionPay, 62, 62, []int{16, 17, 18, 19}},
+	{BrandJCB, 3528, 3589, []int{16, 17, 18, 19}},
+	{BrandVisa, 4, 4, []int{13, 16, 19}},
+	{BrandMaestro, 50, 50, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{BrandMaestro, 56, 69, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+}
+
+// Normalize removes spaces and dashes people usually type in card numbers.
+func Normalize(pan string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(pan)
+}
+
+// Luhn checks the card number checksum.
+func Luhn(pan string) bool {
+	if pan == "" {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(pan) - 1; i >= 0; i-- {
+		c := pan[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// DetectBrand finds brand of the card by its prefix and length.
+func DetectBrand(pan string) string {
+	for _, r := range brandRanges {
+		size := len(strconv.Itoa(r.from))
+		if len(pan) < size {
+			continue
+		}
+		prefix, err := strconv.Atoi(pan[:size])
+		if err != nil {
+			return BrandUnknown
+		}
+		if prefix < r.from || prefix > r.to {
+			continue
+		}
+		for _, l := range r.lengths {
+			if l == len(pan) {
+				return r.brand
+			}
+		}
+	}
+	return BrandUnknown
+}
+
+// ValidateNumber checks that the card number consists of 12-19 digits and passes Luhn check.
+func ValidateNumber(pan string) error {
+	if len(pan) < 12 || len(pan) > 19 || !Luhn(pan) {
+		return ErrCardNumber
+	}
+	return nil
+}
+
+// ValidateExpiry checks that expiry has format MM/YY.
+func ValidateExpiry(expiry string) error {
+	if len(expiry) != 5 || expiry[2] != '/' {
+		return ErrExpiry
+	}
+	month, err := strconv.Atoi(expiry[:2])
+	if err != nil || month < 1 || month > 12 {
+		return ErrExpiry
+	}
+	if _, err := strconv.ParseUint(expiry[3:], 10, 8); err != nil {
+		return ErrExpiry
+	}
+	return nil
+}
+
+// ExpiryDate converts MM/YY expiry to the last day the card is valid.
+func ExpiryDate(expiry string) (time.Time, error) {
+	if err := ValidateExpiry(expiry); err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse("01/06", expiry)
+	if err != nil {
+		return time.Time{}, ErrExpiry
+	}
+	return t.AddDate(0, 1, -1), nil
+}
+
+// Prepare normalizes and validates a card before insert and fills in the brand.
+// Expiry is optional, security codes are not kept at all.
+func Prepare(card *pb.BankCard) error {
+	if card == nil {
+		return nil
+	}
+	card.CardNumber = Normalize(card.CardNumber)
+	if err := ValidateNumber(card.CardNumber); err != nil {
+		return err
+	}
+	card.Brand = DetectBrand(card.CardNumber)
+
+	if card.Expiry != "" {
+		if err := ValidateExpiry(card.Expiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaskNumber hides all digits of the card number except the last 4.
+func MaskNumber(pan string) string {
+	if len(pan) <= 4 {
+		return strings.Repeat("*", len(pan))
+	}
+	return strings.Repeat("*", len(pan)-4) + pan[len(pan)-4:]
+}
+
+// Mask hides card numbers of all cards in the data.
+func Mask(data []*pb.Data) {
+	for _, d := range data {
+		card := d.GetData().GetBankCard()
+		if card == nil {
+			continue
+		}
+		card.CardNumber = MaskNumber(card.CardNumber)
+	}
+}
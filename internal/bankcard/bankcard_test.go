@@ -0,0 +1,138 @@
+package bankcard
+
+import (
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		name string
+		pan  string
+		want bool
+	}{
+		{name: "visa", pan: "4111111111111111", want: true},
+		{name: "amex", pan: "378282246310005", want: true},
+		{name: "leading zero", pan: "0004111111111111111", want: true},
+		{name: "wrong checksum", pan: "4111111111111112", want: false},
+		{name: "letters", pan: "41111111111a1111", want: false},
+		{name: "empty", pan: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Luhn(tt.pan))
+		})
+	}
+}
+
+func TestDetectBrand(t *testing.T) {
+	tests := []struct {
+		pan  string
+		want string
+	}{
+		{pan: "4111111111111111", want: BrandVisa},
+		{pan: "4222222222222", want: BrandVisa},
+		{pan: "5555555555554444", want: BrandMastercard},
+		{pan: "2223003122003222", want: BrandMastercard},
+		{pan: "378282246310005", want: BrandAmex},
+		{pan: "6011111111111117", want: BrandDiscover},
+		{pan: "3566002020360505", want: BrandJCB},
+		{pan: "30569309025904", want: BrandDiners},
+		{pan: "6200000000000005", want: BrandUnionPay},
+		{pan: "2200000000000004", want: BrandMir},
+		{pan: "6759649826438453", want: BrandMaestro},
+		{pan: "9999999999999995", want: BrandUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want+"_"+tt.pan, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectBrand(tt.pan))
+		})
+	}
+}
+
+func TestValidateExpiry(t *testing.T) {
+	tests := []struct {
+		expiry  string
+		wantErr bool
+	}{
+		{expiry: "01/25", wantErr: false},
+		{expiry: "12/99", wantErr: false},
+		{expiry: "13/25", wantErr: true},
+		{expiry: "00/25", wantErr: true},
+		{expiry: "1/25", wantErr: true},
+		{expiry: "01-25", wantErr: true},
+		{expiry: "01/2025", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expiry, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, ValidateExpiry(tt.expiry) != nil)
+		})
+	}
+}
+
+func TestExpiryDate(t *testing.T) {
+	tests := []struct {
+		expiry string
+		want   string
+	}{
+		{expiry: "01/25", want: "2025-01-31"},
+		{expiry: "02/24", want: "2024-02-29"},
+		{expiry: "12/30", want: "2030-12-31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expiry, func(t *testing.T) {
+			got, err := ExpiryDate(tt.expiry)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Format("2006-01-02"))
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		name      string
+		card      *pb.BankCard
+		wantErr   bool
+		wantPAN   string
+		wantBrand string
+	}{
+		{name: "spaces removed",
+			card:    &pb.BankCard{CardNumber: "4111 1111 1111 1111", Expiry: "10/27"},
+			wantPAN: "4111111111111111", wantBrand: BrandVisa,
+		},
+		{name: "amex",
+			card:    &pb.BankCard{CardNumber: "3782 822463 10005"},
+			wantPAN: "378282246310005", wantBrand: BrandAmex,
+		},
+		{name: "bad expiry",
+			card:    &pb.BankCard{CardNumber: "4111111111111111", Expiry: "2027-10"},
+			wantErr: true,
+		},
+		{name: "bad number",
+			card:    &pb.BankCard{CardNumber: "123456789"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Prepare(tt.card)
+			assert.Equal(t, tt.wantErr, err != nil)
+			if !tt.wantErr {
+				assert.Equal(t, tt.wantPAN, tt.card.CardNumber)
+				assert.Equal(t, tt.wantBrand, tt.card.Brand)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	data := []*pb.Data{
+		{Data: &pb.KeepData{BankCard: &pb.BankCard{CardNumber: "4111111111111111"}}},
+		{Data: &pb.KeepData{Text: "no card"}},
+	}
+	Mask(data)
+	assert.Equal(t, "************1111", data[0].Data.BankCard.CardNumber)
+	assert.Equal(t, "no card", data[1].Data.Text)
+}
@@ -0,0 +1,65 @@
+// Package breach checks passwords against a local copy of Pwned Passwords, nothing is sent over the network.
+//
+// Supported corpora:
+//   - a file with one upper case SHA-1 hash per line as HASH:COUNT, ordered by hash
+//   - a directory of range files named by the first 5 hex characters of the hash, every line is SUFFIX:COUNT
+//   - a binary fuse filter built from any of the above with cmd/breachfilter
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Checker tells if password was found in a breach
+type Checker interface {
+	Compromised(password string) (bool, error)
+	Close() error
+}
+
+// Hash returns upper case hex SHA-1 of the password as used by Pwned Passwords
+func Hash(password string) string {
+	h := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(h[:]))
+}
+
+// Key converts hex SHA-1 to the key used in the filter
+func Key(hash string) (uint64, error) {
+	if len(hash) < 16 {
+		return 0, fmt.Errorf("hash is too short: %s", hash)
+	}
+	b, err := hex.DecodeString(hash[:16])
+	if err != nil {
+		return 0, fmt.Errorf("hash is not hex: %v", err)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// Open detects type of the corpus at path and opens it
+func Open(path string) (Checker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breach corpus: %v", err)
+	}
+	if info.IsDir() {
+		return NewRangeDir(path), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breach corpus: %v", err)
+	}
+	magic := make([]byte, len(filterMagic))
+	_, err = io.ReadFull(f, magic)
+	f.Close()
+	if err == nil && bytes.Equal(magic, []byte(filterMagic)) {
+		return LoadFilter(path)
+	}
+	return OpenHashFile(path)
+}
@@ -0,0 +1,112 @@
+package breach
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var breached = []string{"password", "123456", "qwerty", "letmein", "monkey", "dragon"}
+
+// writeCorpus writes hashes of breached passwords and some random ones as sorted hash file and range dir
+func writeCorpus(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	hashes := []string{}
+	for _, p := range breached {
+		hashes = append(hashes, Hash(p))
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		hashes = append(hashes, Hash(string(rune(r.Intn(1<<20)))+"random"))
+	}
+	sort.Strings(hashes)
+
+	var file strings.Builder
+	ranges := map[string][]string{}
+	for _, h := range hashes {
+		file.WriteString(h + ":42\r\n")
+		ranges[h[:5]] = append(ranges[h[:5]], h[5:]+":42")
+	}
+	path := filepath.Join(dir, "hashes.txt")
+	require.NoError(t, os.WriteFile(path, []byte(file.String()), 0600))
+
+	rangeDir := filepath.Join(dir, "ranges")
+	require.NoError(t, os.Mkdir(rangeDir, 0700))
+	for prefix, lines := range ranges {
+		require.NoError(t, os.WriteFile(filepath.Join(rangeDir, prefix+".txt"), []byte(strings.Join(lines, "\n")), 0600))
+	}
+	return path, rangeDir
+}
+
+func TestCheckers(t *testing.T) {
+	path, rangeDir := writeCorpus(t)
+
+	var keys []uint64
+	require.NoError(t, ReadHashes(rangeDir, func(hash string) error {
+		k, err := Key(hash)
+		keys = append(keys, k)
+		return err
+	}))
+	assert.Len(t, keys, 1006)
+	filter, err := BuildFilter(keys)
+	require.NoError(t, err)
+	filterPath := filepath.Join(t.TempDir(), "filter")
+	fw, err := os.Create(filterPath)
+	require.NoError(t, err)
+	_, err = filter.WriteTo(fw)
+	require.NoError(t, err)
+	fw.Close()
+
+	for _, p := range []string{path, rangeDir, filterPath} {
+		t.Run(filepath.Base(p), func(t *testing.T) {
+			c, err := Open(p)
+			require.NoError(t, err)
+			defer c.Close()
+
+			for _, b := range breached {
+				got, err := c.Compromised(b)
+				assert.NoError(t, err)
+				assert.True(t, got, b)
+			}
+			for _, ok := range []string{"correct-horse-battery-staple", "v8#Lq2!mZp9@wR4t", "zzzzzzzzzzzzzzzzzz"} {
+				got, err := c.Compromised(ok)
+				assert.NoError(t, err)
+				assert.False(t, got, ok)
+			}
+		})
+	}
+}
+
+func TestBuildFilter(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	keys := make([]uint64, 100000)
+	for i := range keys {
+		keys[i] = r.Uint64()
+	}
+	keys = append(keys, keys[:10]...)
+	in := append([]uint64{}, keys...)
+
+	f, err := BuildFilter(in)
+	require.NoError(t, err)
+	for _, k := range keys {
+		assert.True(t, f.Contains(k))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 100000; i++ {
+		if f.Contains(r.Uint64()) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 10)
+
+	empty, err := BuildFilter(nil)
+	require.NoError(t, err)
+	assert.False(t, empty.Contains(1))
+}
@@ -0,0 +1,294 @@
+package breach
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+)
+
+// filterMagic starts every serialized filter
+const filterMagic = "GKBFUSE1"
+
+// maxIterations limits attempts to find a seed that builds the filter
+const maxIterations = 100
+
+// Filter is a binary fuse filter with 16 bit fingerprints (Graf, Lemire: "Binary Fuse Filters:
+// Fast and Smaller Than Xor Filters"). It takes about 18 bits per hash and has false positive
+// rate of 1/65536, passwords that are not in the corpus are almost never reported.
+type Filter struct {
+	Seed               uint64
+	SegmentLength      uint32
+	SegmentLengthMask  uint32
+	SegmentCount       uint32
+	SegmentCountLength uint32
+	Fingerprints       []uint16
+}
+
+func murmur64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func splitmix64(seed *uint64) uint64 {
+	*seed += 0x9E3779B97F4A7C15
+	z := *seed
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+func mixsplit(key, seed uint64) uint64 {
+	return murmur64(key + seed)
+}
+
+func fingerprint(hash uint64) uint16 {
+	return uint16(hash ^ (hash >> 32))
+}
+
+func (f *Filter) init(size uint32) {
+	const arity = 3
+	if size == 0 {
+		f.SegmentLength = 4
+	} else {
+		f.SegmentLength = uint32(1) << int(math.Floor(math.Log(float64(size))/math.Log(3.33)+2.25))
+	}
+	if f.SegmentLength > 262144 {
+		f.SegmentLength = 262144
+	}
+	f.SegmentLengthMask = f.SegmentLength - 1
+
+	sizeFactor := 1.125
+	if size > 1 {
+		sizeFactor = math.Max(1.125, 0.875+0.25*math.Log(1000000)/math.Log(float64(size)))
+	}
+	capacity := uint32(0)
+	if size > 1 {
+		capacity = uint32(math.Round(float64(size) * sizeFactor))
+	}
+	initSegmentCount := (capacity+f.SegmentLength-1)/f.SegmentLength - (arity - 1)
+	arrayLength := (initSegmentCount + arity - 1) * f.SegmentLength
+	f.SegmentCount = (arrayLength + f.SegmentLength - 1) / f.SegmentLength
+	if f.SegmentCount <= arity-1 {
+		f.SegmentCount = 1
+	} else {
+		f.SegmentCount -= arity - 1
+	}
+	arrayLength = (f.SegmentCount + arity - 1) * f.SegmentLength
+	f.SegmentCountLength = f.SegmentCount * f.SegmentLength
+	f.Fingerprints = make([]uint16, arrayLength)
+}
+
+func (f *Filter) positions(hash uint64) (uint32, uint32, uint32) {
+	hi, _ := bits.Mul64(hash, uint64(f.SegmentCountLength))
+	h0 := uint32(hi)
+	h1 := h0 + f.SegmentLength
+	h2 := h1 + f.SegmentLength
+	h1 ^= uint32(hash>>18) & f.SegmentLengthMask
+	h2 ^= uint32(hash) & f.SegmentLengthMask
+	return h0, h1, h2
+}
+
+func mod3(x uint8) uint8 {
+	if x > 2 {
+		x -= 3
+	}
+	return x
+}
+
+// BuildFilter builds filter from the keys, duplicates are removed
+func BuildFilter(keys []uint64) (*Filter, error) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	unique := keys[:0]
+	for i, k := range keys {
+		if i == 0 || k != keys[i-1] {
+			unique = append(unique, k)
+		}
+	}
+	keys = unique
+
+	size := uint32(len(keys))
+	f := &Filter{}
+	f.init(size)
+	if size == 0 {
+		return f, nil
+	}
+
+	capacity := uint32(len(f.Fingerprints))
+	alone := make([]uint32, capacity)
+	// lowest 2 bits keep xor of positions (0, 1, 2) of the keys in the cell, the rest is the count
+	t2count := make([]uint8, capacity)
+	t2hash := make([]uint64, capacity)
+	reverseH := make([]uint8, size)
+	reverseOrder := make([]uint64, size)
+	var h012 [5]uint32
+
+	rng := uint64(1)
+	for iteration := 0; ; iteration++ {
+		if iteration >= maxIterations {
+			return nil, errors.New("filter cannot be built, too many iterations")
+		}
+		f.Seed = splitmix64(&rng)
+		for i := range t2count {
+			t2count[i] = 0
+			t2hash[i] = 0
+		}
+
+		overflow := false
+		for _, key := range keys {
+			hash := mixsplit(key, f.Seed)
+			h0, h1, h2 := f.positions(hash)
+			t2count[h0] += 4
+			t2hash[h0] ^= hash
+			t2count[h1] += 4
+			t2count[h1] ^= 1
+			t2hash[h1] ^= hash
+			t2count[h2] += 4
+			t2count[h2] ^= 2
+			t2hash[h2] ^= hash
+			if t2count[h0] < 4 || t2count[h1] < 4 || t2count[h2] < 4 {
+				overflow = true
+			}
+		}
+		if overflow {
+			continue
+		}
+
+		// peel cells that have exactly one key
+		qsize := 0
+		for i := uint32(0); i < capacity; i++ {
+			alone[qsize] = i
+			if t2count[i]>>2 == 1 {
+				qsize++
+			}
+		}
+		stacksize := uint32(0)
+		for qsize > 0 {
+			qsize--
+			index := alone[qsize]
+			if t2count[index]>>2 != 1 {
+				continue
+			}
+			hash := t2hash[index]
+			found := t2count[index] & 3
+			reverseH[stacksize] = found
+			reverseOrder[stacksize] = hash
+			stacksize++
+
+			h0, h1, h2 := f.positions(hash)
+			h012[1], h012[2], h012[3], h012[4] = h1, h2, h0, h1
+
+			other := h012[found+1]
+			alone[qsize] = other
+			if t2count[other]>>2 == 2 {
+				qsize++
+			}
+			t2count[other] -= 4
+			t2count[other] ^= mod3(found + 1)
+			t2hash[other] ^= hash
+
+			other = h012[found+2]
+			alone[qsize] = other
+			if t2count[other]>>2 == 2 {
+				qsize++
+			}
+			t2count[other] -= 4
+			t2count[other] ^= mod3(found + 2)
+			t2hash[other] ^= hash
+		}
+		if stacksize == size {
+			break
+		}
+	}
+
+	for i := int(size) - 1; i >= 0; i-- {
+		hash := reverseOrder[i]
+		h0, h1, h2 := f.positions(hash)
+		found := reverseH[i]
+		h012[0], h012[1], h012[2], h012[3], h012[4] = h0, h1, h2, h0, h1
+		f.Fingerprints[h012[found]] = fingerprint(hash) ^ f.Fingerprints[h012[found+1]] ^ f.Fingerprints[h012[found+2]]
+	}
+	return f, nil
+}
+
+// Contains checks if the key is probably in the filter
+func (f *Filter) Contains(key uint64) bool {
+	if len(f.Fingerprints) == 0 || f.SegmentCountLength == 0 {
+		return false
+	}
+	hash := mixsplit(key, f.Seed)
+	h0, h1, h2 := f.positions(hash)
+	return fingerprint(hash)^f.Fingerprints[h0]^f.Fingerprints[h1]^f.Fingerprints[h2] == 0
+}
+
+// Compromised checks if the password hash is probably in the filter
+func (f *Filter) Compromised(password string) (bool, error) {
+	key, err := Key(Hash(password))
+	if err != nil {
+		return false, err
+	}
+	return f.Contains(key), nil
+}
+
+// Close does nothing, filter is kept in memory
+func (f *Filter) Close() error {
+	return nil
+}
+
+// WriteTo serializes the filter
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	header := []interface{}{f.Seed, f.SegmentLength, f.SegmentLengthMask, f.SegmentCount, f.SegmentCountLength, uint64(len(f.Fingerprints))}
+	if _, err := bw.WriteString(filterMagic); err != nil {
+		return 0, err
+	}
+	for _, v := range header {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return 0, err
+		}
+	}
+	if err := binary.Write(bw, binary.LittleEndian, f.Fingerprints); err != nil {
+		return 0, err
+	}
+	return int64(len(filterMagic) + 8 + 4*4 + 8 + 2*len(f.Fingerprints)), bw.Flush()
+}
+
+// LoadFilter reads filter written by WriteTo
+func LoadFilter(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open filter: %v", err)
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+
+	magic := make([]byte, len(filterMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != filterMagic {
+		return nil, errors.New("file is not a breach filter")
+	}
+
+	f := &Filter{}
+	var length uint64
+	for _, v := range []interface{}{&f.Seed, &f.SegmentLength, &f.SegmentLengthMask, &f.SegmentCount, &f.SegmentCountLength, &length} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return nil, fmt.Errorf("cannot read filter header: %v", err)
+		}
+	}
+	if length != uint64((f.SegmentCount+2)*f.SegmentLength) {
+		return nil, errors.New("filter header is corrupted")
+	}
+	f.Fingerprints = make([]uint16, length)
+	if err := binary.Read(r, binary.LittleEndian, f.Fingerprints); err != nil {
+		return nil, fmt.Errorf("cannot read filter: %v", err)
+	}
+	return f, nil
+}
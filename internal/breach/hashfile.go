@@ -0,0 +1,175 @@
+package breach
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HashFile is a file of HASH:COUNT lines ordered by hash, it is searched with binary search
+// so that the whole corpus does not have to fit into memory
+type HashFile struct {
+	f    *os.File
+	size int64
+}
+
+// OpenHashFile opens sorted hash file
+func OpenHashFile(path string) (*HashFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open hash file: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot open hash file: %v", err)
+	}
+	return &HashFile{f: f, size: info.Size()}, nil
+}
+
+// lineAt returns hash of the first line that starts at or after offset, empty string at the end of file
+func (h *HashFile) lineAt(offset int64) (string, error) {
+	start := offset
+	if offset > 0 {
+		// start one byte earlier, so a line that begins exactly at offset is not skipped
+		start--
+	}
+	r := bufio.NewReader(io.NewSectionReader(h.f, start, h.size-start))
+	if offset > 0 {
+		if _, err := r.ReadString('\n'); err != nil {
+			return "", nil
+		}
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash), nil
+}
+
+// Compromised checks if the password hash is in the file
+func (h *HashFile) Compromised(password string) (bool, error) {
+	hash := Hash(password)
+
+	// find the first line whose hash is not less than the one we look for
+	lo, hi := int64(0), h.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, err := h.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+		if line == "" || line >= hash {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	line, err := h.lineAt(lo)
+	if err != nil {
+		return false, err
+	}
+	return line == hash, nil
+}
+
+// Close closes the file
+func (h *HashFile) Close() error {
+	return h.f.Close()
+}
+
+// RangeDir is a directory of files named by 5 character hash prefix with SUFFIX:COUNT lines
+type RangeDir struct {
+	dir string
+}
+
+// NewRangeDir returns checker for the range directory
+func NewRangeDir(dir string) *RangeDir {
+	return &RangeDir{dir: dir}
+}
+
+// Compromised looks for the hash suffix in the file of its prefix
+func (d *RangeDir) Compromised(password string) (bool, error) {
+	hash := Hash(password)
+	prefix, suffix := hash[:5], hash[5:]
+
+	var f *os.File
+	var err error
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		f, err = os.Open(filepath.Join(d.dir, name))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot open range file: %v", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(s.Text()), ":")
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, s.Err()
+}
+
+// Close does nothing, range files are opened per check
+func (d *RangeDir) Close() error {
+	return nil
+}
+
+// ReadHashes calls fn for every hash of the corpus in file or range directory
+func ReadHashes(path string, fn func(hash string) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return readHashLines(path, "", fn)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		prefix := strings.ToUpper(strings.TrimSuffix(e.Name(), ".txt"))
+		if e.IsDir() || len(prefix) != 5 {
+			continue
+		}
+		if err := readHashLines(filepath.Join(path, e.Name()), prefix, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readHashLines(path string, prefix string, fn func(hash string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(s.Text()), ":")
+		if hash == "" {
+			continue
+		}
+		if err := fn(prefix + strings.ToUpper(hash)); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+const (
+	// ModeDaemon talks to the local client daemon over its http api, it keeps keys, local db and sync
+	ModeDaemon = "daemon"
+	// ModeDirect talks straight to the gRPC server, nothing is kept locally except the session
+	ModeDirect = "direct"
+)
+
+// ErrNotLoggedIn is returned when there is no session or the session is rejected
+var ErrNotLoggedIn = errors.New("not logged in, run gophkeeper login")
+
+// Status describes where the cli is connected, who is logged in and how the last sync of the daemon went
+type Status struct {
+	Mode      string `json:"mode"`
+	Address   string `json:"address"`
+	Login     string `json:"login,omitempty"`
+	Reachable bool   `json:"reachable"`
+	Locked    bool   `json:"locked"`
+	Syncing   bool   `json:"syncing"`
+	Offline   bool   `json:"offline"`
+	LastSync  string `json:"last_sync,omitempty"`
+	SyncError string `json:"sync_error,omitempty"`
+	// Revision grows every time the daemon pulls changes from the server
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// Backend runs commands of the cli either through the client daemon or directly on the server.
+// Secrets are addressed by name, it is their metadata
+type Backend interface {
+	Register(ctx context.Context, login, password string) (*pb.RecoveryKit, error)
+	Login(ctx context.Context, login, password string) error
+	Logout(ctx context.Context) error
+	// Find returns secrets whose name contains name, card numbers are masked unless reveal is set
+	Find(ctx context.Context, name string, reveal bool) ([]*pb.Data, error)
+	// Search runs full-text search
+	Search(ctx context.Context, query string) ([]*pb.Data, error)
+	List(ctx context.Context) ([]*pb.Data, error)
+	Add(ctx context.Context, d *pb.Data) (string, error)
+	// Update replaces the secret with id, so it can be renamed
+	Update(ctx context.Context, id string, d *pb.Data) error
+	// Delete deletes the secret with id
+	Delete(ctx context.Context, id string) ([]*pb.Data, error)
+	Generate(ctx context.Context, g *pb.GenerateRequest) (*pb.GenerateResp, error)
+	Sync(ctx context.Context) (string, error)
+	Status(ctx context.Context) (*Status, error)
+	Import(ctx context.Context, format string, file []byte, passphrase string, dryRun bool) (*pb.ImportReport, error)
+	Export(ctx context.Context, e *pb.ExportRequest) ([]byte, error)
+	Close() error
+}
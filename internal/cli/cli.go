@@ -0,0 +1,548 @@
+// Package cli is the gophkeeper command-line interface. It runs commands through the local client daemon
+// or directly on the gRPC server, secrets are addressed by their name (metadata)
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	clientcfg "github.com/maffka123/GophKeeper/internal/client/config"
+	"github.com/maffka123/GophKeeper/internal/clipboard"
+	"github.com/maffka123/GophKeeper/internal/field"
+	"github.com/maffka123/GophKeeper/internal/secretref"
+)
+
+// Config of the cli, first from flags, then from env, so that env overwrites flags
+type Config struct {
+	Mode       string        `env:"GK_MODE"`
+	Endpoint   string        `env:"APP_ADDRESS"`
+	Socket     string        `env:"APP_SOCKET"`
+	SecretFile string        `env:"API_SECRET_FILE"`
+	Server     string        `env:"SERVER_ADDRESS"`
+	Session    string        `env:"GK_SESSION"`
+	Output     string        `env:"GK_OUTPUT"`
+	Clipboard  string        `env:"GK_CLIPBOARD"`
+	ClearAfter time.Duration `env:"GK_CLEAR_AFTER"`
+}
+
+// command is a subcommand of the cli
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+// App runs the cli
+type App struct {
+	in       io.Reader
+	out      io.Writer
+	errOut   io.Writer
+	prompt   *Prompter
+	print    *printer
+	cfg      *Config
+	session  *Session
+	backend  Backend
+	commands map[string]command
+	// newBackend connects to the daemon or to the server according to the mode
+	newBackend func(cfg *Config, s *Session, p *Prompter) (Backend, error)
+	// clipboard returns clipboard of the kind, it is replaced in tests
+	clipboard func(kind string) (clipboard.Clipboard, error)
+}
+
+// New creates the cli reading answers from in, results are written to out and questions and errors to errOut
+func New(in io.Reader, out, errOut io.Writer) *App {
+	a := &App{in: in, out: out, errOut: errOut, newBackend: connect, clipboard: clipboard.Detect}
+	a.commands = map[string]command{
+		"register": {"register [-login LOGIN] [-kit text|pdf] [-kit-file FILE]  create account and print its recovery kit", a.register},
+		"login":    {"login [-login LOGIN]  log in and unlock the vault", a.login},
+		"logout":   {"logout  lock the vault and forget the session", a.logout},
+		"add":      {"add login|card|text|file NAME [flags]  add secret, run add -h for flags", a.add},
+		"get":      {"get NAME [-field FIELD]  show secret with all fields or only one of them", a.get},
+		"copy":     {"copy NAME [-field FIELD]  copy field to the clipboard, it is cleared after a while", a.copy},
+		"list":     {"list [-q QUERY]  list secrets, with full-text search by QUERY", a.list},
+		"edit":     {"edit NAME [flags]  change secret, asks for fields if no flags are given", a.edit},
+		"rm":       {"rm NAME [-yes]  delete secret", a.rm},
+		"generate": {"generate [flags]  generate password or passphrase, optionally save it as login", a.generate},
+		"sync":     {"sync  sync local copy of the daemon with the server now", a.sync},
+		"render":   {"render [-watch] [-reload CMD] [-perm MODE] TEMPLATE OUTPUT  write text/template with {{ secret NAME FIELD }} to the file", a.render},
+		"run":      {"run [-env-file FILE] [-env NAME=VALUE]... -- COMMAND [ARGS]  run command with secrets of " + secretref.Scheme + "NAME/FIELD references in its environment", a.run},
+		"status":   {"status  show mode, address and session", a.status},
+		"import":   {"import -format FORMAT [-dry-run] [-passphrase] FILE  import export of other password manager", a.importFile},
+		"export":   {"export [-format archive|json|csv] [-file FILE] [-plaintext]  export the vault", a.export},
+		"ui":       {"ui  full-screen terminal ui to browse, search and edit secrets", a.ui},
+	}
+	return a
+}
+
+// connect creates backend of the mode
+func connect(cfg *Config, s *Session, p *Prompter) (Backend, error) {
+	switch cfg.Mode {
+	case ModeDaemon:
+		return newDaemonBackend(cfg, s)
+	case ModeDirect:
+		return newDirectBackend(cfg, s, p.Secret)
+	}
+	return nil, fmt.Errorf("unknown mode %s, use %s or %s", cfg.Mode, ModeDaemon, ModeDirect)
+}
+
+// usage prints global flags and commands
+func (a *App) usage(fs *flag.FlagSet) {
+	fmt.Fprintln(a.errOut, "usage: gophkeeper [flags] COMMAND [args]\n\ncommands:")
+	names := make([]string, 0, len(a.commands))
+	for name := range a.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(a.errOut, "  "+a.commands[name].usage)
+	}
+	fmt.Fprintln(a.errOut, "\nflags:")
+	fs.PrintDefaults()
+}
+
+// parseConfig reads global flags before the command, the rest of args is returned
+func (a *App) parseConfig(args []string) (*Config, []string, error) {
+	cfg := &Config{}
+	fs := flag.NewFlagSet("gophkeeper", flag.ContinueOnError)
+	fs.SetOutput(a.errOut)
+	fs.StringVar(&cfg.Mode, "mode", ModeDaemon, "daemon talks to the local client daemon, direct to the gRPC server")
+	fs.StringVar(&cfg.Endpoint, "a", "127.0.0.1:8081", "address of the client daemon as host:port")
+	fs.StringVar(&cfg.Socket, "socket", "", "unix socket of the client daemon, used instead of the address")
+	fs.StringVar(&cfg.SecretFile, "secret-file", clientcfg.DefaultSecretFile(), "api secret file of the client daemon")
+	fs.StringVar(&cfg.Server, "s", "127.0.0.1:8082", "address of the gRPC server as host:port for direct mode")
+	fs.StringVar(&cfg.Session, "session", DefaultSessionFile(), "file the session is kept in")
+	fs.StringVar(&cfg.Output, "output", OutputTable, "output format: table or json")
+	fs.StringVar(&cfg.Clipboard, "clipboard", clipboard.KindAuto, "clipboard to copy to: auto, wayland, x11 or osc52 of the terminal")
+	fs.DurationVar(&cfg.ClearAfter, "clear-after", 45*time.Second, "clear the clipboard after this time if it still holds the copied value, 0 never clears it")
+	fs.Usage = func() { a.usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if err := env.Parse(cfg); err != nil {
+		return nil, nil, err
+	}
+	if cfg.Output != OutputTable && cfg.Output != OutputJSON {
+		return nil, nil, fmt.Errorf("unknown output %s, use %s or %s", cfg.Output, OutputTable, OutputJSON)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, nil, flag.ErrHelp
+	}
+	return cfg, fs.Args(), nil
+}
+
+// Run runs the command given in args
+func (a *App) Run(ctx context.Context, args []string) error {
+	cfg, args, err := a.parseConfig(args)
+	if err != nil {
+		return err
+	}
+	cmd, ok := a.commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %s, run gophkeeper -h", args[0])
+	}
+	a.cfg = cfg
+	a.print = &printer{w: a.out, format: cfg.Output}
+	a.prompt = NewPrompter(a.in, a.errOut)
+	if a.session, err = loadSession(cfg.Session, cfg.Mode); err != nil {
+		return fmt.Errorf("session cannot be read: %v", err)
+	}
+	if a.backend, err = a.newBackend(cfg, a.session, a.prompt); err != nil {
+		return err
+	}
+	defer a.backend.Close()
+	return cmd.run(ctx, args[1:])
+}
+
+// flags creates flag set of the command
+func (a *App) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("gophkeeper "+name, flag.ContinueOnError)
+	fs.SetOutput(a.errOut)
+	return fs
+}
+
+// parse parses flags of the command given before and after positional arguments, the latter are returned
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// nameArg returns the only positional argument or asks for it
+func (a *App) nameArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return a.prompt.Line("Name", "")
+	case 1:
+		return args[0], nil
+	}
+	return "", fmt.Errorf("only one name is expected, quote names with spaces")
+}
+
+// exact finds the secret named exactly as name
+func (a *App) exact(ctx context.Context, name string, reveal bool) (*pb.Data, error) {
+	return exact(ctx, a.backend, name, reveal)
+}
+
+// exact finds the secret named exactly as name with the backend
+func exact(ctx context.Context, b Backend, name string, reveal bool) (*pb.Data, error) {
+	found, err := b.Find(ctx, name, reveal)
+	if err != nil {
+		return nil, err
+	}
+	var exact []*pb.Data
+	var similar []string
+	for _, d := range found {
+		if d.Metadata == name {
+			exact = append(exact, d)
+		} else {
+			similar = append(similar, d.Metadata)
+		}
+	}
+	switch {
+	case len(exact) == 1:
+		return exact[0], nil
+	case len(exact) > 1:
+		return nil, fmt.Errorf("%d secrets are named %s, rename them first", len(exact), name)
+	case len(similar) > 0:
+		return nil, fmt.Errorf("no secret named %s, similar: %s", name, strings.Join(similar, ", "))
+	}
+	return nil, fmt.Errorf("no secret named %s", name)
+}
+
+func (a *App) register(ctx context.Context, args []string) error {
+	fs := a.flags("register")
+	login := fs.String("login", "", "login of the new account")
+	kitFormat := fs.String("kit", "text", "format of the recovery kit: text or pdf")
+	kitFile := fs.String("kit-file", "gophkeeper-recovery-kit.pdf", "file the pdf kit is written to")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *kitFormat != "text" && *kitFormat != "pdf" {
+		return fmt.Errorf("unknown kit format %s, use text or pdf", *kitFormat)
+	}
+	var err error
+	if *login == "" {
+		if *login, err = a.prompt.Line("Login", ""); err != nil {
+			return err
+		}
+	}
+	password, err := a.prompt.NewSecret("Password")
+	if err != nil {
+		return err
+	}
+	kit, err := a.backend.Register(ctx, *login, password)
+	if err != nil {
+		return err
+	}
+	return a.writeKit(kit, *kitFormat, *kitFile)
+}
+
+func (a *App) login(ctx context.Context, args []string) error {
+	fs := a.flags("login")
+	login := fs.String("login", "", "login, the one of the last session by default")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	var err error
+	if *login == "" {
+		if *login, err = a.prompt.Line("Login", a.session.Login); err != nil {
+			return err
+		}
+	}
+	password, err := a.prompt.Secret("Password")
+	if err != nil {
+		return err
+	}
+	if err = a.backend.Login(ctx, *login, password); err != nil {
+		return err
+	}
+	return a.print.message("logged in as %s", *login)
+}
+
+func (a *App) logout(ctx context.Context, args []string) error {
+	if err := a.backend.Logout(ctx); err != nil && !errors.Is(err, ErrNotLoggedIn) {
+		return err
+	}
+	return a.print.message("logged out")
+}
+
+func (a *App) get(ctx context.Context, args []string) error {
+	fs := a.flags("get")
+	name := fs.String("field", "", "print only this field: login, password, url, number, expiry, text, file...")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	secret, err := a.nameArg(args)
+	if err != nil {
+		return err
+	}
+	d, err := a.exact(ctx, secret, true)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		return a.print.secret(d)
+	}
+	v, err := field.Value(d, *name)
+	if err != nil {
+		return err
+	}
+	if *name == "file" {
+		_, err = io.WriteString(a.out, v)
+		return err
+	}
+	_, err = fmt.Fprintln(a.out, v)
+	return err
+}
+
+// copy puts the field on the clipboard and waits until it is cleared, interrupt clears it at once
+func (a *App) copy(ctx context.Context, args []string) error {
+	fs := a.flags("copy")
+	name := fs.String("field", "", "field to copy, password of logins, number of cards, text or file by default")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	secret, err := a.nameArg(args)
+	if err != nil {
+		return err
+	}
+	d, err := a.exact(ctx, secret, true)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = field.Default(d)
+	}
+	v, err := field.Value(d, *name)
+	if err != nil {
+		return err
+	}
+	c, err := a.clipboard(a.cfg.Clipboard)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	copier := clipboard.NewCopier(c, a.cfg.ClearAfter, func(cleared bool, err error) {
+		if err == nil && !cleared {
+			fmt.Fprintln(a.errOut, "clipboard was changed meanwhile, it is kept")
+		}
+		done <- err
+	})
+	if err = copier.Copy(v); err != nil {
+		return err
+	}
+	if !copier.Pending() {
+		return a.print.message("copied %s of %s to %s clipboard", *name, secret, c.Name())
+	}
+	fmt.Fprintf(a.errOut, "copied %s of %s to %s clipboard, it is cleared in %s, interrupt to clear it now\n",
+		*name, secret, c.Name(), a.cfg.ClearAfter)
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		copier.Flush()
+		err = <-done
+	}
+	if err != nil {
+		return fmt.Errorf("clipboard cannot be cleared: %v", err)
+	}
+	return a.print.message("clipboard cleared")
+}
+
+func (a *App) list(ctx context.Context, args []string) error {
+	fs := a.flags("list")
+	query := fs.String("q", "", "full-text search query")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	var data []*pb.Data
+	var err error
+	if *query != "" {
+		data, err = a.backend.Search(ctx, *query)
+	} else {
+		data, err = a.backend.List(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	return a.print.list(data)
+}
+
+func (a *App) rm(ctx context.Context, args []string) error {
+	fs := a.flags("rm")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := a.nameArg(args)
+	if err != nil {
+		return err
+	}
+	d, err := a.exact(ctx, name, false)
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		ok, err := a.prompt.Confirm(fmt.Sprintf("Delete %s %s?", field.Kind(d), name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return a.print.message("nothing deleted")
+		}
+	}
+	deleted, err := a.backend.Delete(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	return a.print.message("deleted %d secret(s)", len(deleted))
+}
+
+func (a *App) generate(ctx context.Context, args []string) error {
+	fs := a.flags("generate")
+	g := &pb.GenerateRequest{}
+	var length, words int64
+	var urls stringList
+	fs.StringVar(&g.Kind, "kind", "password", "password or passphrase")
+	fs.Int64Var(&length, "length", 0, "length of the password, 20 by default")
+	fs.BoolVar(&g.Lower, "lower", false, "use lower case letters, all classes are used if none is given")
+	fs.BoolVar(&g.Upper, "upper", false, "use upper case letters")
+	fs.BoolVar(&g.Digits, "digits", false, "use digits")
+	fs.BoolVar(&g.Symbols, "symbols", false, "use symbols")
+	fs.BoolVar(&g.AllowAmbiguous, "ambiguous", false, "allow characters that look alike")
+	fs.Int64Var(&words, "words", 0, "number of words of the passphrase")
+	fs.StringVar(&g.Separator, "separator", "", "separator of words, - by default")
+	fs.BoolVar(&g.Capitalize, "capitalize", false, "capitalize words")
+	fs.BoolVar(&g.WithNumber, "number", false, "add a digit to one of the words")
+	fs.StringVar(&g.Metadata, "save", "", "save the password as login with this name")
+	fs.StringVar(&g.Login, "login", "", "login to save with the password")
+	fs.Var(&urls, "url", "website of the saved login, can be repeated")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	g.Length, g.Words, g.URIs = length, words, urls.uris()
+	g.Insert = g.Metadata != ""
+	resp, err := a.backend.Generate(ctx, g)
+	if err != nil {
+		return err
+	}
+	if a.cfg.Output == OutputJSON {
+		return a.print.json(resp)
+	}
+	fmt.Fprintln(a.out, resp.Password)
+	fmt.Fprintf(a.errOut, "entropy: %.1f bits\n", resp.Entropy)
+	if resp.Compromised {
+		fmt.Fprintln(a.errOut, "warning: the password was found in a breach")
+	}
+	if g.Insert {
+		fmt.Fprintf(a.errOut, "saved as %s\n", g.Metadata)
+	}
+	return nil
+}
+
+func (a *App) sync(ctx context.Context, args []string) error {
+	msg, err := a.backend.Sync(ctx)
+	if err != nil {
+		return err
+	}
+	return a.print.message("%s", msg)
+}
+
+func (a *App) status(ctx context.Context, args []string) error {
+	st, err := a.backend.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if a.cfg.Output == OutputJSON {
+		return a.print.json(st)
+	}
+	login := st.Login
+	if login == "" {
+		login = "-"
+	}
+	fmt.Fprintf(a.out, "mode:      %s\naddress:   %s\nreachable: %t\nlogin:     %s\nlocked:    %t\nsync:      %s\n",
+		st.Mode, st.Address, st.Reachable, login, st.Locked, syncSummary(st))
+	return nil
+}
+
+// syncSummary describes sync of the daemon in a few words
+func syncSummary(st *Status) string {
+	switch {
+	case st.Mode == ModeDirect:
+		return "not used in direct mode"
+	case !st.Reachable:
+		return "daemon is not running"
+	case st.Syncing:
+		return "running"
+	case st.SyncError != "":
+		return "failed: " + st.SyncError
+	case st.Offline:
+		return "offline, local copy is used"
+	case st.LastSync != "":
+		return "synced at " + st.LastSync
+	}
+	return "not synced yet"
+}
+
+func (a *App) ui(ctx context.Context, args []string) error {
+	if _, err := parse(a.flags("ui"), args); err != nil {
+		return err
+	}
+	// the client daemon keeps local db and sync, so the ui works offline too
+	if a.cfg.Mode != ModeDaemon {
+		return fmt.Errorf("ui works only through the client daemon, use -mode %s", ModeDaemon)
+	}
+	t := newTUI(ctx, a.backend, nil)
+	if err := t.load(); err != nil {
+		return err
+	}
+	// copied values must not outlive the ui
+	if c, err := a.clipboard(a.cfg.Clipboard); err == nil {
+		t.copier = clipboard.NewCopier(c, a.cfg.ClearAfter, nil)
+		defer t.copier.Flush()
+	}
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	t.s = s
+	return t.run()
+}
+
+// writeFile writes the file only the user can read, - is stdout
+func (a *App) writeFile(path string, data []byte) error {
+	if path == "-" {
+		_, err := a.out.Write(data)
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
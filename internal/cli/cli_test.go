@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/clipboard"
+	"github.com/maffka123/GophKeeper/internal/localapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fakeBackend keeps secrets in memory
+type fakeBackend struct {
+	data    []*pb.Data
+	deleted []string
+	updated map[string]*pb.Data
+}
+
+func (f *fakeBackend) Register(ctx context.Context, login, password string) (*pb.RecoveryKit, error) {
+	return &pb.RecoveryKit{Login: login, RecoveryKey: "KEY"}, nil
+}
+func (f *fakeBackend) Login(ctx context.Context, login, password string) error { return nil }
+func (f *fakeBackend) Logout(ctx context.Context) error                        { return nil }
+func (f *fakeBackend) Find(ctx context.Context, name string, reveal bool) ([]*pb.Data, error) {
+	var out []*pb.Data
+	for _, d := range f.data {
+		if strings.Contains(d.Metadata, name) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+func (f *fakeBackend) Search(ctx context.Context, query string) ([]*pb.Data, error) {
+	return f.Find(ctx, query, false)
+}
+func (f *fakeBackend) List(ctx context.Context) ([]*pb.Data, error) { return f.data, nil }
+func (f *fakeBackend) Add(ctx context.Context, d *pb.Data) (string, error) {
+	f.data = append(f.data, d)
+	return "id1", nil
+}
+func (f *fakeBackend) Update(ctx context.Context, id string, d *pb.Data) error {
+	f.updated[id] = d
+	return nil
+}
+func (f *fakeBackend) Delete(ctx context.Context, id string) ([]*pb.Data, error) {
+	f.deleted = append(f.deleted, id)
+	for _, d := range f.data {
+		if d.ID == id {
+			return []*pb.Data{d}, nil
+		}
+	}
+	return nil, nil
+}
+func (f *fakeBackend) Generate(ctx context.Context, g *pb.GenerateRequest) (*pb.GenerateResp, error) {
+	return &pb.GenerateResp{Password: "generated", Entropy: 100}, nil
+}
+func (f *fakeBackend) Sync(ctx context.Context) (string, error) { return "sync started", nil }
+func (f *fakeBackend) Status(ctx context.Context) (*Status, error) {
+	return &Status{Mode: ModeDaemon, Reachable: true, LastSync: "2022-10-01 10:00:00"}, nil
+}
+func (f *fakeBackend) Import(ctx context.Context, format string, file []byte, passphrase string, dryRun bool) (*pb.ImportReport, error) {
+	return &pb.ImportReport{}, nil
+}
+func (f *fakeBackend) Export(ctx context.Context, e *pb.ExportRequest) ([]byte, error) {
+	return []byte("{}"), nil
+}
+func (f *fakeBackend) Close() error { return nil }
+
+func login(name, user, password string) *pb.Data {
+	return &pb.Data{ID: "id-" + name, Metadata: name, Data: &pb.KeepData{AuthData: &pb.AuthData{Login: user, Password: password,
+		URIs: []*pb.LoginURI{{URI: "https://" + name}}}}}
+}
+
+func TestApp_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		in      string
+		want    string
+		wantErr string
+		check   func(t *testing.T, f *fakeBackend)
+	}{
+		{name: "get field", args: []string{"get", "mail", "-field", "password"}, want: "pass1\n"},
+		{name: "get all", args: []string{"get", "-field", "login", "github"}, want: "octo\n"},
+		{name: "get no field", args: []string{"get", "mail", "-field", "cvv"}, wantErr: "secret mail has no field cvv"},
+		{name: "get similar", args: []string{"get", "mai"}, wantErr: "no secret named mai, similar: mail, mail/work"},
+		{name: "list", args: []string{"list"}, want: "NAME       TYPE   DETAILS                   EXPIRES  CHANGED\n" +
+			"mail       login  me @ https://mail                  \n" +
+			"mail/work  login  boss @ https://mail/work           \n" +
+			"github     login  octo @ https://github              \n"},
+		{name: "list json", args: []string{"-output", "json", "list", "-q", "github"}, want: `"Metadata": "github"`},
+		{name: "add login", args: []string{"add", "login", "bank", "-url", "https://bank.com"}, in: "me\nsecret\n",
+			want: "added bank with id id1\n", check: func(t *testing.T, f *fakeBackend) {
+				d := f.data[len(f.data)-1]
+				assert.Equal(t, "me", d.Data.AuthData.Login)
+				assert.Equal(t, "secret", d.Data.AuthData.Password)
+				assert.Equal(t, "https://bank.com", d.Data.AuthData.URIs[0].URI)
+			}},
+		{name: "add generated", args: []string{"add", "login", "-login", "me", "-generate", "shop"}, want: "added shop",
+			check: func(t *testing.T, f *fakeBackend) {
+				assert.Equal(t, "generated", f.data[len(f.data)-1].Data.AuthData.Password)
+			}},
+		{name: "add unknown", args: []string{"add", "pet", "cat"}, wantErr: "unknown type pet"},
+		{name: "edit flags", args: []string{"edit", "github", "-name", "gh", "-password"}, in: "new\n", want: "updated gh\n",
+			check: func(t *testing.T, f *fakeBackend) {
+				d := f.updated["id-github"]
+				assert.Equal(t, "gh", d.Metadata)
+				assert.Equal(t, "new", d.Data.AuthData.Password)
+				assert.Equal(t, "octo", d.Data.AuthData.Login)
+			}},
+		{name: "edit interactive", args: []string{"edit", "github"}, in: "\nocto2\n\n\n", want: "updated github\n",
+			check: func(t *testing.T, f *fakeBackend) {
+				d := f.updated["id-github"]
+				assert.Equal(t, "octo2", d.Data.AuthData.Login)
+				assert.Equal(t, "pass3", d.Data.AuthData.Password)
+			}},
+		{name: "rm contained", args: []string{"rm", "mail", "-yes"}, want: "deleted 1 secret(s)\n",
+			check: func(t *testing.T, f *fakeBackend) { assert.Equal(t, []string{"id-mail"}, f.deleted) }},
+		{name: "rm not confirmed", args: []string{"rm", "github"}, in: "n\n", want: "nothing deleted\n",
+			check: func(t *testing.T, f *fakeBackend) { assert.Empty(t, f.deleted) }},
+		{name: "rm", args: []string{"rm", "github"}, in: "y\n", want: "deleted 1 secret(s)\n",
+			check: func(t *testing.T, f *fakeBackend) { assert.Equal(t, []string{"id-github"}, f.deleted) }},
+		{name: "export plain", args: []string{"export", "-format", "json"}, wantErr: "confirm it with -plaintext"},
+		{name: "unknown", args: []string{"fly"}, wantErr: "unknown command fly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeBackend{updated: map[string]*pb.Data{}, data: []*pb.Data{
+				login("mail", "me", "pass1"), login("mail/work", "boss", "pass2"), login("github", "octo", "pass3")}}
+			var out, errOut bytes.Buffer
+			a := New(strings.NewReader(tt.in), &out, &errOut)
+			a.newBackend = func(cfg *Config, s *Session, p *Prompter) (Backend, error) { return f, nil }
+			args := append([]string{"-session", filepath.Join(t.TempDir(), "session")}, tt.args...)
+
+			err := a.Run(context.Background(), args)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), tt.want)
+			if tt.check != nil {
+				tt.check(t, f)
+			}
+		})
+	}
+}
+
+// memClipboard keeps copied value in memory, writes are recorded
+type memClipboard struct {
+	mu     sync.Mutex
+	text   string
+	writes []string
+}
+
+func (m *memClipboard) Name() string { return "memory" }
+func (m *memClipboard) Write(text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.text, m.writes = text, append(m.writes, text)
+	return nil
+}
+func (m *memClipboard) Read() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.text, nil
+}
+func (m *memClipboard) Clear() error { return m.Write("") }
+
+func TestApp_Copy(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{name: "default field", args: []string{"-clear-after", "10ms", "copy", "mail"}, want: []string{"pass1", ""}},
+		{name: "field", args: []string{"-clear-after", "10ms", "copy", "-field", "login", "github"}, want: []string{"octo", ""}},
+		{name: "never cleared", args: []string{"-clear-after", "0", "copy", "github"}, want: []string{"pass3"}},
+		{name: "no field", args: []string{"copy", "-field", "cvv", "github"}, wantErr: "secret github has no field cvv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeBackend{data: []*pb.Data{login("mail", "me", "pass1"), login("github", "octo", "pass3")}}
+			cb := &memClipboard{}
+			var out, errOut bytes.Buffer
+			a := New(strings.NewReader(""), &out, &errOut)
+			a.newBackend = func(cfg *Config, s *Session, p *Prompter) (Backend, error) { return f, nil }
+			a.clipboard = func(kind string) (clipboard.Clipboard, error) { return cb, nil }
+			args := append([]string{"-session", filepath.Join(t.TempDir(), "session")}, tt.args...)
+
+			err := a.Run(context.Background(), args)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cb.writes)
+			// the value is never printed
+			assert.NotContains(t, out.String()+errOut.String(), tt.want[0])
+		})
+	}
+}
+
+func TestDaemonBackend(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{Mode: ModeDaemon, SecretFile: filepath.Join(dir, "api-secret"), Session: filepath.Join(dir, "session")}
+	require.NoError(t, localapi.WriteSecret(cfg.SecretFile, "s3cret"))
+
+	var updated *pb.UpdateRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/user/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "jwt", Value: "token1"})
+	})
+	mux.HandleFunc("/api/vault/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Login": "me"}`))
+	})
+	mux.HandleFunc("/api/user/update", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("jwt"); err != nil {
+			http.Error(w, "401 - Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		updated = &pb.UpdateRequest{}
+		protojson.Unmarshal(body, updated)
+	})
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Start()
+	defer srv.Close()
+	cfg.Endpoint = strings.TrimPrefix(srv.URL, "http://")
+	guard := localapi.NewGuard("s3cret", localapi.Hosts(cfg.Endpoint), nil)
+	srv.Config.Handler = guard.Handler(mux)
+
+	s, err := loadSession(cfg.Session, cfg.Mode)
+	require.NoError(t, err)
+	b, err := newDaemonBackend(cfg, s)
+	require.NoError(t, err)
+
+	err = b.Update(context.Background(), "mail", &pb.Data{})
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+
+	require.NoError(t, b.Login(context.Background(), "me", "pass"))
+	saved, err := loadSession(cfg.Session, ModeDaemon)
+	require.NoError(t, err)
+	assert.Equal(t, "token1", saved.Token)
+	assert.Equal(t, "me", saved.Login)
+	other, err := loadSession(cfg.Session, ModeDirect)
+	require.NoError(t, err)
+	assert.Empty(t, other.Token)
+
+	// unsafe request gets csrf token first
+	require.NoError(t, b.Update(context.Background(), "id-mail", &pb.Data{Metadata: "mail"}))
+	require.NotNil(t, updated)
+	assert.Equal(t, "id-mail", updated.ID)
+	assert.Equal(t, guard.CSRFToken("token1"), b.csrf)
+
+	b.secret = "wrong"
+	st, err := b.Status(context.Background())
+	assert.Nil(t, st)
+	assert.Error(t, err)
+}
@@ -0,0 +1,305 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/localapi"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// listPageSize is the page size used to list all secrets
+const listPageSize = 500
+
+// insertIDRe finds id of the inserted secret in the response of the daemon
+var insertIDRe = regexp.MustCompile(`insert id": ?"?([^"}\s]+)`)
+
+// apiError is an error response of the daemon
+type apiError struct {
+	code int
+	msg  string
+}
+
+func (e *apiError) Error() string {
+	switch e.code {
+	case http.StatusUnauthorized:
+		if strings.Contains(e.msg, "api secret") {
+			return e.msg
+		}
+		return ErrNotLoggedIn.Error()
+	case http.StatusLocked:
+		return "vault is locked, run gophkeeper login"
+	}
+	return e.msg
+}
+
+func (e *apiError) Unwrap() error {
+	if e.code == http.StatusUnauthorized && !strings.Contains(e.msg, "api secret") {
+		return ErrNotLoggedIn
+	}
+	return nil
+}
+
+// daemonBackend calls http api of the client daemon with its api secret, jwt of the session and csrf token
+type daemonBackend struct {
+	client  *http.Client
+	base    string
+	address string
+	secret  string
+	csrf    string
+	session *Session
+}
+
+// newDaemonBackend connects to the daemon listening on the unix socket if it is given, on tcp address otherwise
+func newDaemonBackend(cfg *Config, session *Session) (*daemonBackend, error) {
+	secret, err := localapi.ReadSecret(cfg.SecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("api secret cannot be read, is the client daemon running? %v", err)
+	}
+	b := &daemonBackend{client: &http.Client{}, base: "http://" + cfg.Endpoint, address: cfg.Endpoint, secret: secret, session: session}
+	if cfg.Socket != "" {
+		socket := cfg.Socket
+		b.base, b.address = "http://localhost", socket
+		b.client.Transport = &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		}}
+	}
+	return b, nil
+}
+
+// do calls the api, response is decoded into out if it is given, the body is returned otherwise
+func (b *daemonBackend) do(ctx context.Context, method, path string, body []byte, header http.Header, out proto.Message) ([]byte, error) {
+	if method != http.MethodGet && b.session.Token != "" && b.csrf == "" {
+		// csrf token is given with any response to a request with the cookie
+		if _, err := b.do(ctx, http.MethodGet, "/api/vault/status", nil, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.base+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(localapi.SecretHeader, b.secret)
+	if b.session.Token != "" {
+		req.AddCookie(&http.Cookie{Name: "jwt", Value: b.session.Token})
+		if b.csrf != "" {
+			req.Header.Set(localapi.CSRFHeader, b.csrf)
+		}
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client daemon is not available at %s: %v", b.address, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if csrf := resp.Header.Get(localapi.CSRFHeader); csrf != "" {
+		b.csrf = csrf
+	}
+	for _, c := range resp.Cookies() {
+		if c.Name == "jwt" {
+			b.session.Token = c.Value
+		}
+	}
+	if resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if i := strings.Index(msg, " - "); i > 0 && i < 5 {
+			msg = msg[i+3:]
+		}
+		return nil, &apiError{code: resp.StatusCode, msg: msg}
+	}
+	if out != nil {
+		if err = protojson.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("response of the client daemon cannot be decoded: %v", err)
+		}
+	}
+	return data, nil
+}
+
+func (b *daemonBackend) userJSON(login, password string) []byte {
+	body, _ := protojson.Marshal(&pb.User{Login: login, Password: password})
+	return body
+}
+
+func (b *daemonBackend) Register(ctx context.Context, login, password string) (*pb.RecoveryKit, error) {
+	var kit pb.RecoveryKit
+	if _, err := b.do(ctx, http.MethodPost, "/api/user/register", b.userJSON(login, password), nil, &kit); err != nil {
+		return nil, err
+	}
+	b.session.Login = login
+	return &kit, b.session.save()
+}
+
+func (b *daemonBackend) Login(ctx context.Context, login, password string) error {
+	b.session.Token = ""
+	if _, err := b.do(ctx, http.MethodPost, "/api/user/login", b.userJSON(login, password), nil, nil); err != nil {
+		return err
+	}
+	b.session.Login = login
+	return b.session.save()
+}
+
+func (b *daemonBackend) Logout(ctx context.Context) error {
+	_, err := b.do(ctx, http.MethodPost, "/api/vault/lock", nil, nil, nil)
+	if ferr := b.session.forget(); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+func (b *daemonBackend) Find(ctx context.Context, name string, reveal bool) ([]*pb.Data, error) {
+	path := "/api/user/search"
+	if reveal {
+		path = "/api/user/reveal"
+	}
+	body, _ := protojson.Marshal(&pb.Data{Metadata: name})
+	var resp pb.GetDataResp
+	if _, err := b.do(ctx, http.MethodGet, path, body, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (b *daemonBackend) Search(ctx context.Context, query string) ([]*pb.Data, error) {
+	var resp pb.GetDataResp
+	if _, err := b.do(ctx, http.MethodGet, "/api/user/search?limit=100&q="+url.QueryEscape(query), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	var out []*pb.Data
+	for _, h := range resp.Hits {
+		out = append(out, h.Data)
+	}
+	return out, nil
+}
+
+func (b *daemonBackend) List(ctx context.Context) ([]*pb.Data, error) {
+	var out []*pb.Data
+	token := ""
+	for {
+		var resp pb.GetAllDataForUserResp
+		path := fmt.Sprintf("/api/user/list?sort=title&page_size=%d&page_token=%s", listPageSize, url.QueryEscape(token))
+		if _, err := b.do(ctx, http.MethodGet, path, nil, nil, &resp); err != nil {
+			return nil, err
+		}
+		out = append(out, resp.Data...)
+		if token = resp.NextPageToken; token == "" {
+			return out, nil
+		}
+	}
+}
+
+func (b *daemonBackend) Add(ctx context.Context, d *pb.Data) (string, error) {
+	body, err := protojson.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.do(ctx, http.MethodPost, "/api/user/insert", body, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if m := insertIDRe.FindSubmatch(resp); m != nil {
+		return string(m[1]), nil
+	}
+	return "", nil
+}
+
+func (b *daemonBackend) Update(ctx context.Context, id string, d *pb.Data) error {
+	body, err := protojson.Marshal(&pb.UpdateRequest{ID: id, Data: d})
+	if err != nil {
+		return err
+	}
+	_, err = b.do(ctx, http.MethodPost, "/api/user/update", body, nil, nil)
+	return err
+}
+
+func (b *daemonBackend) Delete(ctx context.Context, id string) ([]*pb.Data, error) {
+	body, _ := protojson.Marshal(&pb.Data{ID: id})
+	var resp pb.DeleteResp
+	if _, err := b.do(ctx, http.MethodGet, "/api/user/delete", body, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (b *daemonBackend) Generate(ctx context.Context, g *pb.GenerateRequest) (*pb.GenerateResp, error) {
+	body, err := protojson.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	var resp pb.GenerateResp
+	if _, err = b.do(ctx, http.MethodPost, "/api/user/generate", body, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (b *daemonBackend) Sync(ctx context.Context) (string, error) {
+	if _, err := b.do(ctx, http.MethodPost, "/api/vault/sync", nil, nil, nil); err != nil {
+		return "", err
+	}
+	return "sync started", nil
+}
+
+func (b *daemonBackend) Status(ctx context.Context) (*Status, error) {
+	st := &Status{Mode: ModeDaemon, Address: b.address, Login: b.session.Login}
+	var vs pb.VaultStatus
+	_, err := b.do(ctx, http.MethodGet, "/api/vault/status", nil, nil, &vs)
+	var apiErr *apiError
+	if err != nil && !errors.As(err, &apiErr) {
+		return st, nil
+	} else if err != nil {
+		return nil, err
+	}
+	st.Reachable, st.Locked = true, vs.Locked
+	st.Syncing, st.Offline, st.LastSync, st.SyncError = vs.Syncing, vs.Offline, vs.LastSync, vs.SyncError
+	st.Revision = vs.Revision
+	if vs.Login != "" {
+		st.Login = vs.Login
+	}
+	return st, nil
+}
+
+func (b *daemonBackend) Import(ctx context.Context, format string, file []byte, passphrase string, dryRun bool) (*pb.ImportReport, error) {
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	if passphrase != "" {
+		header.Set("X-Passphrase", passphrase)
+	}
+	var report pb.ImportReport
+	path := fmt.Sprintf("/api/user/import?format=%s&dryrun=%t", url.QueryEscape(format), dryRun)
+	if _, err := b.do(ctx, http.MethodPost, path, file, header, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (b *daemonBackend) Export(ctx context.Context, e *pb.ExportRequest) ([]byte, error) {
+	body, err := protojson.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return b.do(ctx, http.MethodPost, "/api/user/export", body, nil, nil)
+}
+
+func (b *daemonBackend) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,337 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/archive"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+	"github.com/maffka123/GophKeeper/internal/handlers"
+	"github.com/maffka123/GophKeeper/internal/importer"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// directBackend calls the gRPC server with jwt of the session. Keys are never kept: blind indexes of new
+// and changed secrets are computed with the vault key opened by the password asked right then
+type directBackend struct {
+	conn     *grpc.ClientConn
+	c        pb.GophKeeperClient
+	address  string
+	session  *Session
+	password func(label string) (string, error)
+}
+
+// newDirectBackend connects to the gRPC server, password asks for the password of the user when keys are needed
+func newDirectBackend(cfg *Config, session *Session, password func(string) (string, error)) (*directBackend, error) {
+	host, _ := os.Hostname()
+	conn, err := grpc.Dial(cfg.Server, grpc.WithInsecure(), grpc.WithUserAgent("gophkeeper-cli/"+host))
+	if err != nil {
+		return nil, err
+	}
+	return &directBackend{conn: conn, c: pb.NewGophKeeperClient(conn), address: cfg.Server, session: session, password: password}, nil
+}
+
+// grpcError makes error of the server readable
+func grpcError(err error) error {
+	e, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch e.Code() {
+	case codes.Unauthenticated:
+		return ErrNotLoggedIn
+	case codes.Unavailable:
+		return fmt.Errorf("server is not available: %s", e.Message())
+	}
+	return errors.New(e.Message())
+}
+
+// ctx adds token of the session
+func (b *directBackend) ctx(ctx context.Context) (context.Context, error) {
+	if b.session.Token == "" {
+		return nil, ErrNotLoggedIn
+	}
+	return metadata.AppendToOutgoingContext(ctx, "token", b.session.Token), nil
+}
+
+// login logs in and opens the vault, the first login creates vault key and key pair
+func (b *directBackend) login(ctx context.Context, login, password string) (*vault.Key, error) {
+	resp, err := b.c.Login(ctx, &pb.LoginRequest{User: &pb.User{Login: login, Password: password}})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	b.session.Login, b.session.Token = login, resp.Token
+	if err = b.session.save(); err != nil {
+		return nil, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "token", resp.Token)
+	k, master, keys, err := handlers.OpenVault(ctx, b.c, password, resp)
+	if err != nil {
+		return nil, fmt.Errorf("vault key cannot be opened: %v", grpcError(err))
+	}
+	master.Wipe()
+	keys.Wipe()
+	return k, nil
+}
+
+// index computes blind indexes of the secrets, the password is asked only if some of them have indexed fields
+func (b *directBackend) index(ctx context.Context, data ...*pb.Data) error {
+	needed := false
+	for _, d := range data {
+		kd := d.GetData()
+		needed = needed || kd.GetAuthData().GetLogin() != "" || kd.GetBankCard().GetBankName() != "" || kd.GetBankCard().GetHolderName() != ""
+	}
+	if !needed {
+		return nil
+	}
+	if b.session.Login == "" {
+		return ErrNotLoggedIn
+	}
+	password, err := b.password(fmt.Sprintf("Password of %s (for blind indexes)", b.session.Login))
+	if err != nil {
+		return err
+	}
+	k, err := b.login(ctx, b.session.Login, password)
+	if err != nil {
+		return err
+	}
+	defer k.Wipe()
+	for _, d := range data {
+		d.BlindIndex = k.Tokens(d)
+	}
+	return nil
+}
+
+func (b *directBackend) Register(ctx context.Context, login, password string) (*pb.RecoveryKit, error) {
+	resp, err := b.c.Register(ctx, &pb.RegisterRequest{User: &pb.User{Login: login, Password: password}})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if resp.Exists == -1 {
+		return nil, fmt.Errorf("login %s is already taken", login)
+	}
+	k, err := b.login(ctx, login, password)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Wipe()
+	kit, err := handlers.NewRecoveryKit(metadata.AppendToOutgoingContext(ctx, "token", b.session.Token), b.c, login, k)
+	if err != nil {
+		return nil, fmt.Errorf("recovery key cannot be created, create it after login: %v", grpcError(err))
+	}
+	return kit, nil
+}
+
+func (b *directBackend) Login(ctx context.Context, login, password string) error {
+	k, err := b.login(ctx, login, password)
+	if err != nil {
+		return err
+	}
+	k.Wipe()
+	return nil
+}
+
+func (b *directBackend) Logout(ctx context.Context) error {
+	return b.session.forget()
+}
+
+func (b *directBackend) Find(ctx context.Context, name string, reveal bool) ([]*pb.Data, error) {
+	ctx, err := b.ctx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.c.GetData(ctx, &pb.GetDataRequest{Data: &pb.Data{Metadata: name}, Reveal: reveal})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.Data, nil
+}
+
+func (b *directBackend) Search(ctx context.Context, query string) ([]*pb.Data, error) {
+	ctx, err := b.ctx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.c.GetData(ctx, &pb.GetDataRequest{Query: query, Limit: 100})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var out []*pb.Data
+	for _, h := range resp.Hits {
+		out = append(out, h.Data)
+	}
+	return out, nil
+}
+
+// all returns all secrets of the user page by page
+func (b *directBackend) all(ctx context.Context, reveal bool) ([]*pb.Data, error) {
+	ctx, err := b.ctx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*pb.Data
+	page := &pb.PageRequest{PageSize: listPageSize, SortBy: "title"}
+	for {
+		resp, err := b.c.GetAllDataForUser(ctx, &pb.GetAllDataForUserRequest{Time: "1970-01-01 00:00:00", Reveal: reveal, Page: page})
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		out = append(out, resp.Data...)
+		if page.PageToken = resp.NextPageToken; page.PageToken == "" {
+			return out, nil
+		}
+	}
+}
+
+func (b *directBackend) List(ctx context.Context) ([]*pb.Data, error) {
+	return b.all(ctx, false)
+}
+
+func (b *directBackend) Add(ctx context.Context, d *pb.Data) (string, error) {
+	if err := b.index(ctx, d); err != nil {
+		return "", err
+	}
+	ctx, err := b.ctx(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.c.Insert(ctx, &pb.InsertRequest{Data: d})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return resp.Id, nil
+}
+
+func (b *directBackend) Update(ctx context.Context, id string, d *pb.Data) error {
+	if err := b.index(ctx, d); err != nil {
+		return err
+	}
+	ctx, err := b.ctx(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err = b.c.Update(ctx, &pb.UpdateRequest{ID: id, Data: d}); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+func (b *directBackend) Delete(ctx context.Context, id string) ([]*pb.Data, error) {
+	ctx, err := b.ctx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.c.Delete(ctx, &pb.DeleteRequest{ID: id})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.Data, nil
+}
+
+// Generate generates the password locally, without breach corpus of the daemon it is not checked
+func (b *directBackend) Generate(ctx context.Context, g *pb.GenerateRequest) (*pb.GenerateResp, error) {
+	res, err := handlers.Generate(g)
+	if err != nil {
+		return nil, err
+	}
+	out := &pb.GenerateResp{Password: res.Password, Entropy: res.Entropy}
+	if g.Insert {
+		d := &pb.Data{Data: &pb.KeepData{AuthData: &pb.AuthData{Login: g.Login, Password: res.Password, URIs: g.URIs}}, Metadata: g.Metadata}
+		if out.InsertID, err = b.Add(ctx, d); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (b *directBackend) Sync(ctx context.Context) (string, error) {
+	return "direct mode keeps no local copy, there is nothing to sync", nil
+}
+
+func (b *directBackend) Status(ctx context.Context) (*Status, error) {
+	st := &Status{Mode: ModeDirect, Address: b.address, Login: b.session.Login, Locked: b.session.Token == ""}
+	if b.session.Token == "" {
+		return st, nil
+	}
+	ctx, _ = b.ctx(ctx)
+	_, err := b.c.GetAllDataForUser(ctx, &pb.GetAllDataForUserRequest{Time: "1970-01-01 00:00:00", Page: &pb.PageRequest{PageSize: 1}})
+	switch status.Code(err) {
+	case codes.OK:
+		st.Reachable = true
+	case codes.Unavailable:
+	case codes.Unauthenticated:
+		st.Reachable, st.Locked = true, true
+	default:
+		return nil, grpcError(err)
+	}
+	return st, nil
+}
+
+// Import imports the file like the daemon does, without breach corpus passwords are not checked
+func (b *directBackend) Import(ctx context.Context, format string, file []byte, passphrase string, dryRun bool) (*pb.ImportReport, error) {
+	data, err := importer.Parse(format, file, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("import file cannot be parsed: %v", err)
+	}
+	existing, err := b.all(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	report := importer.Plan(format, data, existing)
+	report.DryRun = dryRun
+	if toImport := importer.New(report); !dryRun && len(toImport) > 0 {
+		if err = b.index(ctx, toImport...); err != nil {
+			return nil, err
+		}
+		tctx, err := b.ctx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := b.c.InsertBatch(tctx, &pb.InsertBatchRequest{Data: toImport})
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		i := 0
+		for _, e := range report.Entries {
+			if e.Status == importer.StatusNew {
+				e.Status, e.InsertID = importer.StatusImported, resp.Ids[i]
+				i++
+			}
+		}
+		report.Imported = int64(len(resp.Ids))
+	}
+	var entries []*pb.Data
+	for _, e := range report.Entries {
+		entries = append(entries, e.Data)
+	}
+	bankcard.Mask(entries)
+	return report, nil
+}
+
+func (b *directBackend) Export(ctx context.Context, e *pb.ExportRequest) ([]byte, error) {
+	all, err := b.all(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	a := archive.New(all)
+	switch e.Format {
+	case "", "archive":
+		return archive.Seal(a, e.Passphrase)
+	case "json":
+		return archive.JSON(a)
+	case "csv":
+		return archive.CSV(a)
+	}
+	return nil, fmt.Errorf("unknown format %s, use archive, json or csv", e.Format)
+}
+
+func (b *directBackend) Close() error {
+	return b.conn.Close()
+}
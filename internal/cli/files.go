@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/archive"
+	"github.com/maffka123/GophKeeper/internal/importer"
+	"github.com/maffka123/GophKeeper/internal/recoverykit"
+)
+
+// writeKit prints the recovery kit as text or writes it as pdf
+func (a *App) writeKit(kit *pb.RecoveryKit, format, path string) error {
+	if a.cfg.Output == OutputJSON {
+		return a.print.json(kit)
+	}
+	if format == "text" {
+		_, err := a.out.Write(recoverykit.Text(kit))
+		return err
+	}
+	if err := a.writeFile(path, recoverykit.PDF(kit)); err != nil {
+		return err
+	}
+	return a.print.message("recovery kit is written to %s, print it and keep it offline", path)
+}
+
+func (a *App) importFile(ctx context.Context, args []string) error {
+	fs := a.flags("import")
+	format := fs.String("format", "", "format of the file: "+fmt.Sprint(importer.Formats()))
+	dryRun := fs.Bool("dry-run", false, "only show what would be imported")
+	askPassphrase := fs.Bool("passphrase", false, "ask for passphrase of an encrypted gophkeeper archive")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("one file is expected")
+	}
+	if *format == "" {
+		return fmt.Errorf("format is not given, use -format")
+	}
+	file, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var passphrase string
+	if *askPassphrase || *format == importer.FormatArchive {
+		if passphrase, err = a.prompt.Secret("Passphrase"); err != nil {
+			return err
+		}
+	}
+	report, err := a.backend.Import(ctx, *format, file, passphrase, *dryRun)
+	if err != nil {
+		return err
+	}
+	if a.cfg.Output == OutputJSON {
+		return a.print.json(report)
+	}
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tNAME\tREASON")
+	for _, e := range report.Entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Status, e.Data.GetMetadata(), e.Reason)
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+	return a.print.message("total %d, imported %d, duplicates %d, invalid %d",
+		report.Total, report.Imported, report.Duplicates, report.Invalid)
+}
+
+func (a *App) export(ctx context.Context, args []string) error {
+	fs := a.flags("export")
+	format := fs.String("format", "archive", "archive is encrypted with a passphrase, json and csv are plain text")
+	path := fs.String("file", "", "file to write, - for stdout, gophkeeper-DATE.EXT by default")
+	plaintext := fs.Bool("plaintext", false, "confirm that json and csv exports are not encrypted")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	e := &pb.ExportRequest{Format: *format}
+	ext := *format
+	var err error
+	switch *format {
+	case "archive":
+		ext = "gkarchive"
+		if e.Passphrase, err = a.prompt.NewSecret("Passphrase"); err != nil {
+			return err
+		}
+	case "json", "csv":
+		if !*plaintext {
+			return fmt.Errorf("%s export is not encrypted, confirm it with -plaintext", *format)
+		}
+		e.Confirm = archive.ConfirmPlaintext
+	default:
+		return fmt.Errorf("unknown format %s, use archive, json or csv", *format)
+	}
+	file, err := a.backend.Export(ctx, e)
+	if err != nil {
+		return err
+	}
+	if *path == "" {
+		*path = fmt.Sprintf("gophkeeper-%s.%s", time.Now().Format(app.ExpiryLayout), ext)
+	}
+	if err = a.writeFile(*path, file); err != nil {
+		return err
+	}
+	if *path == "-" {
+		return nil
+	}
+	return a.print.message("vault is exported to %s", *path)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/field"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// OutputTable prints human readable tables
+	OutputTable = "table"
+	// OutputJSON prints json for scripts
+	OutputJSON = "json"
+)
+
+// summary is a short description of the secret for listings
+func summary(d *pb.Data) string {
+	kd := d.GetData()
+	switch field.Kind(d) {
+	case "login":
+		s := kd.AuthData.Login
+		if len(kd.AuthData.URIs) > 0 {
+			s += " @ " + kd.AuthData.URIs[0].URI
+		}
+		return s
+	case "card":
+		return strings.TrimSpace(kd.BankCard.Brand + " " + kd.BankCard.CardNumber)
+	case "file":
+		return fmt.Sprintf("%d bytes", len(kd.Binary))
+	}
+	s := strings.Join(strings.Fields(kd.GetText()), " ")
+	if r := []rune(s); len(r) > 40 {
+		s = string(r[:40]) + "..."
+	}
+	return s
+}
+
+// printer writes results as tables or json
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// json writes proto message or other value as indented json
+func (p *printer) json(v interface{}) error {
+	var b []byte
+	var err error
+	if m, ok := v.(proto.Message); ok {
+		b, err = protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(m)
+	} else {
+		b, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.w, string(b))
+	return err
+}
+
+// list writes secrets one per row
+func (p *printer) list(data []*pb.Data) error {
+	if p.format == OutputJSON {
+		parts := make([]string, 0, len(data))
+		for _, d := range data {
+			b, err := protojson.Marshal(d)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, string(b))
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, []byte("["+strings.Join(parts, ",")+"]"), "", "  "); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(p.w, out.String())
+		return err
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tDETAILS\tEXPIRES\tCHANGED")
+	for _, d := range data {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.Metadata, field.Kind(d), summary(d), d.ExpiresAt, d.ChangeDate)
+	}
+	return tw.Flush()
+}
+
+// secret writes all fields of the secret
+func (p *printer) secret(d *pb.Data) error {
+	if p.format == OutputJSON {
+		return p.json(d)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, f := range field.All(d) {
+		v := f.Value
+		if f.Name == "file" {
+			v = fmt.Sprintf("%d bytes, get it with -field file", len(v))
+		}
+		fmt.Fprintf(tw, "%s:\t%s\n", f.Name, v)
+	}
+	return tw.Flush()
+}
+
+// message writes a line of text, as {"status": text} in json
+func (p *printer) message(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.format == OutputJSON {
+		return p.json(map[string]string{"status": msg})
+	}
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
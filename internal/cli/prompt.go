@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Prompter asks the user for values, secrets are read without echo when input is a terminal
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	fd  int
+	tty bool
+}
+
+// NewPrompter creates prompter reading from in and writing questions to out
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	p := &Prompter{in: bufio.NewReader(in), out: out, fd: -1}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		p.fd, p.tty = int(f.Fd()), true
+	}
+	return p
+}
+
+// Line asks for a value, def is returned for empty answer
+func (p *Prompter) Line(label, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+	s, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || s == "") {
+		return "", fmt.Errorf("%s is not given: %v", strings.ToLower(label), err)
+	}
+	if s = strings.TrimRight(s, "\r\n"); s == "" {
+		return def, nil
+	}
+	return s, nil
+}
+
+// Secret asks for a secret value without echo
+func (p *Prompter) Secret(label string) (string, error) {
+	if !p.tty {
+		return p.Line(label, "")
+	}
+	fmt.Fprintf(p.out, "%s: ", label)
+	b, err := term.ReadPassword(p.fd)
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// NewSecret asks for a new secret twice, both answers must match
+func (p *Prompter) NewSecret(label string) (string, error) {
+	s, err := p.Secret(label)
+	if err != nil {
+		return "", err
+	}
+	if s == "" {
+		return "", fmt.Errorf("%s is empty", strings.ToLower(label))
+	}
+	again, err := p.Secret("Repeat " + strings.ToLower(label))
+	if err != nil {
+		return "", err
+	}
+	if again != s {
+		return "", fmt.Errorf("%s does not match", strings.ToLower(label))
+	}
+	return s, nil
+}
+
+// Confirm asks yes or no question, no is the default
+func (p *Prompter) Confirm(label string) (bool, error) {
+	s, err := p.Line(label+" (y/N)", "")
+	if err != nil {
+		return false, err
+	}
+	s = strings.ToLower(strings.TrimSpace(s))
+	return s == "y" || s == "yes", nil
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/render"
+)
+
+// render writes the template with secrets to the output file, in watch mode it renders again
+// when the daemon pulls changes or the template is changed
+func (a *App) render(ctx context.Context, args []string) error {
+	fs := a.flags("render")
+	watch := fs.Bool("watch", false, "keep running and render again when sync pulls changes or the template changes")
+	interval := fs.Duration("interval", 10*time.Second, "how often changes are checked in watch mode")
+	reload := fs.String("reload", "", "shell command to run after the output file has changed")
+	perm := fs.String("perm", "0600", "mode of the output file")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("template and output file are expected, use gophkeeper render [flags] TEMPLATE OUTPUT")
+	}
+	mode, err := strconv.ParseUint(*perm, 8, 32)
+	if err != nil || mode > 0777 {
+		return fmt.Errorf("mode %s must be octal like 0600", *perm)
+	}
+	r := renderer{a: a, template: args[0], output: args[1], perm: os.FileMode(mode), reload: *reload}
+	if !*watch {
+		return r.render(ctx)
+	}
+	// only the client daemon syncs
+	if a.cfg.Mode != ModeDaemon {
+		return fmt.Errorf("watch works only through the client daemon, use -mode %s", ModeDaemon)
+	}
+	return r.watch(ctx, *interval)
+}
+
+// renderer renders the template to the output file
+type renderer struct {
+	a        *App
+	template string
+	output   string
+	perm     os.FileMode
+	reload   string
+}
+
+// render renders the template once, reload runs only if the output has changed
+func (r *renderer) render(ctx context.Context) error {
+	text, err := os.ReadFile(r.template)
+	if err != nil {
+		return err
+	}
+	out, err := render.Render(ctx, r.template, string(text), func(ctx context.Context, name string) (*pb.Data, error) {
+		return r.a.exact(ctx, name, true)
+	})
+	if err != nil {
+		return err
+	}
+	changed, err := render.WriteFile(r.output, out, r.perm)
+	if err != nil || !changed {
+		return err
+	}
+	fmt.Fprintf(r.a.errOut, "rendered %s to %s\n", r.template, r.output)
+	if r.reload == "" {
+		return nil
+	}
+	cmd := shellCommand(r.reload)
+	cmd.Stdout, cmd.Stderr = r.a.errOut, r.a.errOut
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("reload %q failed: %v", r.reload, err)
+	}
+	return nil
+}
+
+// watch renders when revision of the vault or the template changes until ctx is done,
+// failures are reported and rendering is tried again
+func (r *renderer) watch(ctx context.Context, interval time.Duration) error {
+	revision, modified := int64(-1), time.Time{}
+	failed := ""
+	report := func(err error) {
+		// the same failure is reported once
+		if err != nil && err.Error() != failed {
+			fmt.Fprintln(r.a.errOut, "render:", err)
+		}
+		failed = ""
+		if err != nil {
+			failed = err.Error()
+		}
+	}
+	for {
+		st, err := r.a.backend.Status(ctx)
+		var fi os.FileInfo
+		if err == nil {
+			fi, err = os.Stat(r.template)
+		}
+		if err == nil && (st.Revision != revision || !fi.ModTime().Equal(modified)) {
+			if err = r.render(ctx); err == nil {
+				revision, modified = st.Revision, fi.ModTime()
+			}
+		}
+		report(err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
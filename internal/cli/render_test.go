@@ -0,0 +1,118 @@
+//go:build !windows
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Render(t *testing.T) {
+	dir := t.TempDir()
+	tmpl, out, marker := filepath.Join(dir, "app.conf.tmpl"), filepath.Join(dir, "app.conf"), filepath.Join(dir, "reloaded")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`password={{ secret "db/prod" "password" }}`), 0600))
+	reload := "echo reloaded >> " + marker
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		reloads int
+		wantErr string
+	}{
+		{name: "rendered", args: []string{"render", "-reload", reload, tmpl, out}, want: "password=s3cret", reloads: 1},
+		{name: "unchanged", args: []string{"render", "-reload", reload, tmpl, out}, want: "password=s3cret", reloads: 1},
+		{name: "bad mode", args: []string{"render", "-perm", "rw", tmpl, out}, wantErr: "must be octal"},
+		{name: "no output", args: []string{"render", tmpl}, wantErr: "template and output file are expected"},
+		{name: "reload failed", args: []string{"render", "-reload", "exit 1", tmpl, filepath.Join(dir, "other.conf")}, wantErr: "reload \"exit 1\" failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeBackend{data: []*pb.Data{login("db/prod", "app", "s3cret")}}
+			var stdout, errOut bytes.Buffer
+			a := New(strings.NewReader(""), &stdout, &errOut)
+			a.newBackend = func(cfg *Config, s *Session, p *Prompter) (Backend, error) { return f, nil }
+			args := append([]string{"-session", filepath.Join(t.TempDir(), "session")}, tt.args...)
+
+			err := a.Run(context.Background(), args)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			b, err := os.ReadFile(out)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+			fi, err := os.Stat(out)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+			b, err = os.ReadFile(marker)
+			require.NoError(t, err)
+			assert.Equal(t, tt.reloads, strings.Count(string(b), "reloaded"))
+		})
+	}
+}
+
+// syncingBackend changes the password and its revision like the daemon pulling changes
+type syncingBackend struct {
+	*fakeBackend
+	mu       sync.Mutex
+	revision int64
+}
+
+func (b *syncingBackend) pull(password string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = []*pb.Data{login("db/prod", "app", password)}
+	b.revision++
+}
+
+func (b *syncingBackend) Find(ctx context.Context, name string, reveal bool) ([]*pb.Data, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fakeBackend.Find(ctx, name, reveal)
+}
+
+func (b *syncingBackend) Status(ctx context.Context) (*Status, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &Status{Mode: ModeDaemon, Reachable: true, Revision: b.revision}, nil
+}
+
+func TestApp_RenderWatch(t *testing.T) {
+	dir := t.TempDir()
+	tmpl, out := filepath.Join(dir, "app.conf.tmpl"), filepath.Join(dir, "app.conf")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`{{ secret "db/prod" "password" }}`), 0600))
+	b := &syncingBackend{fakeBackend: &fakeBackend{data: []*pb.Data{login("db/prod", "app", "s3cret")}}}
+	a := New(strings.NewReader(""), io.Discard, io.Discard)
+	a.newBackend = func(cfg *Config, s *Session, p *Prompter) (Backend, error) { return b, nil }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx, []string{"-session", filepath.Join(dir, "session"), "render", "-watch", "-interval", "10ms", tmpl, out})
+	}()
+
+	rendered := func(want string) func() bool {
+		return func() bool {
+			b, _ := os.ReadFile(out)
+			return string(b) == want
+		}
+	}
+	assert.Eventually(t, rendered("s3cret"), time.Second, 10*time.Millisecond)
+	b.pull("n3w")
+	assert.Eventually(t, rendered("n3w"), time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/secretref"
+)
+
+// ExitError is returned when the child of run fails, the cli exits with its code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// run starts the command with secrets of references in its environment, they are masked in its output
+func (a *App) run(ctx context.Context, args []string) error {
+	fs := a.flags("run")
+	var envs stringList
+	envFile := fs.String("env-file", "", ".env template with NAME=VALUE lines, values can be references "+secretref.Scheme+"NAME/FIELD")
+	fs.Var(&envs, "env", "variable NAME=VALUE, the value can be a reference, can be repeated")
+	noMask := fs.Bool("no-masking", false, "do not mask secrets in the output of the command")
+	// flags after the command belong to it
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("command is not given, use gophkeeper run [flags] -- COMMAND [ARGS]")
+	}
+
+	var vars []secretref.Var
+	if *envFile != "" {
+		f, err := os.Open(*envFile)
+		if err != nil {
+			return err
+		}
+		vars, err = secretref.ParseEnv(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", *envFile, err)
+		}
+	}
+	for _, e := range envs {
+		v, err := secretref.ParseVar(e)
+		if err != nil {
+			return err
+		}
+		vars = append(vars, v)
+	}
+
+	r := secretref.NewResolver(func(ctx context.Context, name string) (*pb.Data, error) {
+		return a.exact(ctx, name, true)
+	})
+	env := os.Environ()
+	for _, v := range vars {
+		value, err := r.Resolve(ctx, v.Value)
+		if err != nil {
+			return err
+		}
+		env = append(env, v.Name+"="+value)
+	}
+
+	cmd := exec.Command(fs.Arg(0), fs.Args()[1:]...)
+	cmd.Env, cmd.Stdin, cmd.Stdout, cmd.Stderr = env, a.in, a.out, a.errOut
+	if !*noMask {
+		stdout, stderr := secretref.NewMasker(a.out, r.Secrets()), secretref.NewMasker(a.errOut, r.Secrets())
+		defer stdout.Close()
+		defer stderr.Close()
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+	}
+	return a.wait(cmd)
+}
+
+// wait starts the command and passes signals to it until it exits, interrupt of the cli is not waited for
+func (a *App) wait(cmd *exec.Cmd) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, forwardedSignals()...)
+	defer signal.Stop(sigs)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for {
+		select {
+		case sig := <-sigs:
+			// the child may be gone already
+			_ = cmd.Process.Signal(sig)
+		case err := <-done:
+			var exit *exec.ExitError
+			if errors.As(err, &exit) {
+				code := exit.ExitCode()
+				if code < 0 {
+					// killed by a signal
+					code = 1
+				}
+				return &ExitError{Code: code}
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,65 @@
+//go:build !windows
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_RunCommand(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(envFile, []byte("DB_USER=gk://db/prod/login\nDB_PASSWORD=\"gk://db/prod/password\"\n"), 0600))
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantErr  string
+		exitCode int
+	}{
+		{name: "env file", args: []string{"run", "-env-file", envFile, "--", "sh", "-c", `echo "$DB_USER:$DB_PASSWORD"`},
+			want: "*****:*****\n"},
+		{name: "env flag", args: []string{"run", "-env", "PW=gk://mail/password", "-env", "MODE=prod", "--", "sh", "-c", `echo "$MODE $PW"`},
+			want: "prod *****\n"},
+		{name: "not masked", args: []string{"run", "-no-masking", "-env", "PW=gk://mail/password", "sh", "-c", `echo "$PW"`},
+			want: "pass1\n"},
+		// the child signals the cli, it must come back
+		{name: "signal", args: []string{"run", "sh", "-c", `trap "echo forwarded; exit 0" USR1; kill -USR1 $PPID; while :; do sleep 0.1; done`},
+			want: "forwarded\n"},
+		{name: "exit code", args: []string{"run", "sh", "-c", "exit 3"}, exitCode: 3},
+		{name: "bad ref", args: []string{"run", "-env", "PW=gk://mail", "sh"}, wantErr: "must be gk://NAME/FIELD"},
+		{name: "missing", args: []string{"run", "-env", "PW=gk://db/test/password", "sh"}, wantErr: "no secret named db/test"},
+		{name: "no command", args: []string{"run", "-env", "A=1"}, wantErr: "command is not given"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeBackend{data: []*pb.Data{login("mail", "me", "pass1"), login("db/prod", "app", "s3cret")}}
+			var out, errOut bytes.Buffer
+			a := New(strings.NewReader(""), &out, &errOut)
+			a.newBackend = func(cfg *Config, s *Session, p *Prompter) (Backend, error) { return f, nil }
+			args := append([]string{"-session", filepath.Join(t.TempDir(), "session")}, tt.args...)
+
+			err := a.Run(context.Background(), args)
+			switch {
+			case tt.wantErr != "":
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			case tt.exitCode != 0:
+				var exit *ExitError
+				require.ErrorAs(t, err, &exit)
+				assert.Equal(t, tt.exitCode, exit.Code)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// forwardedSignals are passed to the child of run
+func forwardedSignals() []os.Signal {
+	return []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2}
+}
+
+// shellCommand runs the command line with sh
+func shellCommand(line string) *exec.Cmd {
+	return exec.Command("sh", "-c", line)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+)
+
+// forwardedSignals are passed to the child of run, windows has only interrupt
+func forwardedSignals() []os.Signal {
+	return []os.Signal{os.Interrupt}
+}
+
+// shellCommand runs the command line with cmd
+func shellCommand(line string) *exec.Cmd {
+	return exec.Command("cmd", "/C", line)
+}
@@ -0,0 +1,279 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/field"
+)
+
+// stringList is a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// uris converts the list to websites of a login
+func (l stringList) uris() []*pb.LoginURI {
+	var out []*pb.LoginURI
+	for _, u := range l {
+		out = append(out, &pb.LoginURI{URI: u})
+	}
+	return out
+}
+
+// secretFlags are flags of add and edit
+type secretFlags struct {
+	login, expires, collection          string
+	holder, bank, address, expiry, text string
+	file                                string
+	urls                                stringList
+	generate                            bool
+}
+
+func (f *secretFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&f.login, "login", "", "login")
+	fs.Var(&f.urls, "url", "website of the login, can be repeated")
+	fs.BoolVar(&f.generate, "generate", false, "generate the password instead of asking for it")
+	fs.StringVar(&f.holder, "holder", "", "card holder name")
+	fs.StringVar(&f.bank, "bank", "", "bank of the card")
+	fs.StringVar(&f.address, "address", "", "billing address of the card")
+	fs.StringVar(&f.expiry, "expiry", "", "expiry of the card as MM/YY")
+	fs.StringVar(&f.text, "text", "", "text of the note, asked for if not given")
+	fs.StringVar(&f.file, "file", "", "path of the file to keep")
+	fs.StringVar(&f.expires, "expires", "", "date the secret expires as 2006-01-02")
+	fs.StringVar(&f.collection, "collection", "", "collection of an organisation the secret belongs to")
+}
+
+// password generates password or asks for it
+func (a *App) password(ctx context.Context, generate bool) (string, error) {
+	if !generate {
+		return a.prompt.Secret("Password")
+	}
+	resp, err := a.backend.Generate(ctx, &pb.GenerateRequest{Kind: "password"})
+	if err != nil {
+		return "", err
+	}
+	return resp.Password, nil
+}
+
+func (a *App) add(ctx context.Context, args []string) error {
+	fs := a.flags("add")
+	f := &secretFlags{}
+	f.define(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("type is not given, use login, card, text or file")
+	}
+	name, err := a.nameArg(args[1:])
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	d := &pb.Data{Metadata: name, Data: &pb.KeepData{}, ExpiresAt: f.expires, CollectionID: f.collection}
+	switch args[0] {
+	case "login":
+		auth := &pb.AuthData{Login: f.login, URIs: f.urls.uris()}
+		if auth.Login == "" {
+			if auth.Login, err = a.prompt.Line("Login", ""); err != nil {
+				return err
+			}
+		}
+		if auth.Password, err = a.password(ctx, f.generate); err != nil {
+			return err
+		}
+		d.Data.AuthData = auth
+	case "card":
+		card := &pb.BankCard{HolderName: f.holder, BankName: f.bank, Address: f.address, Expiry: f.expiry}
+		if card.CardNumber, err = a.prompt.Secret("Card number"); err != nil {
+			return err
+		}
+		d.Data.BankCard = card
+	case "text":
+		d.Data.Text = f.text
+		if d.Data.Text == "" {
+			if d.Data.Text, err = a.prompt.Line("Text", ""); err != nil {
+				return err
+			}
+		}
+	case "file":
+		if f.file == "" {
+			return fmt.Errorf("file is not given, use -file PATH")
+		}
+		if d.Data.Binary, err = os.ReadFile(f.file); err != nil {
+			return err
+		}
+		d.Data.Text = f.text
+	default:
+		return fmt.Errorf("unknown type %s, use login, card, text or file", args[0])
+	}
+	id, err := a.backend.Add(ctx, d)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return a.print.message("added %s", name)
+	}
+	return a.print.message("added %s with id %s", name, id)
+}
+
+func (a *App) edit(ctx context.Context, args []string) error {
+	fs := a.flags("edit")
+	f := &secretFlags{}
+	f.define(fs)
+	rename := fs.String("name", "", "new name of the secret")
+	password := fs.Bool("password", false, "ask for a new password")
+	number := fs.Bool("number", false, "ask for a new card number")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := a.nameArg(args)
+	if err != nil {
+		return err
+	}
+	d, err := a.exact(ctx, name, true)
+	if err != nil {
+		return err
+	}
+	if d.Data == nil {
+		d.Data = &pb.KeepData{}
+	}
+	if fs.NFlag() == 0 {
+		err = a.editInteractive(ctx, d)
+	} else {
+		err = a.editFlags(ctx, fs, f, d, *rename, *password, *number)
+	}
+	if err != nil {
+		return err
+	}
+	id := d.ID
+	clean(d)
+	if err = a.backend.Update(ctx, id, d); err != nil {
+		return err
+	}
+	return a.print.message("updated %s", d.Metadata)
+}
+
+// clean drops fields set by storage, so the secret can be sent as update
+func clean(d *pb.Data) {
+	d.ID, d.UserID, d.ChangeDate, d.BlindIndex = "", 0, "", nil
+}
+
+// editFlags changes only the fields given as flags
+func (a *App) editFlags(ctx context.Context, fs *flag.FlagSet, f *secretFlags, d *pb.Data, rename string, password, number bool) error {
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	if rename != "" {
+		d.Metadata = rename
+	}
+	if set["expires"] {
+		d.ExpiresAt = f.expires
+	}
+	if set["collection"] {
+		d.CollectionID = f.collection
+	}
+	if set["text"] {
+		d.Data.Text = f.text
+	}
+	var err error
+	if set["file"] {
+		if d.Data.Binary, err = os.ReadFile(f.file); err != nil {
+			return err
+		}
+	}
+	if auth := d.Data.AuthData; auth != nil {
+		if set["login"] {
+			auth.Login = f.login
+		}
+		if set["url"] {
+			auth.URIs = f.urls.uris()
+		}
+		if password || f.generate {
+			if auth.Password, err = a.password(ctx, f.generate); err != nil {
+				return err
+			}
+		}
+	} else if set["login"] || set["url"] || password || f.generate {
+		return fmt.Errorf("%s is a %s, it has no login", d.Metadata, field.Kind(d))
+	}
+	if card := d.Data.BankCard; card != nil {
+		if set["holder"] {
+			card.HolderName = f.holder
+		}
+		if set["bank"] {
+			card.BankName = f.bank
+		}
+		if set["address"] {
+			card.Address = f.address
+		}
+		if set["expiry"] {
+			card.Expiry = f.expiry
+		}
+		if number {
+			if card.CardNumber, err = a.prompt.Secret("Card number"); err != nil {
+				return err
+			}
+		}
+	} else if set["holder"] || set["bank"] || set["address"] || set["expiry"] || number {
+		return fmt.Errorf("%s is a %s, it is not a card", d.Metadata, field.Kind(d))
+	}
+	return nil
+}
+
+// editInteractive asks for every field, current values are kept on empty answers
+func (a *App) editInteractive(ctx context.Context, d *pb.Data) error {
+	line := func(label string, v *string) error {
+		s, err := a.prompt.Line(label, *v)
+		if err == nil {
+			*v = s
+		}
+		return err
+	}
+	secret := func(label string, v *string) error {
+		s, err := a.prompt.Secret(label + " (empty keeps current)")
+		if err == nil && s != "" {
+			*v = s
+		}
+		return err
+	}
+	steps := []func() error{func() error { return line("Name", &d.Metadata) }}
+	if auth := d.Data.AuthData; auth != nil {
+		steps = append(steps,
+			func() error { return line("Login", &auth.Login) },
+			func() error { return secret("Password", &auth.Password) })
+	}
+	if card := d.Data.BankCard; card != nil {
+		steps = append(steps,
+			func() error { return secret("Card number", &card.CardNumber) },
+			func() error { return line("Expiry", &card.Expiry) },
+			func() error { return line("Holder", &card.HolderName) },
+			func() error { return line("Bank", &card.BankName) },
+			func() error { return line("Address", &card.Address) })
+	}
+	if field.Kind(d) == "text" {
+		steps = append(steps, func() error { return line("Text", &d.Data.Text) })
+	}
+	steps = append(steps, func() error { return line("Expires", &d.ExpiresAt) })
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Session keeps jwt of the logged in user between runs of the cli, it is valid only for the mode it was created in
+type Session struct {
+	Mode  string `json:"mode"`
+	Login string `json:"login"`
+	Token string `json:"token"`
+	path  string
+}
+
+// DefaultSessionFile is in config directory of the user, temp directory if there is none
+func DefaultSessionFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "gophkeeper", "session")
+}
+
+// loadSession reads session of the mode, empty session is returned if there is none
+func loadSession(path, mode string) (*Session, error) {
+	s := &Session{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.Mode, s.path = mode, path
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, s); err != nil || s.Mode != mode {
+		s = &Session{}
+	}
+	s.Mode, s.path = mode, path
+	return s, nil
+}
+
+// save writes the session to the file only the user can read
+func (s *Session) save() error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	return err
+}
+
+// forget removes the session
+func (s *Session) forget() error {
+	s.Login, s.Token = "", ""
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,807 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/clipboard"
+	"github.com/maffka123/GophKeeper/internal/field"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	tuiHelp     = "/ search  tab pane  enter/space reveal  r reveal all  c copy  a add  e edit  d delete  s sync  ctrl-r reload  q quit"
+	tuiFormHelp = "tab/down next  shift-tab/up prev  ctrl-g generate  ctrl-r show  ctrl-u clear  ctrl-s save  esc cancel"
+	// tuiPoll is how often status of sync is refreshed
+	tuiPoll = 5 * time.Second
+	// maskedValue replaces values of masked fields
+	maskedValue = "••••••••"
+)
+
+// masked fields are hidden in the detail pane until they are revealed
+var masked = map[string]bool{"password": true, "number": true}
+
+// quitUI is posted to stop the ui when the context is done
+type quitUI struct{}
+
+// pane of the ui that has focus
+type pane int
+
+const (
+	paneSidebar pane = iota
+	paneList
+	paneDetail
+)
+
+// group is an entry of the sidebar, headers have no match
+type group struct {
+	key   string
+	label string
+	depth int
+	match func(d *pb.Data) bool
+}
+
+// tui is the full-screen terminal ui, it works with the backend from a single goroutine
+type tui struct {
+	ctx       context.Context
+	b         Backend
+	s         tcell.Screen
+	all       []*pb.Data
+	groups    []group
+	group     int
+	query     []rune
+	searching bool
+	items     []*pb.Data
+	item      int
+	top       int
+	current   *pb.Data
+	field     int
+	// revealed fields of the selected secret, card numbers are fetched again unmasked
+	revealed map[string]bool
+	unmasked *pb.Data
+	focus    pane
+	form     *form
+	// prompt asks for a single key, answer gets it
+	prompt  string
+	answer  func(r rune)
+	status  *Status
+	message string
+	failed  bool
+	// copier clears copied values after a while, nil if there is no clipboard
+	copier *clipboard.Copier
+}
+
+// newTUI creates ui drawing on the screen, load must be called before run
+func newTUI(ctx context.Context, b Backend, s tcell.Screen) *tui {
+	return &tui{ctx: ctx, b: b, s: s, focus: paneList, revealed: map[string]bool{}}
+}
+
+// load reads all secrets and rebuilds the sidebar
+func (t *tui) load() error {
+	all, err := t.b.List(t.ctx)
+	if err != nil {
+		return err
+	}
+	t.all = all
+	key := ""
+	if t.group < len(t.groups) {
+		key = t.groups[t.group].key
+	}
+	t.buildGroups()
+	t.group = 0
+	for i, g := range t.groups {
+		if g.key == key {
+			t.group = i
+		}
+	}
+	t.filter()
+	return nil
+}
+
+// reload loads secrets again and keeps the selected one
+func (t *tui) reload() {
+	name := ""
+	if d := t.selected(); d != nil {
+		name = d.Metadata
+	}
+	if err := t.load(); err != nil {
+		t.notify("", err)
+		return
+	}
+	t.selectName(name)
+}
+
+// run shows the ui until it is closed
+func (t *tui) run() error {
+	if err := t.s.Init(); err != nil {
+		return err
+	}
+	defer t.s.Fini()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		tick := time.NewTicker(tuiPoll)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				t.s.PostEvent(tcell.NewEventInterrupt(nil))
+			case <-t.ctx.Done():
+				t.s.PostEvent(tcell.NewEventInterrupt(quitUI{}))
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	t.refreshStatus()
+	for {
+		t.draw()
+		ev := t.s.PollEvent()
+		if ev == nil || t.handle(ev) {
+			return nil
+		}
+	}
+}
+
+// handle reacts to the event, true is returned to close the ui
+func (t *tui) handle(ev tcell.Event) bool {
+	switch ev := ev.(type) {
+	case *tcell.EventResize:
+		t.s.Sync()
+	case *tcell.EventInterrupt:
+		if _, ok := ev.Data().(quitUI); ok {
+			return true
+		}
+		t.refreshStatus()
+	case *tcell.EventKey:
+		return t.key(ev)
+	}
+	return false
+}
+
+func (t *tui) key(ev *tcell.EventKey) bool {
+	if t.answer != nil {
+		answer := t.answer
+		t.answer, t.prompt = nil, ""
+		if ev.Key() == tcell.KeyRune {
+			answer(unicode.ToLower(ev.Rune()))
+		}
+		return false
+	}
+	if t.form != nil {
+		t.formKey(ev)
+		return false
+	}
+	if t.searching {
+		t.searchKey(ev)
+		return false
+	}
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		return true
+	case tcell.KeyTab:
+		t.focus = (t.focus + 1) % 3
+	case tcell.KeyBacktab:
+		t.focus = (t.focus + 2) % 3
+	case tcell.KeyUp:
+		t.move(-1)
+	case tcell.KeyDown:
+		t.move(1)
+	case tcell.KeyPgUp:
+		t.move(-10)
+	case tcell.KeyPgDn:
+		t.move(10)
+	case tcell.KeyEnter:
+		if t.focus == paneDetail {
+			t.toggle()
+		} else {
+			t.focus++
+		}
+	case tcell.KeyEsc:
+		t.query = nil
+		t.filter()
+	case tcell.KeyCtrlR:
+		t.reload()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			return true
+		case '/':
+			t.searching, t.focus = true, paneList
+		case 'j':
+			t.move(1)
+		case 'k':
+			t.move(-1)
+		case ' ':
+			t.toggle()
+		case 'r':
+			t.toggleAll()
+		case 'c':
+			t.copy()
+		case 'a':
+			t.ask("Add login, card, text or file? [l/c/t/f]", func(r rune) {
+				kinds := map[rune]string{'l': "login", 'c': "card", 't': "text", 'f': "file"}
+				if kind, ok := kinds[r]; ok {
+					t.form = newForm(kind, nil)
+				}
+			})
+		case 'e':
+			t.edit()
+		case 'd':
+			t.remove()
+		case 's':
+			msg, err := t.b.Sync(t.ctx)
+			t.notify(msg, err)
+			t.refreshStatus()
+		}
+	}
+	return false
+}
+
+func (t *tui) searchKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter, tcell.KeyTab:
+		t.searching = false
+		return
+	case tcell.KeyEsc:
+		t.searching, t.query = false, nil
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(t.query) > 0 {
+			t.query = t.query[:len(t.query)-1]
+		}
+	case tcell.KeyCtrlU:
+		t.query = nil
+	case tcell.KeyUp:
+		t.move(-1)
+		return
+	case tcell.KeyDown:
+		t.move(1)
+		return
+	case tcell.KeyRune:
+		t.query = append(t.query, ev.Rune())
+	default:
+		return
+	}
+	t.item = 0
+	t.filter()
+}
+
+// ask shows the prompt and passes the next key to answer
+func (t *tui) ask(prompt string, answer func(r rune)) {
+	t.prompt, t.answer = prompt, answer
+}
+
+// notify shows the message or the error in the message line
+func (t *tui) notify(msg string, err error) {
+	if err != nil {
+		t.message, t.failed = err.Error(), true
+		return
+	}
+	t.message, t.failed = msg, false
+}
+
+// refreshStatus polls sync state, secrets are loaded again after every sync of the daemon
+func (t *tui) refreshStatus() {
+	st, err := t.b.Status(t.ctx)
+	if err != nil {
+		t.notify("", err)
+		return
+	}
+	prev := t.status
+	t.status = st
+	if prev != nil && prev.LastSync != st.LastSync && t.form == nil {
+		t.reload()
+	}
+}
+
+// buildGroups lists folders, types and collections of secrets, folders are "/" separated prefixes of names
+func (t *tui) buildGroups() {
+	t.groups = []group{{key: "all", label: "All", match: func(*pb.Data) bool { return true }}}
+	folders := map[string]bool{}
+	kinds := map[string]bool{}
+	collections := map[string]bool{}
+	for _, d := range t.all {
+		parts := strings.Split(d.Metadata, "/")
+		for i := 1; i < len(parts); i++ {
+			folders[strings.Join(parts[:i], "/")] = true
+		}
+		kinds[field.Kind(d)] = true
+		if d.CollectionID != "" {
+			collections[d.CollectionID] = true
+		}
+	}
+
+	if len(folders) > 0 {
+		t.groups = append(t.groups, group{key: "folders", label: "Folders"})
+		for _, f := range sortedKeys(folders) {
+			prefix := f + "/"
+			t.groups = append(t.groups, group{key: "folder:" + f, label: f[strings.LastIndex(f, "/")+1:],
+				depth: strings.Count(f, "/") + 1, match: func(d *pb.Data) bool { return strings.HasPrefix(d.Metadata, prefix) }})
+		}
+	}
+	t.groups = append(t.groups, group{key: "types", label: "Types"})
+	for _, k := range []string{"login", "card", "text", "file"} {
+		if kinds[k] {
+			k := k
+			t.groups = append(t.groups, group{key: "type:" + k, label: k, depth: 1,
+				match: func(d *pb.Data) bool { return field.Kind(d) == k }})
+		}
+	}
+	if len(collections) > 0 {
+		t.groups = append(t.groups, group{key: "collections", label: "Collections"})
+		for _, c := range sortedKeys(collections) {
+			c := c
+			t.groups = append(t.groups, group{key: "collection:" + c, label: c, depth: 1,
+				match: func(d *pb.Data) bool { return d.CollectionID == c }})
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// filter shows secrets of the selected group that match the search query
+func (t *tui) filter() {
+	g := t.groups[t.group]
+	q := strings.ToLower(string(t.query))
+	t.items = nil
+	for _, d := range t.all {
+		text := strings.ToLower(d.Metadata + " " + summary(d) + " " + d.GetData().GetText())
+		if g.match(d) && strings.Contains(text, q) {
+			t.items = append(t.items, d)
+		}
+	}
+	t.setItem(t.item)
+}
+
+func (t *tui) selected() *pb.Data {
+	if t.item >= 0 && t.item < len(t.items) {
+		return t.items[t.item]
+	}
+	return nil
+}
+
+// setItem selects the secret in the list, revealed fields are hidden again when another one is selected
+func (t *tui) setItem(i int) {
+	if i >= len(t.items) {
+		i = len(t.items) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	t.item = i
+	if d := t.selected(); d != t.current {
+		t.current, t.field, t.revealed, t.unmasked = d, 0, map[string]bool{}, nil
+	}
+}
+
+func (t *tui) selectName(name string) {
+	for i, d := range t.items {
+		if d.Metadata == name {
+			t.setItem(i)
+			return
+		}
+	}
+}
+
+func (t *tui) move(n int) {
+	switch t.focus {
+	case paneSidebar:
+		i, step := t.group, 1
+		if n < 0 {
+			n, step = -n, -1
+		}
+		for ; n > 0; n-- {
+			j := i + step
+			for j >= 0 && j < len(t.groups) && t.groups[j].match == nil {
+				j += step
+			}
+			if j < 0 || j >= len(t.groups) {
+				break
+			}
+			i = j
+		}
+		t.group, t.item = i, 0
+		t.filter()
+	case paneList:
+		t.setItem(t.item + n)
+	case paneDetail:
+		t.field += n
+		if last := len(field.All(t.shown())) - 1; t.field > last {
+			t.field = last
+		}
+		if t.field < 0 {
+			t.field = 0
+		}
+	}
+}
+
+// shown is the selected secret, unmasked if its card was revealed
+func (t *tui) shown() *pb.Data {
+	if t.unmasked != nil {
+		return t.unmasked
+	}
+	return t.selected()
+}
+
+// reveal shows or hides the masked field, masked card is fetched again
+func (t *tui) reveal(name string, show bool) {
+	t.revealed[name] = show
+	if d := t.selected(); show && name != "password" && t.unmasked == nil && d != nil {
+		u, err := exact(t.ctx, t.b, d.Metadata, true)
+		if err != nil {
+			t.notify("", err)
+			return
+		}
+		t.unmasked = u
+	}
+}
+
+// toggle reveals or hides the selected field in the detail pane, all fields elsewhere
+func (t *tui) toggle() {
+	if t.focus != paneDetail {
+		t.toggleAll()
+		return
+	}
+	all := field.All(t.shown())
+	if t.field < len(all) && masked[all[t.field].Name] {
+		name := all[t.field].Name
+		t.reveal(name, !t.revealed[name])
+	}
+}
+
+func (t *tui) toggleAll() {
+	show := true
+	for _, f := range field.All(t.shown()) {
+		if masked[f.Name] && t.revealed[f.Name] {
+			show = false
+		}
+	}
+	for _, f := range field.All(t.shown()) {
+		if masked[f.Name] {
+			t.reveal(f.Name, show)
+		}
+	}
+}
+
+// copy puts the selected field on the clipboard, the default field of the secret outside the detail pane
+func (t *tui) copy() {
+	d := t.selected()
+	if d == nil {
+		return
+	}
+	if t.copier == nil {
+		t.notify("", fmt.Errorf("clipboard is not available"))
+		return
+	}
+	u, err := exact(t.ctx, t.b, d.Metadata, true)
+	if err != nil {
+		t.notify("", err)
+		return
+	}
+	name := field.Default(u)
+	if all := field.All(u); t.focus == paneDetail && t.field < len(all) {
+		name = all[t.field].Name
+	}
+	v, err := field.Value(u, name)
+	if err == nil {
+		err = t.copier.Copy(v)
+	}
+	if err != nil {
+		t.notify("", err)
+		return
+	}
+	msg := fmt.Sprintf("copied %s of %s", name, d.Metadata)
+	if t.copier.Pending() {
+		msg += fmt.Sprintf(", clipboard is cleared in %s", t.copier.ClearAfter)
+	}
+	t.notify(msg, nil)
+}
+
+// edit opens the form of the selected secret, it is fetched unmasked
+func (t *tui) edit() {
+	d := t.selected()
+	if d == nil {
+		return
+	}
+	u, err := exact(t.ctx, t.b, d.Metadata, true)
+	if err != nil {
+		t.notify("", err)
+		return
+	}
+	t.form = newForm(field.Kind(u), u)
+}
+
+// remove deletes the selected secret after confirmation
+func (t *tui) remove() {
+	d := t.selected()
+	if d == nil {
+		return
+	}
+	name := d.Metadata
+	t.ask(fmt.Sprintf("Delete %s %s? [y/N]", field.Kind(d), name), func(r rune) {
+		if r != 'y' {
+			t.notify("nothing deleted", nil)
+			return
+		}
+		if _, err := t.b.Delete(t.ctx, d.ID); err != nil {
+			t.notify("", err)
+			return
+		}
+		t.notify("deleted "+name, nil)
+		t.reload()
+	})
+}
+
+// save adds or updates the secret of the form, the form stays open on errors
+func (t *tui) save() {
+	d, err := t.form.build()
+	if err != nil {
+		t.notify("", err)
+		return
+	}
+	if t.form.id == "" {
+		_, err = t.b.Add(t.ctx, d)
+	} else {
+		err = t.b.Update(t.ctx, t.form.id, d)
+	}
+	if err != nil {
+		t.notify("", err)
+		return
+	}
+	t.form = nil
+	t.notify("saved "+d.Metadata, nil)
+	t.reload()
+	t.selectName(d.Metadata)
+}
+
+// indicator describes sync in the title bar
+func (t *tui) indicator() (string, tcell.Style) {
+	if t.status == nil {
+		return "", tcell.StyleDefault
+	}
+	style := tcell.StyleDefault.Reverse(true)
+	switch st := t.status; {
+	case !st.Reachable || st.SyncError != "":
+		style = style.Foreground(tcell.ColorRed)
+	case st.Syncing || st.Offline:
+		style = style.Foreground(tcell.ColorYellow)
+	case st.LastSync != "":
+		style = style.Foreground(tcell.ColorGreen)
+	}
+	return "sync: " + syncSummary(t.status), style
+}
+
+// put writes the string from x within width w, the end of the string is returned
+func (t *tui) put(x, y, w int, style tcell.Style, s string) int {
+	for _, r := range s {
+		if r == '\t' || r == '\n' {
+			r = ' '
+		}
+		rw := runewidth.RuneWidth(r)
+		if rw == 0 || unicode.IsControl(r) {
+			continue
+		}
+		if w < rw {
+			break
+		}
+		t.s.SetContent(x, y, r, nil, style)
+		x, w = x+rw, w-rw
+	}
+	return x
+}
+
+func (t *tui) fill(x, y, w int, style tcell.Style) {
+	for i := 0; i < w; i++ {
+		t.s.SetContent(x+i, y, ' ', nil, style)
+	}
+}
+
+// header draws title of the pane, title of the focused pane is highlighted
+func (t *tui) header(x, y, w int, title string, focused bool) {
+	style := tcell.StyleDefault.Bold(true)
+	if focused {
+		style = style.Reverse(true)
+	}
+	t.fill(x, y, w, style)
+	t.put(x+1, y, w-1, style, title)
+}
+
+func (t *tui) draw() {
+	t.s.Clear()
+	t.s.HideCursor()
+	w, h := t.s.Size()
+	if w < 60 || h < 10 {
+		t.put(0, 0, w, tcell.StyleDefault, "terminal is too small")
+		t.s.Show()
+		return
+	}
+
+	bar := tcell.StyleDefault.Reverse(true)
+	t.fill(0, 0, w, bar)
+	title := " GophKeeper"
+	if t.status != nil && t.status.Login != "" {
+		title += " - " + t.status.Login
+	}
+	t.put(0, 0, w, bar, title)
+	if ind, style := t.indicator(); ind != "" {
+		t.put(w-runewidth.StringWidth(ind)-1, 0, w, style, ind)
+	}
+
+	end := t.put(0, 1, w, tcell.StyleDefault.Bold(true), "Search: ")
+	end = t.put(end, 1, w-end, tcell.StyleDefault, string(t.query))
+	if t.searching {
+		t.s.ShowCursor(end, 1)
+	}
+
+	top, rows := 2, h-4
+	side := 24
+	if w < 100 {
+		side = 18
+	}
+	listW := (w - side) * 2 / 5
+	detailX := side + listW + 2
+	for y := top; y < top+rows; y++ {
+		t.s.SetContent(side, y, '│', nil, tcell.StyleDefault)
+		t.s.SetContent(side+listW+1, y, '│', nil, tcell.StyleDefault)
+	}
+	t.drawSidebar(0, top, side, rows)
+	t.drawList(side+1, top, listW, rows)
+	if t.form != nil {
+		t.drawForm(detailX, top, w-detailX, rows)
+	} else {
+		t.drawDetail(detailX, top, w-detailX, rows)
+	}
+
+	msg, style := t.message, tcell.StyleDefault
+	if t.prompt != "" {
+		msg, style = t.prompt, style.Bold(true)
+	} else if t.failed {
+		style = style.Foreground(tcell.ColorRed)
+	}
+	t.put(0, h-2, w, style, msg)
+	help := tuiHelp
+	if t.form != nil {
+		help = tuiFormHelp
+	}
+	t.put(0, h-1, w, tcell.StyleDefault.Dim(true), help)
+	t.s.Show()
+}
+
+func (t *tui) drawSidebar(x, y, w, rows int) {
+	t.header(x, y, w, "Groups", t.focus == paneSidebar)
+	for i, g := range t.groups {
+		if i+1 >= rows {
+			break
+		}
+		style := tcell.StyleDefault
+		switch {
+		case g.match == nil:
+			style = style.Bold(true)
+		case i == t.group && t.focus == paneSidebar:
+			style = style.Reverse(true)
+		case i == t.group:
+			style = style.Underline(true)
+		}
+		label := strings.Repeat("  ", g.depth) + g.label
+		if g.match != nil {
+			n := 0
+			for _, d := range t.all {
+				if g.match(d) {
+					n++
+				}
+			}
+			label += fmt.Sprintf(" (%d)", n)
+		}
+		t.put(x+1, y+1+i, w-1, style, label)
+	}
+}
+
+func (t *tui) drawList(x, y, w, rows int) {
+	t.header(x, y, w, fmt.Sprintf("Secrets %d/%d", len(t.items), len(t.all)), t.focus == paneList)
+	rows--
+	if len(t.items) == 0 {
+		t.put(x+1, y+1, w-1, tcell.StyleDefault.Dim(true), "nothing matches")
+		return
+	}
+	if t.item < t.top {
+		t.top = t.item
+	}
+	if t.item >= t.top+rows {
+		t.top = t.item - rows + 1
+	}
+	for i := t.top; i < len(t.items) && i < t.top+rows; i++ {
+		d := t.items[i]
+		style, dim := tcell.StyleDefault, tcell.StyleDefault.Dim(true)
+		if i == t.item {
+			if t.focus == paneList {
+				style, dim = style.Reverse(true), dim.Reverse(true)
+			} else {
+				style, dim = style.Underline(true), dim.Underline(true)
+			}
+			t.fill(x, y+1+i-t.top, w, style)
+		}
+		end := t.put(x+1, y+1+i-t.top, w-1, style.Bold(true), d.Metadata)
+		t.put(end+2, y+1+i-t.top, w-(end+2-x), dim, summary(d))
+	}
+}
+
+// fieldText is value of the field as shown in the detail pane
+func (t *tui) fieldText(f field.Field) string {
+	switch {
+	case f.Name == "file":
+		return fmt.Sprintf("%d bytes", len(f.Value))
+	case masked[f.Name] && !t.revealed[f.Name]:
+		return maskedValue
+	}
+	return f.Value
+}
+
+func (t *tui) drawDetail(x, y, w, rows int) {
+	t.header(x, y, w, "Details", t.focus == paneDetail)
+	d := t.shown()
+	if d == nil {
+		return
+	}
+	row := y + 1
+	for i, f := range field.All(d) {
+		style := tcell.StyleDefault
+		if i == t.field && t.focus == paneDetail {
+			style = style.Reverse(true)
+		}
+		end := t.put(x+1, row, 12, style.Bold(true), f.Name+":")
+		t.fill(end, row, x+13-end, style)
+		lines := wrap(t.fieldText(f), w-14)
+		for j, line := range lines {
+			if row >= y+rows {
+				return
+			}
+			if j > 0 {
+				row++
+				if row >= y+rows {
+					return
+				}
+			}
+			t.put(x+13, row, w-14, style, line)
+		}
+		row++
+		if row >= y+rows {
+			return
+		}
+	}
+}
+
+// wrap splits text into lines not wider than w
+func wrap(s string, w int) []string {
+	if w < 1 {
+		w = 1
+	}
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		for runewidth.StringWidth(line) > w {
+			cut := runewidth.Truncate(line, w, "")
+			if cut == "" {
+				break
+			}
+			out = append(out, cut)
+			line = line[len(cut):]
+		}
+		out = append(out, line)
+	}
+	return out
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/mattn/go-runewidth"
+	"google.golang.org/protobuf/proto"
+)
+
+// input is a field of the edit form
+type input struct {
+	label     string
+	value     []rune
+	cursor    int
+	secret    bool
+	shown     bool
+	multiline bool
+}
+
+func (in *input) insert(r rune) {
+	in.value = append(in.value[:in.cursor], append([]rune{r}, in.value[in.cursor:]...)...)
+	in.cursor++
+}
+
+func (in *input) set(s string) {
+	in.value = []rune(s)
+	in.cursor = len(in.value)
+}
+
+// text is the value as shown in the form, hidden secrets are masked
+func (in *input) text() string {
+	if in.secret && !in.shown {
+		return strings.Repeat("•", len(in.value))
+	}
+	return string(in.value)
+}
+
+// form edits fields of the secret that matter for its kind
+type form struct {
+	title string
+	kind  string
+	// id of the edited secret, empty for a new one
+	id     string
+	data   *pb.Data
+	inputs []*input
+	cur    int
+}
+
+// newForm creates form of the secret, d is nil for a new one
+func newForm(kind string, d *pb.Data) *form {
+	f := &form{kind: kind, title: "New " + kind}
+	if d != nil {
+		f.data, f.id, f.title = proto.Clone(d).(*pb.Data), d.ID, "Edit "+d.Metadata
+	} else {
+		f.data = &pb.Data{}
+	}
+	kd := f.data.GetData()
+	f.add("Name", f.data.Metadata)
+	switch kind {
+	case "login":
+		a := kd.GetAuthData()
+		var urls []string
+		for _, u := range a.GetURIs() {
+			urls = append(urls, u.URI)
+		}
+		f.add("Login", a.GetLogin())
+		f.add("Password", a.GetPassword()).secret = true
+		f.add("URLs", strings.Join(urls, " "))
+	case "card":
+		c := kd.GetBankCard()
+		f.add("Number", c.GetCardNumber()).secret = true
+		f.add("Expiry", c.GetExpiry())
+		f.add("Holder", c.GetHolderName())
+		f.add("Bank", c.GetBankName())
+		f.add("Address", c.GetAddress())
+	case "text":
+		f.add("Text", kd.GetText()).multiline = true
+	case "file":
+		// empty path keeps the current content
+		f.add("File", "")
+		f.add("Text", kd.GetText()).multiline = true
+	}
+	f.add("Expires", f.data.ExpiresAt)
+	f.add("Collection", f.data.CollectionID)
+	return f
+}
+
+func (f *form) add(label, value string) *input {
+	in := &input{label: label}
+	in.set(value)
+	f.inputs = append(f.inputs, in)
+	return in
+}
+
+func (f *form) value(label string) string {
+	for _, in := range f.inputs {
+		if in.label == label {
+			return strings.TrimSpace(string(in.value))
+		}
+	}
+	return ""
+}
+
+func (f *form) next(n int) {
+	f.cur = (f.cur + n + len(f.inputs)) % len(f.inputs)
+}
+
+// build returns the secret with values of the form
+func (f *form) build() (*pb.Data, error) {
+	d := proto.Clone(f.data).(*pb.Data)
+	clean(d)
+	if d.Metadata = f.value("Name"); d.Metadata == "" {
+		return nil, fmt.Errorf("name is empty")
+	}
+	d.ExpiresAt, d.CollectionID = f.value("Expires"), f.value("Collection")
+	if d.Data == nil {
+		d.Data = &pb.KeepData{}
+	}
+	switch f.kind {
+	case "login":
+		if d.Data.AuthData == nil {
+			d.Data.AuthData = &pb.AuthData{}
+		}
+		a := d.Data.AuthData
+		// match modes of kept urls stay as they are
+		old := map[string]*pb.LoginURI{}
+		for _, u := range a.URIs {
+			old[u.URI] = u
+		}
+		a.Login, a.Password, a.URIs = f.value("Login"), f.value("Password"), nil
+		for _, u := range strings.Fields(f.value("URLs")) {
+			if o, ok := old[u]; ok {
+				a.URIs = append(a.URIs, o)
+			} else {
+				a.URIs = append(a.URIs, &pb.LoginURI{URI: u})
+			}
+		}
+	case "card":
+		if d.Data.BankCard == nil {
+			d.Data.BankCard = &pb.BankCard{}
+		}
+		c := d.Data.BankCard
+		c.CardNumber, c.Expiry = f.value("Number"), f.value("Expiry")
+		c.HolderName, c.BankName, c.Address = f.value("Holder"), f.value("Bank"), f.value("Address")
+	case "text":
+		d.Data.Text = f.value("Text")
+	case "file":
+		if path := f.value("File"); path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			d.Data.Binary = b
+		} else if len(d.Data.Binary) == 0 {
+			return nil, fmt.Errorf("file is not given")
+		}
+		d.Data.Text = f.value("Text")
+	}
+	return d, nil
+}
+
+func (t *tui) formKey(ev *tcell.EventKey) {
+	f := t.form
+	in := f.inputs[f.cur]
+	switch ev.Key() {
+	case tcell.KeyEsc:
+		t.form = nil
+		t.notify("nothing changed", nil)
+	case tcell.KeyTab, tcell.KeyDown:
+		f.next(1)
+	case tcell.KeyBacktab, tcell.KeyUp:
+		f.next(-1)
+	case tcell.KeyEnter:
+		if in.multiline {
+			in.insert('\n')
+		} else {
+			f.next(1)
+		}
+	case tcell.KeyCtrlS:
+		t.save()
+	case tcell.KeyCtrlG:
+		if in.label != "Password" {
+			return
+		}
+		resp, err := t.b.Generate(t.ctx, &pb.GenerateRequest{Kind: "password"})
+		if err != nil {
+			t.notify("", err)
+			return
+		}
+		in.set(resp.Password)
+		t.notify(fmt.Sprintf("generated password with %.0f bits of entropy", resp.Entropy), nil)
+	case tcell.KeyCtrlR:
+		in.shown = !in.shown
+	case tcell.KeyCtrlU:
+		in.set("")
+	case tcell.KeyLeft:
+		if in.cursor > 0 {
+			in.cursor--
+		}
+	case tcell.KeyRight:
+		if in.cursor < len(in.value) {
+			in.cursor++
+		}
+	case tcell.KeyHome:
+		in.cursor = 0
+	case tcell.KeyEnd:
+		in.cursor = len(in.value)
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if in.cursor > 0 {
+			in.value = append(in.value[:in.cursor-1], in.value[in.cursor:]...)
+			in.cursor--
+		}
+	case tcell.KeyDelete:
+		if in.cursor < len(in.value) {
+			in.value = append(in.value[:in.cursor], in.value[in.cursor+1:]...)
+		}
+	case tcell.KeyRune:
+		in.insert(ev.Rune())
+	}
+}
+
+func (t *tui) drawForm(x, y, w, rows int) {
+	f := t.form
+	t.header(x, y, w, f.title, true)
+	row := y + 1
+	valueX, valueW := x+13, w-14
+	for i, in := range f.inputs {
+		if row >= y+rows {
+			return
+		}
+		style := tcell.StyleDefault
+		if i == f.cur {
+			style = style.Underline(true)
+		}
+		t.put(x+1, row, 12, tcell.StyleDefault.Bold(true), in.label+":")
+		lines := strings.Split(in.text(), "\n")
+		// single line values scroll to keep the cursor visible
+		before := string([]rune(in.text())[:in.cursor])
+		cursorLine := strings.Count(before, "\n")
+		col := runewidth.StringWidth(before[strings.LastIndex(before, "\n")+1:])
+		if !in.multiline && col >= valueW {
+			shift := col - valueW + 1
+			lines[0] = string([]rune(lines[0])[shift:])
+			col -= shift
+		}
+		for j, line := range lines {
+			if row >= y+rows {
+				return
+			}
+			t.fill(valueX, row, valueW, style)
+			t.put(valueX, row, valueW, style, line)
+			if i == f.cur && j == cursorLine {
+				t.s.ShowCursor(valueX+col, row)
+			}
+			row++
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/clipboard"
+	"github.com/maffka123/GophKeeper/internal/field"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// screenText returns rows of the simulation screen
+func screenText(s tcell.SimulationScreen) string {
+	cells, w, _ := s.GetContents()
+	var b strings.Builder
+	for i, c := range cells {
+		if len(c.Runes) > 0 {
+			b.WriteRune(c.Runes[0])
+		}
+		if (i+1)%w == 0 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func newTestTUI(t *testing.T) (*tui, *fakeBackend, tcell.SimulationScreen) {
+	f := &fakeBackend{updated: map[string]*pb.Data{}, data: []*pb.Data{
+		login("mail", "me", "pass1"), login("mail/work", "boss", "pass2"), login("github", "octo", "pass3"),
+		{Metadata: "note", Data: &pb.KeepData{Text: "remember the milk"}},
+	}}
+	s := tcell.NewSimulationScreen("UTF-8")
+	require.NoError(t, s.Init())
+	s.SetSize(120, 30)
+	ui := newTUI(context.Background(), f, s)
+	require.NoError(t, ui.load())
+	ui.refreshStatus()
+	return ui, f, s
+}
+
+// press sends keys to the ui, runes are typed and named keys are pressed
+func press(ui *tui, keys ...interface{}) {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			for _, r := range k {
+				ui.handle(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+			}
+		case tcell.Key:
+			ui.handle(tcell.NewEventKey(k, 0, tcell.ModNone))
+		}
+	}
+}
+
+func TestTUI_Browse(t *testing.T) {
+	ui, _, s := newTestTUI(t)
+	ui.draw()
+	screen := screenText(s)
+	assert.Contains(t, screen, "Secrets 4/4")
+	assert.Contains(t, screen, "sync: synced at 2022-10-01 10:00:00")
+	assert.Contains(t, screen, "  mail (1)")
+	assert.Contains(t, screen, "  login (3)")
+	assert.Contains(t, screen, "password:   "+maskedValue)
+	assert.NotContains(t, screen, "pass1")
+
+	// reveal the password of the first secret
+	press(ui, tcell.KeyTab, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, " ")
+	ui.draw()
+	assert.Contains(t, screenText(s), "password:   pass1")
+	// another secret is masked again
+	press(ui, tcell.KeyBacktab, tcell.KeyDown)
+	ui.draw()
+	assert.Contains(t, screenText(s), "password:   "+maskedValue)
+
+	press(ui, "/", "octo", tcell.KeyEnter)
+	require.Len(t, ui.items, 1)
+	assert.Equal(t, "github", ui.selected().Metadata)
+	press(ui, tcell.KeyEsc)
+	assert.Len(t, ui.items, 4)
+
+	// folder of the sidebar
+	press(ui, tcell.KeyBacktab, tcell.KeyDown)
+	require.Len(t, ui.items, 1)
+	assert.Equal(t, "mail/work", ui.selected().Metadata)
+}
+
+func TestTUI_Edit(t *testing.T) {
+	ui, f, _ := newTestTUI(t)
+
+	press(ui, "/", "github", tcell.KeyEnter, "e")
+	require.NotNil(t, ui.form)
+	press(ui, tcell.KeyDown, tcell.KeyCtrlU, "octo2", tcell.KeyDown, tcell.KeyCtrlG, tcell.KeyCtrlS)
+	assert.Nil(t, ui.form)
+	d := f.updated["id-github"]
+	require.NotNil(t, d)
+	assert.Equal(t, "octo2", d.Data.AuthData.Login)
+	assert.Equal(t, "generated", d.Data.AuthData.Password)
+	assert.Equal(t, "https://github", d.Data.AuthData.URIs[0].URI)
+
+	// name is required, the form stays open
+	press(ui, "a", "t", tcell.KeyCtrlS)
+	require.NotNil(t, ui.form)
+	assert.Equal(t, "name is empty", ui.message)
+	press(ui, "todo", tcell.KeyTab, "one", tcell.KeyEnter, "two", tcell.KeyCtrlS)
+	assert.Nil(t, ui.form)
+	added := f.data[len(f.data)-1]
+	assert.Equal(t, "todo", added.Metadata)
+	assert.Equal(t, "one\ntwo", added.Data.Text)
+
+	press(ui, "e", tcell.KeyEsc)
+	assert.Nil(t, ui.form)
+	assert.Equal(t, "nothing changed", ui.message)
+}
+
+func TestTUI_Delete(t *testing.T) {
+	ui, f, _ := newTestTUI(t)
+
+	// only the selected secret is deleted, not others whose names contain its name
+	press(ui, "d", "n")
+	assert.Empty(t, f.deleted)
+	press(ui, "d", "y")
+	assert.Equal(t, []string{"id-mail"}, f.deleted)
+	assert.Equal(t, "deleted mail", ui.message)
+}
+
+func TestTUI_Copy(t *testing.T) {
+	ui, _, s := newTestTUI(t)
+	press(ui, "c")
+	ui.draw()
+	assert.Contains(t, screenText(s), "clipboard is not available")
+
+	cb := &memClipboard{}
+	ui.copier = clipboard.NewCopier(cb, time.Minute, nil)
+	press(ui, "c")
+	assert.Equal(t, []string{"pass1"}, cb.writes)
+	ui.draw()
+	assert.Contains(t, screenText(s), "copied password of mail, clipboard is cleared in 1m0s")
+	assert.NotContains(t, screenText(s), "pass1")
+
+	// the selected field is copied in the detail pane
+	press(ui, tcell.KeyTab, "c")
+	first := field.All(ui.selected())[0]
+	assert.Equal(t, first.Value, cb.writes[1])
+
+	ui.copier.Flush()
+	assert.Equal(t, "", cb.text)
+}
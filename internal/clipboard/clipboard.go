@@ -0,0 +1,199 @@
+// Package clipboard copies values of secrets to the clipboard of the desktop (wayland or x11) or of the terminal
+// with OSC 52 and clears it later, but only if it still holds the copied value. Values are passed to the tools
+// through stdin and are never logged
+package clipboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// kinds of clipboards
+const (
+	KindAuto    = "auto"
+	KindWayland = "wayland"
+	KindX11     = "x11"
+	KindOSC52   = "osc52"
+)
+
+// ErrUnreadable is returned by clipboards that cannot be read back, like OSC 52 of the terminal
+var ErrUnreadable = errors.New("clipboard cannot be read")
+
+// Clipboard holds text copied by the user
+type Clipboard interface {
+	Name() string
+	Write(text string) error
+	Read() (string, error)
+	Clear() error
+}
+
+// lookPath finds tools, it is replaced in tests
+var lookPath = exec.LookPath
+
+// Detect returns clipboard of the kind, auto uses wayland or x11 if their display is set and OSC 52 otherwise
+func Detect(kind string) (Clipboard, error) {
+	switch kind {
+	case KindWayland:
+		return wayland()
+	case KindX11:
+		return x11()
+	case KindOSC52:
+		return newOSC52()
+	case "", KindAuto:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			if c, err := wayland(); err == nil {
+				return c, nil
+			}
+		}
+		if os.Getenv("DISPLAY") != "" {
+			if c, err := x11(); err == nil {
+				return c, nil
+			}
+		}
+		return newOSC52()
+	}
+	return nil, fmt.Errorf("unknown clipboard %s, use %s, %s, %s or %s", kind, KindAuto, KindWayland, KindX11, KindOSC52)
+}
+
+// command keeps the clipboard with tools like wl-copy or xclip
+type command struct {
+	name  string
+	write []string
+	read  []string
+	// clear is optional, empty text is written without it
+	clear []string
+}
+
+func wayland() (*command, error) {
+	c := &command{name: KindWayland, write: []string{"wl-copy"}, read: []string{"wl-paste", "--no-newline"},
+		clear: []string{"wl-copy", "--clear"}}
+	return c, c.find()
+}
+
+func x11() (*command, error) {
+	xclip := &command{name: KindX11, write: []string{"xclip", "-selection", "clipboard"},
+		read: []string{"xclip", "-selection", "clipboard", "-o"}}
+	if xclip.find() == nil {
+		return xclip, nil
+	}
+	xsel := &command{name: KindX11, write: []string{"xsel", "--clipboard", "--input"},
+		read: []string{"xsel", "--clipboard", "--output"}, clear: []string{"xsel", "--clipboard", "--delete"}}
+	if err := xsel.find(); err != nil {
+		return nil, fmt.Errorf("neither xclip nor xsel is found")
+	}
+	return xsel, nil
+}
+
+// find resolves paths of the tools
+func (c *command) find() error {
+	for _, args := range [][]string{c.write, c.read, c.clear} {
+		if len(args) == 0 {
+			continue
+		}
+		path, err := lookPath(args[0])
+		if err != nil {
+			return fmt.Errorf("%s is not found: %v", args[0], err)
+		}
+		args[0] = path
+	}
+	return nil
+}
+
+func (c *command) Name() string {
+	return c.name
+}
+
+// run runs the tool with text on stdin, the tool may keep serving the selection in background,
+// so its output is not waited for
+func (c *command) run(args []string, text string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %v", args[0], err)
+	}
+	return nil
+}
+
+func (c *command) Write(text string) error {
+	return c.run(c.write, text)
+}
+
+func (c *command) Read() (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(c.read[0], c.read[1:]...)
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %v", c.read[0], err)
+	}
+	return out.String(), nil
+}
+
+func (c *command) Clear() error {
+	if len(c.clear) == 0 {
+		return c.Write("")
+	}
+	return c.run(c.clear, "")
+}
+
+// osc52 asks the terminal to set its clipboard, it works over ssh too. Terminals do not let the clipboard
+// to be read back, so it is cleared without the check
+type osc52 struct {
+	w io.Writer
+	// tmux passes the sequence to the terminal only wrapped
+	tmux bool
+}
+
+func newOSC52() (*osc52, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("no display for wayland or x11 and no terminal for OSC 52: %v", err)
+	}
+	return &osc52{w: tty, tmux: os.Getenv("TMUX") != ""}, nil
+}
+
+func (o *osc52) Name() string {
+	return KindOSC52
+}
+
+func (o *osc52) Write(text string) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if o.tmux {
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	_, err := io.WriteString(o.w, seq)
+	return err
+}
+
+func (o *osc52) Read() (string, error) {
+	return "", ErrUnreadable
+}
+
+func (o *osc52) Clear() error {
+	return o.Write("")
+}
+
+// Sum is the hash the copied value is recognised by, so the value is not kept until the clipboard is cleared
+func Sum(text string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(text))
+}
+
+// ClearIf clears the clipboard if it still holds the value with the sum, true is returned if it was cleared.
+// Clipboards that cannot be read are cleared without the check
+func ClearIf(c Clipboard, want [sha256.Size]byte) (bool, error) {
+	text, err := c.Read()
+	switch {
+	case errors.Is(err, ErrUnreadable):
+	case err != nil:
+		return false, err
+	case Sum(text) != want:
+		return false, nil
+	}
+	return true, c.Clear()
+}
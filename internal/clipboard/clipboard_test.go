@@ -0,0 +1,168 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTools writes xclip and wl-copy scripts that keep the clipboard in a file
+func fakeTools(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("tools are shell scripts")
+	}
+	dir := t.TempDir()
+	board := filepath.Join(dir, "board")
+	scripts := map[string]string{
+		"xclip":    `if [ "$3" = "-o" ]; then cat "` + board + `"; else cat > "` + board + `"; fi`,
+		"wl-copy":  `if [ "$1" = "--clear" ]; then : > "` + board + `"; else cat > "` + board + `"; fi`,
+		"wl-paste": `cat "` + board + `"`,
+	}
+	for name, script := range scripts {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0700))
+	}
+	old := lookPath
+	lookPath = func(file string) (string, error) {
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	t.Cleanup(func() { lookPath = old })
+	return board
+}
+
+func TestDetect(t *testing.T) {
+	fakeTools(t)
+	tests := []struct {
+		kind    string
+		want    string
+		wantErr string
+	}{
+		{kind: KindX11, want: KindX11},
+		{kind: KindWayland, want: KindWayland},
+		{kind: "pigeon", wantErr: "unknown clipboard pigeon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			c, err := Detect(tt.kind)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c.Name())
+
+			require.NoError(t, c.Write("s3cret"))
+			got, err := c.Read()
+			require.NoError(t, err)
+			assert.Equal(t, "s3cret", got)
+			require.NoError(t, c.Clear())
+			got, err = c.Read()
+			require.NoError(t, err)
+			assert.Empty(t, got)
+		})
+	}
+}
+
+func TestClearIf(t *testing.T) {
+	board := fakeTools(t)
+	c, err := x11()
+	require.NoError(t, err)
+
+	require.NoError(t, c.Write("s3cret"))
+	cleared, err := ClearIf(c, Sum("s3cret"))
+	require.NoError(t, err)
+	assert.True(t, cleared)
+	b, _ := os.ReadFile(board)
+	assert.Empty(t, b)
+
+	// the user copied something else meanwhile
+	require.NoError(t, c.Write("shopping list"))
+	cleared, err = ClearIf(c, Sum("s3cret"))
+	require.NoError(t, err)
+	assert.False(t, cleared)
+	b, _ = os.ReadFile(board)
+	assert.Equal(t, "shopping list", string(b))
+
+	// terminal clipboard cannot be checked
+	var out bytes.Buffer
+	cleared, err = ClearIf(&osc52{w: &out}, Sum("s3cret"))
+	require.NoError(t, err)
+	assert.True(t, cleared)
+	assert.Equal(t, "\x1b]52;c;\a", out.String())
+}
+
+func TestOSC52(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, (&osc52{w: &out}).Write("s3cret"))
+	assert.Equal(t, "\x1b]52;c;czNjcmV0\a", out.String())
+	out.Reset()
+	require.NoError(t, (&osc52{w: &out, tmux: true}).Write("s3cret"))
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;czNjcmV0\a\x1b\\", out.String())
+	_, err := (&osc52{w: &out}).Read()
+	assert.True(t, errors.Is(err, ErrUnreadable))
+}
+
+// memory is clipboard in memory
+type memory struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (m *memory) Name() string { return "memory" }
+func (m *memory) Write(text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.text = text
+	return nil
+}
+func (m *memory) Read() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.text, nil
+}
+func (m *memory) Clear() error { return m.Write("") }
+
+func TestCopier(t *testing.T) {
+	m := &memory{}
+	reports := make(chan bool, 3)
+	p := NewCopier(m, 20*time.Millisecond, func(cleared bool, err error) {
+		assert.NoError(t, err)
+		reports <- cleared
+	})
+
+	require.NoError(t, p.Copy("first"))
+	require.NoError(t, p.Copy("second"))
+	assert.True(t, p.Pending())
+	assert.True(t, <-reports)
+	text, _ := m.Read()
+	assert.Empty(t, text)
+	assert.False(t, p.Pending())
+	// the first copy does not clear on its own
+	select {
+	case <-reports:
+		t.Fatal("clipboard is cleared twice")
+	case <-time.After(40 * time.Millisecond):
+	}
+
+	require.NoError(t, p.Copy("third"))
+	m.Write("changed by user")
+	p.Flush()
+	assert.False(t, <-reports)
+	text, _ = m.Read()
+	assert.Equal(t, "changed by user", text)
+
+	never := NewCopier(m, 0, nil)
+	require.NoError(t, never.Copy("kept"))
+	assert.False(t, never.Pending())
+}
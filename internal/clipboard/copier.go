@@ -0,0 +1,77 @@
+package clipboard
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Copier copies values to the clipboard and clears it after ClearAfter, a new copy cancels clearing of the previous one
+type Copier struct {
+	Clipboard
+	ClearAfter time.Duration
+	// report is called after every clear with its result, the value is not given
+	report  func(cleared bool, err error)
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending *[sha256.Size]byte
+}
+
+// NewCopier creates copier to the clipboard, it is never cleared if clearAfter is zero, report can be nil
+func NewCopier(c Clipboard, clearAfter time.Duration, report func(cleared bool, err error)) *Copier {
+	return &Copier{Clipboard: c, ClearAfter: clearAfter, report: report}
+}
+
+// Copy puts text into the clipboard and schedules clearing
+func (p *Copier) Copy(text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.Write(text); err != nil {
+		return err
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer, p.pending = nil, nil
+	}
+	if p.ClearAfter <= 0 {
+		return nil
+	}
+	s := Sum(text)
+	p.pending = &s
+	var timer *time.Timer
+	timer = time.AfterFunc(p.ClearAfter, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		// copy made meanwhile has its own timer
+		if p.timer == timer {
+			p.clear()
+		}
+	})
+	p.timer = timer
+	return nil
+}
+
+// Flush clears the clipboard now if clearing is pending, the copied value must not outlive the process
+func (p *Copier) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.clear()
+	}
+}
+
+// Pending tells whether the clipboard is still to be cleared
+func (p *Copier) Pending() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pending != nil
+}
+
+func (p *Copier) clear() {
+	cleared, err := ClearIf(p.Clipboard, *p.pending)
+	p.timer, p.pending = nil, nil
+	if p.report != nil {
+		p.report(cleared, err)
+	}
+}
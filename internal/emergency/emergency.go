@@ -0,0 +1,64 @@
+// Package emergency holds rules of emergency access: trusted contact requests access to the vault of the grantor,
+// the grantor has a waiting period to reject it, after that the contact gets read-only access or takes over the account.
+//
+// Steps go invited -> accepted -> confirmed -> recovery initiated -> recovery approved, rejected request goes back to confirmed.
+// When confirming the grantor seals the vault key to public key of the contact, so the server never sees it.
+package emergency
+
+import (
+	"fmt"
+
+	"github.com/maffka123/GophKeeper/internal/sharing"
+	"github.com/maffka123/GophKeeper/internal/vault"
+)
+
+// Access types
+const (
+	AccessView     = "view"
+	AccessTakeover = "takeover"
+)
+
+// Statuses of emergency access
+const (
+	StatusInvited           = "invited"
+	StatusAccepted          = "accepted"
+	StatusConfirmed         = "confirmed"
+	StatusRecoveryInitiated = "recovery_initiated"
+	StatusRecoveryApproved  = "recovery_approved"
+)
+
+const (
+	DefaultWaitDays = 7
+	MaxWaitDays     = 90
+)
+
+// ValidAccess reports whether the access type is known
+func ValidAccess(access string) bool {
+	return access == AccessView || access == AccessTakeover
+}
+
+// WaitDays returns waiting period within allowed bounds, 0 means the default one
+func WaitDays(days int64) (int64, error) {
+	switch {
+	case days == 0:
+		return DefaultWaitDays, nil
+	case days < 1 || days > MaxWaitDays:
+		return 0, fmt.Errorf("waiting period must be from 1 to %d days: %d", MaxWaitDays, days)
+	default:
+		return days, nil
+	}
+}
+
+// SealVaultKey seals vault key of the grantor to public key of the contact
+func SealVaultKey(k *vault.Key, public []byte) ([]byte, error) {
+	return sharing.WrapKey(k.Bytes(), public)
+}
+
+// OpenVaultKey opens vault key of the version sealed by SealVaultKey with key pair of the contact
+func OpenVaultKey(kp *sharing.KeyPair, sealed []byte, version int64) (*vault.Key, error) {
+	secret, err := kp.UnwrapKey(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return vault.FromBytes(version, secret)
+}
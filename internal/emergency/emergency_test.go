@@ -0,0 +1,52 @@
+package emergency
+
+import (
+	"testing"
+
+	"github.com/maffka123/GophKeeper/internal/sharing"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitDays(t *testing.T) {
+	tests := []struct {
+		name    string
+		days    int64
+		want    int64
+		wantErr bool
+	}{
+		{name: "default", days: 0, want: DefaultWaitDays},
+		{name: "given", days: 3, want: 3},
+		{name: "negative", days: -1, wantErr: true},
+		{name: "too_long", days: MaxWaitDays + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WaitDays(tt.days)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSealOpenVaultKey(t *testing.T) {
+	contact, err := sharing.NewKeyPair()
+	require.NoError(t, err)
+	k, err := vault.NewKey(3)
+	require.NoError(t, err)
+
+	sealed, err := SealVaultKey(k, contact.Public)
+	require.NoError(t, err)
+	got, err := OpenVaultKey(contact, sealed, k.Version)
+	require.NoError(t, err)
+	assert.Equal(t, k, got)
+
+	other, _ := sharing.NewKeyPair()
+	_, err = OpenVaultKey(other, sealed, k.Version)
+	assert.ErrorIs(t, err, sharing.ErrKey)
+}
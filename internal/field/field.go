@@ -0,0 +1,81 @@
+// Package field names values of secrets, so they can be shown, copied or referenced one by one
+package field
+
+import (
+	"fmt"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// Field is a named value of a secret
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Kind returns type of the secret: login, card, file or text
+func Kind(d *pb.Data) string {
+	kd := d.GetData()
+	switch {
+	case kd.GetAuthData() != nil:
+		return "login"
+	case kd.GetBankCard() != nil:
+		return "card"
+	case len(kd.GetBinary()) > 0:
+		return "file"
+	default:
+		return "text"
+	}
+}
+
+// All returns non empty fields of the secret in the order they are shown
+func All(d *pb.Data) []Field {
+	kd := d.GetData()
+	all := []Field{{"name", d.Metadata}, {"type", Kind(d)}}
+	if a := kd.GetAuthData(); a != nil {
+		all = append(all, Field{"login", a.Login}, Field{"password", a.Password})
+		for _, u := range a.URIs {
+			all = append(all, Field{"url", u.URI})
+		}
+	}
+	if c := kd.GetBankCard(); c != nil {
+		all = append(all, Field{"number", c.CardNumber}, Field{"brand", c.Brand}, Field{"expiry", c.Expiry},
+			Field{"holder", c.HolderName}, Field{"bank", c.BankName}, Field{"address", c.Address})
+	}
+	all = append(all, Field{"text", kd.GetText()})
+	if len(kd.GetBinary()) > 0 {
+		all = append(all, Field{"file", string(kd.GetBinary())})
+	}
+	all = append(all, Field{"expires", d.ExpiresAt}, Field{"changed", d.ChangeDate}, Field{"collection", d.CollectionID})
+
+	var out []Field
+	for _, f := range all {
+		if f.Value != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Value returns value of the named field of the secret, the first one if there are several
+func Value(d *pb.Data, name string) (string, error) {
+	for _, f := range All(d) {
+		if f.Name == name {
+			return f.Value, nil
+		}
+	}
+	return "", fmt.Errorf("secret %s has no field %s", d.Metadata, name)
+}
+
+// Default is the field that is meant when none is named: password of logins, number of cards, text of notes
+func Default(d *pb.Data) string {
+	switch Kind(d) {
+	case "login":
+		return "password"
+	case "card":
+		return "number"
+	case "file":
+		return "file"
+	}
+	return "text"
+}
@@ -0,0 +1,50 @@
+package field
+
+import (
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	login := &pb.Data{Metadata: "mail", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "me", Password: "pass",
+		URIs: []*pb.LoginURI{{URI: "https://mail"}, {URI: "https://mail2"}}}}}
+	card := &pb.Data{Metadata: "visa", Data: &pb.KeepData{BankCard: &pb.BankCard{CardNumber: "4111", Expiry: "10/27"}}}
+	file := &pb.Data{Metadata: "key", Data: &pb.KeepData{Binary: []byte("ssh"), Text: "my key"}}
+	note := &pb.Data{Metadata: "note", Data: &pb.KeepData{Text: "milk"}}
+	tests := []struct {
+		name    string
+		d       *pb.Data
+		field   string
+		kind    string
+		want    string
+		wantErr bool
+	}{
+		{name: "default_login", d: login, kind: "login", want: "pass"},
+		{name: "first_url", d: login, field: "url", kind: "login", want: "https://mail"},
+		{name: "default_card", d: card, kind: "card", want: "4111"},
+		{name: "expiry", d: card, field: "expiry", kind: "card", want: "10/27"},
+		{name: "no_cvv", d: card, field: "cvv", kind: "card", wantErr: true},
+		{name: "default_file", d: file, kind: "file", want: "ssh"},
+		{name: "file_text", d: file, field: "text", kind: "file", want: "my key"},
+		{name: "default_note", d: note, kind: "text", want: "milk"},
+		{name: "empty", d: note, field: "login", kind: "text", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.kind, Kind(tt.d))
+			name := tt.field
+			if name == "" {
+				name = Default(tt.d)
+			}
+			got, err := Value(tt.d, name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Package generator generates random passwords and diceware passphrases.
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Character classes of generated passwords
+const (
+	LowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	UpperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DigitChars  = "0123456789"
+	SymbolChars = "!#$%&()*+,-./:;<=>?@[]^_{}~"
+	// AmbiguousChars are easy to confuse when the password is read or typed
+	AmbiguousChars = "0Oo1lI|"
+)
+
+const (
+	DefaultLength = 20
+	DefaultWords  = 6
+	MaxLength     = 1024
+	MaxWords      = 64
+)
+
+// eff_large_wordlist.txt is EFF's large wordlist for passphrases (CC BY 3.0):
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed eff_large_wordlist.txt
+var wordlistFile []byte
+
+// wordlist is the parsed list of 7776 diceware words
+var wordlist = parseWordlist(wordlistFile)
+
+// PasswordOptions configure password generation, if no class is chosen all of them are used
+type PasswordOptions struct {
+	Length         int
+	Lower          bool
+	Upper          bool
+	Digits         bool
+	Symbols        bool
+	AllowAmbiguous bool
+}
+
+// PassphraseOptions configure passphrase generation
+type PassphraseOptions struct {
+	Words      int
+	Separator  string
+	Capitalize bool
+	// WithNumber appends a random digit to one of the words
+	WithNumber bool
+}
+
+// Result is generated password with its entropy estimate in bits
+type Result struct {
+	Password string
+	Entropy  float64
+}
+
+func parseWordlist(file []byte) []string {
+	var words []string
+	s := bufio.NewScanner(bytes.NewReader(file))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		words = append(words, fields[len(fields)-1])
+	}
+	return words
+}
+
+// randInt returns uniform random number in [0, n)
+func randInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("random generator failed: %v", err)
+	}
+	return int(i.Int64()), nil
+}
+
+// removeAmbiguous drops ambiguous characters from the class
+func removeAmbiguous(class string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(AmbiguousChars, r) {
+			return -1
+		}
+		return r
+	}, class)
+}
+
+// Password generates password with at least one character of every chosen class
+func Password(opts PasswordOptions) (*Result, error) {
+	if opts.Length == 0 {
+		opts.Length = DefaultLength
+	}
+	if !opts.Lower && !opts.Upper && !opts.Digits && !opts.Symbols {
+		opts.Lower, opts.Upper, opts.Digits, opts.Symbols = true, true, true, true
+	}
+
+	var classes []string
+	for _, c := range []struct {
+		use   bool
+		chars string
+	}{{opts.Lower, LowerChars}, {opts.Upper, UpperChars}, {opts.Digits, DigitChars}, {opts.Symbols, SymbolChars}} {
+		if !c.use {
+			continue
+		}
+		chars := c.chars
+		if !opts.AllowAmbiguous {
+			chars = removeAmbiguous(chars)
+		}
+		classes = append(classes, chars)
+	}
+	if opts.Length < len(classes) || opts.Length > MaxLength {
+		return nil, fmt.Errorf("length must be between %d and %d", len(classes), MaxLength)
+	}
+
+	pool := strings.Join(classes, "")
+	out := make([]byte, 0, opts.Length)
+	for _, c := range classes {
+		i, err := randInt(len(c))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c[i])
+	}
+	for len(out) < opts.Length {
+		i, err := randInt(len(pool))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pool[i])
+	}
+
+	// shuffle so that required characters are not always in front
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return &Result{
+		Password: string(out),
+		Entropy:  float64(opts.Length) * math.Log2(float64(len(pool))),
+	}, nil
+}
+
+// Passphrase generates diceware passphrase from EFF's large wordlist
+func Passphrase(opts PassphraseOptions) (*Result, error) {
+	if opts.Words == 0 {
+		opts.Words = DefaultWords
+	}
+	if opts.Words < 1 || opts.Words > MaxWords {
+		return nil, fmt.Errorf("number of words must be between 1 and %d", MaxWords)
+	}
+	if opts.Separator == "" {
+		opts.Separator = "-"
+	}
+
+	words := make([]string, opts.Words)
+	for i := range words {
+		j, err := randInt(len(wordlist))
+		if err != nil {
+			return nil, err
+		}
+		words[i] = wordlist[j]
+		if opts.Capitalize {
+			r := []rune(words[i])
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+	}
+	entropy := float64(opts.Words) * math.Log2(float64(len(wordlist)))
+
+	if opts.WithNumber {
+		i, err := randInt(len(words))
+		if err != nil {
+			return nil, err
+		}
+		d, err := randInt(10)
+		if err != nil {
+			return nil, err
+		}
+		words[i] += fmt.Sprint(d)
+		entropy += math.Log2(10) + math.Log2(float64(len(words)))
+	}
+
+	return &Result{
+		Password: strings.Join(words, opts.Separator),
+		Entropy:  entropy,
+	}, nil
+}
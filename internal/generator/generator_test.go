@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    PasswordOptions
+		wantLen int
+		wantErr bool
+		classes []string
+	}{
+		{name: "defaults", opts: PasswordOptions{}, wantLen: DefaultLength,
+			classes: []string{LowerChars, UpperChars, DigitChars, SymbolChars}},
+		{name: "digits only", opts: PasswordOptions{Length: 8, Digits: true}, wantLen: 8,
+			classes: []string{DigitChars}},
+		{name: "lower and upper", opts: PasswordOptions{Length: 32, Lower: true, Upper: true}, wantLen: 32,
+			classes: []string{LowerChars, UpperChars}},
+		{name: "too short", opts: PasswordOptions{Length: 2, Lower: true, Upper: true, Digits: true}, wantErr: true},
+		{name: "too long", opts: PasswordOptions{Length: MaxLength + 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Password(tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, got.Password, tt.wantLen)
+			assert.Greater(t, got.Entropy, 0.0)
+			for _, c := range tt.classes {
+				assert.True(t, strings.ContainsAny(got.Password, removeAmbiguous(c)), "no characters of class %s", c)
+			}
+			assert.False(t, strings.ContainsAny(got.Password, AmbiguousChars))
+		})
+	}
+}
+
+func TestPassword_Entropy(t *testing.T) {
+	got, err := Password(PasswordOptions{Length: 10, Digits: true, AllowAmbiguous: true})
+	assert.NoError(t, err)
+	assert.InDelta(t, 33.2, got.Entropy, 0.1)
+}
+
+func TestPassphrase(t *testing.T) {
+	assert.Len(t, wordlist, 7776)
+
+	got, err := Passphrase(PassphraseOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(got.Password, "-"), DefaultWords)
+	assert.InDelta(t, 77.5, got.Entropy, 0.1)
+
+	got, err = Passphrase(PassphraseOptions{Words: 4, Separator: " ", Capitalize: true, WithNumber: true})
+	assert.NoError(t, err)
+	words := strings.Split(got.Password, " ")
+	assert.Len(t, words, 4)
+	assert.True(t, strings.ContainsAny(got.Password, DigitChars))
+	for _, w := range words {
+		assert.Equal(t, strings.ToUpper(w[:1]), w[:1])
+	}
+
+	_, err = Passphrase(PassphraseOptions{Words: MaxWords + 1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// HandlerPostPassword changes password of current user: opened vault key is wrapped with master key
+// of the new password, so secrets stay readable and nothing is re-encrypted. The server revokes old tokens,
+// the new one replaces the jwt cookie and the token of sync
+func (h *Handler) HandlerPostPassword(tokenChan chan string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.vault == nil {
+			http.Error(w, "401 - Vault key is not opened, log in first", http.StatusUnauthorized)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Password json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var req pb.ChangePasswordRequest
+		if err = protojson.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Password json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+		if req.OldPassword == "" || req.NewPassword == "" {
+			http.Error(w, "400 - Old and new passwords are required", http.StatusBadRequest)
+			return
+		}
+		compromised, err := h.compromised(req.NewPassword)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Breach check failed: %s", err), http.StatusInternalServerError)
+			return
+		} else if compromised {
+			http.Error(w, "422 - Password was found in a breach, choose another one", http.StatusUnprocessableEntity)
+			return
+		}
+
+		master, err := vault.DeriveMasterKey(req.NewPassword, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		if req.VaultKey, err = master.Wrap(h.vault); err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		req.VaultSalt = master.Salt
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.ChangePassword(h.ctx, &req)
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.replaceMaster(master)
+		h.logger.Info("password changed: ", zap.Int64("user", h.userID))
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     "jwt",
+			Value:    resp.Token,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		h.setToken(resp.Token)
+		h.renewSyncToken(tokenChan, h.userID, resp.Token)
+		h.writeJSON(w, &pb.ChangePasswordResp{})
+	}
+}
+
+// renewSyncToken gives the new token of the user to sync if sync runs for the user,
+// sync may be running, so it is not waited for
+func (h *Handler) renewSyncToken(tokenChan chan string, userID int64, token string) {
+	if !h.syncStart || h.syncUser != userID {
+		return
+	}
+	go func(ctx context.Context) {
+		select {
+		case tokenChan <- token:
+		case <-ctx.Done():
+		}
+	}(h.ctx)
+}
+
+// HandlerPostDeleteAccount deletes current user on the server and its local copy of secrets, password is asked again,
+// keys are forgotten and the jwt cookie is expired
+func (h *Handler) HandlerPostDeleteAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Delete account json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var req pb.DeleteAccountRequest
+		if err = protojson.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Delete account json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+		if req.Password == "" {
+			http.Error(w, "400 - Password is required", http.StatusBadRequest)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.DeleteAccount(h.ctx, &req)
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		if err := h.db.DeleteAccount(h.ctx, h.userID); err != nil {
+			h.logger.Error("local copy of the account cannot be deleted: ", zap.Int64("user", h.userID), zap.Error(err))
+		}
+		h.logger.Info("account deleted: ", zap.Int64("user", h.userID))
+		h.forgetKeys()
+		h.userID, h.login = 0, ""
+
+		http.SetCookie(w, &http.Cookie{
+			Name:   "jwt",
+			Value:  "",
+			MaxAge: -1,
+		})
+		h.writeJSON(w, resp)
+	}
+}
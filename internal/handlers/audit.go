@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/jwtauth/v5"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// HandlerGetAudit lists audit log of current user filtered by url parameters action, since and until (RFC 3339 times),
+// events go by id after the after parameter, at most limit of them, admins get events of all users unless user is set
+func (h *Handler) HandlerGetAudit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		req := pb.ListAuditEventsRequest{Action: q.Get("action"), Since: q.Get("since"), Until: q.Get("until")}
+		for name, to := range map[string]*int64{"user": &req.UserID, "after": &req.AfterID, "limit": &req.Limit} {
+			if v := q.Get(name); v != "" {
+				n, err := strconv.ParseInt(v, 10, 64)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("400 - %s must be a number: %s", name, v), http.StatusBadRequest)
+					return
+				}
+				*to = n
+			}
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.ListAuditEvents(h.ctx, &req)
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.writeJSON(w, resp)
+	}
+}
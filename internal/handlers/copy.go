@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/field"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// HandlerPostCopy copies field of the secret to the clipboard of the client instead of sending it in the response,
+// the clipboard is cleared later if it still holds the value. The value is never logged
+func (h *Handler) HandlerPostCopy() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.copier == nil {
+			http.Error(w, "503 - Clipboard is not available", http.StatusServiceUnavailable)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Copy json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var req pb.CopyRequest
+		if err = protojson.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Copy json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			http.Error(w, "400 - Name is empty", http.StatusBadRequest)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		d, code, err := h.byName(req.Name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%d - %s", code, err), code)
+			return
+		}
+		if req.Field == "" {
+			req.Field = field.Default(d)
+		}
+		value, err := field.Value(d, req.Field)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+			return
+		}
+		if err = h.copier.Copy(value); err != nil {
+			h.logger.Error("clipboard: ", zap.Error(err))
+			http.Error(w, fmt.Sprintf("500 - Copy failed: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		h.logger.Debug("copied to clipboard: ", zap.String("field", req.Field), zap.String("clipboard", h.copier.Name()))
+		h.writeJSON(w, &pb.CopyResp{Field: req.Field, Clipboard: h.copier.Name(), ClearAfter: int64(h.copier.ClearAfter.Seconds())})
+	}
+}
+
+// byName returns the secret named exactly as name with revealed card, from local db if the server is not available.
+// In case of error http status code to respond with is returned
+func (h *Handler) byName(name string) (*pb.Data, int, error) {
+	resp, err := h.c.GetData(h.ctx, &pb.GetDataRequest{Data: &pb.Data{Metadata: name}, Reveal: true})
+	if err != nil {
+		code, msg := grpcStatus(err)
+		if code != http.StatusServiceUnavailable {
+			return nil, code, fmt.Errorf(msg)
+		}
+		h.logger.Warn("server is not available getting data from local base")
+		if resp, err = h.getDataIfUnavailable(pb.Data{Metadata: name}, true); err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("Select from local db failed: %s", err)
+		}
+	}
+	var found []*pb.Data
+	for _, d := range resp.Data {
+		if d.Metadata == name {
+			found = append(found, d)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, http.StatusNotFound, fmt.Errorf("No secret named %s", name)
+	case 1:
+		return found[0], 0, nil
+	}
+	return nil, http.StatusConflict, fmt.Errorf("%d secrets are named %s", len(found), name)
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/jwtauth/v5"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/emergency"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// readEmergencyRequest reads emergency request from the body and sets token of the user, ID is required unless noID is set
+func (h *Handler) readEmergencyRequest(w http.ResponseWriter, r *http.Request, noID bool) (*pb.EmergencyRequest, bool) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("400 - Emergency json cannot be read: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+	var req pb.EmergencyRequest
+	if err = protojson.Unmarshal(body, &req); err != nil {
+		http.Error(w, fmt.Sprintf("400 - Emergency json cannot be decoded: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+	if !noID && req.ID == "" {
+		http.Error(w, "400 - ID of emergency access is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	tokenstr := jwtauth.TokenFromCookie(r)
+	h.setToken(tokenstr)
+	return &req, true
+}
+
+// emergencyCall writes response of the call to the server or its error
+func (h *Handler) emergencyCall(w http.ResponseWriter, resp proto.Message, err error) {
+	if err != nil {
+		code, msg := grpcStatus(err)
+		http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+		return
+	}
+	h.writeJSON(w, resp)
+}
+
+// HandlerPostEmergencyInvite invites the user by login to become emergency contact of current user,
+// access type is view or takeover, waiting period is 7 days by default
+func (h *Handler) HandlerPostEmergencyInvite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readEmergencyRequest(w, r, true)
+		if !ok {
+			return
+		}
+		if req.Login == "" {
+			http.Error(w, "400 - Login of the contact is required", http.StatusBadRequest)
+			return
+		}
+		if req.AccessType == "" {
+			req.AccessType = emergency.AccessView
+		}
+		resp, err := h.c.InviteEmergencyContact(h.ctx, &pb.InviteEmergencyContactRequest{Login: req.Login, AccessType: req.AccessType, WaitDays: req.WaitDays})
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerGetEmergency lists emergency contacts of current user and users who trust current user
+func (h *Handler) HandlerGetEmergency() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.ListEmergencyAccess(h.ctx, &pb.ListEmergencyAccessRequest{})
+		if err == nil {
+			for _, e := range resp.Trusted {
+				e.PublicKey = nil
+			}
+		}
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerPostEmergencyAccept accepts invitation to become emergency contact
+func (h *Handler) HandlerPostEmergencyAccept() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readEmergencyRequest(w, r, false)
+		if !ok {
+			return
+		}
+		resp, err := h.c.AcceptEmergencyAccess(h.ctx, &pb.AcceptEmergencyAccessRequest{ID: req.ID})
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerPostEmergencyConfirm seals vault key of current user to public key of the contact who accepted the invitation,
+// vault key must be opened by login
+func (h *Handler) HandlerPostEmergencyConfirm() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.vault == nil {
+			http.Error(w, "401 - Vault is not opened, log in first", http.StatusUnauthorized)
+			return
+		}
+		req, ok := h.readEmergencyRequest(w, r, false)
+		if !ok {
+			return
+		}
+
+		list, err := h.c.ListEmergencyAccess(h.ctx, &pb.ListEmergencyAccessRequest{})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		var contact *pb.EmergencyAccess
+		for _, e := range list.Trusted {
+			if e.ID == req.ID {
+				contact = e
+			}
+		}
+		if contact == nil {
+			http.Error(w, fmt.Sprintf("404 - Emergency contact %s is not found", req.ID), http.StatusNotFound)
+			return
+		}
+		if contact.Status != emergency.StatusAccepted || len(contact.PublicKey) == 0 {
+			http.Error(w, fmt.Sprintf("409 - Emergency access is %s, contact must accept it and have a key pair", contact.Status), http.StatusConflict)
+			return
+		}
+
+		sealed, err := emergency.SealVaultKey(h.vault, contact.PublicKey)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+			return
+		}
+		resp, err := h.c.ConfirmEmergencyAccess(h.ctx, &pb.ConfirmEmergencyAccessRequest{ID: req.ID, WrappedKey: sealed, KeyVersion: h.vault.Version})
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerPostEmergencyInitiate requests access to the vault of the grantor, waiting period starts
+func (h *Handler) HandlerPostEmergencyInitiate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readEmergencyRequest(w, r, false)
+		if !ok {
+			return
+		}
+		resp, err := h.c.InitiateEmergencyAccess(h.ctx, &pb.InitiateEmergencyAccessRequest{ID: req.ID})
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerPostEmergencyAnswer approves or rejects access requested by the contact
+func (h *Handler) HandlerPostEmergencyAnswer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readEmergencyRequest(w, r, false)
+		if !ok {
+			return
+		}
+		resp, err := h.c.AnswerEmergencyAccess(h.ctx, &pb.AnswerEmergencyAccessRequest{ID: req.ID, Approve: req.Approve})
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerGetEmergencyView gets secrets of the grantor by id url parameter once access is approved,
+// card numbers are masked unless reveal url parameter is set
+func (h *Handler) HandlerGetEmergencyView() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "400 - ID of emergency access is required", http.StatusBadRequest)
+			return
+		}
+		reveal := false
+		if q := r.URL.Query().Get("reveal"); q != "" {
+			var err error
+			if reveal, err = strconv.ParseBool(q); err != nil {
+				http.Error(w, fmt.Sprintf("400 - reveal must be true or false: %s", q), http.StatusBadRequest)
+				return
+			}
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.GetEmergencyData(h.ctx, &pb.GetEmergencyDataRequest{ID: id, Reveal: reveal})
+		if err == nil {
+			resp.WrappedKey = nil
+		}
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerPostEmergencyTakeover sets new password of the grantor: vault key of the grantor is opened with key pair
+// of current user and wrapped with master key of the new password, so all secrets of the grantor stay readable
+func (h *Handler) HandlerPostEmergencyTakeover() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.keys == nil {
+			http.Error(w, "401 - Key pair is not opened, log in first", http.StatusUnauthorized)
+			return
+		}
+		req, ok := h.readEmergencyRequest(w, r, false)
+		if !ok {
+			return
+		}
+		if req.Password == "" {
+			http.Error(w, "400 - New password of the grantor is required", http.StatusBadRequest)
+			return
+		}
+		compromised, err := h.compromised(req.Password)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Breach check failed: %s", err), http.StatusInternalServerError)
+			return
+		} else if compromised {
+			http.Error(w, "422 - Password was found in a breach, choose another one", http.StatusUnprocessableEntity)
+			return
+		}
+
+		data, err := h.c.GetEmergencyData(h.ctx, &pb.GetEmergencyDataRequest{ID: req.ID})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		if data.AccessType != emergency.AccessTakeover {
+			http.Error(w, "403 - Emergency access gives only view access", http.StatusForbidden)
+			return
+		}
+		k, err := emergency.OpenVaultKey(h.keys, data.WrappedKey, data.KeyVersion)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Vault key of the grantor cannot be opened: %s", err), http.StatusInternalServerError)
+			return
+		}
+		master, err := vault.DeriveMasterKey(req.Password, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		wrapped, err := master.Wrap(k)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := h.c.TakeoverEmergencyAccess(h.ctx, &pb.TakeoverEmergencyAccessRequest{
+			ID: req.ID, Password: req.Password, VaultKey: wrapped, VaultSalt: master.Salt,
+		})
+		if err == nil {
+			h.logger.Info("account taken over: ", zap.String("emergency", req.ID))
+		}
+		h.emergencyCall(w, resp, err)
+	}
+}
+
+// HandlerPostEmergencyRemove removes emergency access, both the grantor and the contact can do it
+func (h *Handler) HandlerPostEmergencyRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readEmergencyRequest(w, r, false)
+		if !ok {
+			return
+		}
+		resp, err := h.c.RemoveEmergencyAccess(h.ctx, &pb.RemoveEmergencyAccessRequest{ID: req.ID})
+		h.emergencyCall(w, resp, err)
+	}
+}
@@ -5,17 +5,41 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/jwtauth/v5"
 	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/archive"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+	"github.com/maffka123/GophKeeper/internal/breach"
+	"github.com/maffka123/GophKeeper/internal/clipboard"
+	"github.com/maffka123/GophKeeper/internal/generator"
+	"github.com/maffka123/GophKeeper/internal/health"
+	"github.com/maffka123/GophKeeper/internal/importer"
+	"github.com/maffka123/GophKeeper/internal/paging"
+	"github.com/maffka123/GophKeeper/internal/search"
+	"github.com/maffka123/GophKeeper/internal/sharing"
 	"github.com/maffka123/GophKeeper/internal/storage"
+	"github.com/maffka123/GophKeeper/internal/syncdb"
+	"github.com/maffka123/GophKeeper/internal/urlmatch"
+	"github.com/maffka123/GophKeeper/internal/vault"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
+// allTime is used to select all data for user regardless of its change date
+const allTime = "1970-01-01 00:00:00"
+
+// generateAttempts is how many times generator tries to get password that is not in the breach corpus
+const generateAttempts = 10
+
 // Handler struct for api handler
 type Handler struct {
 	logger    *zap.Logger
@@ -23,22 +47,51 @@ type Handler struct {
 	c         pb.GophKeeperClient
 	db        storage.StoregeInterface
 	syncStart bool
+	syncUser  int64
+	userID    int64
+	login     string
+	breached  breach.Checker
+	// vault key of logged in user, master key it is wrapped with and key pair for sharing, nil while the vault is locked
+	vault  *vault.Key
+	master *vault.MasterKey
+	keys   *sharing.KeyPair
+	lock   *vaultLock
+	// syncNow wakes up sync of local db, nil if there is no sync
+	syncNow   chan<- time.Time
+	syncState *syncdb.State
+	// copier puts values to the clipboard and clears it, nil if there is no clipboard
+	copier *clipboard.Copier
 }
 
-// NewHandler returns new initilized handler
-func NewHandler(ctx context.Context, logger *zap.Logger, c pb.GophKeeperClient, db storage.StoregeInterface) Handler {
+// NewHandler returns new initilized handler, breached can be nil if no breach corpus is configured
+func NewHandler(ctx context.Context, logger *zap.Logger, c pb.GophKeeperClient, db storage.StoregeInterface, breached breach.Checker) Handler {
 	return Handler{
 		logger:    logger,
 		ctx:       ctx,
 		c:         c,
 		db:        db,
 		syncStart: false,
+		breached:  breached,
+		lock:      &vaultLock{},
+	}
+}
+
+// compromised checks password against local breach corpus, without corpus nothing is compromised
+func (h *Handler) compromised(password string) (bool, error) {
+	if h.breached == nil || password == "" {
+		return false, nil
 	}
+	return h.breached.Compromised(password)
 }
 
-// HandlerPostRegister creates new user if user with such login not yet exist
+// HandlerPostRegister creates new user if user with such login not yet exist, the response is the recovery kit
+// of the new vault key, kit=text or kit=pdf gives printable kit
 func (h *Handler) HandlerPostRegister(tokenChan chan string, idChan chan int64) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := kitFormat(w, r)
+		if !ok {
+			return
+		}
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("400 - Register json cannot be read: %s", err), http.StatusBadRequest)
@@ -63,15 +116,27 @@ func (h *Handler) HandlerPostRegister(tokenChan chan string, idChan chan int64)
 
 			h.logger.Debug("logged in: ", zap.String("login", u.Login))
 			http.SetCookie(w, &http.Cookie{
-				Name:  "jwt",
-				Value: resp.Token,
+				Name:     "jwt",
+				Value:    resp.Token,
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
 			})
 			tokenChan <- resp.Token
 			idChan <- resp.Exists
 
-			w.Header().Set("application-type", "text/plain")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status":"ok}`))
+			// vault key is created with the account, so the recovery kit is shown right away
+			h.setToken(resp.Token)
+			h.login = u.Login
+			if err = h.openVault(u.Password, &pb.LoginResp{}); err != nil {
+				http.Error(w, fmt.Sprintf("500 - Vault key cannot be created, it is created at the first login: %s", err), http.StatusInternalServerError)
+				return
+			}
+			kit, err := h.newRecoveryKit()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("500 - Recovery key cannot be created, create it after login: %s", err), http.StatusInternalServerError)
+				return
+			}
+			h.writeKit(w, format, kit)
 		}
 	}
 }
@@ -95,21 +160,35 @@ func (h *Handler) HandlerPostLogin(tokenChan chan string, idChan chan int64) htt
 
 		resp, err := h.c.Login(h.ctx, &pb.LoginRequest{User: &u})
 
-		if err != nil {
+		if e, ok := status.FromError(err); ok && e.Code() == codes.ResourceExhausted {
+			http.Error(w, fmt.Sprintf("429 - %s", e.Message()), http.StatusTooManyRequests)
+			return
+		} else if err != nil {
 			http.Error(w, fmt.Sprintf("500 - Internal error: %s", err), http.StatusInternalServerError)
 			return
 		}
 
 		h.logger.Debug("logged in: ", zap.String("login", u.Login))
 		http.SetCookie(w, &http.Cookie{
-			Name:  "jwt",
-			Value: resp.Token,
+			Name:     "jwt",
+			Value:    resp.Token,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
 		})
 
+		h.setToken(resp.Token)
+		if err = h.openVault(u.Password, resp); err != nil {
+			http.Error(w, fmt.Sprintf("500 - Vault key cannot be opened: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		h.userID, h.login = resp.UserId, u.Login
 		if !h.syncStart {
 			tokenChan <- resp.Token
 			idChan <- resp.UserId
-			h.syncStart = true
+			h.syncStart, h.syncUser = true, resp.UserId
+		} else {
+			h.renewSyncToken(tokenChan, resp.UserId, resp.Token)
 		}
 
 		w.Header().Set("application-type", "text/plain")
@@ -119,6 +198,106 @@ func (h *Handler) HandlerPostLogin(tokenChan chan string, idChan chan int64) htt
 	}
 }
 
+// HandlerPostUnlock lifts lockout of the login after failed logins, current user must be admin of the server
+func (h *Handler) HandlerPostUnlock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Unlock json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var req pb.UnlockAccountRequest
+		if err = protojson.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Unlock json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.UnlockAccount(h.ctx, &req)
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.writeJSON(w, resp)
+	}
+}
+
+// openVault opens keys of the user with the password and keeps them in memory, the vault is unlocked
+func (h *Handler) openVault(password string, resp *pb.LoginResp) error {
+	k, master, keys, err := OpenVault(h.ctx, h.c, password, resp)
+	if err != nil {
+		return err
+	}
+	h.setKeys(k, master, keys)
+	return nil
+}
+
+// OpenVault unwraps vault key of the user with the password and key pair with the vault key,
+// on the first login new vault key and key pair are created, ctx must carry token of the user
+func OpenVault(ctx context.Context, c pb.GophKeeperClient, password string, resp *pb.LoginResp) (*vault.Key, *vault.MasterKey, *sharing.KeyPair, error) {
+	master, err := vault.DeriveMasterKey(password, resp.VaultSalt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var k *vault.Key
+	if len(resp.VaultKey) > 0 {
+		if k, err = master.Unwrap(resp.VaultKey, resp.VaultKeyVersion); err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		if k, err = vault.NewKey(1); err != nil {
+			return nil, nil, nil, err
+		}
+		wrapped, err := master.Wrap(k)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		_, err = c.SetVaultKey(ctx, &pb.SetVaultKeyRequest{VaultKey: wrapped, VaultSalt: master.Salt, VaultKeyVersion: k.Version})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var keys *sharing.KeyPair
+	if len(resp.PublicKey) > 0 {
+		if keys, err = sharing.OpenKeyPair(k, resp.PublicKey, resp.PrivateKey); err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		if keys, err = sharing.NewKeyPair(); err != nil {
+			return nil, nil, nil, err
+		}
+		wrapped, err := keys.Wrap(k)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if _, err = c.SetKeyPair(ctx, &pb.SetKeyPairRequest{PublicKey: keys.Public, PrivateKey: wrapped}); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return k, master, keys, nil
+}
+
+// setToken makes calls to the server with the token, it replaces the token of earlier requests
+func (h *Handler) setToken(token string) {
+	md, _ := metadata.FromOutgoingContext(h.ctx)
+	md = md.Copy()
+	md.Set("token", token)
+	h.ctx = metadata.NewOutgoingContext(h.ctx, md)
+}
+
+// index computes blind index of the secret with the current vault key, tokens sent by the user are never trusted.
+// Secrets of collections are indexed with the key of the member too, other members cannot look them up by the index
+func (h *Handler) index(d *pb.Data) {
+	d.BlindIndex = nil
+	if h.vault != nil {
+		d.BlindIndex = h.vault.Tokens(d)
+	}
+}
+
 // HandlerPostData adds new secret for current user
 func (h *Handler) HandlerPostData() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -134,26 +313,38 @@ func (h *Handler) HandlerPostData() http.HandlerFunc {
 			http.Error(w, fmt.Sprintf("400 - Data json cannot be decoded: %s", err), http.StatusBadRequest)
 			return
 		}
+
+		if err = bankcard.Prepare(d.GetData().GetBankCard()); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Bank card is not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, err = app.ExpiryFromData(&d); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Expiry date is not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		if err = urlmatch.Validate(d.GetData().GetAuthData()); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Login urls are not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		compromised, err := h.compromised(d.GetData().GetAuthData().GetPassword())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Breach check failed: %s", err), http.StatusInternalServerError)
+			return
+		} else if compromised {
+			http.Error(w, "422 - Password was found in a breach, choose another one", http.StatusUnprocessableEntity)
+			return
+		}
+
 		tokenstr := jwtauth.TokenFromCookie(r)
-		h.ctx = metadata.AppendToOutgoingContext(h.ctx, "token", tokenstr)
+		h.setToken(tokenstr)
 
-		resp, err := h.c.Insert(h.ctx, &pb.InsertRequest{Data: &d})
+		resp, code, err := h.insert(&d)
 		if err != nil {
-			if e, ok := status.FromError(err); ok {
-				switch e.Code() {
-				case codes.Code(codes.Unavailable):
-					h.logger.Warn("server is not available saving data locally")
-					resp, err = h.insertIfUnavailable(d)
-					if err != nil {
-						http.Error(w, fmt.Sprintf("500 - Insert to local db failed: %s", err), http.StatusInternalServerError)
-						return
-					}
-				}
-			} else {
-				h.logger.Debug(err.Error())
-				http.Error(w, fmt.Sprintf("500 - Internal error: %s", err.Error()), http.StatusInternalServerError)
-				return
-			}
+			http.Error(w, fmt.Sprintf("%d - %s", code, err), code)
+			return
 		}
 
 		h.logger.Debug("data accepted: ", zap.String("login", fmt.Sprint(resp.Id)))
@@ -163,8 +354,37 @@ func (h *Handler) HandlerPostData() http.HandlerFunc {
 	}
 }
 
-func (h *Handler) insertIfUnavailable(data pb.Data) (*pb.InsertResp, error) {
-	id, err := h.db.InsertData(h.ctx, &data, false)
+// insert sends data to the server or saves it locally if server is not available,
+// in case of error http status code to respond with is returned
+func (h *Handler) insert(d *pb.Data) (*pb.InsertResp, int, error) {
+	h.index(d)
+	resp, err := h.c.Insert(h.ctx, &pb.InsertRequest{Data: d})
+	if err != nil {
+		if e, ok := status.FromError(err); ok {
+			switch e.Code() {
+			case codes.Code(codes.Unavailable):
+				h.logger.Warn("server is not available saving data locally")
+				resp, err = h.insertIfUnavailable(d)
+				if err != nil {
+					return nil, http.StatusInternalServerError, fmt.Errorf("Insert to local db failed: %s", err)
+				}
+			case codes.Code(codes.InvalidArgument):
+				return nil, http.StatusBadRequest, fmt.Errorf("Data is not valid: %s", e.Message())
+			case codes.Code(codes.PermissionDenied):
+				return nil, http.StatusForbidden, fmt.Errorf("Not allowed: %s", e.Message())
+			default:
+				return nil, http.StatusInternalServerError, fmt.Errorf("Internal error: %s", e.Message())
+			}
+		} else {
+			h.logger.Debug(err.Error())
+			return nil, http.StatusInternalServerError, fmt.Errorf("Internal error: %s", err.Error())
+		}
+	}
+	return resp, 0, nil
+}
+
+func (h *Handler) insertIfUnavailable(data *pb.Data) (*pb.InsertResp, error) {
+	id, err := h.db.InsertData(h.ctx, data, false)
 	if err != nil {
 		return nil, err
 	}
@@ -172,36 +392,70 @@ func (h *Handler) insertIfUnavailable(data pb.Data) (*pb.InsertResp, error) {
 	return &resp, nil
 }
 
-// HandlerGetData gets data for current user for given search criteria
-func (h *Handler) HandlerGetData() http.HandlerFunc {
+// HandlerGetData gets data for current user for given search criteria or runs full-text search
+// if q url parameter is given, card numbers are masked unless reveal is set.
+// Login, bank and holder names are looked up by blind index, with prefix url parameter as prefixes.
+// With paging url parameters data is returned page by page
+func (h *Handler) HandlerGetData(reveal bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("400 - Data json cannot be read: %s", err.Error()), http.StatusBadRequest)
-			return
-		}
+		var err error
+		req := &pb.GetDataRequest{Reveal: reveal}
 		var d pb.Data
-		err = protojson.Unmarshal(body, &d)
+		if q := r.URL.Query().Get("q"); q != "" {
+			req.Query = q
+			if req.Limit, req.Offset, err = page(r); err != nil {
+				http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+				return
+			}
+		} else {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("400 - Data json cannot be read: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			err = protojson.Unmarshal(body, &d)
 
-		if err != nil {
-			http.Error(w, fmt.Sprintf("400 - Data json cannot be decoded: %s", err.Error()), http.StatusBadRequest)
-			return
+			if err != nil {
+				http.Error(w, fmt.Sprintf("400 - Data json cannot be decoded: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			if d.BlindIndex, err = h.queryTokens(&d, r.URL.Query().Get("prefix")); err != nil {
+				http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+				return
+			}
+			if req.Page, err = pageRequest(r, false); err != nil {
+				http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+				return
+			}
+			req.Data = &d
 		}
 
 		tokenstr := jwtauth.TokenFromCookie(r)
-		h.ctx = metadata.AppendToOutgoingContext(h.ctx, "token", tokenstr)
+		h.setToken(tokenstr)
 
-		resp, err := h.c.GetData(h.ctx, &pb.GetDataRequest{Data: &d})
+		resp, err := h.c.GetData(h.ctx, req)
 		if err != nil {
 			if e, ok := status.FromError(err); ok {
 				switch e.Code() {
 				case codes.Code(codes.Unavailable):
 					h.logger.Warn("server is not available getting data from local base")
-					resp, err = h.getDataIfUnavailable(d)
+					if req.Query != "" {
+						resp, err = h.searchTextIfUnavailable(req)
+					} else if req.Page != nil {
+						resp, err = h.searchDataPageIfUnavailable(req)
+					} else {
+						resp, err = h.getDataIfUnavailable(d, reveal)
+					}
 					if err != nil {
 						http.Error(w, fmt.Sprintf("500 - Select from local db failed: %s", err.Error()), http.StatusInternalServerError)
 						return
 					}
+				case codes.Code(codes.InvalidArgument):
+					http.Error(w, fmt.Sprintf("400 - %s", e.Message()), http.StatusBadRequest)
+					return
+				case codes.Code(codes.PermissionDenied):
+					http.Error(w, fmt.Sprintf("403 - %s", e.Message()), http.StatusForbidden)
+					return
 				}
 			} else {
 				h.logger.Debug(err.Error())
@@ -224,16 +478,113 @@ func (h *Handler) HandlerGetData() http.HandlerFunc {
 	}
 }
 
-func (h *Handler) getDataIfUnavailable(data pb.Data) (*pb.GetDataResp, error) {
+func (h *Handler) searchDataPageIfUnavailable(req *pb.GetDataRequest) (*pb.GetDataResp, error) {
+	req.Data.UserID = h.userID
+	d, next, err := h.db.SearchDataPage(h.ctx, req.Data, req.Page)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Reveal {
+		bankcard.Mask(d)
+	}
+	return &pb.GetDataResp{Data: d, NextPageToken: next}, nil
+}
+
+func (h *Handler) getDataIfUnavailable(data pb.Data, reveal bool) (*pb.GetDataResp, error) {
 	d, err := h.db.SearchData(h.ctx, &data)
 	if err != nil {
 		return nil, err
 	}
+	if !reveal {
+		bankcard.Mask(d)
+	}
 
 	return &pb.GetDataResp{Data: d}, nil
 }
 
-// HandlerGetDelete deletes for current user for given search criteria
+func (h *Handler) searchTextIfUnavailable(req *pb.GetDataRequest) (*pb.GetDataResp, error) {
+	hits, total, err := h.db.SearchText(h.ctx, h.userID, req.Query, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Reveal {
+		bankcard.Mask(search.Data(hits))
+	}
+	return &pb.GetDataResp{Hits: hits, Total: total}, nil
+}
+
+// queryTokens replaces plain values of indexed fields in search criteria with blind index tokens,
+// before login values are sent as they are
+func (h *Handler) queryTokens(d *pb.Data, prefix string) ([]string, error) {
+	isPrefix := false
+	if prefix != "" {
+		var err error
+		if isPrefix, err = strconv.ParseBool(prefix); err != nil {
+			return nil, fmt.Errorf("prefix must be true or false: %s", prefix)
+		}
+	}
+	if h.vault == nil {
+		if isPrefix {
+			return nil, fmt.Errorf("prefix lookup needs vault key, log in first")
+		}
+		return nil, nil
+	}
+	return h.vault.QueryTokens(d, isPrefix)
+}
+
+// pageRequest reads page_size, page_token, sort, order and fields url parameters, fields are comma separated.
+// Without any of them nil is returned unless always is set
+func pageRequest(r *http.Request, always bool) (*pb.PageRequest, error) {
+	q := r.URL.Query()
+	p := &pb.PageRequest{PageToken: q.Get("page_token"), SortBy: q.Get("sort")}
+	given := always || p.PageToken != "" || p.SortBy != ""
+
+	if v := q.Get("page_size"); v != "" {
+		var err error
+		if p.PageSize, err = strconv.ParseInt(v, 10, 64); err != nil || p.PageSize < 0 {
+			return nil, fmt.Errorf("page_size must be a positive number: %s", v)
+		}
+		given = true
+	}
+	switch v := q.Get("order"); v {
+	case "", "asc":
+	case "desc":
+		p.Descending = true
+	default:
+		return nil, fmt.Errorf("order must be asc or desc: %s", v)
+	}
+	if v := q.Get("fields"); v != "" {
+		p.Fields = &fieldmaskpb.FieldMask{Paths: strings.Split(v, ",")}
+		given = true
+	}
+
+	if !given {
+		return nil, nil
+	}
+	if err := paging.Validate(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// page reads limit and offset url parameters
+func page(r *http.Request) (int64, int64, error) {
+	var limit, offset int64
+	var err error
+	if q := r.URL.Query().Get("limit"); q != "" {
+		if limit, err = strconv.ParseInt(q, 10, 64); err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("limit must be a positive number: %s", q)
+		}
+	}
+	if q := r.URL.Query().Get("offset"); q != "" {
+		if offset, err = strconv.ParseInt(q, 10, 64); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a positive number: %s", q)
+		}
+	}
+	return limit, offset, nil
+}
+
+// HandlerGetDelete deletes for current user for given search criteria, only the secret with ID if it is given
 func (h *Handler) HandlerGetDelete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := ioutil.ReadAll(r.Body)
@@ -250,15 +601,32 @@ func (h *Handler) HandlerGetDelete() http.HandlerFunc {
 		}
 
 		tokenstr := jwtauth.TokenFromCookie(r)
-		h.ctx = metadata.AppendToOutgoingContext(h.ctx, "token", tokenstr)
+		h.setToken(tokenstr)
 
-		resp, err := h.c.Delete(h.ctx, &pb.DeleteRequest{Data: &d})
+		req := &pb.DeleteRequest{Data: &d}
+		if d.ID != "" {
+			req = &pb.DeleteRequest{ID: d.ID}
+		}
+		resp, err := h.c.Delete(h.ctx, req)
 		if err != nil {
 			if e, ok := status.FromError(err); ok {
 				switch e.Code() {
 				case codes.Code(codes.Unavailable):
 					h.logger.Warn("server is not available getting data from local base")
-					h.deleteDataIfUnavailable(d)
+					var deleted []*pb.Data
+					deleted, err = h.deleteDataIfUnavailable(d)
+					if err != nil {
+						http.Error(w, fmt.Sprintf("500 - Delete from local db failed: %s", err), http.StatusInternalServerError)
+						return
+					}
+					bankcard.Mask(deleted)
+					resp = &pb.DeleteResp{Data: deleted}
+				case codes.Code(codes.PermissionDenied):
+					http.Error(w, fmt.Sprintf("403 - %s", e.Message()), http.StatusForbidden)
+					return
+				case codes.InvalidArgument:
+					http.Error(w, fmt.Sprintf("400 - %s", e.Message()), http.StatusBadRequest)
+					return
 				}
 			} else {
 				h.logger.Debug(err.Error())
@@ -281,6 +649,77 @@ func (h *Handler) HandlerGetDelete() http.HandlerFunc {
 	}
 }
 
+// HandlerPostUpdate replaces the secret with ID of the request with Data, without id Name must match metadata of exactly
+// one secret. Secrets are changed only online, so the change never conflicts with sync
+func (h *Handler) HandlerPostUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Update json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var req pb.UpdateRequest
+		if err = protojson.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Update json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+		if req.Data == nil {
+			req.Data = &pb.Data{}
+		}
+
+		if err = bankcard.Prepare(req.Data.GetData().GetBankCard()); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Bank card is not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+		if _, err = app.ExpiryFromData(req.Data); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Expiry date is not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err = urlmatch.Validate(req.Data.GetData().GetAuthData()); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Login urls are not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+		compromised, err := h.compromised(req.Data.GetData().GetAuthData().GetPassword())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Breach check failed: %s", err), http.StatusInternalServerError)
+			return
+		} else if compromised {
+			http.Error(w, "422 - Password was found in a breach, choose another one", http.StatusUnprocessableEntity)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		h.index(req.Data)
+		resp, err := h.c.Update(h.ctx, &req)
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.writeJSON(w, resp)
+	}
+}
+
+// HandlerPostSync starts sync of local db with the server right away instead of waiting for the next period
+func (h *Handler) HandlerPostSync() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.syncNow == nil {
+			http.Error(w, "503 - Sync is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case h.syncNow <- time.Now():
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"status":"sync started"}`))
+		default:
+			http.Error(w, "409 - Sync is running already or starts after login", http.StatusConflict)
+		}
+	}
+}
+
 func (h *Handler) deleteDataIfUnavailable(data pb.Data) ([]*pb.Data, error) {
 	d, err := h.db.DeleteData(h.ctx, &data)
 	if err != nil {
@@ -288,3 +727,617 @@ func (h *Handler) deleteDataIfUnavailable(data pb.Data) ([]*pb.Data, error) {
 	}
 	return d, nil
 }
+
+// HandlerGetExpiring lists secrets of current user that expire within given number of days
+func (h *Handler) HandlerGetExpiring() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var days int64
+		if q := r.URL.Query().Get("days"); q != "" {
+			var err error
+			days, err = strconv.ParseInt(q, 10, 64)
+			if err != nil || days < 0 {
+				http.Error(w, fmt.Sprintf("400 - days must be a positive number: %s", q), http.StatusBadRequest)
+				return
+			}
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.ListExpiring(h.ctx, &pb.ListExpiringRequest{Days: days})
+		if err != nil {
+			if e, ok := status.FromError(err); ok {
+				switch e.Code() {
+				case codes.Code(codes.Unavailable):
+					h.logger.Warn("server is not available getting data from local base")
+					resp, err = h.listExpiringIfUnavailable(days)
+					if err != nil {
+						http.Error(w, fmt.Sprintf("500 - Select from local db failed: %s", err.Error()), http.StatusInternalServerError)
+						return
+					}
+				default:
+					http.Error(w, fmt.Sprintf("500 - Internal error: %s", e.Message()), http.StatusInternalServerError)
+					return
+				}
+			} else {
+				h.logger.Debug(err.Error())
+				http.Error(w, fmt.Sprintf("500 - Internal error: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		data, err := protojson.Marshal(resp)
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+func (h *Handler) listExpiringIfUnavailable(days int64) (*pb.ListExpiringResp, error) {
+	if days <= 0 {
+		days = app.DefaultExpiryDays
+	}
+	// user id 0 selects secrets of all users
+	if h.userID == 0 {
+		return nil, fmt.Errorf("user is not logged in")
+	}
+	d, err := h.db.SelectExpiring(h.ctx, h.userID, time.Now().AddDate(0, 0, int(days)))
+	if err != nil {
+		return nil, err
+	}
+	bankcard.Mask(d)
+	return &pb.ListExpiringResp{Data: d}, nil
+}
+
+// allData gets all data of current user from the server or from local db if server is not available,
+// card numbers from the server are masked unless reveal is set
+func (h *Handler) allData(reveal bool) ([]*pb.Data, error) {
+	resp, err := h.c.GetAllDataForUser(h.ctx, &pb.GetAllDataForUserRequest{Time: allTime, Reveal: reveal})
+	if err != nil {
+		if e, ok := status.FromError(err); ok {
+			switch e.Code() {
+			case codes.Code(codes.Unavailable):
+				h.logger.Warn("server is not available getting data from local base")
+				all, err := h.db.SelectAllDataForUser(h.ctx, h.userID, allTime)
+				if err != nil {
+					return nil, fmt.Errorf("Select from local db failed: %s", err)
+				}
+				return all, nil
+			default:
+				return nil, fmt.Errorf("Internal error: %s", e.Message())
+			}
+		}
+		h.logger.Debug(err.Error())
+		return nil, fmt.Errorf("Internal error: %s", err)
+	}
+	return resp.Data, nil
+}
+
+// HandlerGetList lists all secrets of current user page by page, card numbers are masked
+func (h *Handler) HandlerGetList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := pageRequest(r, true)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.GetAllDataForUser(h.ctx, &pb.GetAllDataForUserRequest{Time: allTime, Page: page})
+		if err != nil {
+			if e, ok := status.FromError(err); ok {
+				switch e.Code() {
+				case codes.Code(codes.Unavailable):
+					h.logger.Warn("server is not available getting data from local base")
+					var d []*pb.Data
+					var next string
+					d, next, err = h.db.SelectDataPage(h.ctx, h.userID, allTime, page)
+					if err != nil {
+						http.Error(w, fmt.Sprintf("500 - Select from local db failed: %s", err), http.StatusInternalServerError)
+						return
+					}
+					bankcard.Mask(d)
+					resp = &pb.GetAllDataForUserResp{Data: d, NextPageToken: next}
+				case codes.Code(codes.InvalidArgument):
+					http.Error(w, fmt.Sprintf("400 - %s", e.Message()), http.StatusBadRequest)
+					return
+				default:
+					http.Error(w, fmt.Sprintf("500 - Internal error: %s", e.Message()), http.StatusInternalServerError)
+					return
+				}
+			} else {
+				h.logger.Debug(err.Error())
+				http.Error(w, fmt.Sprintf("500 - Internal error: %s", err), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		data, err := protojson.Marshal(resp)
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// HandlerGetMatch finds logins of current user for the website given as url parameter, best matches go first
+func (h *Handler) HandlerGetMatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		website := r.URL.Query().Get("url")
+		if website == "" {
+			http.Error(w, "400 - url parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		all, err := h.allData(false)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		matches, err := urlmatch.Rank(all, website)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - url is not valid: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		data, err := protojson.Marshal(&pb.LoginMatches{Matches: matches})
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// HandlerPostGenerate generates random password or passphrase and optionally saves it as a new login
+func (h *Handler) HandlerPostGenerate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Generate json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var g pb.GenerateRequest
+		if len(body) > 0 {
+			if err = protojson.Unmarshal(body, &g); err != nil {
+				http.Error(w, fmt.Sprintf("400 - Generate json cannot be decoded: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if g.Kind != "" && g.Kind != "password" && g.Kind != "passphrase" {
+			http.Error(w, fmt.Sprintf("400 - Unknown kind %s, use password or passphrase", g.Kind), http.StatusBadRequest)
+			return
+		}
+
+		var res *generator.Result
+		compromised := true
+		for i := 0; i < generateAttempts && compromised; i++ {
+			res, err = Generate(&g)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("400 - Password cannot be generated: %s", err), http.StatusBadRequest)
+				return
+			}
+			compromised, err = h.compromised(res.Password)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("500 - Breach check failed: %s", err), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		out := pb.GenerateResp{Password: res.Password, Entropy: res.Entropy, Compromised: compromised}
+		if g.Insert && compromised {
+			http.Error(w, "422 - Generated password was found in a breach, choose other options", http.StatusUnprocessableEntity)
+			return
+		}
+		if g.Insert {
+			d := pb.Data{
+				Data:     &pb.KeepData{AuthData: &pb.AuthData{Login: g.Login, Password: res.Password, URIs: g.URIs}},
+				Metadata: g.Metadata,
+			}
+			if err = urlmatch.Validate(d.Data.AuthData); err != nil {
+				http.Error(w, fmt.Sprintf("400 - Login urls are not valid: %s", err), http.StatusBadRequest)
+				return
+			}
+
+			tokenstr := jwtauth.TokenFromCookie(r)
+			h.setToken(tokenstr)
+
+			resp, code, err := h.insert(&d)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("%d - %s", code, err), code)
+				return
+			}
+			out.InsertID = resp.Id
+		}
+
+		data, err := protojson.Marshal(&out)
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// Generate generates password or passphrase as requested
+func Generate(g *pb.GenerateRequest) (*generator.Result, error) {
+	if g.Kind == "passphrase" {
+		return generator.Passphrase(generator.PassphraseOptions{
+			Words:      int(g.Words),
+			Separator:  g.Separator,
+			Capitalize: g.Capitalize,
+			WithNumber: g.WithNumber,
+		})
+	}
+	return generator.Password(generator.PasswordOptions{
+		Length:         int(g.Length),
+		Lower:          g.Lower,
+		Upper:          g.Upper,
+		Digits:         g.Digits,
+		Symbols:        g.Symbols,
+		AllowAmbiguous: g.AllowAmbiguous,
+	})
+}
+
+// HandlerGetReport audits logins of current user: weak, reused, breached and not changed for given number of days passwords
+func (h *Handler) HandlerGetReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts := health.DefaultOptions()
+		if q := r.URL.Query().Get("days"); q != "" {
+			days, err := strconv.ParseInt(q, 10, 64)
+			if err != nil || days < 0 {
+				http.Error(w, fmt.Sprintf("400 - days must be a positive number: %s", q), http.StatusBadRequest)
+				return
+			}
+			opts.MaxAge = time.Duration(days) * 24 * time.Hour
+		}
+		if q := r.URL.Query().Get("minscore"); q != "" {
+			score, err := strconv.Atoi(q)
+			if err != nil || score < 0 || score > 4 {
+				http.Error(w, fmt.Sprintf("400 - minscore must be a number from 0 to 4: %s", q), http.StatusBadRequest)
+				return
+			}
+			opts.MinScore = score
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		all, err := h.allData(false)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		opts.Breached = h.breached
+		report, err := health.Report(all, opts)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Report failed: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := protojson.Marshal(report)
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// HandlerPostImport imports file exported from other password manager or GophKeeper archive, format is given as url parameter,
+// passphrase of the archive as X-Passphrase header. With dryrun nothing is saved, only the report is returned
+func (h *Handler) HandlerPostImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			http.Error(w, fmt.Sprintf("400 - format parameter is required, use one of: %s", strings.Join(importer.Formats(), ", ")), http.StatusBadRequest)
+			return
+		}
+		dryRun := false
+		if q := r.URL.Query().Get("dryrun"); q != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(q); err != nil {
+				http.Error(w, fmt.Sprintf("400 - dryrun must be true or false: %s", q), http.StatusBadRequest)
+				return
+			}
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Import file cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		data, err := importer.Parse(format, body, r.Header.Get("X-Passphrase"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Import file cannot be parsed: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		existing, err := h.allData(true)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		report := importer.Plan(format, data, existing)
+		report.DryRun = dryRun
+		for _, e := range report.Entries {
+			if e.Status != importer.StatusNew {
+				continue
+			}
+			compromised, err := h.compromised(e.Data.GetData().GetAuthData().GetPassword())
+			if err != nil {
+				http.Error(w, fmt.Sprintf("500 - Breach check failed: %s", err), http.StatusInternalServerError)
+				return
+			} else if compromised {
+				e.Status, e.Reason = importer.StatusInvalid, "password was found in a breach"
+				report.Invalid++
+			}
+		}
+
+		if toImport := importer.New(report); !dryRun && len(toImport) > 0 {
+			ids, code, err := h.insertBatch(toImport)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("%d - %s", code, err), code)
+				return
+			}
+			i := 0
+			for _, e := range report.Entries {
+				if e.Status == importer.StatusNew {
+					e.Status, e.InsertID = importer.StatusImported, ids[i]
+					i++
+				}
+			}
+			report.Imported = int64(len(ids))
+		}
+
+		var entries []*pb.Data
+		for _, e := range report.Entries {
+			entries = append(entries, e.Data)
+		}
+		bankcard.Mask(entries)
+
+		out, err := protojson.Marshal(report)
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(out)
+	}
+}
+
+// insertBatch sends data to the server in one request or saves it locally if server is not available,
+// in case of error http status code to respond with is returned
+func (h *Handler) insertBatch(data []*pb.Data) ([]string, int, error) {
+	for _, d := range data {
+		h.index(d)
+	}
+	resp, err := h.c.InsertBatch(h.ctx, &pb.InsertBatchRequest{Data: data})
+	if err != nil {
+		if e, ok := status.FromError(err); ok {
+			switch e.Code() {
+			case codes.Code(codes.Unavailable):
+				h.logger.Warn("server is not available saving data locally")
+				for _, d := range data {
+					d.UserID = h.userID
+				}
+				ids, err := h.db.InsertBatch(h.ctx, data, false)
+				if err != nil {
+					return nil, http.StatusInternalServerError, fmt.Errorf("Insert to local db failed: %s", err)
+				}
+				var out []string
+				for _, id := range ids {
+					out = append(out, id.String())
+				}
+				return out, 0, nil
+			case codes.Code(codes.InvalidArgument):
+				return nil, http.StatusBadRequest, fmt.Errorf("Data is not valid: %s", e.Message())
+			case codes.Code(codes.PermissionDenied):
+				return nil, http.StatusForbidden, fmt.Errorf("Not allowed: %s", e.Message())
+			default:
+				return nil, http.StatusInternalServerError, fmt.Errorf("Internal error: %s", e.Message())
+			}
+		}
+		h.logger.Debug(err.Error())
+		return nil, http.StatusInternalServerError, fmt.Errorf("Internal error: %s", err.Error())
+	}
+	return resp.Ids, 0, nil
+}
+
+// HandlerPostExport exports all secrets of current user as archive encrypted with the passphrase,
+// plain json or csv are returned only if explicitly confirmed
+func (h *Handler) HandlerPostExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Export json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var e pb.ExportRequest
+		if err = protojson.Unmarshal(body, &e); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Export json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+		if e.Format == "" {
+			e.Format = "archive"
+		}
+		if e.Format != "archive" && e.Format != "json" && e.Format != "csv" {
+			http.Error(w, fmt.Sprintf("400 - Unknown format %s, use archive, json or csv", e.Format), http.StatusBadRequest)
+			return
+		}
+		if e.Format != "archive" && e.Confirm != archive.ConfirmPlaintext {
+			http.Error(w, fmt.Sprintf("400 - Secrets will be exported unencrypted, confirm it with \"Confirm\": \"%s\"", archive.ConfirmPlaintext), http.StatusBadRequest)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		all, err := h.allData(true)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		a := archive.New(all)
+		var out []byte
+		contentType, ext := "application/json", "gkarchive"
+		switch e.Format {
+		case "archive":
+			out, err = archive.Seal(a, e.Passphrase)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("400 - Archive cannot be sealed: %s", err), http.StatusBadRequest)
+				return
+			}
+		case "json":
+			out, err = archive.JSON(a)
+			ext = "json"
+		case "csv":
+			out, err = archive.CSV(a)
+			contentType, ext = "text/csv", "csv"
+		}
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - Export failed: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		h.logger.Debug("vault exported: ", zap.String("format", e.Format), zap.Int("secrets", len(all)))
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gophkeeper-%s.%s"`, time.Now().Format(app.ExpiryLayout), ext))
+		w.WriteHeader(http.StatusOK)
+		w.Write(out)
+	}
+}
+
+// HandlerPostRotate replaces vault key of current user with a new one and recomputes blind indexes of all secrets,
+// server has to be available so the key is never rotated only locally
+func (h *Handler) HandlerPostRotate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.vault == nil || h.master == nil {
+			http.Error(w, "401 - Vault key is not opened, log in first", http.StatusUnauthorized)
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.GetAllDataForUser(h.ctx, &pb.GetAllDataForUserRequest{Time: allTime})
+		if err != nil {
+			if e, ok := status.FromError(err); ok && e.Code() == codes.Unavailable {
+				http.Error(w, "503 - Server is not available, vault key cannot be rotated offline", http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, fmt.Sprintf("500 - Internal error: %s", err), http.StatusInternalServerError)
+			return
+		}
+		local, err := h.db.SelectAllDataForUser(h.ctx, h.userID, allTime)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Select from local db failed: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		k, err := vault.NewKey(h.vault.Version + 1)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Vault key cannot be generated: %s", err), http.StatusInternalServerError)
+			return
+		}
+		wrapped, err := h.master.Wrap(k)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Vault key cannot be wrapped: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		var private []byte
+		if h.keys != nil {
+			if private, err = h.keys.Wrap(k); err != nil {
+				http.Error(w, fmt.Sprintf("500 - Private key cannot be wrapped: %s", err), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		updates := indexUpdates(k, append(resp.Data, local...))
+		rotated, err := h.c.RotateVaultKey(h.ctx, &pb.RotateVaultKeyRequest{
+			VaultKey: wrapped, VaultSalt: h.master.Salt, VaultKeyVersion: k.Version, Updates: updates, PrivateKey: private,
+		})
+		if err != nil {
+			if e, ok := status.FromError(err); ok && e.Code() == codes.FailedPrecondition {
+				http.Error(w, fmt.Sprintf("409 - %s, log in again", e.Message()), http.StatusConflict)
+				return
+			}
+			http.Error(w, fmt.Sprintf("500 - Internal error: %s", err), http.StatusInternalServerError)
+			return
+		}
+		h.replaceVault(k)
+
+		if _, err = h.db.UpdateBlindIndexes(h.ctx, h.userID, updates); err != nil {
+			h.logger.Error("local blind indexes were not updated: ", zap.Error(err))
+		}
+
+		out, err := protojson.Marshal(rotated)
+		if err != nil {
+			h.logger.Debug(err.Error())
+			http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		h.logger.Debug("vault key rotated: ", zap.Int64("version", k.Version))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(out)
+	}
+}
+
+// indexUpdates maps blind indexes of secrets to the ones computed with new key. Tokens depend only on indexed fields,
+// so secrets with the same old tokens get the same new ones. Secrets saved before blind indexes have nothing to replace
+func indexUpdates(k *vault.Key, data []*pb.Data) []*pb.IndexUpdate {
+	var out []*pb.IndexUpdate
+	seen := map[string]bool{}
+	for _, d := range data {
+		old := strings.Join(d.BlindIndex, " ")
+		if old == "" || seen[old] {
+			continue
+		}
+		seen[old] = true
+		out = append(out, &pb.IndexUpdate{Old: d.BlindIndex, New: k.Tokens(d)})
+	}
+	return out
+}
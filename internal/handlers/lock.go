@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/sharing"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// vaultLock guards keys of the handler: handlers that use secrets or keys hold it for reading,
+// locking the vault waits for them and wipes the keys. The vault is locked while no keys are in memory,
+// also before the first login
+type vaultLock struct {
+	sync.RWMutex
+	// sealed keeps keys wrapped at lock, so the vault is unlocked with the password also offline
+	sealed *pb.LoginResp
+	// lastUsed is unix time in nanoseconds of the last call that used the vault
+	lastUsed int64
+}
+
+func (l *vaultLock) touch() {
+	atomic.StoreInt64(&l.lastUsed, time.Now().UnixNano())
+}
+
+func (l *vaultLock) idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&l.lastUsed)))
+}
+
+// unlocked lets the call through only while the vault is unlocked, 423 is returned otherwise
+func (h *Handler) unlocked(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.lock.RLock()
+		defer h.lock.RUnlock()
+		if h.vault == nil {
+			http.Error(w, "423 - Vault is locked, unlock it or log in", http.StatusLocked)
+			return
+		}
+		h.lock.touch()
+		next.ServeHTTP(w, r)
+	}
+}
+
+// exclusive is unlocked for calls that replace or forget keys, they hold the lock for writing,
+// so no other call uses the keys meanwhile
+func (h *Handler) exclusive(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		if h.vault == nil {
+			http.Error(w, "423 - Vault is locked, unlock it or log in", http.StatusLocked)
+			return
+		}
+		h.lock.touch()
+		next.ServeHTTP(w, r)
+	}
+}
+
+// replaceVault keeps the new vault key and wipes the old one, lock must be held for writing
+func (h *Handler) replaceVault(k *vault.Key) {
+	if h.vault != nil && h.vault != k {
+		h.vault.Wipe()
+	}
+	h.vault = k
+}
+
+// replaceMaster keeps the new master key and wipes the old one, lock must be held for writing
+func (h *Handler) replaceMaster(master *vault.MasterKey) {
+	if h.master != nil && h.master != master {
+		h.master.Wipe()
+	}
+	h.master = master
+}
+
+// setKeys keeps opened keys in memory, the vault is unlocked
+func (h *Handler) setKeys(k *vault.Key, master *vault.MasterKey, keys *sharing.KeyPair) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.vault, h.master, h.keys = k, master, keys
+	h.lock.sealed = nil
+	h.lock.touch()
+}
+
+// wipeKeys overwrites keys in memory and forgets them, lock must be held
+func (h *Handler) wipeKeys() {
+	if h.vault != nil {
+		h.vault.Wipe()
+	}
+	if h.master != nil {
+		h.master.Wipe()
+	}
+	if h.keys != nil {
+		h.keys.Wipe()
+	}
+	h.vault, h.master, h.keys = nil, nil, nil
+}
+
+// forgetKeys wipes keys without keeping them sealed, the user has to log in again, lock must be held for writing
+func (h *Handler) forgetKeys() {
+	h.wipeKeys()
+	h.lock.sealed = nil
+}
+
+// lockVault wraps the keys with the master key and wipes them, idle is how long the vault has to be unused
+// to be locked, zero locks it at once. It returns false if the vault is locked already or was used recently
+func (h *Handler) lockVault(reason string, idle time.Duration) (bool, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.vault == nil || h.lock.idle() < idle {
+		return false, nil
+	}
+	var sealed *pb.LoginResp
+	if h.master != nil && h.keys != nil {
+		wrapped, err := h.master.Wrap(h.vault)
+		if err != nil {
+			return false, err
+		}
+		private, err := h.keys.Wrap(h.vault)
+		if err != nil {
+			return false, err
+		}
+		sealed = &pb.LoginResp{UserId: h.userID, VaultKey: wrapped, VaultSalt: h.master.Salt, VaultKeyVersion: h.vault.Version,
+			PublicKey: h.keys.Public, PrivateKey: private}
+	}
+	h.wipeKeys()
+	h.lock.sealed = sealed
+	h.logger.Info("vault locked: ", zap.String("reason", reason))
+	return true, nil
+}
+
+// AutoLock locks the vault when it is not used for idle time and on every signal, zero idle never locks it on idle
+func (h *Handler) AutoLock(ctx context.Context, idle time.Duration, signals <-chan os.Signal) {
+	var tick <-chan time.Time
+	if idle > 0 {
+		period := idle / 4
+		if period < time.Second {
+			period = time.Second
+		}
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			_, err = h.lockVault(fmt.Sprintf("signal %s", sig), 0)
+		case <-tick:
+			_, err = h.lockVault("idle", idle)
+		}
+		if err != nil {
+			h.logger.Error("vault cannot be locked: ", zap.Error(err))
+		}
+	}
+}
+
+// HandlerGetVaultStatus tells whether the vault is locked and how the last sync went
+func (h *Handler) HandlerGetVaultStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.lock.RLock()
+		status := &pb.VaultStatus{Locked: h.vault == nil, Login: h.login}
+		h.lock.RUnlock()
+		h.syncState.Fill(status)
+		h.writeJSON(w, status)
+	}
+}
+
+// HandlerPostVaultLock locks the vault, keys are wiped from memory until unlock or login
+func (h *Handler) HandlerPostVaultLock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.lockVault("request", 0); err != nil {
+			http.Error(w, fmt.Sprintf("500 - Vault cannot be locked: %s", err), http.StatusInternalServerError)
+			return
+		}
+		h.writeJSON(w, &pb.VaultStatus{Locked: true, Login: h.login})
+	}
+}
+
+// HandlerPostVaultUnlock opens the locked vault with the password, server is not needed
+func (h *Handler) HandlerPostVaultUnlock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Unlock json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var req pb.UnlockRequest
+		if err = protojson.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Unlock json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		h.lock.RLock()
+		sealed, unlocked := h.lock.sealed, h.vault != nil
+		h.lock.RUnlock()
+		if unlocked {
+			h.writeJSON(w, &pb.VaultStatus{Locked: false, Login: h.login})
+			return
+		}
+		if sealed == nil {
+			http.Error(w, "401 - Vault was not opened, log in first", http.StatusUnauthorized)
+			return
+		}
+		if err := h.openVault(req.Password, sealed); err != nil {
+			http.Error(w, "403 - Password is wrong", http.StatusForbidden)
+			return
+		}
+		h.logger.Info("vault unlocked: ", zap.Int64("user", h.userID))
+		h.writeJSON(w, &pb.VaultStatus{Locked: false, Login: h.login})
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/recoverykit"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// kitFormat reads format of the recovery kit from kit parameter: text or pdf, json if it is not given
+func kitFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch format := r.URL.Query().Get("kit"); format {
+	case "", "text", "pdf":
+		return format, true
+	default:
+		http.Error(w, fmt.Sprintf("400 - Kit format %s is not valid, use text or pdf", format), http.StatusBadRequest)
+		return "", false
+	}
+}
+
+// writeKit writes the recovery kit as json or as printable text or pdf
+func (h *Handler) writeKit(w http.ResponseWriter, format string, kit *pb.RecoveryKit) {
+	var out []byte
+	contentType := "text/plain; charset=utf-8"
+	switch format {
+	case "text":
+		out = recoverykit.Text(kit)
+	case "pdf":
+		out, contentType = recoverykit.PDF(kit), "application/pdf"
+	default:
+		h.writeJSON(w, kit)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gophkeeper-recovery-kit.%s"`, map[string]string{"text": "txt", "pdf": "pdf"}[format]))
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// wrapRecovery generates new recovery key and wraps the vault key with it
+func wrapRecovery(k *vault.Key) (*vault.RecoveryKey, []byte, error) {
+	rk, err := vault.NewRecoveryKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := rk.MasterKey().Wrap(k)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rk, wrapped, nil
+}
+
+// newRecoveryKit creates new recovery key for the opened vault key
+func (h *Handler) newRecoveryKit() (*pb.RecoveryKit, error) {
+	return NewRecoveryKit(h.ctx, h.c, h.login, h.vault)
+}
+
+// NewRecoveryKit creates new recovery key for the vault key and saves the vault key wrapped with it on the server,
+// the key itself is only returned in the kit, ctx must carry token of the user
+func NewRecoveryKit(ctx context.Context, c pb.GophKeeperClient, login string, k *vault.Key) (*pb.RecoveryKit, error) {
+	rk, wrapped, err := wrapRecovery(k)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.SetRecoveryKey(ctx, &pb.SetRecoveryKeyRequest{WrappedKey: wrapped, Verifier: rk.Verifier(), KeyVersion: k.Version})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RecoveryKit{Login: login, RecoveryKey: rk.String(), CreatedAt: time.Now().UTC().Format(time.RFC3339)}, nil
+}
+
+// HandlerPostRecovery replaces recovery key of current user with a new one, it is needed after vault key rotation,
+// kit=text or kit=pdf gives printable kit
+func (h *Handler) HandlerPostRecovery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.vault == nil {
+			http.Error(w, "401 - Vault key is not opened, log in first", http.StatusUnauthorized)
+			return
+		}
+		format, ok := kitFormat(w, r)
+		if !ok {
+			return
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		kit, err := h.newRecoveryKit()
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.logger.Info("recovery key created: ", zap.Int64("user", h.userID))
+		h.writeKit(w, format, kit)
+	}
+}
+
+// HandlerPostRecover sets new password of the user who forgot it: vault key wrapped with the recovery key is unwrapped
+// on the client and wrapped with master key of the new password, so the server never sees it.
+// The used recovery key is replaced with a new one, its kit is returned, after that the user logs in with the new password
+func (h *Handler) HandlerPostRecover() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := kitFormat(w, r)
+		if !ok {
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - Recover json cannot be read: %s", err), http.StatusBadRequest)
+			return
+		}
+		var req pb.RecoverRequest
+		if err = protojson.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Recover json cannot be decoded: %s", err), http.StatusBadRequest)
+			return
+		}
+		if req.Login == "" || req.RecoveryKey == "" || req.NewPassword == "" {
+			http.Error(w, "400 - Login, recovery key and new password are required", http.StatusBadRequest)
+			return
+		}
+		rk, err := vault.ParseRecoveryKey(req.RecoveryKey)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+			return
+		}
+		compromised, err := h.compromised(req.NewPassword)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Breach check failed: %s", err), http.StatusInternalServerError)
+			return
+		} else if compromised {
+			http.Error(w, "422 - Password was found in a breach, choose another one", http.StatusUnprocessableEntity)
+			return
+		}
+
+		resp, err := h.c.GetRecoveryKey(h.ctx, &pb.GetRecoveryKeyRequest{User: &pb.User{Login: req.Login}, Verifier: rk.Verifier()})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		k, err := rk.MasterKey().Unwrap(resp.WrappedKey, resp.KeyVersion)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - Vault key cannot be opened with the recovery key: %s", err), http.StatusInternalServerError)
+			return
+		}
+		master, err := vault.DeriveMasterKey(req.NewPassword, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		wrapped, err := master.Wrap(k)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		next, nextWrapped, err := wrapRecovery(k)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		_, err = h.c.RecoverAccount(h.ctx, &pb.RecoverAccountRequest{
+			User: &pb.User{Login: req.Login, Password: req.NewPassword}, Verifier: rk.Verifier(),
+			VaultKey: wrapped, VaultSalt: master.Salt, RecoveryKey: nextWrapped, RecoveryVerifier: next.Verifier(),
+		})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.logger.Info("account recovered: ", zap.String("login", req.Login))
+		h.writeKit(w, format, &pb.RecoveryKit{Login: req.Login, RecoveryKey: next.String(), CreatedAt: time.Now().UTC().Format(time.RFC3339)})
+	}
+}
@@ -2,20 +2,33 @@ package handlers
 
 import (
 	"context"
+	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/breach"
+	"github.com/maffka123/GophKeeper/internal/clipboard"
 	"github.com/maffka123/GophKeeper/internal/storage"
+	"github.com/maffka123/GophKeeper/internal/syncdb"
 	"go.uber.org/zap"
 )
 
-// KeeperRouter arranges the whole API endpoints and their correponding handlers
+// KeeperRouter arranges the whole API endpoints and their correponding handlers, the vault is locked after lockAfter
+// without use (never if zero) and on every signal from lockSignals, sync is started on demand through syncNow
+// and its result is reported from syncState, fields are copied with copier, nil if there is no clipboard
 func KeeperRouter(ctx context.Context, logger *zap.Logger, c pb.GophKeeperClient,
-	db storage.StoregeInterface, tokenChan chan string, idChan chan int64) chi.Router {
+	db storage.StoregeInterface, tokenChan chan string, idChan chan int64, breached breach.Checker,
+	lockAfter time.Duration, lockSignals <-chan os.Signal, syncNow chan<- time.Time, syncState *syncdb.State,
+	copier *clipboard.Copier) chi.Router {
 
 	r := chi.NewRouter()
-	mh := NewHandler(ctx, logger, c, db)
+	mh := NewHandler(ctx, logger, c, db, breached)
+	mh.syncNow, mh.syncState, mh.copier = syncNow, syncState, copier
+	if lockAfter > 0 || lockSignals != nil {
+		go mh.AutoLock(ctx, lockAfter, lockSignals)
+	}
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
@@ -25,11 +38,48 @@ func KeeperRouter(ctx context.Context, logger *zap.Logger, c pb.GophKeeperClient
 	r.Route("/api/user/", func(r chi.Router) {
 		r.Post("/register", Conveyor(mh.HandlerPostRegister(tokenChan, idChan), unpackGZIP, checkForJSON))
 		r.Post("/login", Conveyor(mh.HandlerPostLogin(tokenChan, idChan), unpackGZIP, checkForJSON))
-		r.Post("/insert", Conveyor(mh.HandlerPostData(), unpackGZIP, checkForJSON))
-		r.Get("/search", Conveyor(mh.HandlerGetData(), unpackGZIP, packGZIP))
-		r.Get("/delete", Conveyor(mh.HandlerGetDelete(), unpackGZIP, packGZIP))
+		r.Post("/insert", Conveyor(mh.HandlerPostData(), unpackGZIP, checkForJSON, mh.unlocked))
+		r.Get("/search", Conveyor(mh.HandlerGetData(false), unpackGZIP, packGZIP, mh.unlocked))
+		r.Get("/reveal", Conveyor(mh.HandlerGetData(true), unpackGZIP, packGZIP, mh.unlocked))
+		r.Get("/delete", Conveyor(mh.HandlerGetDelete(), unpackGZIP, packGZIP, mh.unlocked))
+		r.Post("/copy", Conveyor(mh.HandlerPostCopy(), unpackGZIP, checkForJSON, mh.unlocked))
+		r.Post("/update", Conveyor(mh.HandlerPostUpdate(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Get("/list", Conveyor(mh.HandlerGetList(), packGZIP, mh.unlocked))
+		r.Get("/expiring", Conveyor(mh.HandlerGetExpiring(), packGZIP, mh.unlocked))
+		r.Get("/match", Conveyor(mh.HandlerGetMatch(), packGZIP, mh.unlocked))
+		r.Post("/generate", Conveyor(mh.HandlerPostGenerate(), unpackGZIP, packGZIP, mh.unlocked))
+		r.Get("/report", Conveyor(mh.HandlerGetReport(), packGZIP, mh.unlocked))
+		r.Post("/import", Conveyor(mh.HandlerPostImport(), unpackGZIP, packGZIP, mh.unlocked))
+		r.Post("/export", Conveyor(mh.HandlerPostExport(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Post("/rotate", Conveyor(mh.HandlerPostRotate(), packGZIP, mh.exclusive))
+		r.Post("/share", Conveyor(mh.HandlerPostShare(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Get("/shares", Conveyor(mh.HandlerGetShares(), packGZIP, mh.unlocked))
+		r.Post("/share/update", Conveyor(mh.HandlerPostShareUpdate(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Post("/share/revoke", Conveyor(mh.HandlerPostShareRevoke(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Get("/emergency", Conveyor(mh.HandlerGetEmergency(), packGZIP, mh.unlocked))
+		r.Post("/emergency/invite", Conveyor(mh.HandlerPostEmergencyInvite(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Post("/emergency/accept", Conveyor(mh.HandlerPostEmergencyAccept(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Post("/emergency/confirm", Conveyor(mh.HandlerPostEmergencyConfirm(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Post("/emergency/initiate", Conveyor(mh.HandlerPostEmergencyInitiate(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Post("/emergency/answer", Conveyor(mh.HandlerPostEmergencyAnswer(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Get("/emergency/view", Conveyor(mh.HandlerGetEmergencyView(), packGZIP, mh.unlocked))
+		r.Post("/emergency/takeover", Conveyor(mh.HandlerPostEmergencyTakeover(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Post("/emergency/remove", Conveyor(mh.HandlerPostEmergencyRemove(), unpackGZIP, checkForJSON, packGZIP, mh.unlocked))
+		r.Get("/audit", Conveyor(mh.HandlerGetAudit(), packGZIP, mh.unlocked))
+		r.Post("/unlock", Conveyor(mh.HandlerPostUnlock(), unpackGZIP, checkForJSON, packGZIP))
+		r.Post("/password", Conveyor(mh.HandlerPostPassword(tokenChan), unpackGZIP, checkForJSON, packGZIP, mh.exclusive))
+		r.Post("/delete-account", Conveyor(mh.HandlerPostDeleteAccount(), unpackGZIP, checkForJSON, packGZIP, mh.exclusive))
+		r.Post("/recovery", Conveyor(mh.HandlerPostRecovery(), packGZIP, mh.unlocked))
+		r.Post("/recover", Conveyor(mh.HandlerPostRecover(), unpackGZIP, checkForJSON, packGZIP))
 
 	})
 
+	r.Route("/api/vault/", func(r chi.Router) {
+		r.Get("/status", Conveyor(mh.HandlerGetVaultStatus(), packGZIP))
+		r.Post("/lock", Conveyor(mh.HandlerPostVaultLock(), packGZIP))
+		r.Post("/unlock", Conveyor(mh.HandlerPostVaultUnlock(), unpackGZIP, checkForJSON, packGZIP))
+		r.Post("/sync", Conveyor(mh.HandlerPostSync(), packGZIP))
+	})
+
 	return r
 }
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/jwtauth/v5"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+	"github.com/maffka123/GophKeeper/internal/sharing"
+	"github.com/maffka123/GophKeeper/internal/urlmatch"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcStatus converts error of the server to http status code, for calls that never fall back to local db
+func grpcStatus(err error) (int, string) {
+	e, ok := status.FromError(err)
+	if !ok {
+		return http.StatusInternalServerError, err.Error()
+	}
+	switch e.Code() {
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable, "Server is not available, this works only online"
+	case codes.InvalidArgument:
+		return http.StatusBadRequest, e.Message()
+	case codes.NotFound:
+		return http.StatusNotFound, e.Message()
+	case codes.PermissionDenied:
+		return http.StatusForbidden, e.Message()
+	case codes.FailedPrecondition, codes.AlreadyExists:
+		return http.StatusConflict, e.Message()
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests, e.Message()
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized, e.Message()
+	default:
+		return http.StatusInternalServerError, e.Message()
+	}
+}
+
+// readShareRequest reads share request from the body and sets token of the user, key pair must be opened by login
+func (h *Handler) readShareRequest(w http.ResponseWriter, r *http.Request) (*pb.ShareRequest, bool) {
+	if h.keys == nil {
+		http.Error(w, "401 - Key pair is not opened, log in first", http.StatusUnauthorized)
+		return nil, false
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("400 - Share json cannot be read: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+	var req pb.ShareRequest
+	if err = protojson.Unmarshal(body, &req); err != nil {
+		http.Error(w, fmt.Sprintf("400 - Share json cannot be decoded: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+
+	tokenstr := jwtauth.TokenFromCookie(r)
+	h.setToken(tokenstr)
+	return &req, true
+}
+
+// writeJSON writes response of the server as json
+func (h *Handler) writeJSON(w http.ResponseWriter, resp proto.Message) {
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		h.logger.Debug(err.Error())
+		http.Error(w, fmt.Sprintf("500 - could not convert data to json: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandlerPostShare shares the secret found by search criteria with other users by their logins,
+// permission of every recipient is read or write, read by default
+func (h *Handler) HandlerPostShare() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readShareRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.Data == nil || len(req.Recipients) == 0 {
+			http.Error(w, "400 - Data to find the secret and recipients are required", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		if req.Data.BlindIndex, err = h.queryTokens(req.Data, ""); err != nil {
+			http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+			return
+		}
+		found, err := h.c.GetData(h.ctx, &pb.GetDataRequest{Data: req.Data, Reveal: true})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		switch len(found.Data) {
+		case 0:
+			http.Error(w, "404 - Secret to share is not found", http.StatusNotFound)
+			return
+		case 1:
+		default:
+			http.Error(w, fmt.Sprintf("400 - %d secrets match, narrow down the search", len(found.Data)), http.StatusBadRequest)
+			return
+		}
+
+		recipients := []*pb.ShareRecipient{{UserID: h.userID, PublicKey: h.keys.Public, Permission: sharing.PermissionOwner}}
+		for _, rec := range req.Recipients {
+			if rec.Permission == "" {
+				rec.Permission = sharing.PermissionRead
+			}
+			if rec.Permission != sharing.PermissionRead && rec.Permission != sharing.PermissionWrite {
+				http.Error(w, fmt.Sprintf("400 - Permission %s is not valid, use read or write", rec.Permission), http.StatusBadRequest)
+				return
+			}
+			key, err := h.c.GetPublicKey(h.ctx, &pb.GetPublicKeyRequest{Login: rec.Login})
+			if err != nil {
+				code, msg := grpcStatus(err)
+				http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+				return
+			}
+			recipients = append(recipients, &pb.ShareRecipient{UserID: key.UserID, Login: rec.Login, PublicKey: key.PublicKey, Permission: rec.Permission})
+		}
+
+		key, err := sharing.NewKey()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		ciphertext, err := sharing.Encrypt(key, found.Data[0])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		if recipients, err = sharing.Recipients(key, recipients); err != nil {
+			http.Error(w, fmt.Sprintf("400 - %s", err), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.c.ShareSecret(h.ctx, &pb.ShareSecretRequest{Ciphertext: ciphertext, Recipients: recipients})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.logger.Debug("secret shared: ", zap.String("share", resp.ID), zap.Int("recipients", len(req.Recipients)))
+		h.writeJSON(w, resp)
+	}
+}
+
+// HandlerGetShares lists secrets shared by current user and with current user decrypted,
+// card numbers are masked unless reveal url parameter is set
+func (h *Handler) HandlerGetShares() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.keys == nil {
+			http.Error(w, "401 - Key pair is not opened, log in first", http.StatusUnauthorized)
+			return
+		}
+		reveal := false
+		if q := r.URL.Query().Get("reveal"); q != "" {
+			var err error
+			if reveal, err = strconv.ParseBool(q); err != nil {
+				http.Error(w, fmt.Sprintf("400 - reveal must be true or false: %s", q), http.StatusBadRequest)
+				return
+			}
+		}
+
+		tokenstr := jwtauth.TokenFromCookie(r)
+		h.setToken(tokenstr)
+
+		resp, err := h.c.ListShares(h.ctx, &pb.ListSharesRequest{})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+
+		var data []*pb.Data
+		for _, s := range resp.Shares {
+			d, err := h.keys.Open(s)
+			if err != nil {
+				h.logger.Warn("share cannot be opened: ", zap.String("share", s.ID), zap.Error(err))
+			} else {
+				s.Data = d
+				data = append(data, d)
+			}
+			s.Ciphertext, s.WrappedKey = nil, nil
+			for _, rec := range s.Recipients {
+				rec.PublicKey, rec.WrappedKey = nil, nil
+			}
+		}
+		if !reveal {
+			bankcard.Mask(data)
+		}
+		h.writeJSON(w, resp)
+	}
+}
+
+// share finds share by id among shares of current user and opens its key
+func (h *Handler) share(id string) (*pb.Share, []byte, int, error) {
+	resp, err := h.c.ListShares(h.ctx, &pb.ListSharesRequest{})
+	if err != nil {
+		code, msg := grpcStatus(err)
+		return nil, nil, code, fmt.Errorf("%s", msg)
+	}
+	for _, s := range resp.Shares {
+		if s.ID != id {
+			continue
+		}
+		key, err := h.keys.UnwrapKey(s.WrappedKey)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+		return s, key, 0, nil
+	}
+	return nil, nil, http.StatusNotFound, fmt.Errorf("share %s is not found", id)
+}
+
+// HandlerPostShareUpdate replaces content of the share, current user must be its owner or have write permission
+func (h *Handler) HandlerPostShareUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readShareRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.ID == "" || req.Data.GetData() == nil {
+			http.Error(w, "400 - ID of the share and new Data are required", http.StatusBadRequest)
+			return
+		}
+		if err := bankcard.Prepare(req.Data.GetData().GetBankCard()); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Bank card is not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+		if _, err := app.ExpiryFromData(req.Data); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Expiry date is not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := urlmatch.Validate(req.Data.GetData().GetAuthData()); err != nil {
+			http.Error(w, fmt.Sprintf("400 - Login urls are not valid: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		s, key, code, err := h.share(req.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%d - %s", code, err), code)
+			return
+		}
+		ciphertext, err := sharing.Encrypt(key, req.Data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := h.c.UpdateShare(h.ctx, &pb.UpdateShareRequest{ID: s.ID, Ciphertext: ciphertext, KeyVersion: s.KeyVersion})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.writeJSON(w, resp)
+	}
+}
+
+// HandlerPostShareRevoke revokes access of the users to the share of current user: the secret is encrypted
+// with a new key, which only the remaining recipients get, so old key is useless for the new content
+func (h *Handler) HandlerPostShareRevoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := h.readShareRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.ID == "" || len(req.Recipients) == 0 {
+			http.Error(w, "400 - ID of the share and recipients to revoke are required", http.StatusBadRequest)
+			return
+		}
+
+		s, key, code, err := h.share(req.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%d - %s", code, err), code)
+			return
+		}
+		if s.Permission != sharing.PermissionOwner {
+			http.Error(w, "403 - Only the owner can revoke access", http.StatusForbidden)
+			return
+		}
+
+		revoked := map[string]bool{}
+		for _, rec := range req.Recipients {
+			revoked[rec.Login] = true
+		}
+		var remaining []*pb.ShareRecipient
+		for _, rec := range s.Recipients {
+			if rec.UserID == s.OwnerID {
+				rec.Permission = sharing.PermissionOwner
+				remaining = append(remaining, rec)
+				continue
+			}
+			if revoked[rec.Login] {
+				delete(revoked, rec.Login)
+				continue
+			}
+			remaining = append(remaining, rec)
+		}
+		for login := range revoked {
+			http.Error(w, fmt.Sprintf("400 - %s is not a recipient of the share", login), http.StatusBadRequest)
+			return
+		}
+
+		d, err := sharing.Decrypt(key, s.Ciphertext)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		newKey, err := sharing.NewKey()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		ciphertext, err := sharing.Encrypt(newKey, d)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+		if remaining, err = sharing.Recipients(newKey, remaining); err != nil {
+			http.Error(w, fmt.Sprintf("500 - %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := h.c.RevokeShare(h.ctx, &pb.RevokeShareRequest{ID: s.ID, Ciphertext: ciphertext, KeyVersion: s.KeyVersion + 1, Recipients: remaining})
+		if err != nil {
+			code, msg := grpcStatus(err)
+			http.Error(w, fmt.Sprintf("%d - %s", code, msg), code)
+			return
+		}
+		h.logger.Debug("share revoked: ", zap.String("share", s.ID), zap.Int("remaining", len(remaining)))
+		h.writeJSON(w, resp)
+	}
+}
@@ -0,0 +1,120 @@
+// Package health audits logins of the vault: weak, reused and old passwords.
+package health
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/breach"
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+const (
+	// DefaultMaxAgeDays is how old password can be before it is reported
+	DefaultMaxAgeDays = 180
+	// DefaultMinScore is the lowest zxcvbn score (0-4) that is not reported as weak
+	DefaultMinScore = 3
+)
+
+// Options of the report
+type Options struct {
+	MaxAge   time.Duration
+	MinScore int
+	Now      time.Time
+	// Breached checks passwords against local breach corpus, nil skips the check
+	Breached breach.Checker
+}
+
+// DefaultOptions returns options with default thresholds
+func DefaultOptions() Options {
+	return Options{
+		MaxAge:   DefaultMaxAgeDays * 24 * time.Hour,
+		MinScore: DefaultMinScore,
+		Now:      time.Now(),
+	}
+}
+
+// Strength estimates password strength from 0 to 4, login and other user data of the entry
+// make password weaker if it contains them
+func Strength(password string, userInputs []string) int {
+	return zxcvbn.PasswordStrength(password, userInputs).Score
+}
+
+// userInputs collects everything about the login an attacker could guess
+func userInputs(d *pb.Data) []string {
+	auth := d.GetData().GetAuthData()
+	inputs := []string{auth.GetLogin()}
+	if d.Metadata != "" {
+		inputs = append(inputs, strings.Fields(d.Metadata)...)
+	}
+	for _, u := range auth.GetURIs() {
+		if p, err := url.Parse(u.URI); err == nil && p.Hostname() != "" {
+			inputs = append(inputs, strings.Split(p.Hostname(), ".")...)
+		} else {
+			inputs = append(inputs, u.URI)
+		}
+	}
+	return inputs
+}
+
+func finding(d *pb.Data, reason string, score int) *pb.Finding {
+	return &pb.Finding{
+		ID:         d.ID,
+		Login:      d.GetData().GetAuthData().GetLogin(),
+		Metadata:   d.Metadata,
+		Reason:     reason,
+		Score:      int64(score),
+		ChangeDate: d.ChangeDate,
+	}
+}
+
+// Report checks all logins in data, other secrets are ignored
+func Report(data []*pb.Data, opts Options) (*pb.HealthReport, error) {
+	report := &pb.HealthReport{}
+	reused := map[string][]*pb.Finding{}
+	var order []string
+
+	for _, d := range data {
+		auth := d.GetData().GetAuthData()
+		if auth == nil || auth.Password == "" {
+			continue
+		}
+		report.Total++
+
+		score := Strength(auth.Password, userInputs(d))
+		if score < opts.MinScore {
+			report.Weak = append(report.Weak, finding(d, "password is weak", score))
+		}
+
+		if opts.Breached != nil {
+			compromised, err := opts.Breached.Compromised(auth.Password)
+			if err != nil {
+				return nil, err
+			}
+			if compromised {
+				report.Breached = append(report.Breached, finding(d, "password was found in a breach", score))
+			}
+		}
+
+		if _, ok := reused[auth.Password]; !ok {
+			order = append(order, auth.Password)
+		}
+		reused[auth.Password] = append(reused[auth.Password], finding(d, "password is reused", score))
+
+		if changed, err := time.Parse(app.ChangeDateLayout, d.ChangeDate); err == nil && opts.MaxAge > 0 {
+			if opts.Now.Sub(changed) > opts.MaxAge {
+				report.Old = append(report.Old, finding(d, "password was not changed since "+d.ChangeDate, score))
+			}
+		}
+	}
+
+	for _, p := range order {
+		if len(reused[p]) > 1 {
+			report.Reused = append(report.Reused, &pb.ReusedPassword{Findings: reused[p]})
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func login(id string, login, password, changed string) *pb.Data {
+	return &pb.Data{ID: id, ChangeDate: changed,
+		Data: &pb.KeepData{AuthData: &pb.AuthData{Login: login, Password: password}}}
+}
+
+// fakeBreach reports listed passwords as compromised
+type fakeBreach map[string]bool
+
+func (f fakeBreach) Compromised(password string) (bool, error) { return f[password], nil }
+func (f fakeBreach) Close() error                              { return nil }
+
+func TestReport(t *testing.T) {
+	now, _ := time.Parse("2006-01-02", "2022-06-01")
+	data := []*pb.Data{
+		login("1", "alice", "password1", "2022-05-01 10:00:00"),
+		login("2", "bob", "correct-horse-battery-staple-42", "2022-05-01 10:00:00"),
+		login("3", "bob2", "correct-horse-battery-staple-42", "2021-01-01 10:00:00"),
+		login("4", "carol", "carol2022", "2022-05-01 10:00:00"),
+		{ID: "5", Data: &pb.KeepData{Text: "not a login"}},
+	}
+
+	report, err := Report(data, Options{MaxAge: 180 * 24 * time.Hour, MinScore: DefaultMinScore, Now: now,
+		Breached: fakeBreach{"password1": true}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(4), report.Total)
+
+	var weak []string
+	for _, f := range report.Weak {
+		weak = append(weak, f.ID)
+	}
+	assert.Equal(t, []string{"1", "4"}, weak)
+
+	assert.Len(t, report.Reused, 1)
+	assert.Len(t, report.Reused[0].Findings, 2)
+	assert.Equal(t, "2", report.Reused[0].Findings[0].ID)
+	assert.Equal(t, "3", report.Reused[0].Findings[1].ID)
+
+	assert.Len(t, report.Old, 1)
+	assert.Equal(t, "3", report.Old[0].ID)
+
+	assert.Len(t, report.Breached, 1)
+	assert.Equal(t, "1", report.Breached[0].ID)
+}
+
+func TestStrength(t *testing.T) {
+	assert.Less(t, Strength("qwerty", nil), DefaultMinScore)
+	assert.Less(t, Strength("johnsmith1984", []string{"johnsmith"}), DefaultMinScore)
+	assert.GreaterOrEqual(t, Strength("v8#Lq2!mZp9@wR4t", nil), DefaultMinScore)
+}
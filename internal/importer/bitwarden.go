@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// item types of Bitwarden export
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+)
+
+// bitwardenMatch maps Bitwarden uri match detection to match modes, never (5) is dropped
+var bitwardenMatch = map[int]pb.MatchMode{
+	0: pb.MatchMode_BASE_DOMAIN,
+	1: pb.MatchMode_HOST,
+	2: pb.MatchMode_STARTS_WITH,
+	3: pb.MatchMode_EXACT,
+	4: pb.MatchMode_REGEX,
+}
+
+type bitwardenExport struct {
+	Encrypted bool            `json:"encrypted"`
+	Items     []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Type   int    `json:"type"`
+	Name   string `json:"name"`
+	Notes  string `json:"notes"`
+	Fields []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		URIs     []struct {
+			URI   string `json:"uri"`
+			Match *int   `json:"match"`
+		} `json:"uris"`
+	} `json:"login"`
+	Card *struct {
+		CardholderName string `json:"cardholderName"`
+		Number         string `json:"number"`
+		ExpMonth       string `json:"expMonth"`
+		ExpYear        string `json:"expYear"`
+	} `json:"card"`
+	Identity map[string]interface{} `json:"identity"`
+}
+
+// ParseBitwarden parses unencrypted Bitwarden JSON export
+func ParseBitwarden(file []byte) ([]*pb.Data, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(file, &export); err != nil {
+		return nil, fmt.Errorf("bitwarden json cannot be decoded: %v", err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("encrypted bitwarden exports are not supported, export as unencrypted json")
+	}
+
+	var out []*pb.Data
+	for _, item := range export.Items {
+		notes := item.Notes
+		for _, f := range item.Fields {
+			notes = metadata(notes, f.Name+": "+f.Value)
+		}
+
+		switch {
+		case item.Type == bitwardenLogin && item.Login != nil:
+			var loginURIs []*pb.LoginURI
+			for _, u := range item.Login.URIs {
+				match := pb.MatchMode_BASE_DOMAIN
+				if u.Match != nil {
+					m, ok := bitwardenMatch[*u.Match]
+					if !ok {
+						continue
+					}
+					match = m
+				}
+				if strings.TrimSpace(u.URI) != "" {
+					loginURIs = append(loginURIs, &pb.LoginURI{URI: strings.TrimSpace(u.URI), Match: match})
+				}
+			}
+			out = append(out, login(item.Name, item.Login.Username, item.Login.Password, notes, loginURIs))
+		case item.Type == bitwardenCard && item.Card != nil:
+			out = append(out, &pb.Data{
+				Metadata: metadata(item.Name, notes),
+				Data: &pb.KeepData{BankCard: &pb.BankCard{
+					HolderName: item.Card.CardholderName,
+					CardNumber: item.Card.Number,
+					Expiry:     expiry(item.Card.ExpMonth, item.Card.ExpYear),
+				}},
+			})
+		case item.Type == bitwardenIdentity && item.Identity != nil:
+			var lines []string
+			for _, k := range []string{"title", "firstName", "middleName", "lastName", "company", "email", "phone",
+				"address1", "address2", "address3", "city", "state", "postalCode", "country",
+				"ssn", "passportNumber", "licenseNumber", "username"} {
+				if v, ok := item.Identity[k].(string); ok && v != "" {
+					lines = append(lines, k+": "+v)
+				}
+			}
+			out = append(out, &pb.Data{Metadata: item.Name, Data: &pb.KeepData{Text: metadata(strings.Join(lines, "\n"), notes)}})
+		default:
+			out = append(out, &pb.Data{Metadata: item.Name, Data: &pb.KeepData{Text: notes}})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// csvColumns maps header names used by 1Password and browsers to fields
+var csvColumns = map[string]string{
+	"title":          "title",
+	"name":           "title",
+	"url":            "url",
+	"website":        "url",
+	"login_uri":      "url",
+	"username":       "username",
+	"login_username": "username",
+	"password":       "password",
+	"login_password": "password",
+	"notes":          "notes",
+	"note":           "notes",
+	"notesplain":     "notes",
+}
+
+// ParseCSV parses CSV exports of 1Password, Chrome and Firefox, columns are found by header
+func ParseCSV(file []byte) ([]*pb.Data, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(file, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv cannot be read: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv is empty")
+	}
+
+	columns := map[string]int{}
+	for i, h := range records[0] {
+		if f, ok := csvColumns[strings.ToLower(strings.TrimSpace(h))]; ok {
+			if _, dup := columns[f]; !dup {
+				columns[f] = i
+			}
+		}
+	}
+	if _, ok := columns["password"]; !ok {
+		return nil, errors.New("csv has no password column")
+	}
+
+	var out []*pb.Data
+	for _, rec := range records[1:] {
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		if get("username") == "" && get("password") == "" && get("notes") == "" {
+			continue
+		}
+		title := get("title")
+		if title == "" {
+			title = host(get("url"))
+		}
+		out = append(out, login(title, get("username"), get("password"), get("notes"), uris(get("url"))))
+	}
+	return out, nil
+}
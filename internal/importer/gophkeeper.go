@@ -0,0 +1,21 @@
+package importer
+
+import (
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/archive"
+)
+
+// FormatArchive is the encrypted archive exported from GophKeeper itself
+const FormatArchive = "gophkeeper"
+
+// ParseArchive decrypts GophKeeper archive, ids and owner of secrets are dropped so it can be restored to any account
+func ParseArchive(file []byte, passphrase string) ([]*pb.Data, error) {
+	a, err := archive.Open(file, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range a.Data {
+		d.ID, d.UserID, d.ChangeDate = "", 0, ""
+	}
+	return a.Data, nil
+}
@@ -0,0 +1,176 @@
+// Package importer converts exports of other password managers to secrets of the vault.
+//
+// Supported formats:
+//   - bitwarden: unencrypted Bitwarden JSON export
+//   - keepass: KeePass 2 XML export
+//   - 1pux: 1Password export archive
+//   - 1password: 1Password CSV export
+//   - chrome, firefox: browser password CSV exports
+//   - gophkeeper: encrypted archive exported from GophKeeper, passphrase is needed
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+)
+
+// Statuses of imported entries
+const (
+	StatusNew       = "new"
+	StatusImported  = "imported"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+)
+
+// parsers by format name
+var parsers = map[string]func([]byte) ([]*pb.Data, error){
+	"bitwarden": ParseBitwarden,
+	"keepass":   ParseKeePass,
+	"1pux":      Parse1PUX,
+	"1password": ParseCSV,
+	"chrome":    ParseCSV,
+	"firefox":   ParseCSV,
+	"csv":       ParseCSV,
+}
+
+// Formats lists supported format names
+func Formats() []string {
+	return []string{"bitwarden", "keepass", "1pux", "1password", "chrome", "firefox", "csv", FormatArchive}
+}
+
+// Parse converts exported file of the format to secrets, passphrase is used only for encrypted archives
+func Parse(format string, file []byte, passphrase string) ([]*pb.Data, error) {
+	if strings.ToLower(format) == FormatArchive {
+		return ParseArchive(file, passphrase)
+	}
+	parse, ok := parsers[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %s, use one of: %s", format, strings.Join(Formats(), ", "))
+	}
+	return parse(file)
+}
+
+// Plan checks parsed secrets before import: invalid ones and ones already in the vault or earlier in the file
+// are marked, the rest get status new
+func Plan(format string, data []*pb.Data, existing []*pb.Data) *pb.ImportReport {
+	report := &pb.ImportReport{Format: format, Total: int64(len(data))}
+	seen := map[string]bool{}
+	for _, d := range existing {
+		seen[Key(d)] = true
+	}
+
+	for _, d := range data {
+		entry := &pb.ImportEntry{Data: d, Status: StatusNew}
+		if err := app.Validate(d); err != nil {
+			entry.Status, entry.Reason = StatusInvalid, err.Error()
+			report.Invalid++
+		} else if k := Key(d); seen[k] {
+			entry.Status, entry.Reason = StatusDuplicate, "same secret is already in the vault or in the file"
+			report.Duplicates++
+		} else {
+			seen[k] = true
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	return report
+}
+
+// New returns data of entries that should be imported
+func New(report *pb.ImportReport) []*pb.Data {
+	var out []*pb.Data
+	for _, e := range report.Entries {
+		if e.Status == StatusNew {
+			out = append(out, e.Data)
+		}
+	}
+	return out
+}
+
+// Key identifies secret for duplicate detection: logins by login, password and hosts,
+// cards by number, other secrets by their content
+func Key(d *pb.Data) string {
+	kd := d.GetData()
+	switch {
+	case kd.GetAuthData() != nil:
+		a := kd.GetAuthData()
+		var hosts []string
+		for _, u := range a.URIs {
+			hosts = append(hosts, host(u.URI))
+		}
+		return "auth:" + strings.ToLower(a.Login) + ":" + a.Password + ":" + strings.Join(hosts, ",")
+	case kd.GetBankCard() != nil:
+		return "card:" + bankcard.Normalize(kd.GetBankCard().CardNumber)
+	case len(kd.GetBinary()) > 0:
+		h := sha256.Sum256(kd.GetBinary())
+		return "binary:" + hex.EncodeToString(h[:])
+	default:
+		return "text:" + d.Metadata + ":" + kd.GetText()
+	}
+}
+
+// host returns lower case host of the uri, uris without scheme are allowed
+func host(uri string) string {
+	if !strings.Contains(uri, "://") {
+		uri = "https://" + uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return strings.ToLower(uri)
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// metadata joins title and notes of the entry
+func metadata(parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// login builds secret from common fields of exported entries, entries without login and password become notes
+func login(title, username, password, notes string, uris []*pb.LoginURI) *pb.Data {
+	if username == "" && password == "" {
+		return &pb.Data{Metadata: title, Data: &pb.KeepData{Text: notes}}
+	}
+	return &pb.Data{
+		Metadata: metadata(title, notes),
+		Data:     &pb.KeepData{AuthData: &pb.AuthData{Login: username, Password: password, URIs: uris}},
+	}
+}
+
+// uris converts list of urls to login uris matched by domain, empty ones are dropped
+func uris(urls ...string) []*pb.LoginURI {
+	var out []*pb.LoginURI
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, &pb.LoginURI{URI: u})
+		}
+	}
+	return out
+}
+
+// expiry formats month and year of card as MM/YY
+func expiry(month, year string) string {
+	month, year = strings.TrimSpace(month), strings.TrimSpace(year)
+	if month == "" || year == "" {
+		return ""
+	}
+	if len(month) == 1 {
+		month = "0" + month
+	}
+	if len(year) > 2 {
+		year = year[len(year)-2:]
+	}
+	return month + "/" + year
+}
@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const bitwardenJSON = `{"encrypted": false, "items": [
+	{"type": 1, "name": "GitHub", "notes": "work", "login": {"username": "alice", "password": "s3cret",
+		"uris": [{"uri": "https://github.com", "match": null}, {"uri": "https://gist.github.com/", "match": 2}, {"uri": "never.example.com", "match": 5}]}},
+	{"type": 3, "name": "Visa", "card": {"cardholderName": "Alice", "number": "4111 1111 1111 1111", "expMonth": "7", "expYear": "2027", "code": "123"}},
+	{"type": 2, "name": "Wifi", "notes": "password is 42"}
+]}`
+
+const keepassXML = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta><RecycleBinUUID>bin</RecycleBinUUID></Meta>
+	<Root><Group><UUID>root</UUID><Name>Database</Name>
+		<Entry>
+			<String><Key>Title</Key><Value>Mail</Value></String>
+			<String><Key>UserName</Key><Value>alice@example.com</Value></String>
+			<String><Key>Password</Key><Value ProtectInMemory="True">pa55</Value></String>
+			<String><Key>URL</Key><Value>https://mail.example.com</Value></String>
+			<String><Key>PIN</Key><Value>1234</Value></String>
+		</Entry>
+		<Group><UUID>work</UUID><Name>Work</Name>
+			<Entry><String><Key>Title</Key><Value>VPN</Value></String><String><Key>UserName</Key><Value>alice</Value></String></Entry>
+		</Group>
+		<Group><UUID>bin</UUID><Name>Recycle Bin</Name>
+			<Entry><String><Key>Title</Key><Value>Old</Value></String><String><Key>Password</Key><Value>old</Value></String></Entry>
+		</Group>
+	</Group></Root>
+</KeePassFile>`
+
+const onePasswordJSON = `{"accounts": [{"vaults": [{"attrs": {"name": "Private"}, "items": [
+	{"state": "active", "categoryUuid": "001", "overview": {"title": "Shop", "url": "https://shop.example.com"},
+		"details": {"loginFields": [{"value": "bob", "designation": "username"}, {"value": "hunter2", "designation": "password"}], "notesPlain": ""}},
+	{"state": "active", "categoryUuid": "002", "overview": {"title": "Amex"},
+		"details": {"sections": [{"fields": [
+			{"id": "ccnum", "title": "number", "value": {"creditCardNumber": "378282246310005"}},
+			{"id": "cvv", "title": "cvv", "value": {"concealed": "1234"}},
+			{"id": "expiry", "title": "expiry", "value": {"monthYear": 202801}}]}]}},
+	{"state": "archived", "categoryUuid": "001", "overview": {"title": "Archived"}, "details": {}}
+]}]}]}`
+
+func onePasswordArchive(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("export.data")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(onePasswordJSON))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		file    []byte
+		want    []*pb.Data
+		wantErr bool
+	}{
+		{name: "bitwarden", format: "bitwarden", file: []byte(bitwardenJSON), want: []*pb.Data{
+			{Metadata: "GitHub\nwork", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice", Password: "s3cret",
+				URIs: []*pb.LoginURI{{URI: "https://github.com"}, {URI: "https://gist.github.com/", Match: pb.MatchMode_STARTS_WITH}}}}},
+			{Metadata: "Visa", Data: &pb.KeepData{BankCard: &pb.BankCard{HolderName: "Alice", CardNumber: "4111 1111 1111 1111", Expiry: "07/27"}}},
+			{Metadata: "Wifi", Data: &pb.KeepData{Text: "password is 42"}},
+		}},
+		{name: "bitwarden_encrypted", format: "bitwarden", file: []byte(`{"encrypted": true, "items": []}`), wantErr: true},
+		{name: "keepass", format: "keepass", file: []byte(keepassXML), want: []*pb.Data{
+			{Metadata: "Mail\nPIN: 1234", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice@example.com", Password: "pa55",
+				URIs: []*pb.LoginURI{{URI: "https://mail.example.com"}}}}},
+			{Metadata: "Work/VPN", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice"}}},
+		}},
+		{name: "1pux", format: "1pux", file: onePasswordArchive(t), want: []*pb.Data{
+			{Metadata: "Private/Shop", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "bob", Password: "hunter2",
+				URIs: []*pb.LoginURI{{URI: "https://shop.example.com"}}}}},
+			{Metadata: "Private/Amex", Data: &pb.KeepData{BankCard: &pb.BankCard{CardNumber: "378282246310005", Expiry: "01/28"}}},
+		}},
+		{name: "1password_csv", format: "1password", file: []byte("\"Title\",\"Url\",\"Username\",\"Password\",\"OTPAuth\",\"Favorite\",\"Archived\",\"Tags\",\"Notes\"\n\"Shop\",\"shop.example.com\",\"bob\",\"hunter2\",\"\",\"false\",\"false\",\"\",\"note\"\n"), want: []*pb.Data{
+			{Metadata: "Shop\nnote", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "bob", Password: "hunter2", URIs: []*pb.LoginURI{{URI: "shop.example.com"}}}}},
+		}},
+		{name: "chrome", format: "chrome", file: []byte("name,url,username,password,note\nexample.com,https://example.com/login,alice,pw,\n,,,,\n"), want: []*pb.Data{
+			{Metadata: "example.com", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice", Password: "pw", URIs: []*pb.LoginURI{{URI: "https://example.com/login"}}}}},
+		}},
+		{name: "firefox", format: "firefox", file: []byte("\"url\",\"username\",\"password\",\"httpRealm\",\"formActionOrigin\",\"guid\",\"timeCreated\",\"timeLastUsed\",\"timePasswordChanged\"\n\"https://www.example.org\",\"carol\",\"pw2\",,\"https://www.example.org\",\"{1}\",\"1\",\"1\",\"1\"\n"), want: []*pb.Data{
+			{Metadata: "www.example.org", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "carol", Password: "pw2", URIs: []*pb.LoginURI{{URI: "https://www.example.org"}}}}},
+		}},
+		{name: "csv_without_password", format: "chrome", file: []byte("name,url\na,b\n"), wantErr: true},
+		{name: "unknown_format", format: "lastpass", file: []byte(""), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.format, tt.file, "")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i := range got {
+				assert.Equal(t, tt.want[i].Metadata, got[i].Metadata)
+				assert.True(t, proto.Equal(tt.want[i].Data, got[i].Data), "got %v", got[i].Data)
+			}
+		})
+	}
+}
+
+func TestParseArchive(t *testing.T) {
+	file, err := archive.Seal(archive.New([]*pb.Data{{ID: "7", UserID: 3, Metadata: "note", Data: &pb.KeepData{Text: "secret"}}}), "passphrase")
+	require.NoError(t, err)
+
+	_, err = Parse(FormatArchive, file, "wrong passphrase")
+	assert.ErrorIs(t, err, archive.ErrPassphrase)
+
+	got, err := Parse(FormatArchive, file, "passphrase")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Empty(t, got[0].ID)
+	assert.Equal(t, int64(0), got[0].UserID)
+	assert.Equal(t, "secret", got[0].Data.Text)
+}
+
+func TestPlan(t *testing.T) {
+	existing := []*pb.Data{
+		{Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice", Password: "pw", URIs: []*pb.LoginURI{{URI: "https://example.com"}}}}},
+	}
+	data := []*pb.Data{
+		{Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "Alice", Password: "pw", URIs: []*pb.LoginURI{{URI: "example.com/login"}}}}},
+		{Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "bob", Password: "pw"}}},
+		{Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "bob", Password: "pw"}}},
+		{Data: &pb.KeepData{BankCard: &pb.BankCard{CardNumber: "4111111111111112"}}},
+		{Data: &pb.KeepData{BankCard: &pb.BankCard{CardNumber: "4111 1111 1111 1111", Expiry: "10/27"}}},
+	}
+
+	report := Plan("chrome", data, existing)
+	assert.Equal(t, int64(5), report.Total)
+	assert.Equal(t, int64(2), report.Duplicates)
+	assert.Equal(t, int64(1), report.Invalid)
+
+	var statuses []string
+	for _, e := range report.Entries {
+		statuses = append(statuses, e.Status)
+	}
+	assert.Equal(t, []string{StatusDuplicate, StatusNew, StatusDuplicate, StatusInvalid, StatusNew}, statuses)
+	assert.Len(t, New(report), 2)
+	assert.Equal(t, "visa", report.Entries[4].Data.Data.BankCard.Brand)
+}
@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+type keepassFile struct {
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+type keepassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+}
+
+// ParseKeePass parses KeePass 2 XML export, entries in the recycle bin are skipped,
+// group path is added to metadata
+func ParseKeePass(file []byte) ([]*pb.Data, error) {
+	var kp keepassFile
+	d := xml.NewDecoder(bytes.NewReader(file))
+	// KeePass writes utf-8 only, but declares it in different ways
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := d.Decode(&kp); err != nil {
+		return nil, fmt.Errorf("keepass xml cannot be decoded: %v", err)
+	}
+
+	var out []*pb.Data
+	var walk func(g keepassGroup, path []string)
+	walk = func(g keepassGroup, path []string) {
+		if kp.Meta.RecycleBinUUID != "" && g.UUID == kp.Meta.RecycleBinUUID {
+			return
+		}
+		path = append(path, g.Name)
+		for _, e := range g.Entries {
+			fields := map[string]string{}
+			var extra []string
+			for _, s := range e.Strings {
+				switch s.Key {
+				case "Title", "UserName", "Password", "URL", "Notes":
+					fields[s.Key] = s.Value
+				default:
+					if s.Value != "" {
+						extra = append(extra, s.Key+": "+s.Value)
+					}
+				}
+			}
+			// root group is the database itself, it is not part of the path
+			title := strings.Join(append(append([]string{}, path[1:]...), fields["Title"]), "/")
+			notes := metadata(append([]string{fields["Notes"]}, extra...)...)
+			out = append(out, login(title, fields["UserName"], fields["Password"], notes, uris(fields["URL"])))
+		}
+		for _, sub := range g.Groups {
+			walk(sub, path)
+		}
+	}
+	for _, g := range kp.Root.Groups {
+		walk(g, nil)
+	}
+	return out, nil
+}
@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// categories of 1Password items
+const (
+	onePasswordLogin    = "001"
+	onePasswordCard     = "002"
+	onePasswordPassword = "005"
+)
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	State        string `json:"state"`
+	CategoryUUID string `json:"categoryUuid"`
+	Overview     struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []struct {
+				Title string                 `json:"title"`
+				ID    string                 `json:"id"`
+				Value map[string]interface{} `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+	} `json:"details"`
+}
+
+// fieldValue converts typed value of 1Password field to string, month and year become MM/YY
+func fieldValue(v map[string]interface{}) string {
+	for k, val := range v {
+		switch val := val.(type) {
+		case string:
+			return val
+		case float64:
+			if k == "monthYear" {
+				s := fmt.Sprintf("%06d", int64(val))
+				return s[4:] + "/" + s[2:4]
+			}
+			return fmt.Sprint(int64(val))
+		case map[string]interface{}:
+			for _, inner := range val {
+				if s, ok := inner.(string); ok && s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Parse1PUX parses 1Password export archive, archived items are skipped
+func Parse1PUX(file []byte) ([]*pb.Data, error) {
+	zr, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		return nil, fmt.Errorf("1pux archive cannot be read: %v", err)
+	}
+	var exportData []byte
+	for _, f := range zr.File {
+		if f.Name != "export.data" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("1pux archive cannot be read: %v", err)
+		}
+		exportData, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("1pux archive cannot be read: %v", err)
+		}
+	}
+	if exportData == nil {
+		return nil, errors.New("1pux archive has no export.data")
+	}
+
+	var export onePasswordExport
+	if err := json.Unmarshal(exportData, &export); err != nil {
+		return nil, fmt.Errorf("1pux json cannot be decoded: %v", err)
+	}
+
+	var out []*pb.Data
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, item := range vault.Items {
+				if item.State == "archived" {
+					continue
+				}
+				out = append(out, onePasswordData(vault.Attrs.Name, item))
+			}
+		}
+	}
+	return out, nil
+}
+
+func onePasswordData(vault string, item onePasswordItem) *pb.Data {
+	title := vault + "/" + item.Overview.Title
+	fields := map[string]string{}
+	var extra []string
+	for _, s := range item.Details.Sections {
+		for _, f := range s.Fields {
+			v := fieldValue(f.Value)
+			fields[f.ID] = v
+			if v != "" {
+				extra = append(extra, f.Title+": "+v)
+			}
+		}
+	}
+
+	switch item.CategoryUUID {
+	case onePasswordLogin, onePasswordPassword:
+		var username, password string
+		for _, f := range item.Details.LoginFields {
+			switch f.Designation {
+			case "username":
+				username = f.Value
+			case "password":
+				password = f.Value
+			}
+		}
+		if password == "" {
+			password = item.Details.Password
+		}
+		urls := []string{item.Overview.URL}
+		for _, u := range item.Overview.URLs {
+			if u.URL != item.Overview.URL {
+				urls = append(urls, u.URL)
+			}
+		}
+		return login(title, username, password, metadata(append([]string{item.Details.NotesPlain}, extra...)...), uris(urls...))
+	case onePasswordCard:
+		return &pb.Data{
+			Metadata: metadata(title, item.Details.NotesPlain),
+			Data: &pb.KeepData{BankCard: &pb.BankCard{
+				HolderName: fields["cardholder"],
+				CardNumber: fields["ccnum"],
+				Expiry:     fields["expiry"],
+				BankName:   fields["bank"],
+			}},
+		}
+	default:
+		return &pb.Data{Metadata: title, Data: &pb.KeepData{Text: metadata(append([]string{item.Details.NotesPlain}, extra...)...)}}
+	}
+}
@@ -0,0 +1,37 @@
+package localapi
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Listen listens on the unix socket if it is given, on tcp address otherwise. Directory of the socket must belong
+// to the user and must not be writable by others, the socket is readable and writable only by the user
+func Listen(addr, socket string) (net.Listener, error) {
+	if socket == "" {
+		return net.Listen("tcp", addr)
+	}
+	if err := checkDir(filepath.Dir(socket)); err != nil {
+		return nil, err
+	}
+	// socket left by the previous run is removed, other files are not touched
+	if fi, err := os.Lstat(socket); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := listenUnix(socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,181 @@
+// Package localapi protects the local http api of the client from other processes and web pages on the machine.
+//
+// Every request must carry the api secret, which is generated at every start and written to a file only the user
+// can read. Host header must be one of the addresses the api listens on, so dns rebinding does not work, and requests
+// from web pages (with Origin header or cross-site fetch metadata) are refused unless their origin is allowed.
+// Calls with the jwt cookie that change something must also carry csrf token of the cookie, it is given in
+// X-CSRF-Token header of every response.
+package localapi
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SecretHeader carries the api secret
+	SecretHeader = "X-GophKeeper-Secret"
+	// CSRFHeader carries csrf token of the jwt cookie
+	CSRFHeader = "X-CSRF-Token"
+	// cookieName is the name of the jwt cookie set by login
+	cookieName = "jwt"
+)
+
+// NewSecret generates random api secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("random generator failed: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// WriteSecret writes the secret to the file only the user can read, directory is created if needed
+func WriteSecret(path, secret string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// file could exist with wider permissions
+	if err := f.Chmod(0600); err != nil {
+		return err
+	}
+	_, err = f.WriteString(secret + "\n")
+	return err
+}
+
+// ReadSecret reads the secret written by WriteSecret
+func ReadSecret(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Hosts returns values of Host header allowed for the api listening on tcp address or on unix socket if addr is empty
+func Hosts(addr string) []string {
+	if addr == "" {
+		return []string{"localhost", "unix"}
+	}
+	hosts := []string{addr}
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		hosts = append(hosts, "localhost:"+port, "127.0.0.1:"+port, "[::1]:"+port)
+	}
+	return hosts
+}
+
+// Guard checks requests to the local api
+type Guard struct {
+	secret  []byte
+	hosts   map[string]bool
+	origins map[string]bool
+}
+
+// NewGuard creates guard with the api secret, allowed hosts and origins of web pages allowed to call the api
+func NewGuard(secret string, hosts, origins []string) *Guard {
+	g := &Guard{secret: []byte(secret), hosts: map[string]bool{}, origins: map[string]bool{}}
+	for _, h := range hosts {
+		g.hosts[strings.ToLower(h)] = true
+	}
+	for _, o := range origins {
+		if o = strings.TrimSpace(o); o != "" {
+			g.origins[strings.ToLower(strings.TrimSuffix(o, "/"))] = true
+		}
+	}
+	return g
+}
+
+// CSRFToken returns csrf token of the jwt, it is bound to the secret, so it changes with every start
+func (g *Guard) CSRFToken(jwt string) string {
+	h := hmac.New(sha256.New, g.secret)
+	h.Write([]byte("csrf " + jwt))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// check returns status and reason if the request is refused
+func (g *Guard) check(r *http.Request) (int, string) {
+	if !g.hosts[strings.ToLower(r.Host)] {
+		return http.StatusForbidden, fmt.Sprintf("host %s is not allowed", r.Host)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !g.origins[strings.ToLower(origin)] {
+		return http.StatusForbidden, fmt.Sprintf("origin %s is not allowed", origin)
+	}
+	if site := r.Header.Get("Sec-Fetch-Site"); site == "cross-site" || site == "same-site" {
+		if !g.origins[strings.ToLower(r.Header.Get("Origin"))] {
+			return http.StatusForbidden, "requests from other sites are not allowed"
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(SecretHeader)), g.secret) != 1 {
+		return http.StatusUnauthorized, fmt.Sprintf("api secret is missing or wrong, give it in %s header", SecretHeader)
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return 0, ""
+	}
+	if c, err := r.Cookie(cookieName); err == nil && c.Value != "" {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(CSRFHeader)), []byte(g.CSRFToken(c.Value))) != 1 {
+			return http.StatusForbidden, fmt.Sprintf("csrf token is missing or wrong, give it in %s header", CSRFHeader)
+		}
+	}
+	return 0, ""
+}
+
+// Handler refuses requests that fail the checks and gives csrf token of the jwt cookie with every response
+func (g *Guard) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code, reason := g.check(r); code != 0 {
+			http.Error(w, fmt.Sprintf("%d - %s", code, reason), code)
+			return
+		}
+		var jwt string
+		if c, err := r.Cookie(cookieName); err == nil {
+			jwt = c.Value
+		}
+		next.ServeHTTP(&csrfWriter{ResponseWriter: w, guard: g, jwt: jwt}, r)
+	})
+}
+
+// csrfWriter adds csrf token of the jwt cookie to the response, token of the new cookie if the response sets it
+type csrfWriter struct {
+	http.ResponseWriter
+	guard       *Guard
+	jwt         string
+	wroteHeader bool
+}
+
+func (w *csrfWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		jwt := w.jwt
+		for _, c := range (&http.Response{Header: w.Header()}).Cookies() {
+			if c.Name == cookieName {
+				jwt = c.Value
+			}
+		}
+		if jwt != "" {
+			w.Header().Set(CSRFHeader, w.guard.CSRFToken(jwt))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *csrfWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
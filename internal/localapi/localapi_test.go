@@ -0,0 +1,145 @@
+package localapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecret(t *testing.T) {
+	s1, err := NewSecret()
+	require.NoError(t, err)
+	s2, err := NewSecret()
+	require.NoError(t, err)
+	assert.Len(t, s1, 64)
+	assert.NotEqual(t, s1, s2)
+
+	path := filepath.Join(t.TempDir(), "gophkeeper", "api-secret")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+	require.NoError(t, WriteSecret(path, s1))
+	got, err := ReadSecret(path)
+	require.NoError(t, err)
+	assert.Equal(t, s1, got)
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	}
+}
+
+func TestGuard(t *testing.T) {
+	secret := "s3cret"
+	g := NewGuard(secret, Hosts("127.0.0.1:8081"), []string{"http://localhost:3000/"})
+	h := g.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "jwt", Value: "new"})
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		host    string
+		headers map[string]string
+		cookie  string
+		want    int
+		csrf    string
+	}{
+		{name: "ok", method: http.MethodGet, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret}, want: 200},
+		{name: "localhost", method: http.MethodGet, host: "localhost:8081", headers: map[string]string{SecretHeader: secret}, want: 200},
+		{name: "no_secret", method: http.MethodGet, host: "127.0.0.1:8081", want: 401},
+		{name: "wrong_secret", method: http.MethodGet, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: "s3cres"}, want: 401},
+		{name: "rebinding", method: http.MethodGet, host: "evil.example:8081", headers: map[string]string{SecretHeader: secret}, want: 403},
+		{name: "other_port", method: http.MethodGet, host: "localhost:8082", headers: map[string]string{SecretHeader: secret}, want: 403},
+		{name: "origin", method: http.MethodPost, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret, "Origin": "http://evil.example"}, want: 403},
+		{name: "allowed_origin", method: http.MethodPost, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret, "Origin": "http://localhost:3000", "Sec-Fetch-Site": "same-site"}, want: 200},
+		{name: "cross_site", method: http.MethodGet, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret, "Sec-Fetch-Site": "cross-site"}, want: 403},
+		{name: "cookie_get", method: http.MethodGet, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret}, cookie: "old", want: 200, csrf: g.CSRFToken("old")},
+		{name: "cookie_no_csrf", method: http.MethodPost, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret}, cookie: "old", want: 403},
+		{name: "cookie_wrong_csrf", method: http.MethodPost, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret, CSRFHeader: g.CSRFToken("other")}, cookie: "old", want: 403},
+		{name: "cookie_csrf", method: http.MethodPost, host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret, CSRFHeader: g.CSRFToken("old")}, cookie: "old", want: 200, csrf: g.CSRFToken("old")},
+		{name: "login", method: http.MethodPost, path: "/login", host: "127.0.0.1:8081", headers: map[string]string{SecretHeader: secret}, want: 200, csrf: g.CSRFToken("new")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "/api/user/search"
+			}
+			r := httptest.NewRequest(tt.method, path, nil)
+			r.Host = tt.host
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "jwt", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			assert.Equal(t, tt.csrf, w.Header().Get(CSRFHeader))
+		})
+	}
+
+	// token of other start does not work
+	other := NewGuard("other", nil, nil)
+	assert.NotEqual(t, g.CSRFToken("old"), other.CSRFToken("old"))
+}
+
+func TestListen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not checked on windows")
+	}
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "api.sock")
+
+	// stale socket is replaced
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := Listen("", socket)
+	require.NoError(t, err)
+	defer ln.Close()
+	fi, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	go http.Serve(ln, NewGuard("s", Hosts(""), nil).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})))
+	client := http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+	}}}
+	req, _ := http.NewRequest(http.MethodGet, "http://unix/api/user/search", nil)
+	req.Header.Set(SecretHeader, "s")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// regular file is not removed
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0600))
+	_, err = Listen("", file)
+	assert.Error(t, err)
+
+	// directory writable by others is refused
+	open := filepath.Join(dir, "open")
+	require.NoError(t, os.Mkdir(open, 0700))
+	require.NoError(t, os.Chmod(open, 0777))
+	_, err = Listen("", filepath.Join(open, "api.sock"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package localapi
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkDir checks that the directory belongs to the user and others cannot write to it,
+// otherwise they could replace the socket
+func checkDir(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("directory %s of the socket does not belong to the user", dir)
+	}
+	if fi.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("directory %s of the socket is writable by others, mode %s", dir, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// listenUnix creates the socket with no permissions for others from the start
+func listenUnix(socket string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socket)
+}
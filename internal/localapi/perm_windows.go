@@ -0,0 +1,25 @@
+//go:build windows
+
+package localapi
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// checkDir checks only that the directory exists, access to it is controlled by acl on windows
+func checkDir(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+func listenUnix(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
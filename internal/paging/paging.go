@@ -0,0 +1,155 @@
+// Package paging holds cursor pagination, sorting and field masks of secret lists shared by server and client storage.
+//
+// Pages are keyset based: page token is an opaque cursor with sort value and id of the last secret on the page,
+// so pages do not shift when secrets are added or deleted in between.
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// Sort options
+const (
+	SortTitle      = "title"
+	SortChangeDate = "change_date"
+	SortType       = "type"
+)
+
+// ErrToken is returned for page tokens that were not issued for the same sort order
+var ErrToken = errors.New("page token is not valid")
+
+// Cursor points to the last secret of the page
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Token encodes cursor as page token
+func (c *Cursor) Token() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Validate checks sort option and field mask of the page request
+func Validate(p *pb.PageRequest) error {
+	if p.GetPageSize() < 0 {
+		return fmt.Errorf("page size must be a positive number: %d", p.GetPageSize())
+	}
+	if _, err := Sort(p); err != nil {
+		return err
+	}
+	if p.GetFields() != nil && !p.GetFields().IsValid(&pb.Data{}) {
+		return fmt.Errorf("fields are not valid: %s", strings.Join(p.GetFields().GetPaths(), ", "))
+	}
+	_, err := Parse(p)
+	return err
+}
+
+// Size returns page size within allowed bounds
+func Size(p *pb.PageRequest) int64 {
+	switch size := p.GetPageSize(); {
+	case size <= 0:
+		return DefaultPageSize
+	case size > MaxPageSize:
+		return MaxPageSize
+	default:
+		return size
+	}
+}
+
+// Sort returns sort option of the page request, change date by default
+func Sort(p *pb.PageRequest) (string, error) {
+	switch s := strings.ToLower(p.GetSortBy()); s {
+	case "":
+		return SortChangeDate, nil
+	case SortTitle, SortChangeDate, SortType:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown sort %s, use title, change_date or type", p.GetSortBy())
+	}
+}
+
+// Parse decodes page token of the request, nil is returned for the first page
+func Parse(p *pb.PageRequest) (*Cursor, error) {
+	if p.GetPageToken() == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(p.GetPageToken())
+	if err != nil {
+		return nil, ErrToken
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return nil, ErrToken
+	}
+	if sort, _ := Sort(p); c.Sort != sort || c.Desc != p.GetDescending() {
+		return nil, ErrToken
+	}
+	return &c, nil
+}
+
+// WithBody reports whether the mask needs contents of secrets, without it only metadata can be read
+func WithBody(mask *fieldmaskpb.FieldMask) bool {
+	if len(mask.GetPaths()) == 0 {
+		return true
+	}
+	for _, p := range mask.GetPaths() {
+		if p == "Data" || strings.HasPrefix(p, "Data.") {
+			return true
+		}
+	}
+	return false
+}
+
+// Project returns copy of the secret with only the fields of the mask, without mask the secret is returned as is
+func Project(d *pb.Data, mask *fieldmaskpb.FieldMask) *pb.Data {
+	if len(mask.GetPaths()) == 0 {
+		return d
+	}
+	out := &pb.Data{}
+	for _, p := range mask.GetPaths() {
+		copyPath(d.ProtoReflect(), out.ProtoReflect(), strings.Split(p, "."))
+	}
+	return out
+}
+
+// copyPath copies field at the path from src to dst, valid masks never go through lists
+func copyPath(src, dst protoreflect.Message, path []string) {
+	fd := src.Descriptor().Fields().ByName(protoreflect.Name(path[0]))
+	if fd == nil || !src.Has(fd) {
+		return
+	}
+	if len(path) == 1 || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+		dst.Set(fd, src.Get(fd))
+		return
+	}
+	child := dst.NewField(fd).Message()
+	if dst.Has(fd) {
+		child = dst.Get(fd).Message()
+	}
+	copyPath(src.Get(fd).Message(), child, path[1:])
+	// empty messages are not set, so missing fields stay missing
+	empty := true
+	child.Range(func(protoreflect.FieldDescriptor, protoreflect.Value) bool {
+		empty = false
+		return false
+	})
+	if !empty {
+		dst.Set(fd, protoreflect.ValueOfMessage(child))
+	}
+}
@@ -0,0 +1,90 @@
+package paging
+
+import (
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestValidate(t *testing.T) {
+	token := (&Cursor{Sort: SortTitle, Value: "bank", ID: "3c8a"}).Token()
+	tests := []struct {
+		name    string
+		page    *pb.PageRequest
+		wantErr bool
+	}{
+		{name: "empty", page: &pb.PageRequest{}},
+		{name: "sort", page: &pb.PageRequest{SortBy: "Type", Descending: true}},
+		{name: "unknown_sort", page: &pb.PageRequest{SortBy: "password"}, wantErr: true},
+		{name: "negative_size", page: &pb.PageRequest{PageSize: -1}, wantErr: true},
+		{name: "fields", page: &pb.PageRequest{Fields: &fieldmaskpb.FieldMask{Paths: []string{"Metadata", "Data.AuthData.login"}}}},
+		{name: "unknown_field", page: &pb.PageRequest{Fields: &fieldmaskpb.FieldMask{Paths: []string{"Title"}}}, wantErr: true},
+		{name: "token", page: &pb.PageRequest{SortBy: SortTitle, PageToken: token}},
+		{name: "token_of_other_sort", page: &pb.PageRequest{PageToken: token}, wantErr: true},
+		{name: "token_of_other_order", page: &pb.PageRequest{SortBy: SortTitle, Descending: true, PageToken: token}, wantErr: true},
+		{name: "broken_token", page: &pb.PageRequest{PageToken: "not a token"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.page)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	c := &Cursor{Sort: SortChangeDate, Desc: true, Value: "2022-10-19 12:00:00.123456", ID: "3c8a"}
+	got, err := Parse(&pb.PageRequest{Descending: true, PageToken: c.Token()})
+	require.NoError(t, err)
+	assert.Equal(t, c, got)
+
+	got, err = Parse(&pb.PageRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestSize(t *testing.T) {
+	assert.Equal(t, int64(DefaultPageSize), Size(nil))
+	assert.Equal(t, int64(10), Size(&pb.PageRequest{PageSize: 10}))
+	assert.Equal(t, int64(MaxPageSize), Size(&pb.PageRequest{PageSize: 100000}))
+}
+
+func TestProject(t *testing.T) {
+	d := &pb.Data{
+		ID:         "1",
+		Metadata:   "mail",
+		ChangeDate: "2022-10-19 12:00:00",
+		Data: &pb.KeepData{
+			AuthData: &pb.AuthData{Login: "alice", Password: "secret", URIs: []*pb.LoginURI{{URI: "example.com"}}},
+			Binary:   []byte("large file"),
+		},
+	}
+	tests := []struct {
+		name     string
+		paths    []string
+		want     *pb.Data
+		withBody bool
+	}{
+		{name: "no_mask", want: d, withBody: true},
+		{name: "metadata", paths: []string{"Metadata", "ChangeDate"},
+			want: &pb.Data{Metadata: "mail", ChangeDate: "2022-10-19 12:00:00"}},
+		{name: "login", paths: []string{"Metadata", "Data.AuthData.login", "Data.AuthData.URIs"},
+			want:     &pb.Data{Metadata: "mail", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice", URIs: []*pb.LoginURI{{URI: "example.com"}}}}},
+			withBody: true},
+		{name: "missing_field", paths: []string{"ExpiresAt", "Data.BankCard.BankName"}, want: &pb.Data{}, withBody: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mask *fieldmaskpb.FieldMask
+			if tt.paths != nil {
+				mask = &fieldmaskpb.FieldMask{Paths: tt.paths}
+			}
+			assert.True(t, proto.Equal(tt.want, Project(d, mask)), Project(d, mask).String())
+			assert.Equal(t, tt.withBody, WithBody(mask))
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Package ratelimit protects login from brute force: token buckets limit attempts per ip address and per login,
+// failed attempts in a row lock the login out for a time that doubles with every further failure.
+//
+// State is kept in a Store: MemoryStore is enough for one server, several replicas share storage.RateStore in postgres.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate of a token bucket: Burst attempts at once, then PerMinute attempts a minute
+type Rate struct {
+	PerMinute float64
+	Burst     int
+}
+
+// Bucket is the state of a token bucket
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take refills the bucket for the time passed and takes one token, it returns how long to wait for a token if there is none,
+// zero bucket is full
+func (r Rate) Take(b *Bucket, now time.Time) time.Duration {
+	if b.Updated.IsZero() {
+		b.Tokens = float64(r.Burst)
+	} else if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = math.Min(float64(r.Burst), b.Tokens+elapsed.Minutes()*r.PerMinute)
+	}
+	b.Updated = now
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return 0
+	}
+	if r.PerMinute <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration((1 - b.Tokens) / r.PerMinute * float64(time.Minute))
+}
+
+// Lockout locks the login out for Base after Threshold failures in a row, every further failure doubles the time up to Max
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Until returns end of the lockout after given number of failures in a row, zero time if the login is not locked
+func (l Lockout) Until(failures int, now time.Time) time.Time {
+	if l.Threshold <= 0 || failures < l.Threshold {
+		return time.Time{}
+	}
+	d := l.Base
+	for i := l.Threshold; i < failures && d < l.Max; i++ {
+		d *= 2
+	}
+	if d > l.Max {
+		d = l.Max
+	}
+	return now.Add(d)
+}
+
+// Store keeps buckets and failures
+type Store interface {
+	// Take takes a token from the bucket of the key, it returns how long to wait if there is none
+	Take(ctx context.Context, key string, rate Rate, now time.Time) (time.Duration, error)
+	// Fail counts failure of the login and returns end of its lockout
+	Fail(ctx context.Context, login string, lockout Lockout, now time.Time) (time.Time, error)
+	// Locked returns end of the lockout of the login, zero time if it was never locked
+	Locked(ctx context.Context, login string) (time.Time, error)
+	// Reset forgets failures and lockout of the login
+	Reset(ctx context.Context, login string) error
+}
+
+// Error is returned when attempt is not allowed
+type Error struct {
+	// Wait is set when there are too many attempts, Until when the login is locked
+	Wait  time.Duration
+	Until time.Time
+}
+
+func (e *Error) Error() string {
+	if !e.Until.IsZero() {
+		return fmt.Sprintf("account is locked after failed logins until %s", e.Until.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("too many login attempts, try again in %s", e.Wait.Round(time.Second))
+}
+
+// Limiter limits login attempts
+type Limiter struct {
+	store   Store
+	ip      Rate
+	login   Rate
+	lockout Lockout
+	now     func() time.Time
+}
+
+// New creates limiter with given limits
+func New(store Store, ip, login Rate, lockout Lockout) *Limiter {
+	return &Limiter{store: store, ip: ip, login: login, lockout: lockout, now: time.Now}
+}
+
+// Allow checks limits of the ip address and the login and takes a token from each, empty login is not checked
+func (l *Limiter) Allow(ctx context.Context, ip, login string) error {
+	now := l.now()
+	if login != "" {
+		until, err := l.store.Locked(ctx, login)
+		if err != nil {
+			return err
+		}
+		if until.After(now) {
+			return &Error{Until: until}
+		}
+	}
+	if wait, err := l.store.Take(ctx, "ip:"+ip, l.ip, now); err != nil {
+		return err
+	} else if wait > 0 {
+		return &Error{Wait: wait}
+	}
+	if login == "" {
+		return nil
+	}
+	if wait, err := l.store.Take(ctx, "login:"+login, l.login, now); err != nil {
+		return err
+	} else if wait > 0 {
+		return &Error{Wait: wait}
+	}
+	return nil
+}
+
+// Failed counts failed login, it returns end of the lockout if the login is locked now
+func (l *Limiter) Failed(ctx context.Context, login string) (time.Time, error) {
+	return l.store.Fail(ctx, login, l.lockout, l.now())
+}
+
+// Succeeded forgets failures of the login
+func (l *Limiter) Succeeded(ctx context.Context, login string) error {
+	return l.store.Reset(ctx, login)
+}
+
+// Unlock lifts the lockout of the login
+func (l *Limiter) Unlock(ctx context.Context, login string) error {
+	return l.store.Reset(ctx, login)
+}
+
+type failures struct {
+	count int
+	until time.Time
+}
+
+// MemoryStore keeps state in memory of one server
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*Bucket
+	failures map[string]*failures
+}
+
+// NewMemoryStore creates empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*Bucket{}, failures: map[string]*failures{}}
+}
+
+// Take takes a token from the bucket of the key
+func (s *MemoryStore) Take(ctx context.Context, key string, rate Rate, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &Bucket{}
+		s.buckets[key] = b
+	}
+	return rate.Take(b, now), nil
+}
+
+// Fail counts failure of the login
+func (s *MemoryStore) Fail(ctx context.Context, login string, lockout Lockout, now time.Time) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.failures[login]
+	if !ok {
+		f = &failures{}
+		s.failures[login] = f
+	}
+	f.count++
+	f.until = lockout.Until(f.count, now)
+	return f.until, nil
+}
+
+// Locked returns end of the lockout of the login
+func (s *MemoryStore) Locked(ctx context.Context, login string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.failures[login]; ok {
+		return f.until, nil
+	}
+	return time.Time{}, nil
+}
+
+// Reset forgets failures and lockout of the login
+func (s *MemoryStore) Reset(ctx context.Context, login string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, login)
+	return nil
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRate_Take(t *testing.T) {
+	now := time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC)
+	r := Rate{PerMinute: 6, Burst: 2}
+	var b Bucket
+
+	assert.Zero(t, r.Take(&b, now))
+	assert.Zero(t, r.Take(&b, now))
+	assert.Equal(t, 10*time.Second, r.Take(&b, now))
+	assert.Equal(t, 5*time.Second, r.Take(&b, now.Add(5*time.Second)))
+	assert.Zero(t, r.Take(&b, now.Add(10*time.Second)))
+	// bucket does not fill over its burst
+	assert.Zero(t, r.Take(&b, now.Add(time.Hour)))
+	assert.Zero(t, r.Take(&b, now.Add(time.Hour)))
+	assert.NotZero(t, r.Take(&b, now.Add(time.Hour)))
+}
+
+func TestLockout_Until(t *testing.T) {
+	now := time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC)
+	l := Lockout{Threshold: 3, Base: time.Minute, Max: 5 * time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 2},
+		{failures: 3, want: time.Minute},
+		{failures: 4, want: 2 * time.Minute},
+		{failures: 5, want: 4 * time.Minute},
+		{failures: 6, want: 5 * time.Minute},
+		{failures: 100, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		got := l.Until(tt.failures, now)
+		if tt.want == 0 {
+			assert.True(t, got.IsZero())
+		} else {
+			assert.Equal(t, now.Add(tt.want), got, tt.failures)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC)
+	l := New(NewMemoryStore(), Rate{PerMinute: 60, Burst: 4}, Rate{PerMinute: 1, Burst: 2}, Lockout{Threshold: 2, Base: time.Minute, Max: time.Hour})
+	l.now = func() time.Time { return now }
+
+	var limited *Error
+	assert.NoError(t, l.Allow(ctx, "10.0.0.1", "alice"))
+	assert.NoError(t, l.Allow(ctx, "10.0.0.1", "alice"))
+	// login bucket is empty, other logins from the ip still pass
+	assert.True(t, errors.As(l.Allow(ctx, "10.0.0.1", "alice"), &limited))
+	assert.Equal(t, time.Minute, limited.Wait)
+	assert.NoError(t, l.Allow(ctx, "10.0.0.1", "bob"))
+	// ip bucket is empty
+	assert.Error(t, l.Allow(ctx, "10.0.0.1", "carol"))
+	assert.NoError(t, l.Allow(ctx, "10.0.0.2", "carol"))
+
+	now = now.Add(time.Hour)
+	until, err := l.Failed(ctx, "alice")
+	assert.NoError(t, err)
+	assert.True(t, until.IsZero())
+	until, _ = l.Failed(ctx, "alice")
+	assert.Equal(t, now.Add(time.Minute), until)
+	assert.True(t, errors.As(l.Allow(ctx, "10.0.0.3", "alice"), &limited))
+	assert.Equal(t, until, limited.Until)
+
+	assert.NoError(t, l.Unlock(ctx, "alice"))
+	assert.NoError(t, l.Allow(ctx, "10.0.0.3", "alice"))
+
+	now = now.Add(time.Hour)
+	l.Failed(ctx, "bob")
+	until, _ = l.Failed(ctx, "bob")
+	now = until
+	assert.NoError(t, l.Allow(ctx, "10.0.0.4", "bob"))
+	until, _ = l.Failed(ctx, "bob")
+	assert.Equal(t, now.Add(2*time.Minute), until)
+}
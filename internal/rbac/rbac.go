@@ -0,0 +1,80 @@
+// Package rbac holds roles of organisation members and actions they allow.
+//
+// Roles are ordered: owner, admin, manager, user, read-only. Every role allows everything the lower ones do,
+// members can assign and manage only roles lower than their own, owners can also assign owners.
+package rbac
+
+// Roles of organisation members
+const (
+	RoleOwner    = "owner"
+	RoleAdmin    = "admin"
+	RoleManager  = "manager"
+	RoleUser     = "user"
+	RoleReadOnly = "read-only"
+)
+
+// Actions on organisations and their secrets
+const (
+	ActionRead              = "read"
+	ActionWrite             = "write"
+	ActionDelete            = "delete"
+	ActionManageCollections = "manage collections"
+	ActionManageMembers     = "manage members"
+	ActionDeleteOrg         = "delete org"
+)
+
+// ranks of roles, higher rank allows more
+var ranks = map[string]int{
+	RoleReadOnly: 1,
+	RoleUser:     2,
+	RoleManager:  3,
+	RoleAdmin:    4,
+	RoleOwner:    5,
+}
+
+// lowest roles allowing the actions
+var minRoles = map[string]string{
+	ActionRead:              RoleReadOnly,
+	ActionWrite:             RoleUser,
+	ActionDelete:            RoleManager,
+	ActionManageCollections: RoleManager,
+	ActionManageMembers:     RoleAdmin,
+	ActionDeleteOrg:         RoleOwner,
+}
+
+// Valid reports whether the role is known
+func Valid(role string) bool {
+	return ranks[role] > 0
+}
+
+// Allowed reports whether the role allows the action, unknown roles and actions allow nothing
+func Allowed(role, action string) bool {
+	min, ok := minRoles[action]
+	return ok && Valid(role) && ranks[role] >= ranks[min]
+}
+
+// Roles lists roles allowing the action from the highest one
+func Roles(action string) []string {
+	var out []string
+	for _, r := range []string{RoleOwner, RoleAdmin, RoleManager, RoleUser, RoleReadOnly} {
+		if Allowed(r, action) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// CanAssign reports whether member with the actor role can give the role to others
+// and change or remove members who have it
+func CanAssign(actor, role string) bool {
+	if !Allowed(actor, ActionManageMembers) || !Valid(role) {
+		return false
+	}
+	return actor == RoleOwner || ranks[role] < ranks[actor]
+}
+
+// KeepsOwner reports whether the organisation with the number of owners still has one after the member
+// with the role gets the new role, empty new role means the member is removed
+func KeepsOwner(owners int, role, newRole string) bool {
+	return role != RoleOwner || newRole == RoleOwner || owners > 1
+}
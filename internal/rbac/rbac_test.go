@@ -0,0 +1,80 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		role    string
+		allowed []string
+	}{
+		{role: RoleOwner, allowed: []string{ActionRead, ActionWrite, ActionDelete, ActionManageCollections, ActionManageMembers, ActionDeleteOrg}},
+		{role: RoleAdmin, allowed: []string{ActionRead, ActionWrite, ActionDelete, ActionManageCollections, ActionManageMembers}},
+		{role: RoleManager, allowed: []string{ActionRead, ActionWrite, ActionDelete, ActionManageCollections}},
+		{role: RoleUser, allowed: []string{ActionRead, ActionWrite}},
+		{role: RoleReadOnly, allowed: []string{ActionRead}},
+		{role: "guest"},
+	}
+	actions := []string{ActionRead, ActionWrite, ActionDelete, ActionManageCollections, ActionManageMembers, ActionDeleteOrg}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			var got []string
+			for _, a := range actions {
+				if Allowed(tt.role, a) {
+					got = append(got, a)
+				}
+			}
+			assert.Equal(t, tt.allowed, got)
+		})
+	}
+	assert.False(t, Allowed(RoleOwner, "unknown"))
+}
+
+func TestRoles(t *testing.T) {
+	assert.Equal(t, []string{RoleOwner, RoleAdmin, RoleManager}, Roles(ActionDelete))
+	assert.Len(t, Roles(ActionRead), 5)
+	assert.Empty(t, Roles("unknown"))
+}
+
+func TestKeepsOwner(t *testing.T) {
+	tests := []struct {
+		name          string
+		owners        int
+		role, newRole string
+		want          bool
+	}{
+		{name: "last_owner_demoted", owners: 1, role: RoleOwner, newRole: RoleAdmin, want: false},
+		{name: "last_owner_removed", owners: 1, role: RoleOwner, newRole: "", want: false},
+		{name: "one_of_owners_demoted", owners: 2, role: RoleOwner, newRole: RoleUser, want: true},
+		{name: "owner_stays_owner", owners: 1, role: RoleOwner, newRole: RoleOwner, want: true},
+		{name: "admin_demoted", owners: 1, role: RoleAdmin, newRole: RoleUser, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, KeepsOwner(tt.owners, tt.role, tt.newRole))
+		})
+	}
+}
+
+func TestCanAssign(t *testing.T) {
+	tests := []struct {
+		name        string
+		actor, role string
+		want        bool
+	}{
+		{name: "owner_makes_owner", actor: RoleOwner, role: RoleOwner, want: true},
+		{name: "owner_makes_admin", actor: RoleOwner, role: RoleAdmin, want: true},
+		{name: "admin_makes_manager", actor: RoleAdmin, role: RoleManager, want: true},
+		{name: "admin_makes_admin", actor: RoleAdmin, role: RoleAdmin, want: false},
+		{name: "manager_makes_user", actor: RoleManager, role: RoleUser, want: false},
+		{name: "unknown_role", actor: RoleOwner, role: "guest", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CanAssign(tt.actor, tt.role))
+		})
+	}
+}
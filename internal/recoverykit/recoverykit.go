@@ -0,0 +1,107 @@
+// Package recoverykit renders the recovery kit the user prints or saves offline: the recovery key with the login
+// it belongs to. Kit is plain text or a one page PDF, the PDF is written by hand with standard fonts,
+// so no fonts or libraries are needed.
+package recoverykit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+// lines of the kit, the recovery key is the only line in monospace font
+func lines(k *pb.RecoveryKit) (title string, before []string, key string, after []string) {
+	return "GophKeeper Recovery Kit",
+		[]string{
+			"Login: " + k.Login,
+			"Created: " + k.CreatedAt,
+			"",
+			"Recovery key:",
+		},
+		k.RecoveryKey,
+		[]string{
+			"",
+			"The recovery key opens your vault when you forget your password.",
+			"Keep this kit offline, in a safe place. Anyone with the key and your",
+			"login can set a new password and read all your secrets.",
+			"The key is shown only once. Rotating the vault key makes it invalid,",
+			"create a new kit after that. Recovery replaces the key with a new one.",
+		}
+}
+
+// Text renders the kit as plain text
+func Text(k *pb.RecoveryKit) []byte {
+	title, before, key, after := lines(k)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n%s\n\n", title, strings.Repeat("=", len(title)))
+	for _, l := range before {
+		buf.WriteString(l + "\n")
+	}
+	buf.WriteString("    " + key + "\n")
+	for _, l := range after {
+		buf.WriteString(l + "\n")
+	}
+	return buf.Bytes()
+}
+
+// pdfString escapes text for a PDF string literal, characters out of latin-1 are replaced
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// PDF renders the kit as one A4 page
+func PDF(k *pb.RecoveryKit) []byte {
+	title, before, key, after := lines(k)
+	var content bytes.Buffer
+	y := 780
+	text := func(font string, size int, s string) {
+		fmt.Fprintf(&content, "BT /%s %d Tf 60 %d Td (%s) Tj ET\n", font, size, y, pdfString(s))
+		y -= size + 8
+	}
+	text("F1", 20, title)
+	y -= 10
+	for _, l := range before {
+		text("F1", 12, l)
+	}
+	text("F2", 13, key)
+	for _, l := range after {
+		text("F1", 11, l)
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,52 @@
+package recoverykit
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var kit = &pb.RecoveryKit{Login: "test (me)", RecoveryKey: "ABCDE-FGHIJ-KLMNO", CreatedAt: "2022-10-01T10:00:00Z"}
+
+func TestText(t *testing.T) {
+	got := string(Text(kit))
+	assert.Contains(t, got, "Login: test (me)\n")
+	assert.Contains(t, got, "\n    ABCDE-FGHIJ-KLMNO\n")
+	assert.Contains(t, got, "Created: 2022-10-01T10:00:00Z\n")
+}
+
+func TestPDF(t *testing.T) {
+	got := PDF(kit)
+	assert.True(t, bytes.HasPrefix(got, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(got, []byte("%%EOF\n")))
+	assert.Contains(t, string(got), `(Login: test \(me\)) Tj`)
+	assert.Contains(t, string(got), "(ABCDE-FGHIJ-KLMNO) Tj")
+
+	// startxref and every offset of the xref table point to the right places
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(got)
+	require.NotNil(t, m)
+	xref, _ := strconv.Atoi(string(m[1]))
+	assert.True(t, bytes.HasPrefix(got[xref:], []byte("xref\n0 7\n")))
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(got[xref:], -1)
+	require.Len(t, offsets, 6)
+	for i, o := range offsets {
+		off, _ := strconv.Atoi(string(o[1]))
+		assert.True(t, bytes.HasPrefix(got[off:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), i+1)
+	}
+
+	// length of the content stream is right
+	idx := regexp.MustCompile(`<< /Length (\d+) >>\nstream\n`).FindSubmatchIndex(got)
+	require.NotNil(t, idx)
+	length, _ := strconv.Atoi(string(got[idx[2]:idx[3]]))
+	assert.True(t, bytes.HasPrefix(got[idx[1]+length:], []byte("endstream")))
+}
+
+func Test_pdfString(t *testing.T) {
+	assert.Equal(t, `a\(b\)\\ ?`, pdfString(`a(b)\ ж`))
+}
@@ -0,0 +1,97 @@
+// Package reminder finds secrets that are about to expire and notifies their owners.
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"go.uber.org/zap"
+)
+
+// Reminder is a list of expiring secrets of one user
+type Reminder struct {
+	UserID int64
+	Login  string
+	Items  []*pb.Data
+}
+
+// Notifier sends reminders somewhere
+type Notifier interface {
+	Notify(context.Context, Reminder) error
+}
+
+// LogNotifier writes reminders to the log
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+// NewLogNotifier returns notifier that writes to the given logger
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Notify logs every expiring secret, secret content itself is not logged
+func (n *LogNotifier) Notify(ctx context.Context, r Reminder) error {
+	for _, d := range r.Items {
+		n.logger.Info("secret expires soon",
+			zap.Int64("user_id", r.UserID),
+			zap.String("login", r.Login),
+			zap.String("secret_id", d.ID),
+			zap.String("metadata", d.Metadata),
+			zap.String("expires_at", d.ExpiresAt))
+	}
+	return nil
+}
+
+// SMTPNotifier sends reminders by email through a local smtp server without authentication
+type SMTPNotifier struct {
+	addr string
+	from string
+	to   string
+}
+
+// NewSMTPNotifier returns notifier that sends mails via smtp server at addr.
+// Mails go to the login of the user if it is an email, otherwise to the default address to.
+func NewSMTPNotifier(addr string, from string, to string) *SMTPNotifier {
+	return &SMTPNotifier{addr: addr, from: from, to: to}
+}
+
+// Notify sends one mail per user with the list of expiring secrets
+func (n *SMTPNotifier) Notify(ctx context.Context, r Reminder) error {
+	to := n.to
+	if strings.Contains(r.Login, "@") {
+		to = r.Login
+	}
+	if to == "" {
+		return fmt.Errorf("no email address for user %d", r.UserID)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\nTo: %s\r\nSubject: GophKeeper: %d secret(s) expire soon\r\n\r\n", n.from, to, len(r.Items))
+	fmt.Fprintf(&body, "Hello %s,\r\n\r\nthe following secrets expire soon or have already expired:\r\n\r\n", r.Login)
+	for _, d := range r.Items {
+		fmt.Fprintf(&body, "  * %s %s, expires at %s\r\n", d.ID, d.Metadata, d.ExpiresAt)
+	}
+
+	if err := smtp.SendMail(n.addr, nil, n.from, []string{to}, []byte(body.String())); err != nil {
+		return fmt.Errorf("sending mail failed: %v", err)
+	}
+	return nil
+}
+
+// MultiNotifier sends reminders to all given notifiers
+type MultiNotifier []Notifier
+
+// Notify calls all notifiers, the first error is returned but all notifiers are tried
+func (m MultiNotifier) Notify(ctx context.Context, r Reminder) error {
+	var firstErr error
+	for _, n := range m {
+		if err := n.Notify(ctx, r); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
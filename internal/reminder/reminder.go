@@ -0,0 +1,108 @@
+package reminder
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Job periodically looks for secrets expiring within the window
+type Job struct {
+	db       storage.StoregeInterface
+	notifier Notifier
+	window   time.Duration
+	logger   *zap.Logger
+	// notified are secrets users were reminded of, a changed expiry is reminded again
+	notified map[notice]bool
+}
+
+// notice is a reminder of the secret about its expiry
+type notice struct {
+	id        string
+	expiresAt string
+}
+
+// NewJob returns new expiry check job
+func NewJob(db storage.StoregeInterface, notifier Notifier, window time.Duration, logger *zap.Logger) *Job {
+	return &Job{
+		db:       db,
+		notifier: notifier,
+		window:   window,
+		logger:   logger,
+		notified: map[notice]bool{},
+	}
+}
+
+// Check finds expiring secrets users were not reminded of yet and sends one reminder per user,
+// it must not be called concurrently
+func (j *Job) Check(ctx context.Context) error {
+	data, err := j.db.SelectExpiring(ctx, 0, time.Now().Add(j.window))
+	if err != nil {
+		return err
+	}
+
+	// secrets that are gone or were renewed are forgotten
+	notified := map[notice]bool{}
+	var fresh []*pb.Data
+	for _, d := range data {
+		n := notice{d.ID, d.ExpiresAt}
+		if j.notified[n] {
+			notified[n] = true
+		} else {
+			fresh = append(fresh, d)
+		}
+	}
+	j.notified = notified
+
+	for _, r := range groupByUser(fresh) {
+		login, err := j.db.SelectLogin(ctx, r.UserID)
+		if err != nil {
+			j.logger.Error("could not find user for reminder", zap.Int64("user_id", r.UserID), zap.Error(err))
+		}
+		r.Login = login
+		if err := j.notifier.Notify(ctx, r); err != nil {
+			// tried again on the next check
+			j.logger.Error("reminder was not sent", zap.Int64("user_id", r.UserID), zap.Error(err))
+			continue
+		}
+		for _, d := range r.Items {
+			j.notified[notice{d.ID, d.ExpiresAt}] = true
+		}
+	}
+	return nil
+}
+
+// Run runs the check at once and then every time t ticks until context is canceled
+func (j *Job) Run(ctx context.Context, t <-chan time.Time) {
+	j.logger.Info("checking expiring secrets")
+	if err := j.Check(ctx); err != nil {
+		j.logger.Error("expiry check failed", zap.Error(err))
+	}
+	for {
+		select {
+		case <-t:
+			j.logger.Info("checking expiring secrets")
+			if err := j.Check(ctx); err != nil {
+				j.logger.Error("expiry check failed", zap.Error(err))
+			}
+		case <-ctx.Done():
+			j.logger.Info("context canceled")
+			return
+		}
+	}
+}
+
+// groupByUser splits secrets sorted by user into reminders
+func groupByUser(data []*pb.Data) []Reminder {
+	var out []Reminder
+	for _, d := range data {
+		if len(out) == 0 || out[len(out)-1].UserID != d.UserID {
+			out = append(out, Reminder{UserID: d.UserID})
+		}
+		out[len(out)-1].Items = append(out[len(out)-1].Items, d)
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package reminder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeDB struct {
+	storage.StoregeInterface
+	data []*pb.Data
+}
+
+func (db *fakeDB) SelectExpiring(context.Context, int64, time.Time) ([]*pb.Data, error) {
+	return db.data, nil
+}
+
+func (db *fakeDB) SelectLogin(_ context.Context, id int64) (string, error) {
+	return map[int64]string{1: "first@example.com", 2: "second"}[id], nil
+}
+
+type fakeNotifier struct {
+	got []Reminder
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, r Reminder) error {
+	n.got = append(n.got, r)
+	return nil
+}
+
+func TestJob_Check(t *testing.T) {
+	db := &fakeDB{data: []*pb.Data{
+		{ID: "1", UserID: 1, ExpiresAt: "2022-01-31"},
+		{ID: "2", UserID: 1, ExpiresAt: "2022-02-28"},
+		{ID: "3", UserID: 2, ExpiresAt: "2022-03-31"},
+	}}
+	n := &fakeNotifier{}
+	logger, _ := zap.NewDevelopment()
+
+	j := NewJob(db, n, time.Hour, logger)
+	err := j.Check(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, n.got, 2)
+	assert.Equal(t, "first@example.com", n.got[0].Login)
+	assert.Len(t, n.got[0].Items, 2)
+	assert.Equal(t, "second", n.got[1].Login)
+	assert.Len(t, n.got[1].Items, 1)
+
+	// reminded secrets are not reminded again, unless their expiry changes
+	n.got = nil
+	db.data[2].ExpiresAt = "2022-04-30"
+	assert.NoError(t, j.Check(context.Background()))
+	assert.Len(t, n.got, 1)
+	assert.Equal(t, "3", n.got[0].Items[0].ID)
+	n.got = nil
+	assert.NoError(t, j.Check(context.Background()))
+	assert.Empty(t, n.got)
+}
+
+func TestJob_Run(t *testing.T) {
+	db := &fakeDB{data: []*pb.Data{{ID: "1", UserID: 1, ExpiresAt: "2022-01-31"}}}
+	n := &fakeNotifier{}
+	logger, _ := zap.NewDevelopment()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the first check does not wait for the tick
+	NewJob(db, n, time.Hour, logger).Run(ctx, nil)
+	assert.Len(t, n.got, 1)
+}
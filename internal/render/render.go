@@ -0,0 +1,83 @@
+// Package render renders config files from text/template templates with secrets of the vault
+// and writes them atomically, readable only by the user
+package render
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"text/template"
+
+	"github.com/maffka123/GophKeeper/internal/secretref"
+)
+
+// Perm is the default mode of rendered files
+const Perm os.FileMode = 0600
+
+// Render executes the template, secret NAME FIELD and ref gk://NAME/FIELD give values of the fields
+func Render(ctx context.Context, name, text string, lookup secretref.Lookup) ([]byte, error) {
+	r := secretref.NewResolver(lookup)
+	t, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"secret": func(name, field string) (string, error) {
+			return r.Value(ctx, secretref.Ref{Name: name, Field: field})
+		},
+		"ref": func(ref string) (string, error) {
+			parsed, err := secretref.Parse(ref)
+			if err != nil {
+				return "", err
+			}
+			return r.Value(ctx, parsed)
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err = t.Execute(&b, nil); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// WriteFile replaces the file with data atomically, readers see either the old or the new content.
+// The file is written only if its content or mode differs, false is returned if it was kept
+func WriteFile(path string, data []byte, perm os.FileMode) (bool, error) {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		// windows has no unix modes
+		if fi, err := os.Stat(path); err == nil && (fi.Mode().Perm() == perm || runtime.GOOS == "windows") {
+			return false, nil
+		}
+	}
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".*")
+	if err != nil {
+		return false, err
+	}
+	tmp := f.Name()
+	// temp file is removed if it was not renamed
+	defer os.Remove(tmp)
+	if err = f.Chmod(perm); err != nil {
+		f.Close()
+		return false, err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err = f.Close(); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
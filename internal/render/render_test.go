@@ -0,0 +1,78 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookup(ctx context.Context, name string) (*pb.Data, error) {
+	if name != "db/prod" {
+		return nil, fmt.Errorf("no secret named %s", name)
+	}
+	return &pb.Data{Metadata: name, Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "app", Password: "s3cret"}}}, nil
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{name: "secret", text: `user={{ secret "db/prod" "login" }} password={{ secret "db/prod" "password" }}`,
+			want: "user=app password=s3cret"},
+		{name: "ref", text: `{{ ref "gk://db/prod/password" | printf "%q" }}`, want: `"s3cret"`},
+		{name: "no_field", text: `{{ secret "db/prod" "cvv" }}`, wantErr: "secret db/prod has no field cvv"},
+		{name: "no_secret", text: `{{ secret "db/test" "password" }}`, wantErr: "no secret named db/test"},
+		{name: "bad_ref", text: `{{ ref "db/prod/password" }}`, wantErr: "is not a reference"},
+		{name: "syntax", text: `{{ secret "db/prod" `, wantErr: "unclosed action"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(context.Background(), tt.name, tt.text, lookup)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+
+	changed, err := WriteFile(path, []byte("a"), Perm)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = WriteFile(path, []byte("a"), Perm)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	changed, err = WriteFile(path, []byte("b"), Perm)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, Perm, fi.Mode().Perm())
+	}
+	// no temp files are left
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
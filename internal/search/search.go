@@ -0,0 +1,165 @@
+// Package search holds the parts of full-text search shared by server and client storage:
+// searchable fields of a secret, query terms, paging and highlighting.
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	// snippetContext is how many characters around the first match are kept in snippet
+	snippetContext = 30
+)
+
+// Field is a searchable field of a secret, passwords, card numbers and binary data are never searched
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns searchable fields of the secret in order of their weight
+func Fields(d *pb.Data) []Field {
+	kd := d.GetData()
+	var uris []string
+	for _, u := range kd.GetAuthData().GetURIs() {
+		uris = append(uris, u.URI)
+	}
+	return []Field{
+		{"Metadata", d.Metadata},
+		{"Login", kd.GetAuthData().GetLogin()},
+		{"URIs", strings.Join(uris, " ")},
+		{"Text", kd.GetText()},
+		{"HolderName", kd.GetBankCard().GetHolderName()},
+		{"BankName", kd.GetBankCard().GetBankName()},
+	}
+}
+
+// Terms splits query the same way as websearch_to_tsquery does: quotes, or and excluded words are dropped
+func Terms(query string) []string {
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"'
+	}) {
+		if w == "or" || strings.HasPrefix(w, "-") {
+			continue
+		}
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// Page returns limit and offset within allowed bounds
+func Page(limit, offset int64) (int64, int64) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// Data returns secrets of the hits
+func Data(hits []*pb.SearchHit) []*pb.Data {
+	out := make([]*pb.Data, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.Data)
+	}
+	return out
+}
+
+// EscapeLike escapes wildcards of LIKE pattern
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Highlight marks terms of the query in searchable fields of the secret with <b></b>,
+// long fields are cut to the part around the first match. Fields without exact match are skipped,
+// so fuzzy only hits have no highlights
+func Highlight(d *pb.Data, query string) []*pb.Highlight {
+	terms := Terms(query)
+	var out []*pb.Highlight
+	for _, f := range Fields(d) {
+		if s, ok := mark(f.Value, terms); ok {
+			out = append(out, &pb.Highlight{Field: f.Name, Snippet: s})
+		}
+	}
+	return out
+}
+
+// mark wraps all occurrences of terms in value, case is ignored
+func mark(value string, terms []string) (string, bool) {
+	lower := strings.ToLower(value)
+	// lower case can change length of some characters, then positions would not match
+	if len(lower) != len(value) || value == "" {
+		return "", false
+	}
+
+	marked := make([]bool, len(value))
+	for _, t := range terms {
+		for i := 0; t != "" && i < len(lower); {
+			j := strings.Index(lower[i:], t)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(t); k++ {
+				marked[k] = true
+			}
+			i += j + len(t)
+		}
+	}
+
+	first, last := -1, -1
+	for i, m := range marked {
+		if m {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start, end := first-snippetContext, last+1+snippetContext
+	if start < 0 {
+		start = 0
+	}
+	if end > len(value) {
+		end = len(value)
+	}
+	for start > 0 && !utf8.RuneStart(value[start]) {
+		start--
+	}
+	for end < len(value) && !utf8.RuneStart(value[end]) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString("<b>")
+		}
+		b.WriteByte(value[i])
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString("</b>")
+		}
+	}
+	if end < len(value) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"alice", "mail", "box"}, Terms(`Alice or "mail box" -spam`))
+	assert.Empty(t, Terms("  "))
+}
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		name                  string
+		limit, offset         int64
+		wantLimit, wantOffset int64
+	}{
+		{name: "defaults", wantLimit: DefaultLimit},
+		{name: "too_big", limit: 1000, offset: 40, wantLimit: MaxLimit, wantOffset: 40},
+		{name: "negative_offset", limit: 5, offset: -1, wantLimit: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, offset := Page(tt.limit, tt.offset)
+			assert.Equal(t, tt.wantLimit, limit)
+			assert.Equal(t, tt.wantOffset, offset)
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\% \_a\\b`, EscapeLike(`100% _a\b`))
+}
+
+func TestHighlight(t *testing.T) {
+	d := &pb.Data{
+		Metadata: "Work mail",
+		Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "alice@mail.example.com", Password: "mail",
+			URIs: []*pb.LoginURI{{URI: "https://mail.example.com"}}}},
+	}
+	got := Highlight(d, "Mail ali")
+	assert.Equal(t, []*pb.Highlight{
+		{Field: "Metadata", Snippet: "Work <b>mail</b>"},
+		{Field: "Login", Snippet: "<b>ali</b>ce@<b>mail</b>.example.com"},
+		{Field: "URIs", Snippet: "https://<b>mail</b>.example.com"},
+	}, got)
+
+	long := &pb.Data{Data: &pb.KeepData{Text: "the quick brown fox jumps over the lazy dog and then the fox sleeps for a very long time"}}
+	got = Highlight(long, "lazy")
+	assert.Equal(t, "…uick brown fox jumps over the <b>lazy</b> dog and then the fox sleeps f…", got[0].Snippet)
+
+	assert.Empty(t, Highlight(d, "mial"))
+}
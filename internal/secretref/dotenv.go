@@ -0,0 +1,68 @@
+package secretref
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Var is a variable of the environment
+type Var struct {
+	Name  string
+	Value string
+}
+
+func (v Var) String() string {
+	return v.Name + "=" + v.Value
+}
+
+// ParseVar parses NAME=VALUE
+func ParseVar(s string) (Var, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return Var{}, fmt.Errorf("%q must be NAME=VALUE", s)
+	}
+	return Var{Name: name, Value: value}, nil
+}
+
+// ParseEnv reads .env file: NAME=VALUE lines with optional export, values can be quoted, # starts comments
+func ParseEnv(r io.Reader) ([]Var, error) {
+	var vars []Var
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		v, err := ParseVar(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		if v.Value, err = unquote(strings.TrimSpace(v.Value)); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		vars = append(vars, v)
+	}
+	return vars, s.Err()
+}
+
+// unquote removes quotes of the value, comments after unquoted values are dropped
+func unquote(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		return strconv.Unquote(v)
+	case strings.HasPrefix(v, "'"):
+		if len(v) < 2 || !strings.HasSuffix(v, "'") {
+			return "", fmt.Errorf("unterminated quote in %s", v)
+		}
+		return v[1 : len(v)-1], nil
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v, nil
+}
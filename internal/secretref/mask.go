@@ -0,0 +1,78 @@
+package secretref
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Mask replaces secrets in the output
+const Mask = "*****"
+
+// Masker writes to w with secrets replaced by Mask. Output that may be the beginning of a secret is held back
+// until the next write or Close, so secrets split between writes are masked too
+type Masker struct {
+	w       io.Writer
+	secrets [][]byte
+	mu      sync.Mutex
+	buf     []byte
+}
+
+// NewMasker creates masker of the secrets, empty ones are ignored
+func NewMasker(w io.Writer, secrets []string) *Masker {
+	m := &Masker{w: w}
+	for _, s := range secrets {
+		if s != "" {
+			m.secrets = append(m.secrets, []byte(s))
+		}
+	}
+	// longer secrets first, so the ones containing others are masked whole
+	sort.Slice(m.secrets, func(i, j int) bool { return len(m.secrets[i]) > len(m.secrets[j]) })
+	return m
+}
+
+// Write masks p and writes all of it that cannot be a part of a secret, length of p is returned on success
+func (m *Masker) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.buf = append(m.buf, p...)
+	for _, s := range m.secrets {
+		m.buf = bytes.ReplaceAll(m.buf, s, []byte(Mask))
+	}
+	n := len(m.buf) - m.partial()
+	if err := m.flush(n); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the held back output
+func (m *Masker) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flush(len(m.buf))
+}
+
+// partial is the length of the longest end of the buffer that starts some secret
+func (m *Masker) partial() int {
+	longest := 0
+	for _, s := range m.secrets {
+		for k := len(s) - 1; k > longest; k-- {
+			if k <= len(m.buf) && bytes.HasPrefix(s, m.buf[len(m.buf)-k:]) {
+				longest = k
+				break
+			}
+		}
+	}
+	return longest
+}
+
+func (m *Masker) flush(n int) error {
+	if n == 0 {
+		return nil
+	}
+	_, err := m.w.Write(m.buf[:n])
+	m.buf = append(m.buf[:0], m.buf[n:]...)
+	return err
+}
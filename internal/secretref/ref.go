@@ -0,0 +1,97 @@
+// Package secretref resolves references to fields of vault secrets like gk://db/prod/password,
+// so secrets can be given to other programs without writing them to disk
+package secretref
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/field"
+)
+
+// Scheme starts every reference
+const Scheme = "gk://"
+
+// Ref points to a field of the secret, the last segment of the path is the field, the rest is the name
+type Ref struct {
+	Name  string
+	Field string
+}
+
+func (r Ref) String() string {
+	return Scheme + r.Name + "/" + r.Field
+}
+
+// IsRef tells whether the value is a reference
+func IsRef(s string) bool {
+	return strings.HasPrefix(s, Scheme)
+}
+
+// Parse parses reference gk://folder/item/field
+func Parse(s string) (Ref, error) {
+	if !IsRef(s) {
+		return Ref{}, fmt.Errorf("%s is not a reference, it must start with %s", s, Scheme)
+	}
+	path := strings.TrimPrefix(s, Scheme)
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		return Ref{}, fmt.Errorf("reference %s must be %sNAME/FIELD", s, Scheme)
+	}
+	return Ref{Name: path[:i], Field: path[i+1:]}, nil
+}
+
+// Lookup returns the unmasked secret named exactly as name
+type Lookup func(ctx context.Context, name string) (*pb.Data, error)
+
+// Resolver returns values of references, every secret is looked up once
+type Resolver struct {
+	lookup Lookup
+	found  map[string]*pb.Data
+	values map[string]bool
+}
+
+// NewResolver creates resolver looking secrets up with lookup
+func NewResolver(lookup Lookup) *Resolver {
+	return &Resolver{lookup: lookup, found: map[string]*pb.Data{}, values: map[string]bool{}}
+}
+
+// Value returns value of the field the reference points to
+func (r *Resolver) Value(ctx context.Context, ref Ref) (string, error) {
+	d, ok := r.found[ref.Name]
+	if !ok {
+		var err error
+		if d, err = r.lookup(ctx, ref.Name); err != nil {
+			return "", fmt.Errorf("%s: %v", ref, err)
+		}
+		r.found[ref.Name] = d
+	}
+	v, err := field.Value(d, ref.Field)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", ref, err)
+	}
+	r.values[v] = true
+	return v, nil
+}
+
+// Resolve returns the value of s if it is a reference and s otherwise
+func (r *Resolver) Resolve(ctx context.Context, s string) (string, error) {
+	if !IsRef(s) {
+		return s, nil
+	}
+	ref, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return r.Value(ctx, ref)
+}
+
+// Secrets returns all values resolved so far, they are to be masked
+func (r *Resolver) Secrets() []string {
+	secrets := make([]string, 0, len(r.values))
+	for v := range r.values {
+		secrets = append(secrets, v)
+	}
+	return secrets
+}
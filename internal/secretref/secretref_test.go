@@ -0,0 +1,122 @@
+package secretref
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		want    Ref
+		wantErr bool
+	}{
+		{name: "folder", ref: "gk://db/prod/password", want: Ref{Name: "db/prod", Field: "password"}},
+		{name: "no_folder", ref: "gk://mail/login", want: Ref{Name: "mail", Field: "login"}},
+		{name: "no_field", ref: "gk://mail", wantErr: true},
+		{name: "empty_field", ref: "gk://mail/", wantErr: true},
+		{name: "no_scheme", ref: "db/prod/password", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ref, got.String())
+		})
+	}
+}
+
+func TestResolver(t *testing.T) {
+	lookups := 0
+	r := NewResolver(func(ctx context.Context, name string) (*pb.Data, error) {
+		lookups++
+		if name != "db/prod" {
+			return nil, fmt.Errorf("no secret named %s", name)
+		}
+		return &pb.Data{Metadata: name, Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "app", Password: "s3cret"}}}, nil
+	})
+	ctx := context.Background()
+
+	v, err := r.Resolve(ctx, "gk://db/prod/password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", v)
+	v, err = r.Resolve(ctx, "gk://db/prod/login")
+	require.NoError(t, err)
+	assert.Equal(t, "app", v)
+	v, err = r.Resolve(ctx, "plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", v)
+	assert.Equal(t, 1, lookups)
+	assert.ElementsMatch(t, []string{"s3cret", "app"}, r.Secrets())
+
+	_, err = r.Resolve(ctx, "gk://db/prod/cvv")
+	assert.EqualError(t, err, "gk://db/prod/cvv: secret db/prod has no field cvv")
+	_, err = r.Resolve(ctx, "gk://db/test/password")
+	assert.EqualError(t, err, "gk://db/test/password: no secret named db/test")
+}
+
+func TestParseEnv(t *testing.T) {
+	env := `# database
+export DB_USER=gk://db/prod/login
+DB_PASSWORD="gk://db/prod/password"
+GREETING='hello # world'
+MODE=prod # comment
+QUOTED="a\nb"
+
+`
+	vars, err := ParseEnv(strings.NewReader(env))
+	require.NoError(t, err)
+	assert.Equal(t, []Var{{"DB_USER", "gk://db/prod/login"}, {"DB_PASSWORD", "gk://db/prod/password"},
+		{"GREETING", "hello # world"}, {"MODE", "prod"}, {"QUOTED", "a\nb"}}, vars)
+
+	_, err = ParseEnv(strings.NewReader("A=1\nB\n"))
+	assert.EqualError(t, err, `line 2: "B" must be NAME=VALUE`)
+	_, err = ParseEnv(strings.NewReader("A='1\n"))
+	assert.Error(t, err)
+}
+
+func TestMasker(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{name: "whole", writes: []string{"password is s3cret\n"}, want: "password is *****\n"},
+		{name: "split", writes: []string{"password is s3", "cr", "et\n"}, want: "password is *****\n"},
+		{name: "not_secret", writes: []string{"s3c", "ond\n"}, want: "s3cond\n"},
+		{name: "longest_first", writes: []string{"s3cret-long s3cret"}, want: "***** *****"},
+		{name: "end", writes: []string{"ends with s3c"}, want: "ends with s3c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			m := NewMasker(&out, []string{"s3cret", "", "s3cret-long"})
+			for _, w := range tt.writes {
+				n, err := m.Write([]byte(w))
+				require.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+			require.NoError(t, m.Close())
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	// output that cannot start a secret is written at once
+	var out bytes.Buffer
+	m := NewMasker(&out, []string{"s3cret"})
+	_, _ = m.Write([]byte("line\nnext s"))
+	assert.Equal(t, "line\nnext ", out.String())
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/ratelimit"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkPassword authenticates the user again before changes of the account, wrong passwords count
+// to the lockout of the login as wrong logins do
+func (s *secretService) checkPassword(ctx context.Context, userID int64, password string) error {
+	login, err := s.db.SelectLogin(ctx, userID)
+	if err != nil {
+		return status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	if s.limiter != nil {
+		ip, _ := client(ctx)
+		if err := s.limiter.Allow(ctx, ip, login); err != nil {
+			var limited *ratelimit.Error
+			if errors.As(err, &limited) {
+				return status.Errorf(
+					codes.ResourceExhausted, limited.Error(),
+				)
+			}
+			return status.Errorf(
+				codes.Internal, err.Error(),
+			)
+		}
+	}
+
+	pass, _, err := s.db.SelectPass(ctx, &pb.User{Login: login})
+	if err != nil {
+		return status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	if pass == nil || !comparePass(*pass, password) {
+		if s.limiter != nil {
+			if _, err := s.limiter.Failed(ctx, login); err != nil {
+				s.logger.Error("failed login cannot be counted: ", zap.String("login", login), zap.Error(err))
+			}
+		}
+		return status.Errorf(
+			codes.PermissionDenied, "password is wrong",
+		)
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Succeeded(ctx, login); err != nil {
+			s.logger.Error("failed logins cannot be reset: ", zap.String("login", login), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// ChangePassword sets new password of authorized user, vault key wrapped with the new master key is saved with it,
+// so secrets stay readable and the version of the vault key stays the same. Tokens of the user are revoked,
+// the new one is returned
+func (s *secretService) ChangePassword(ctx context.Context, request *pb.ChangePasswordRequest) (*pb.ChangePasswordResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if request.NewPassword == "" || len(request.VaultKey) == 0 || len(request.VaultSalt) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "new password, vault key and salt are required",
+		)
+	}
+	if err := s.checkPassword(ctx, currUser, request.OldPassword); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.SetPassword(ctx, currUser, request.NewPassword, request.VaultKey, request.VaultSalt); err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Info("password changed: ", zap.Int64("user", currUser))
+	tokenString, err := s.issueToken(ctx, currUser)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ChangePasswordResp{Token: tokenString}, nil
+}
+
+// DeleteAccount deletes authorized user with all secrets, shares, emergency access and memberships,
+// last owner of an organisation with other members cannot be deleted
+func (s *secretService) DeleteAccount(ctx context.Context, request *pb.DeleteAccountRequest) (*pb.DeleteAccountResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if err := s.checkPassword(ctx, currUser, request.Password); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.DeleteAccount(ctx, currUser); err != nil {
+		if errors.Is(err, storage.ErrLastOwner) {
+			return nil, status.Errorf(
+				codes.FailedPrecondition, err.Error(),
+			)
+		}
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Info("account deleted: ", zap.Int64("user", currUser))
+	return &pb.DeleteAccountResp{}, nil
+}
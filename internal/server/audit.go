@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/audit"
+	"github.com/maffka123/GophKeeper/internal/paging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// maxTargets is how many ids of secrets are kept in target of one event
+const maxTargets = 20
+
+// SetAdmins sets logins of admins, they can see audit log of all users
+func (s *secretService) SetAdmins(logins []string) {
+	s.admins = map[string]bool{}
+	for _, l := range logins {
+		if l = strings.TrimSpace(l); l != "" {
+			s.admins[l] = true
+		}
+	}
+}
+
+// isAdmin checks if the user is admin
+func (s *secretService) isAdmin(ctx context.Context, userID int64) (bool, error) {
+	if len(s.admins) == 0 {
+		return false, nil
+	}
+	login, err := s.db.SelectLogin(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return s.admins[login], nil
+}
+
+// AuditInterceptor records audited calls in the audit log with the user, target, outcome, ip and device of the client,
+// it goes after auth interceptor, so the user is known, failed logins and recoveries are recorded for the user with the login
+func (s *secretService) AuditInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+
+		method := audit.Method(info.FullMethod)
+		action, ok := audit.Action(method)
+		if !ok {
+			return resp, err
+		}
+		e := &pb.AuditEvent{Method: method, Action: action, Target: auditTarget(req, resp), Outcome: status.Code(err).String()}
+		e.IP, e.Device = client(ctx)
+		switch r := req.(type) {
+		case *pb.RegisterRequest:
+			e.UserID, e.Login = r.GetUser().GetID(), r.GetUser().GetLogin()
+			if registered, _ := resp.(*pb.RegisterResp); registered.GetExists() == -1 {
+				e.Outcome = codes.AlreadyExists.String()
+			}
+		case *pb.LoginRequest:
+			// user id is found by login also when the password is wrong
+			e.UserID, e.Login = r.GetUser().GetID(), r.GetUser().GetLogin()
+			if err != nil {
+				e.Action = audit.ActionLoginFailed
+			}
+		case *pb.GetRecoveryKeyRequest:
+			e.UserID, e.Login = r.GetUser().GetID(), r.GetUser().GetLogin()
+		case *pb.RecoverAccountRequest:
+			e.UserID, e.Login = r.GetUser().GetID(), r.GetUser().GetLogin()
+		default:
+			e.UserID, _ = app.UserIDFromContext(ctx)
+		}
+
+		// event is saved even if the client is gone already
+		if err := s.db.InsertAuditEvent(context.Background(), e); err != nil {
+			s.logger.Error("audit event cannot be saved: ", zap.String("method", method), zap.Int64("user", e.UserID), zap.Error(err))
+		}
+		return resp, err
+	}
+}
+
+// client returns ip address and user agent of the client
+func client(ctx context.Context) (string, string) {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return ip, metautils.ExtractIncoming(ctx).Get("user-agent")
+}
+
+// auditTarget returns ids of secrets the call got or changed, or id of the share, organisation or other object of the call
+func auditTarget(req, resp interface{}) string {
+	var ids []string
+	switch r := resp.(type) {
+	case *pb.InsertResp:
+		return r.GetId()
+	case *pb.InsertBatchResp:
+		ids = r.GetIds()
+	case *pb.GetDataResp:
+		ids = dataIDs(r.GetData())
+	case *pb.DeleteResp:
+		ids = dataIDs(r.GetData())
+	case *pb.GetAllDataForUserResp:
+		ids = dataIDs(r.GetData())
+	case *pb.GetEmergencyDataResp:
+		ids = dataIDs(r.GetData())
+	case *pb.ListSharesResp:
+		for _, sh := range r.GetShares() {
+			ids = append(ids, sh.ID)
+		}
+	case interface{ GetID() string }:
+		if id := r.GetID(); id != "" {
+			return id
+		}
+	}
+	if len(ids) > maxTargets {
+		ids = append(ids[:maxTargets], fmt.Sprintf("+%d", len(ids)-maxTargets))
+	}
+	if len(ids) > 0 {
+		return strings.Join(ids, ",")
+	}
+
+	switch r := req.(type) {
+	case interface{ GetID() string }:
+		return r.GetID()
+	case interface{ GetOrgID() string }:
+		return r.GetOrgID()
+	case interface{ GetData() *pb.Data }:
+		if id := r.GetData().GetID(); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func dataIDs(data []*pb.Data) []string {
+	var ids []string
+	for _, d := range data {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+// ListAuditEvents lists audit log of authorized user, admins can list events of any user or all of them
+func (s *secretService) ListAuditEvents(ctx context.Context, request *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if request.UserID != currUser {
+		admin, err := s.isAdmin(ctx, currUser)
+		if err != nil {
+			return nil, status.Errorf(
+				codes.Internal, err.Error(),
+			)
+		}
+		if !admin && request.UserID != 0 {
+			return nil, status.Errorf(
+				codes.PermissionDenied, "only admins can see audit log of other users",
+			)
+		} else if !admin {
+			request.UserID = currUser
+		}
+	}
+	if request.Action != "" && !audit.ValidAction(request.Action) {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "action is not valid: %s", request.Action,
+		)
+	}
+	if request.AfterID < 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "after id must be a positive number: %d", request.AfterID,
+		)
+	}
+	var since, until time.Time
+	for _, t := range []struct {
+		value string
+		to    *time.Time
+	}{{request.Since, &since}, {request.Until, &until}} {
+		if t.value == "" {
+			continue
+		}
+		if *t.to, err = time.Parse(time.RFC3339, t.value); err != nil {
+			return nil, status.Errorf(
+				codes.InvalidArgument, "time is not valid, use RFC 3339: %s", t.value,
+			)
+		}
+	}
+	request.Limit = paging.Size(&pb.PageRequest{PageSize: request.Limit})
+
+	events, err := s.db.SelectAuditEvents(ctx, request, since, until)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ListAuditEventsResp{Events: events}, nil
+}
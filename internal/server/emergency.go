@@ -0,0 +1,372 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution/uuid"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/audit"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+	"github.com/maffka123/GophKeeper/internal/emergency"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// emergencyError converts storage errors of emergency access to grpc status
+func emergencyError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Errorf(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrEmergencyExists):
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	case errors.Is(err, storage.ErrEmergencyStatus):
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Errorf(codes.Internal, err.Error())
+	}
+}
+
+// emergencyFor gets emergency access by id, the user must be its grantor or its contact
+func (s *secretService) emergencyFor(ctx context.Context, userID int64, id string, grantor bool) (*pb.EmergencyAccess, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "emergency access id is not valid: %s", id,
+		)
+	}
+	e, err := s.db.SelectEmergency(ctx, id)
+	if err != nil {
+		return nil, emergencyError(err)
+	}
+	if (grantor && e.GrantorID != userID) || (!grantor && e.GranteeID != userID) {
+		return nil, status.Errorf(
+			codes.PermissionDenied, "emergency access %s does not belong to the user", id,
+		)
+	}
+	return e, nil
+}
+
+// approveExpired approves requested access once its waiting period is over, the grantor did not answer in time
+func (s *secretService) approveExpired(ctx context.Context, e *pb.EmergencyAccess, userID int64) error {
+	if e.Status != emergency.StatusRecoveryInitiated {
+		return nil
+	}
+	approved, err := s.db.ApproveExpiredEmergency(ctx, e.ID)
+	if err != nil {
+		return emergencyError(err)
+	}
+	if approved {
+		e.Status = emergency.StatusRecoveryApproved
+		s.logEmergency(ctx, "recovery approved after waiting period", e, userID)
+	}
+	return nil
+}
+
+// logEmergency records the step of emergency access in the log and in the audit log
+func (s *secretService) logEmergency(ctx context.Context, step string, e *pb.EmergencyAccess, userID int64) {
+	s.logger.Info("emergency access: ", zap.String("step", step), zap.String("id", e.ID), zap.Int64("user", userID),
+		zap.Int64("grantor", e.GrantorID), zap.Int64("grantee", e.GranteeID))
+
+	// the step is saved even if the client is gone already
+	event := &pb.AuditEvent{UserID: userID, Method: step, Action: audit.ActionEmergency, Target: e.ID, Outcome: codes.OK.String()}
+	event.IP, event.Device = client(ctx)
+	if err := s.db.InsertAuditEvent(context.Background(), event); err != nil {
+		s.logger.Error("audit event cannot be saved: ", zap.String("step", step), zap.Int64("user", userID), zap.Error(err))
+	}
+}
+
+// InviteEmergencyContact invites user to become emergency contact of authorized user
+func (s *secretService) InviteEmergencyContact(ctx context.Context, request *pb.InviteEmergencyContactRequest) (*pb.InviteEmergencyContactResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if !emergency.ValidAccess(request.AccessType) {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "access type %s is not valid, use view or takeover", request.AccessType,
+		)
+	}
+	days, err := emergency.WaitDays(request.WaitDays)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, err.Error(),
+		)
+	}
+
+	id, err := s.db.InsertEmergencyAccess(ctx, currUser, request.Login, request.AccessType, days)
+	if err != nil {
+		return nil, emergencyError(err)
+	}
+	s.logEmergency(ctx, "invited", &pb.EmergencyAccess{ID: id.String(), GrantorID: currUser}, currUser)
+	return &pb.InviteEmergencyContactResp{ID: id.String()}, nil
+}
+
+// ListEmergencyAccess lists emergency contacts of authorized user and users who trust authorized user,
+// sealed keys are never listed
+func (s *secretService) ListEmergencyAccess(ctx context.Context, request *pb.ListEmergencyAccessRequest) (*pb.ListEmergencyAccessResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	all, err := s.db.SelectEmergencyAccess(ctx, currUser)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	resp := &pb.ListEmergencyAccessResp{}
+	for _, e := range all {
+		e.WrappedKey = nil
+		if e.GrantorID == currUser {
+			resp.Trusted = append(resp.Trusted, e)
+		} else {
+			e.PublicKey = nil
+			resp.Granted = append(resp.Granted, e)
+		}
+	}
+	return resp, nil
+}
+
+// AcceptEmergencyAccess accepts invitation of authorized user to become emergency contact
+func (s *secretService) AcceptEmergencyAccess(ctx context.Context, request *pb.AcceptEmergencyAccessRequest) (*pb.AcceptEmergencyAccessResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	e, err := s.emergencyFor(ctx, currUser, request.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.SetEmergencyStatus(ctx, e.ID, emergency.StatusInvited, emergency.StatusAccepted); err != nil {
+		return nil, emergencyError(err)
+	}
+	s.logEmergency(ctx, "accepted", e, currUser)
+	return &pb.AcceptEmergencyAccessResp{}, nil
+}
+
+// ConfirmEmergencyAccess saves vault key of authorized user sealed to the contact, key must be of the current version
+func (s *secretService) ConfirmEmergencyAccess(ctx context.Context, request *pb.ConfirmEmergencyAccessRequest) (*pb.ConfirmEmergencyAccessResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if len(request.WrappedKey) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "wrapped key is required",
+		)
+	}
+	e, err := s.emergencyFor(ctx, currUser, request.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	_, _, version, err := s.db.SelectVaultKey(ctx, currUser)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	if request.KeyVersion != version {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "vault key version %d is not the current one", request.KeyVersion,
+		)
+	}
+
+	if err := s.db.ConfirmEmergencyAccess(ctx, e.ID, request.WrappedKey, request.KeyVersion); err != nil {
+		return nil, emergencyError(err)
+	}
+	s.logEmergency(ctx, "confirmed", e, currUser)
+	return &pb.ConfirmEmergencyAccessResp{}, nil
+}
+
+// InitiateEmergencyAccess requests access to the vault of the grantor, the waiting period starts
+func (s *secretService) InitiateEmergencyAccess(ctx context.Context, request *pb.InitiateEmergencyAccessRequest) (*pb.InitiateEmergencyAccessResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	e, err := s.emergencyFor(ctx, currUser, request.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.SetEmergencyStatus(ctx, e.ID, emergency.StatusConfirmed, emergency.StatusRecoveryInitiated); err != nil {
+		return nil, emergencyError(err)
+	}
+	s.logEmergency(ctx, "recovery initiated", e, currUser)
+	return &pb.InitiateEmergencyAccessResp{}, nil
+}
+
+// AnswerEmergencyAccess approves requested access or rejects requested or approved access, rejected access can be requested again
+func (s *secretService) AnswerEmergencyAccess(ctx context.Context, request *pb.AnswerEmergencyAccessRequest) (*pb.AnswerEmergencyAccessResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	e, err := s.emergencyFor(ctx, currUser, request.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	to, step := emergency.StatusConfirmed, "recovery rejected"
+	if request.Approve {
+		to, step = emergency.StatusRecoveryApproved, "recovery approved"
+	}
+	if e.Status != emergency.StatusRecoveryInitiated && (request.Approve || e.Status != emergency.StatusRecoveryApproved) {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "emergency access is %s, there is no request to answer", e.Status,
+		)
+	}
+	if err := s.db.SetEmergencyStatus(ctx, e.ID, e.Status, to); err != nil {
+		return nil, emergencyError(err)
+	}
+	s.logEmergency(ctx, step, e, currUser)
+	return &pb.AnswerEmergencyAccessResp{}, nil
+}
+
+// GetEmergencyData gets personal secrets of the grantor once access is approved or the waiting period is over,
+// contacts with takeover access also get vault key of the grantor sealed to them
+func (s *secretService) GetEmergencyData(ctx context.Context, request *pb.GetEmergencyDataRequest) (*pb.GetEmergencyDataResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	e, err := s.emergencyFor(ctx, currUser, request.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.approveExpired(ctx, e, currUser); err != nil {
+		return nil, err
+	}
+	if e.Status != emergency.StatusRecoveryApproved {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "emergency access is %s, it is not approved yet", e.Status,
+		)
+	}
+
+	all, err := s.db.SelectAllDataForUser(ctx, e.GrantorID, "-infinity")
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	// secrets of organisations are not the grantor's to give
+	var data []*pb.Data
+	for _, d := range all {
+		if d.CollectionID == "" {
+			data = append(data, d)
+		}
+	}
+	if !request.Reveal {
+		bankcard.Mask(data)
+	}
+
+	resp := &pb.GetEmergencyDataResp{Data: data, AccessType: e.AccessType}
+	if e.AccessType == emergency.AccessTakeover {
+		resp.WrappedKey, resp.KeyVersion = e.WrappedKey, e.KeyVersion
+	}
+	s.logEmergency(ctx, "data read", e, currUser)
+	return resp, nil
+}
+
+// TakeoverEmergencyAccess sets new password of the grantor, the contact must have approved takeover access
+func (s *secretService) TakeoverEmergencyAccess(ctx context.Context, request *pb.TakeoverEmergencyAccessRequest) (*pb.TakeoverEmergencyAccessResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if request.Password == "" || len(request.VaultKey) == 0 || len(request.VaultSalt) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "password, vault key and salt are required",
+		)
+	}
+	e, err := s.emergencyFor(ctx, currUser, request.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	if e.AccessType != emergency.AccessTakeover {
+		return nil, status.Errorf(
+			codes.PermissionDenied, "emergency access gives only view access",
+		)
+	}
+	if err := s.approveExpired(ctx, e, currUser); err != nil {
+		return nil, err
+	}
+	if e.Status != emergency.StatusRecoveryApproved {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "emergency access is %s, it is not approved yet", e.Status,
+		)
+	}
+
+	if err := s.db.SetPassword(ctx, e.GrantorID, request.Password, request.VaultKey, request.VaultSalt); err != nil {
+		return nil, emergencyError(err)
+	}
+	s.logEmergency(ctx, "account taken over", e, currUser)
+	return &pb.TakeoverEmergencyAccessResp{}, nil
+}
+
+// RemoveEmergencyAccess removes emergency access, both the grantor and the contact can do it
+func (s *secretService) RemoveEmergencyAccess(ctx context.Context, request *pb.RemoveEmergencyAccessRequest) (*pb.RemoveEmergencyAccessResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	e, err := s.emergencyFor(ctx, currUser, request.ID, true)
+	if status.Code(err) == codes.PermissionDenied {
+		e, err = s.emergencyFor(ctx, currUser, request.ID, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.DeleteEmergencyAccess(ctx, e.ID); err != nil {
+		return nil, emergencyError(err)
+	}
+	s.logEmergency(ctx, "removed", e, currUser)
+	return &pb.RemoveEmergencyAccessResp{}, nil
+}
@@ -0,0 +1,419 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution/uuid"
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/rbac"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxNameLen is the length of names of organisations and collections in db
+const maxNameLen = 100
+
+// orgError converts storage errors of organisations to grpc status
+func orgError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotMember):
+		return status.Errorf(codes.PermissionDenied, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Errorf(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrMember):
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	case errors.Is(err, storage.ErrLastOwner):
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Errorf(codes.Internal, err.Error())
+	}
+}
+
+// validateName checks name of organisation or collection
+func validateName(name string) error {
+	if name == "" || len(name) > maxNameLen {
+		return status.Errorf(
+			codes.InvalidArgument, "name is required and must be at most %d characters", maxNameLen,
+		)
+	}
+	return nil
+}
+
+// authorize checks that role of the user in the organisation allows the action and returns the role
+func (s *secretService) authorize(ctx context.Context, userID int64, orgID, action string) (string, error) {
+	if _, err := uuid.Parse(orgID); err != nil {
+		return "", status.Errorf(
+			codes.InvalidArgument, "organisation id is not valid: %s", orgID,
+		)
+	}
+	role, err := s.db.SelectOrgRole(ctx, userID, orgID)
+	if err != nil {
+		return "", orgError(err)
+	}
+	if !rbac.Allowed(role, action) {
+		return "", status.Errorf(
+			codes.PermissionDenied, "role %s does not allow to %s", role, action,
+		)
+	}
+	return role, nil
+}
+
+// authorizeCollection checks that role of the user in the organisation of the collection allows the action,
+// personal secrets have no collection and are always allowed
+func (s *secretService) authorizeCollection(ctx context.Context, userID int64, collectionID, action string) error {
+	if collectionID == "" {
+		return nil
+	}
+	if _, err := uuid.Parse(collectionID); err != nil {
+		return status.Errorf(
+			codes.InvalidArgument, "collection id is not valid: %s", collectionID,
+		)
+	}
+	role, err := s.db.SelectCollectionRole(ctx, userID, collectionID)
+	if err != nil {
+		return orgError(err)
+	}
+	if !rbac.Allowed(role, action) {
+		return status.Errorf(
+			codes.PermissionDenied, "role %s does not allow to %s secrets of the collection", role, action,
+		)
+	}
+	return nil
+}
+
+// CreateOrg creates organisation, authorized user becomes its owner
+func (s *secretService) CreateOrg(ctx context.Context, request *pb.CreateOrgRequest) (*pb.CreateOrgResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if err := validateName(request.Name); err != nil {
+		return nil, err
+	}
+	id, err := s.db.InsertOrg(ctx, currUser, request.Name)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.CreateOrgResp{ID: id.String()}, nil
+}
+
+// ListOrgs lists organisations of authorized user
+func (s *secretService) ListOrgs(ctx context.Context, request *pb.ListOrgsRequest) (*pb.ListOrgsResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	orgs, err := s.db.SelectOrgs(ctx, currUser)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ListOrgsResp{Orgs: orgs}, nil
+}
+
+// DeleteOrg deletes organisation with all its collections and secrets
+func (s *secretService) DeleteOrg(ctx context.Context, request *pb.DeleteOrgRequest) (*pb.DeleteOrgResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if _, err := s.authorize(ctx, currUser, request.ID, rbac.ActionDeleteOrg); err != nil {
+		return nil, err
+	}
+	if err := s.db.DeleteOrg(ctx, request.ID); err != nil {
+		return nil, orgError(err)
+	}
+	return &pb.DeleteOrgResp{}, nil
+}
+
+// InviteMember invites user to the organisation, the role must be lower than the role of authorized user
+func (s *secretService) InviteMember(ctx context.Context, request *pb.InviteMemberRequest) (*pb.InviteMemberResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if !rbac.Valid(request.Role) {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "unknown role %s", request.Role,
+		)
+	}
+	actor, err := s.authorize(ctx, currUser, request.OrgID, rbac.ActionManageMembers)
+	if err != nil {
+		return nil, err
+	}
+	if !rbac.CanAssign(actor, request.Role) {
+		return nil, status.Errorf(
+			codes.PermissionDenied, "role %s cannot be given by %s", request.Role, actor,
+		)
+	}
+
+	id, err := s.db.InsertInvitation(ctx, request.OrgID, request.Login, request.Role, currUser)
+	if err != nil {
+		return nil, orgError(err)
+	}
+	return &pb.InviteMemberResp{ID: id.String()}, nil
+}
+
+// ListInvitations lists invitations of authorized user
+func (s *secretService) ListInvitations(ctx context.Context, request *pb.ListInvitationsRequest) (*pb.ListInvitationsResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	invitations, err := s.db.SelectInvitations(ctx, currUser)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ListInvitationsResp{Invitations: invitations}, nil
+}
+
+// AnswerInvitation accepts or declines invitation of authorized user
+func (s *secretService) AnswerInvitation(ctx context.Context, request *pb.AnswerInvitationRequest) (*pb.AnswerInvitationResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if _, err := uuid.Parse(request.ID); err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "invitation id is not valid: %s", request.ID,
+		)
+	}
+	if err := s.db.AnswerInvitation(ctx, currUser, request.ID, request.Accept); err != nil {
+		return nil, orgError(err)
+	}
+	return &pb.AnswerInvitationResp{}, nil
+}
+
+// ListMembers lists members of the organisation, any member can see them
+func (s *secretService) ListMembers(ctx context.Context, request *pb.ListMembersRequest) (*pb.ListMembersResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if _, err := s.authorize(ctx, currUser, request.OrgID, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+	members, err := s.db.SelectMembers(ctx, request.OrgID)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ListMembersResp{Members: members}, nil
+}
+
+// UpdateMember changes role of the member, both old and new roles must be lower than the role of authorized user
+func (s *secretService) UpdateMember(ctx context.Context, request *pb.UpdateMemberRequest) (*pb.UpdateMemberResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if !rbac.Valid(request.Role) {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "unknown role %s", request.Role,
+		)
+	}
+	actor, err := s.authorize(ctx, currUser, request.OrgID, rbac.ActionManageMembers)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.db.SelectOrgRole(ctx, request.UserID, request.OrgID)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.NotFound, "user %d is not a member of the organisation", request.UserID,
+		)
+	}
+	if !rbac.CanAssign(actor, target) || !rbac.CanAssign(actor, request.Role) {
+		return nil, status.Errorf(
+			codes.PermissionDenied, "role %s cannot change %s to %s", actor, target, request.Role,
+		)
+	}
+	members, err := s.db.SelectMembers(ctx, request.OrgID)
+	if err != nil {
+		return nil, orgError(err)
+	}
+	owners := 0
+	for _, m := range members {
+		if m.Role == rbac.RoleOwner {
+			owners++
+		}
+	}
+	// storage checks it again with members locked
+	if !rbac.KeepsOwner(owners, target, request.Role) {
+		return nil, orgError(storage.ErrLastOwner)
+	}
+
+	if err := s.db.UpdateMember(ctx, request.OrgID, request.UserID, request.Role); err != nil {
+		return nil, orgError(err)
+	}
+	return &pb.UpdateMemberResp{}, nil
+}
+
+// RemoveMember removes the member from the organisation, authorized user can always remove themselves
+func (s *secretService) RemoveMember(ctx context.Context, request *pb.RemoveMemberRequest) (*pb.RemoveMemberResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if request.UserID != currUser {
+		actor, err := s.authorize(ctx, currUser, request.OrgID, rbac.ActionManageMembers)
+		if err != nil {
+			return nil, err
+		}
+		target, err := s.db.SelectOrgRole(ctx, request.UserID, request.OrgID)
+		if err != nil {
+			return nil, status.Errorf(
+				codes.NotFound, "user %d is not a member of the organisation", request.UserID,
+			)
+		}
+		if !rbac.CanAssign(actor, target) {
+			return nil, status.Errorf(
+				codes.PermissionDenied, "role %s cannot remove %s", actor, target,
+			)
+		}
+	} else if _, err := uuid.Parse(request.OrgID); err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "organisation id is not valid: %s", request.OrgID,
+		)
+	}
+
+	if err := s.db.DeleteMember(ctx, request.OrgID, request.UserID); err != nil {
+		return nil, orgError(err)
+	}
+	return &pb.RemoveMemberResp{}, nil
+}
+
+// CreateCollection creates collection in the organisation
+func (s *secretService) CreateCollection(ctx context.Context, request *pb.CreateCollectionRequest) (*pb.CreateCollectionResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if err := validateName(request.Name); err != nil {
+		return nil, err
+	}
+	if _, err := s.authorize(ctx, currUser, request.OrgID, rbac.ActionManageCollections); err != nil {
+		return nil, err
+	}
+	id, err := s.db.InsertCollection(ctx, request.OrgID, request.Name)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.CreateCollectionResp{ID: id.String()}, nil
+}
+
+// ListCollections lists collections of the organisation or of all organisations of authorized user
+func (s *secretService) ListCollections(ctx context.Context, request *pb.ListCollectionsRequest) (*pb.ListCollectionsResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if request.OrgID != "" {
+		if _, err := s.authorize(ctx, currUser, request.OrgID, rbac.ActionRead); err != nil {
+			return nil, err
+		}
+	}
+	collections, err := s.db.SelectCollections(ctx, currUser, request.OrgID)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ListCollectionsResp{Collections: collections}, nil
+}
+
+// DeleteCollection deletes collection with all its secrets
+func (s *secretService) DeleteCollection(ctx context.Context, request *pb.DeleteCollectionRequest) (*pb.DeleteCollectionResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if _, err := uuid.Parse(request.ID); err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "collection id is not valid: %s", request.ID,
+		)
+	}
+	orgID, err := s.db.SelectCollectionOrg(ctx, request.ID)
+	if err != nil {
+		return nil, orgError(err)
+	}
+	if _, err := s.authorize(ctx, currUser, orgID, rbac.ActionManageCollections); err != nil {
+		return nil, err
+	}
+	if err := s.db.DeleteCollection(ctx, request.ID); err != nil {
+		return nil, orgError(err)
+	}
+	return &pb.DeleteCollectionResp{}, nil
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SetLimiter sets limiter of login attempts
+func (s *secretService) SetLimiter(l *ratelimit.Limiter) {
+	s.limiter = l
+}
+
+// LimitInterceptor limits logins and recoveries per ip address and per login and registrations per ip address,
+// wrong passwords and recovery keys lock the login out, it does nothing without limiter
+func (s *secretService) LimitInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var login string
+		switch r := req.(type) {
+		case *pb.LoginRequest:
+			login = r.GetUser().GetLogin()
+		case *pb.GetRecoveryKeyRequest:
+			login = r.GetUser().GetLogin()
+		case *pb.RecoverAccountRequest:
+			login = r.GetUser().GetLogin()
+		case *pb.RegisterRequest:
+		default:
+			return handler(ctx, req)
+		}
+		if s.limiter == nil {
+			return handler(ctx, req)
+		}
+
+		ip, _ := client(ctx)
+		if err := s.limiter.Allow(ctx, ip, login); err != nil {
+			var limited *ratelimit.Error
+			if errors.As(err, &limited) {
+				s.logger.Warn("login attempt is limited: ", zap.String("ip", ip), zap.String("login", login), zap.Error(err))
+				return nil, status.Errorf(
+					codes.ResourceExhausted, limited.Error(),
+				)
+			}
+			return nil, status.Errorf(
+				codes.Internal, err.Error(),
+			)
+		}
+
+		resp, err := handler(ctx, req)
+		if login == "" {
+			return resp, err
+		}
+		switch status.Code(err) {
+		case codes.OK:
+			if err := s.limiter.Succeeded(ctx, login); err != nil {
+				s.logger.Error("failed logins cannot be reset: ", zap.String("login", login), zap.Error(err))
+			}
+		case codes.PermissionDenied:
+			until, ferr := s.limiter.Failed(ctx, login)
+			if ferr != nil {
+				s.logger.Error("failed login cannot be counted: ", zap.String("login", login), zap.Error(ferr))
+			} else if !until.IsZero() {
+				s.logger.Warn("login is locked out: ", zap.String("login", login), zap.Time("until", until))
+			}
+		}
+		return resp, err
+	}
+}
+
+// UnlockAccount lifts lockout of the login, authorized user must be admin
+func (s *secretService) UnlockAccount(ctx context.Context, request *pb.UnlockAccountRequest) (*pb.UnlockAccountResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	admin, err := s.isAdmin(ctx, currUser)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	if !admin {
+		return nil, status.Errorf(
+			codes.PermissionDenied, "only admins can unlock accounts",
+		)
+	}
+	if request.Login == "" {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "login is required",
+		)
+	}
+	if s.limiter == nil {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "login attempts are not limited",
+		)
+	}
+
+	if err := s.limiter.Unlock(ctx, request.Login); err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Info("account unlocked: ", zap.String("login", request.Login), zap.Int64("admin", currUser))
+	return &pb.UnlockAccountResp{}, nil
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// verifierHash is kept instead of the verifier, so the db alone does not let anyone through recovery
+func verifierHash(verifier []byte) []byte {
+	h := sha256.Sum256(verifier)
+	return h[:]
+}
+
+// SetRecoveryKey saves vault key of authorized user wrapped with the recovery key, key must be of the current version
+func (s *secretService) SetRecoveryKey(ctx context.Context, request *pb.SetRecoveryKeyRequest) (*pb.SetRecoveryKeyResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if len(request.WrappedKey) == 0 || len(request.Verifier) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "wrapped key and verifier are required",
+		)
+	}
+	err = s.db.SetRecoveryKey(ctx, currUser, request.WrappedKey, verifierHash(request.Verifier), request.KeyVersion)
+	if errors.Is(err, storage.ErrVaultKey) {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "vault key version %d is not the current one", request.KeyVersion,
+		)
+	} else if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Info("recovery key set: ", zap.Int64("user", currUser))
+	return &pb.SetRecoveryKeyResp{}, nil
+}
+
+// recoveryFor checks the verifier of the recovery key of the user and returns wrapped vault key with its version,
+// id of the user is set. Unknown login, missing recovery key and wrong verifier look the same
+func (s *secretService) recoveryFor(ctx context.Context, user *pb.User, verifier []byte) ([]byte, int64, error) {
+	wrapped, hash, version, err := s.db.SelectRecoveryKey(ctx, user)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, 0, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	if err != nil || len(hash) == 0 || subtle.ConstantTimeCompare(hash, verifierHash(verifier)) != 1 {
+		return nil, 0, status.Errorf(
+			codes.PermissionDenied, "login or recovery key are wrong",
+		)
+	}
+	return wrapped, version, nil
+}
+
+// GetRecoveryKey gives vault key wrapped with the recovery key to the user who has the recovery key but not the password
+func (s *secretService) GetRecoveryKey(ctx context.Context, request *pb.GetRecoveryKeyRequest) (*pb.GetRecoveryKeyResp, error) {
+	wrapped, version, err := s.recoveryFor(ctx, request.GetUser(), request.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetRecoveryKeyResp{WrappedKey: wrapped, KeyVersion: version}, nil
+}
+
+// RecoverAccount sets new password of the user who has the recovery key, vault key is wrapped with the new master key
+// on the client, so the server never sees it. The used recovery key is replaced with a new one
+func (s *secretService) RecoverAccount(ctx context.Context, request *pb.RecoverAccountRequest) (*pb.RecoverAccountResp, error) {
+	user := request.GetUser()
+	if user.GetPassword() == "" || len(request.VaultKey) == 0 || len(request.VaultSalt) == 0 ||
+		len(request.RecoveryKey) == 0 || len(request.RecoveryVerifier) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "new password, vault key, salt and new recovery key are required",
+		)
+	}
+	if _, _, err := s.recoveryFor(ctx, user, request.Verifier); err != nil {
+		return nil, err
+	}
+
+	err := s.db.RecoverAccount(ctx, user.ID, user.Password, request.VaultKey, request.VaultSalt,
+		request.RecoveryKey, verifierHash(request.RecoveryVerifier))
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Info("account recovered: ", zap.Int64("user", user.ID))
+	return &pb.RecoverAccountResp{}, nil
+}
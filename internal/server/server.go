@@ -5,12 +5,20 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/docker/distribution/uuid"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
 	pb "github.com/maffka123/GophKeeper/api/proto"
 	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/bankcard"
+	"github.com/maffka123/GophKeeper/internal/paging"
+	"github.com/maffka123/GophKeeper/internal/ratelimit"
+	"github.com/maffka123/GophKeeper/internal/rbac"
+	"github.com/maffka123/GophKeeper/internal/search"
 	"github.com/maffka123/GophKeeper/internal/storage"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -24,6 +32,10 @@ type secretService struct {
 	logger *zap.Logger
 	db     storage.StoregeInterface
 	token  *jwtauth.JWTAuth
+	// admins are logins of users who can see audit log of all users
+	admins map[string]bool
+	// limiter limits login attempts, nil means no limits
+	limiter *ratelimit.Limiter
 }
 
 // New creates new instance of grpc service
@@ -46,7 +58,12 @@ func (s *secretService) Register(ctx context.Context, request *pb.RegisterReques
 			codes.Internal, err.Error(),
 		)
 	}
-	_, tokenString, _ := s.token.Encode(map[string]interface{}{"user_id": request.User.ID})
+	tokenString, err := s.issueToken(ctx, request.User.ID)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
 	return &pb.RegisterResp{Message: "Successfully created user", Exists: 0, Token: tokenString}, nil
 }
 
@@ -65,8 +82,89 @@ func (s *secretService) Login(ctx context.Context, request *pb.LoginRequest) (*p
 			)
 	}
 
-	_, tokenString, _ := s.token.Encode(map[string]interface{}{"user_id": request.User.ID})
-	return &pb.LoginResp{Message: "Logged in successfully", Token: tokenString, UserId: *id}, nil
+	key, salt, version, err := s.db.SelectVaultKey(ctx, *id)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	public, private, err := s.db.SelectKeyPair(ctx, *id)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+
+	tokenString, err := s.issueToken(ctx, *id)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.LoginResp{Message: "Logged in successfully", Token: tokenString, UserId: *id,
+		VaultKey: key, VaultSalt: salt, VaultKeyVersion: version, PublicKey: public, PrivateKey: private}, nil
+}
+
+// SetVaultKey saves the first wrapped vault key of authorized user
+func (s *secretService) SetVaultKey(ctx context.Context, request *pb.SetVaultKeyRequest) (*pb.SetVaultKeyResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if len(request.VaultKey) == 0 || len(request.VaultSalt) == 0 || request.VaultKeyVersion < 1 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "vault key, salt and version are required",
+		)
+	}
+
+	err = s.db.SetVaultKey(ctx, currUser, request.VaultKey, request.VaultSalt, request.VaultKeyVersion)
+	if errors.Is(err, storage.ErrVaultKey) {
+		return nil, status.Errorf(
+			codes.AlreadyExists, "vault key is set already, rotate it instead",
+		)
+	}
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.SetVaultKeyResp{}, nil
+}
+
+// RotateVaultKey replaces vault key of authorized user with the next version together with blind indexes
+func (s *secretService) RotateVaultKey(ctx context.Context, request *pb.RotateVaultKeyRequest) (*pb.RotateVaultKeyResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if len(request.VaultKey) == 0 || len(request.VaultSalt) == 0 || request.VaultKeyVersion < 2 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "vault key, salt and version are required",
+		)
+	}
+
+	n, err := s.db.RotateVaultKey(ctx, currUser, request.VaultKey, request.VaultSalt, request.VaultKeyVersion, request.Updates, request.PrivateKey)
+	if errors.Is(err, storage.ErrVaultKey) {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "vault key version %d is not the next one", request.VaultKeyVersion,
+		)
+	}
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.RotateVaultKeyResp{Updated: n}, nil
 }
 
 // Insert inserts data to postgres from authorized user
@@ -80,6 +178,15 @@ func (s *secretService) Insert(ctx context.Context, request *pb.InsertRequest) (
 	}
 	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
 
+	if err := app.Validate(request.Data); err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, err.Error(),
+		)
+	}
+	if err := s.authorizeCollection(ctx, currUser, request.Data.CollectionID, rbac.ActionWrite); err != nil {
+		return nil, err
+	}
+
 	request.Data.UserID = currUser
 	id, err := s.db.InsertData(ctx, request.Data, true)
 	if err != nil {
@@ -90,7 +197,43 @@ func (s *secretService) Insert(ctx context.Context, request *pb.InsertRequest) (
 	return &pb.InsertResp{Id: fmt.Sprint(id)}, nil
 }
 
-// GetData gets data for athorized user
+// InsertBatch inserts several secrets of authorized user at once, nothing is saved if any of them is not valid
+func (s *secretService) InsertBatch(ctx context.Context, request *pb.InsertBatchRequest) (*pb.InsertBatchResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	for i, d := range request.Data {
+		if err := app.Validate(d); err != nil {
+			return nil, status.Errorf(
+				codes.InvalidArgument, fmt.Sprintf("data %d: %s", i, err),
+			)
+		}
+		if err := s.authorizeCollection(ctx, currUser, d.CollectionID, rbac.ActionWrite); err != nil {
+			return nil, err
+		}
+		d.UserID = currUser
+	}
+
+	ids, err := s.db.InsertBatch(ctx, request.Data, true)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	resp := &pb.InsertBatchResp{}
+	for _, id := range ids {
+		resp.Ids = append(resp.Ids, id.String())
+	}
+	return resp, nil
+}
+
+// GetData gets data for athorized user including collections of their organisations, with query it runs full-text search
 func (s *secretService) GetData(ctx context.Context, request *pb.GetDataRequest) (*pb.GetDataResp, error) {
 	currUser, err := app.UserIDFromContext(ctx)
 	if err != nil {
@@ -101,17 +244,50 @@ func (s *secretService) GetData(ctx context.Context, request *pb.GetDataRequest)
 	}
 	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
 
+	if request.Query != "" {
+		hits, total, err := s.db.SearchText(ctx, currUser, request.Query, request.Limit, request.Offset)
+		if err != nil {
+			return nil, status.Errorf(
+				codes.Internal, err.Error(),
+			)
+		}
+		if !request.Reveal {
+			bankcard.Mask(search.Data(hits))
+		}
+		return &pb.GetDataResp{Hits: hits, Total: total}, nil
+	}
+
+	if request.Page != nil {
+		if err := paging.Validate(request.Page); err != nil {
+			return nil, status.Errorf(
+				codes.InvalidArgument, err.Error(),
+			)
+		}
+	}
+	if err := s.authorizeCollection(ctx, currUser, request.Data.GetCollectionID(), rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
 	request.Data.UserID = currUser
-	data, err := s.db.SearchData(ctx, request.Data)
+	var data []*pb.Data
+	var next string
+	if request.Page != nil {
+		data, next, err = s.db.SearchDataPage(ctx, request.Data, request.Page)
+	} else {
+		data, err = s.db.SearchData(ctx, request.Data)
+	}
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal, err.Error(),
 		)
 	}
-	return &pb.GetDataResp{Data: data}, nil
+	if !request.Reveal {
+		bankcard.Mask(data)
+	}
+	return &pb.GetDataResp{Data: data, NextPageToken: next}, nil
 }
 
-// Delete deletes data for athorized user
+// Delete deletes data for athorized user, secrets of collections only if role of the user allows it
 func (s *secretService) Delete(ctx context.Context, request *pb.DeleteRequest) (*pb.DeleteResp, error) {
 	currUser, err := app.UserIDFromContext(ctx)
 	if err != nil {
@@ -122,6 +298,18 @@ func (s *secretService) Delete(ctx context.Context, request *pb.DeleteRequest) (
 	}
 	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
 
+	if request.ID != "" {
+		if _, err := uuid.Parse(request.ID); err != nil {
+			return nil, status.Errorf(
+				codes.InvalidArgument, "secret id is not valid: %s", request.ID,
+			)
+		}
+		request.Data = &pb.Data{ID: request.ID}
+	}
+	if err := s.authorizeCollection(ctx, currUser, request.Data.GetCollectionID(), rbac.ActionDelete); err != nil {
+		return nil, err
+	}
+
 	request.Data.UserID = currUser
 	data, err := s.db.DeleteData(ctx, request.Data)
 	if err != nil {
@@ -129,9 +317,56 @@ func (s *secretService) Delete(ctx context.Context, request *pb.DeleteRequest) (
 			codes.Internal, err.Error(),
 		)
 	}
+	bankcard.Mask(data)
 	return &pb.DeleteResp{Data: data}, nil
 }
 
+// Update replaces the secret of authorized user with request.ID, without id the name must match exactly one secret
+func (s *secretService) Update(ctx context.Context, request *pb.UpdateRequest) (*pb.UpdateResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if request.ID == "" && request.Name == "" {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "id or name of the secret is required",
+		)
+	}
+	if err := app.Validate(request.Data); err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, err.Error(),
+		)
+	}
+
+	err = s.db.UpdateData(ctx, currUser, request.ID, request.Name, request.Data)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		key := request.Name
+		if request.ID != "" {
+			key = request.ID
+		}
+		return nil, status.Errorf(
+			codes.NotFound, "no secret %s can be changed", key,
+		)
+	case errors.Is(err, storage.ErrAmbiguous):
+		return nil, status.Errorf(
+			codes.FailedPrecondition, err.Error(),
+		)
+	case err != nil:
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.UpdateResp{}, nil
+}
+
+// GetAllDataForUser gets all data of authorized user and of collections of their organisations changed after given time,
+// page by page if page is given. If collections are given, all their secrets are returned instead
 func (s *secretService) GetAllDataForUser(ctx context.Context, request *pb.GetAllDataForUserRequest) (*pb.GetAllDataForUserResp, error) {
 	currUser, err := app.UserIDFromContext(ctx)
 	if err != nil {
@@ -142,13 +377,33 @@ func (s *secretService) GetAllDataForUser(ctx context.Context, request *pb.GetAl
 	}
 	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
 
-	data, err := s.db.SelectAllDataForUser(ctx, currUser, request.Time, true)
+	if request.Page != nil {
+		if err := paging.Validate(request.Page); err != nil {
+			return nil, status.Errorf(
+				codes.InvalidArgument, err.Error(),
+			)
+		}
+	}
+
+	var data []*pb.Data
+	var next string
+	switch {
+	case len(request.CollectionIDs) > 0:
+		data, err = s.db.SelectCollectionData(ctx, currUser, request.CollectionIDs)
+	case request.Page != nil:
+		data, next, err = s.db.SelectDataPage(ctx, currUser, request.Time, request.Page)
+	default:
+		data, err = s.db.SelectAllDataForUser(ctx, currUser, request.Time)
+	}
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal, err.Error(),
 		)
 	}
-	return &pb.GetAllDataForUserResp{Data: data}, nil
+	if !request.Reveal {
+		bankcard.Mask(data)
+	}
+	return &pb.GetAllDataForUserResp{Data: data, NextPageToken: next}, nil
 }
 
 func (s *secretService) InsertSyncData(ctx context.Context, request *pb.InsertSyncDataRequest) (*pb.InsertSyncDataResp, error) {
@@ -162,8 +417,24 @@ func (s *secretService) InsertSyncData(ctx context.Context, request *pb.InsertSy
 	}
 	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
 
-	err = s.db.InserDataForUser(ctx, request.Data, currUser)
-	if err != nil {
+	for i, d := range request.Data {
+		if err := app.Validate(d); err != nil {
+			return nil, status.Errorf(
+				codes.InvalidArgument, fmt.Sprintf("data %d: %s", i, err),
+			)
+		}
+		if err := s.authorizeCollection(ctx, currUser, d.CollectionID, rbac.ActionWrite); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.db.InsertSyncData(ctx, request.Data, currUser)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return nil, status.Errorf(
+			codes.NotFound, "secret cannot be changed: %v", err,
+		)
+	case err != nil:
 		return nil, status.Errorf(
 			codes.Internal, err.Error(),
 		)
@@ -171,6 +442,32 @@ func (s *secretService) InsertSyncData(ctx context.Context, request *pb.InsertSy
 	return &pb.InsertSyncDataResp{Message: "data inserted"}, nil
 }
 
+// ListExpiring lists secrets of authorized user that expire within given number of days or have already expired
+func (s *secretService) ListExpiring(ctx context.Context, request *pb.ListExpiringRequest) (*pb.ListExpiringResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	days := request.Days
+	if days <= 0 {
+		days = app.DefaultExpiryDays
+	}
+
+	data, err := s.db.SelectExpiring(ctx, currUser, time.Now().AddDate(0, 0, int(days)))
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	bankcard.Mask(data)
+	return &pb.ListExpiringResp{Data: data}, nil
+}
+
 // ComparePass compares hashed passwords.
 func comparePass(expected string, actual string) bool {
 	np := sha256.Sum256([]byte(actual))
@@ -184,7 +481,8 @@ func (s *secretService) JWTAuthFunction() func(ctx context.Context) (context.Con
 
 		// ignore some endpoints for jwt check
 		method, _ := grpc.Method(ctx)
-		for _, imethod := range []string{"/proto.GophKeeper/Register", "/proto.GophKeeper/Login"} {
+		for _, imethod := range []string{"/proto.GophKeeper/Register", "/proto.GophKeeper/Login",
+			"/proto.GophKeeper/GetRecoveryKey", "/proto.GophKeeper/RecoverAccount"} {
 			if method == imethod {
 				return ctx, nil
 			}
@@ -198,7 +496,41 @@ func (s *secretService) JWTAuthFunction() func(ctx context.Context) (context.Con
 				codes.Unauthenticated, err.Error(),
 			)
 		}
+		if err = s.checkTokenVersion(ctx, token.PrivateClaims()); errors.Is(err, errTokenRevoked) {
+			return nil, status.Errorf(
+				codes.Unauthenticated, err.Error(),
+			)
+		} else if err != nil {
+			return nil, status.Errorf(
+				codes.Internal, err.Error(),
+			)
+		}
 		ctx = jwtauth.NewContext(ctx, token, err)
 		return ctx, nil
 	}
 }
+
+// errTokenRevoked is returned for tokens issued before the password was changed and tokens of deleted users
+var errTokenRevoked = errors.New("token is revoked, log in again")
+
+// issueToken gives jwt of the user with the current version of tokens
+func (s *secretService) issueToken(ctx context.Context, userID int64) (string, error) {
+	version, err := s.db.SelectTokenVersion(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	_, tokenString, err := s.token.Encode(map[string]interface{}{"user_id": userID, "token_version": version})
+	return tokenString, err
+}
+
+// checkTokenVersion checks that the token is of the current version of tokens of its user,
+// tokens issued before versions were kept have version 0
+func (s *secretService) checkTokenVersion(ctx context.Context, claims map[string]interface{}) error {
+	id, _ := claims["user_id"].(float64)
+	version, _ := claims["token_version"].(float64)
+	current, err := s.db.SelectTokenVersion(ctx, int64(id))
+	if errors.Is(err, storage.ErrNotFound) || err == nil && current != int64(version) {
+		return errTokenRevoked
+	}
+	return err
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/sharing"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SetKeyPair saves key pair of authorized user, private key comes wrapped with the vault key
+func (s *secretService) SetKeyPair(ctx context.Context, request *pb.SetKeyPairRequest) (*pb.SetKeyPairResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if len(request.PublicKey) == 0 || len(request.PrivateKey) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "public and private keys are required",
+		)
+	}
+
+	err = s.db.SetKeyPair(ctx, currUser, request.PublicKey, request.PrivateKey)
+	if errors.Is(err, storage.ErrKeyPair) {
+		return nil, status.Errorf(
+			codes.AlreadyExists, err.Error(),
+		)
+	}
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.SetKeyPairResp{}, nil
+}
+
+// GetPublicKey gets public key of the user to share secrets with
+func (s *secretService) GetPublicKey(ctx context.Context, request *pb.GetPublicKeyRequest) (*pb.GetPublicKeyResp, error) {
+	id, public, err := s.db.SelectPublicKey(ctx, request.Login)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.NotFound, err.Error(),
+		)
+	}
+	if len(public) == 0 {
+		return nil, status.Errorf(
+			codes.FailedPrecondition, "user %s has no key pair yet, secrets can be shared after the next login", request.Login,
+		)
+	}
+	return &pb.GetPublicKeyResp{UserID: id, PublicKey: public}, nil
+}
+
+// ShareSecret saves secret encrypted by authorized user for the recipients
+func (s *secretService) ShareSecret(ctx context.Context, request *pb.ShareSecretRequest) (*pb.ShareSecretResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if err := validateRecipients(currUser, request.Ciphertext, request.Recipients); err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument, err.Error(),
+		)
+	}
+
+	id, err := s.db.InsertShare(ctx, currUser, request.Ciphertext, request.Recipients)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ShareSecretResp{ID: id.String()}, nil
+}
+
+// validateRecipients checks that every recipient has the key and a known permission and the owner is one of them
+func validateRecipients(owner int64, ciphertext []byte, recipients []*pb.ShareRecipient) error {
+	if len(ciphertext) == 0 {
+		return errors.New("ciphertext is required")
+	}
+	seen := map[int64]bool{}
+	for _, r := range recipients {
+		if seen[r.UserID] {
+			return fmt.Errorf("user %d is listed twice", r.UserID)
+		}
+		seen[r.UserID] = true
+		if len(r.WrappedKey) == 0 {
+			return fmt.Errorf("wrapped key of user %d is required", r.UserID)
+		}
+		switch {
+		case r.UserID == owner && r.Permission == sharing.PermissionOwner:
+		case r.UserID != owner && (r.Permission == sharing.PermissionRead || r.Permission == sharing.PermissionWrite):
+		default:
+			return fmt.Errorf("permission %s of user %d is not valid, use read or write", r.Permission, r.UserID)
+		}
+	}
+	if !seen[owner] {
+		return errors.New("owner must be one of recipients")
+	}
+	if len(recipients) < 2 {
+		return errors.New("at least one recipient besides the owner is required")
+	}
+	return nil
+}
+
+// ListShares lists secrets shared by authorized user and with authorized user
+func (s *secretService) ListShares(ctx context.Context, request *pb.ListSharesRequest) (*pb.ListSharesResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	shares, err := s.db.SelectShares(ctx, currUser)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	return &pb.ListSharesResp{Shares: shares}, nil
+}
+
+// UpdateShare replaces content of the share, authorized user must be its owner or have write permission
+func (s *secretService) UpdateShare(ctx context.Context, request *pb.UpdateShareRequest) (*pb.UpdateShareResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if len(request.Ciphertext) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "ciphertext is required",
+		)
+	}
+	err = s.db.UpdateShare(ctx, currUser, request.ID, request.Ciphertext, request.KeyVersion)
+	if err != nil {
+		return nil, shareError(err)
+	}
+	return &pb.UpdateShareResp{}, nil
+}
+
+// RevokeShare re-encrypts the share of authorized user with a new key for the remaining recipients
+func (s *secretService) RevokeShare(ctx context.Context, request *pb.RevokeShareRequest) (*pb.RevokeShareResp, error) {
+	currUser, err := app.UserIDFromContext(ctx)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		return nil, status.Errorf(
+			codes.Internal, err.Error(),
+		)
+	}
+	s.logger.Debug("found user: ", zap.String("login", fmt.Sprint(currUser)))
+
+	if len(request.Ciphertext) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "ciphertext is required",
+		)
+	}
+	for _, r := range request.Recipients {
+		if len(r.WrappedKey) == 0 {
+			return nil, status.Errorf(
+				codes.InvalidArgument, "wrapped key of user %d is required", r.UserID,
+			)
+		}
+	}
+	err = s.db.RevokeShare(ctx, currUser, request.ID, request.Ciphertext, request.KeyVersion, request.Recipients)
+	if err != nil {
+		return nil, shareError(err)
+	}
+	return &pb.RevokeShareResp{}, nil
+}
+
+// shareError converts storage errors of shares to grpc status
+func shareError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrShareNotFound):
+		return status.Errorf(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrSharePermission):
+		return status.Errorf(codes.PermissionDenied, err.Error())
+	case errors.Is(err, storage.ErrShareKeyVersion):
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, storage.ErrShareRecipients):
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	default:
+		return status.Errorf(codes.Internal, err.Error())
+	}
+}
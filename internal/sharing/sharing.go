@@ -0,0 +1,169 @@
+// Package sharing encrypts secrets shared between users on the client side.
+//
+// Every user has an X25519 key pair, the private key is kept on the server wrapped with the vault key.
+// A shared secret is encrypted with a random share key, which is sealed to the public key of every recipient,
+// so the server stores only ciphertext and can only check permissions. Revoking access re-encrypts the secret
+// with a new share key of the next version that is sealed only to the remaining recipients.
+package sharing
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+	"google.golang.org/protobuf/proto"
+)
+
+// Permissions of share recipients
+const (
+	PermissionOwner = "owner"
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+)
+
+// purpose of the vault subkey that wraps private key
+const privateKeyPurpose = "private key"
+
+const (
+	keyLen   = 32
+	nonceLen = 24
+)
+
+var (
+	// ErrKey is returned when share key cannot be unsealed with the private key
+	ErrKey = errors.New("share key cannot be opened")
+	// ErrDecrypt is returned when share cannot be decrypted with the share key
+	ErrDecrypt = errors.New("share cannot be decrypted")
+)
+
+// KeyPair is the key pair of the user
+type KeyPair struct {
+	Public  []byte
+	private *[keyLen]byte
+}
+
+// NewKeyPair generates random key pair
+func NewKeyPair() (*KeyPair, error) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("key pair cannot be generated: %v", err)
+	}
+	return &KeyPair{Public: pub[:], private: priv}, nil
+}
+
+// Wrap encrypts private key with the vault key
+func (kp *KeyPair) Wrap(k *vault.Key) ([]byte, error) {
+	return k.Seal(privateKeyPurpose, kp.private[:])
+}
+
+// Wipe overwrites the private key, the pair cannot be used after it
+func (kp *KeyPair) Wipe() {
+	*kp.private = [keyLen]byte{}
+}
+
+// OpenKeyPair decrypts private key wrapped by Wrap
+func OpenKeyPair(k *vault.Key, public, wrapped []byte) (*KeyPair, error) {
+	priv, err := k.Open(privateKeyPurpose, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if len(priv) != keyLen || len(public) != keyLen {
+		return nil, errors.New("key pair is damaged")
+	}
+	kp := &KeyPair{Public: public, private: new([keyLen]byte)}
+	copy(kp.private[:], priv)
+	return kp, nil
+}
+
+// NewKey generates random share key
+func NewKey() ([]byte, error) {
+	key := make([]byte, keyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("random generator failed: %v", err)
+	}
+	return key, nil
+}
+
+// WrapKey seals share key to the public key of the recipient
+func WrapKey(key, public []byte) ([]byte, error) {
+	if len(public) != keyLen {
+		return nil, errors.New("public key is not valid")
+	}
+	var pub [keyLen]byte
+	copy(pub[:], public)
+	return box.SealAnonymous(nil, key, &pub, rand.Reader)
+}
+
+// UnwrapKey opens share key sealed to the public key of the pair
+func (kp *KeyPair) UnwrapKey(wrapped []byte) ([]byte, error) {
+	var pub [keyLen]byte
+	copy(pub[:], kp.Public)
+	key, ok := box.OpenAnonymous(nil, wrapped, &pub, kp.private)
+	if !ok || len(key) != keyLen {
+		return nil, ErrKey
+	}
+	return key, nil
+}
+
+// Encrypt encrypts content of the secret with the share key, ids and blind index are not shared
+func Encrypt(key []byte, d *pb.Data) ([]byte, error) {
+	plain, err := proto.Marshal(&pb.Data{Data: d.Data, Metadata: d.Metadata, ExpiresAt: d.ExpiresAt})
+	if err != nil {
+		return nil, fmt.Errorf("secret cannot be encoded: %v", err)
+	}
+	var nonce [nonceLen]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("random generator failed: %v", err)
+	}
+	return secretbox.Seal(nonce[:], plain, &nonce, secretKey(key)), nil
+}
+
+// Decrypt decrypts secret encrypted by Encrypt
+func Decrypt(key, ciphertext []byte) (*pb.Data, error) {
+	if len(ciphertext) < nonceLen || len(key) != keyLen {
+		return nil, ErrDecrypt
+	}
+	var nonce [nonceLen]byte
+	copy(nonce[:], ciphertext)
+	plain, ok := secretbox.Open(nil, ciphertext[nonceLen:], &nonce, secretKey(key))
+	if !ok {
+		return nil, ErrDecrypt
+	}
+	var d pb.Data
+	if err := proto.Unmarshal(plain, &d); err != nil {
+		return nil, fmt.Errorf("secret cannot be decoded: %v", err)
+	}
+	return &d, nil
+}
+
+// Open unseals share key of the share and decrypts it
+func (kp *KeyPair) Open(s *pb.Share) (*pb.Data, error) {
+	key, err := kp.UnwrapKey(s.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(key, s.Ciphertext)
+}
+
+// Recipients seals share key to every recipient, recipients must have public keys
+func Recipients(key []byte, recipients []*pb.ShareRecipient) ([]*pb.ShareRecipient, error) {
+	out := make([]*pb.ShareRecipient, 0, len(recipients))
+	for _, r := range recipients {
+		wrapped, err := WrapKey(key, r.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("key cannot be shared with %s: %v", r.Login, err)
+		}
+		out = append(out, &pb.ShareRecipient{UserID: r.UserID, Login: r.Login, WrappedKey: wrapped, Permission: r.Permission})
+	}
+	return out, nil
+}
+
+func secretKey(key []byte) *[keyLen]byte {
+	var k [keyLen]byte
+	copy(k[:], key)
+	return &k
+}
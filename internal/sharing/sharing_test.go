@@ -0,0 +1,63 @@
+package sharing
+
+import (
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestKeyPairWrap(t *testing.T) {
+	kp, err := NewKeyPair()
+	require.NoError(t, err)
+	vk, err := vault.NewKey(1)
+	require.NoError(t, err)
+
+	wrapped, err := kp.Wrap(vk)
+	require.NoError(t, err)
+	got, err := OpenKeyPair(vk, kp.Public, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, kp, got)
+
+	other, _ := vault.NewKey(1)
+	_, err = OpenKeyPair(other, kp.Public, wrapped)
+	assert.ErrorIs(t, err, vault.ErrUnwrap)
+}
+
+func TestShare(t *testing.T) {
+	owner, _ := NewKeyPair()
+	bob, _ := NewKeyPair()
+	eve, _ := NewKeyPair()
+	secret := &pb.Data{ID: "5", UserID: 1, Metadata: "team mail", BlindIndex: []string{"1:login:00"},
+		Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "team", Password: "s3cret"}}}
+
+	key, err := NewKey()
+	require.NoError(t, err)
+	ciphertext, err := Encrypt(key, secret)
+	require.NoError(t, err)
+	recipients, err := Recipients(key, []*pb.ShareRecipient{
+		{UserID: 1, Login: "owner", PublicKey: owner.Public, Permission: PermissionOwner},
+		{UserID: 2, Login: "bob", PublicKey: bob.Public, Permission: PermissionRead},
+	})
+	require.NoError(t, err)
+
+	want := &pb.Data{Metadata: "team mail", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "team", Password: "s3cret"}}}
+	for i, kp := range []*KeyPair{owner, bob} {
+		got, err := kp.Open(&pb.Share{Ciphertext: ciphertext, WrappedKey: recipients[i].WrappedKey})
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(want, got), got.String())
+	}
+
+	_, err = eve.Open(&pb.Share{Ciphertext: ciphertext, WrappedKey: recipients[1].WrappedKey})
+	assert.ErrorIs(t, err, ErrKey)
+
+	other, _ := NewKey()
+	_, err = Decrypt(other, ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = Recipients(key, []*pb.ShareRecipient{{Login: "nokey"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	rpc "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/rbac"
+)
+
+// SetPassword sets new password of the user and vault key wrapped with the new master key,
+// version of the vault key stays the same, tokens issued before are revoked
+func (db *PGDB) SetPassword(ctx context.Context, userID int64, password string, vaultKey, vaultSalt []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	np := sha256.Sum256([]byte(password))
+	tag, err := db.Conn.Exec(ctx, `UPDATE users SET password=$2, vault_key=$3, vault_salt=$4, token_version=token_version+1 WHERE id=$1`,
+		userID, hex.EncodeToString(np[:]), vaultKey, vaultSalt)
+	if err != nil {
+		return fmt.Errorf("update users failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: user %d", ErrNotFound, userID)
+	}
+	return nil
+}
+
+// DeleteAccount removes the user with all secrets, attachments, shares, emergency access and memberships in one transaction.
+// Organisations where the user is the only member are removed too, secrets the user added to collections of other
+// organisations go to another owner. Audit log keeps events of the user.
+func (db *PGDB) DeleteAccount(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var lastOwner int
+	err = tx.QueryRow(ctx, `
+	SELECT count(*) FROM org_members m WHERE m.user_id=$1 AND m.role=$2
+		AND NOT EXISTS (SELECT 1 FROM org_members o WHERE o.org_id=m.org_id AND o.user_id<>$1 AND o.role=$2)
+		AND EXISTS (SELECT 1 FROM org_members o WHERE o.org_id=m.org_id AND o.user_id<>$1)`, userID, rbac.RoleOwner).Scan(&lastOwner)
+	if err != nil {
+		return fmt.Errorf("select from org_members failed: %v", err)
+	}
+	if lastOwner > 0 {
+		return fmt.Errorf("%w: give the owner role to another member or delete the organisation first", ErrLastOwner)
+	}
+
+	for _, q := range []struct {
+		table string
+		sql   string
+		args  []interface{}
+	}{
+		{"orgs", `DELETE FROM orgs o WHERE EXISTS (SELECT 1 FROM org_members m WHERE m.org_id=o.id AND m.user_id=$1)
+			AND NOT EXISTS (SELECT 1 FROM org_members m WHERE m.org_id=o.id AND m.user_id<>$1)`, []interface{}{userID}},
+		{"secrets", `UPDATE secrets s SET user_id=(SELECT m.user_id FROM collections c JOIN org_members m ON m.org_id=c.org_id
+			WHERE c.id=s.collection_id AND m.user_id<>$1 AND m.role=$2 LIMIT 1)
+			WHERE s.user_id=$1 AND s.collection_id IS NOT NULL`, []interface{}{userID, rbac.RoleOwner}},
+		{"org_invitations", `DELETE FROM org_invitations WHERE user_id=$1 OR invited_by=$1`, []interface{}{userID}},
+		{"org_members", `DELETE FROM org_members WHERE user_id=$1`, []interface{}{userID}},
+		{"share_recipients", `DELETE FROM share_recipients WHERE user_id=$1`, []interface{}{userID}},
+		{"shares", `DELETE FROM shares WHERE owner_id=$1`, []interface{}{userID}},
+		{"emergency_access", `DELETE FROM emergency_access WHERE grantor_id=$1 OR grantee_id=$1`, []interface{}{userID}},
+		{"secrets", `DELETE FROM secrets WHERE user_id=$1`, []interface{}{userID}},
+		{"login_failures", `DELETE FROM login_failures WHERE login=(SELECT login FROM users WHERE id=$1)`, []interface{}{userID}},
+		{"users", `DELETE FROM users WHERE id=$1`, []interface{}{userID}},
+	} {
+		if _, err := tx.Exec(ctx, q.sql, q.args...); err != nil {
+			return fmt.Errorf("delete from %s failed: %v", q.table, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// SetRecoveryKey saves vault key wrapped with new recovery key and hash of its verifier, vault key must be of the current version
+func (db *PGDB) SetRecoveryKey(ctx context.Context, userID int64, wrapped, hash []byte, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `UPDATE users SET recovery_key=$2, recovery_hash=$3 WHERE id=$1 AND vault_key_version=$4`,
+		userID, wrapped, hash, version)
+	if err != nil {
+		return fmt.Errorf("update users failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVaultKey
+	}
+	return nil
+}
+
+// SelectRecoveryKey gets vault key wrapped with recovery key, hash of the verifier and version of the vault key
+// of the user with the login, id of the user is set, both are nil if the user has no recovery key
+func (db *PGDB) SelectRecoveryKey(ctx context.Context, user *rpc.User) ([]byte, []byte, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var wrapped, hash []byte
+	var version int64
+	err := db.Conn.QueryRow(ctx, "SELECT id, recovery_key, recovery_hash, vault_key_version FROM users WHERE login=$1", user.Login).
+		Scan(&user.ID, &wrapped, &hash, &version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, 0, fmt.Errorf("%w: user %s", ErrNotFound, user.Login)
+	} else if err != nil {
+		return nil, nil, 0, fmt.Errorf("select from users failed: %v", err)
+	}
+	return wrapped, hash, version, nil
+}
+
+// RecoverAccount sets new password of the user with vault key wrapped with the new master key and replaces recovery key,
+// tokens issued before are revoked
+func (db *PGDB) RecoverAccount(ctx context.Context, userID int64, password string, vaultKey, vaultSalt, recoveryKey, recoveryHash []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	np := sha256.Sum256([]byte(password))
+	tag, err := db.Conn.Exec(ctx, `UPDATE users SET password=$2, vault_key=$3, vault_salt=$4, recovery_key=$5, recovery_hash=$6,
+	token_version=token_version+1 WHERE id=$1`,
+		userID, hex.EncodeToString(np[:]), vaultKey, vaultSalt, recoveryKey, recoveryHash)
+	if err != nil {
+		return fmt.Errorf("update users failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: user %d", ErrNotFound, userID)
+	}
+	return nil
+}
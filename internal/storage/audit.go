@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	rpc "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/audit"
+)
+
+// auditLock is the key of advisory lock that keeps one chain when several servers write to the audit log
+const auditLock = 0x617564697400
+
+// InsertAuditEvent appends event to the audit log: it gets the next id, time and hash chained to the last event
+func (db *PGDB) InsertAuditEvent(ctx context.Context, e *rpc.AuditEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLock); err != nil {
+		return fmt.Errorf("cannot lock audit_log: %v", err)
+	}
+	var last int64
+	var prev []byte
+	err = tx.QueryRow(ctx, `SELECT id, hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&last, &prev)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("select from audit_log failed: %v", err)
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	e.ID, e.CreatedAt, e.PrevHash = last+1, createdAt.Format(audit.TimeLayout), prev
+	e.Hash = audit.Hash(prev, e)
+	_, err = tx.Exec(ctx, `
+	INSERT INTO audit_log (id, user_id, login, method, action, target, outcome, ip, device, created_at, prev_hash, hash)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`,
+		e.ID, e.UserID, e.Login, e.Method, e.Action, e.Target, e.Outcome, e.IP, e.Device, createdAt, e.PrevHash, e.Hash)
+	if err != nil {
+		return fmt.Errorf("insert into audit_log failed: %v", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// auditFilter builds where clause and its arguments from the filter, times must be parsed already
+func auditFilter(f *rpc.ListAuditEventsRequest, since, until time.Time) (string, []interface{}) {
+	conds := []string{"id>$1"}
+	args := []interface{}{f.AfterID}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.UserID != 0 {
+		add("user_id=$%d", f.UserID)
+	}
+	if f.Action != "" {
+		add("action=$%d", f.Action)
+	}
+	if !since.IsZero() {
+		add("created_at>=$%d", since.UTC())
+	}
+	if !until.IsZero() {
+		add("created_at<$%d", until.UTC())
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// SelectAuditEvents gets events of the audit log by the filter ordered by id, at most limit of them
+func (db *PGDB) SelectAuditEvents(ctx context.Context, f *rpc.ListAuditEventsRequest, since, until time.Time) ([]*rpc.AuditEvent, error) {
+	where, args := auditFilter(f, since, until)
+	args = append(args, f.Limit)
+	row, err := db.Conn.Query(ctx, fmt.Sprintf(`
+	SELECT id, user_id, login, method, action, target, outcome, ip, device, created_at, prev_hash, hash
+	FROM audit_log WHERE %s ORDER BY id LIMIT $%d`, where, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("select from audit_log failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.AuditEvent
+	for row.Next() {
+		var e rpc.AuditEvent
+		var createdAt time.Time
+		err := row.Scan(&e.ID, &e.UserID, &e.Login, &e.Method, &e.Action, &e.Target, &e.Outcome, &e.IP, &e.Device,
+			&createdAt, &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("select from audit_log failed: %v", err)
+		}
+		e.CreatedAt = createdAt.UTC().Format(audit.TimeLayout)
+		out = append(out, &e)
+	}
+	return out, row.Err()
+}
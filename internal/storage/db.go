@@ -3,17 +3,25 @@ package storage
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/docker/distribution/uuid"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	rpc "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/emergency"
+	"github.com/maffka123/GophKeeper/internal/paging"
+	"github.com/maffka123/GophKeeper/internal/rbac"
+	"github.com/maffka123/GophKeeper/internal/search"
 	"go.uber.org/zap"
 	//"google.golang.org/protobuf/encoding/protojson"
 	"io/ioutil"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -32,10 +40,87 @@ type StoregeInterface interface {
 	CreateNewUser(context.Context, *rpc.User) (int64, error)
 	SelectPass(context.Context, *rpc.User) (*string, *int64, error)
 	InsertData(context.Context, *rpc.Data, bool) (*uuid.UUID, error)
+	InsertBatch(context.Context, []*rpc.Data, bool) ([]uuid.UUID, error)
 	SearchData(context.Context, *rpc.Data) ([]*rpc.Data, error)
+	SearchText(ctx context.Context, userID int64, query string, limit, offset int64) ([]*rpc.SearchHit, int64, error)
+	SearchDataPage(context.Context, *rpc.Data, *rpc.PageRequest) ([]*rpc.Data, string, error)
+	SelectDataPage(ctx context.Context, userID int64, since string, page *rpc.PageRequest) ([]*rpc.Data, string, error)
 	DeleteData(context.Context, *rpc.Data) ([]*rpc.Data, error)
 	InserDataForUser(context.Context, []*rpc.Data, int64) error
-	SelectAllDataForUser(context.Context, int64, string, bool) ([]*rpc.Data, error)
+	InsertSyncData(context.Context, []*rpc.Data, int64) error
+	SelectCollectionData(ctx context.Context, userID int64, collectionIDs []string) ([]*rpc.Data, error)
+	SelectAllDataForUser(context.Context, int64, string) ([]*rpc.Data, error)
+	PushUnsynchronized(ctx context.Context, userID int64, push func([]*rpc.Data) error) error
+	SelectExpiring(context.Context, int64, time.Time) ([]*rpc.Data, error)
+	SelectLogin(context.Context, int64) (string, error)
+	SelectTokenVersion(context.Context, int64) (int64, error)
+	SelectVaultKey(context.Context, int64) ([]byte, []byte, int64, error)
+	SetVaultKey(ctx context.Context, userID int64, key, salt []byte, version int64) error
+	RotateVaultKey(ctx context.Context, userID int64, key, salt []byte, version int64, updates []*rpc.IndexUpdate, privateKey []byte) (int64, error)
+	UpdateBlindIndexes(context.Context, int64, []*rpc.IndexUpdate) (int64, error)
+	UpdateData(ctx context.Context, userID int64, id, name string, data *rpc.Data) error
+	SelectKeyPair(context.Context, int64) ([]byte, []byte, error)
+	SetKeyPair(ctx context.Context, userID int64, public, private []byte) error
+	SelectPublicKey(context.Context, string) (int64, []byte, error)
+	InsertShare(ctx context.Context, ownerID int64, ciphertext []byte, recipients []*rpc.ShareRecipient) (*uuid.UUID, error)
+	SelectShares(context.Context, int64) ([]*rpc.Share, error)
+	UpdateShare(ctx context.Context, userID int64, id string, ciphertext []byte, keyVersion int64) error
+	RevokeShare(ctx context.Context, ownerID int64, id string, ciphertext []byte, keyVersion int64, recipients []*rpc.ShareRecipient) error
+	InsertOrg(ctx context.Context, ownerID int64, name string) (*uuid.UUID, error)
+	SelectOrgs(context.Context, int64) ([]*rpc.Org, error)
+	DeleteOrg(context.Context, string) error
+	SelectOrgRole(ctx context.Context, userID int64, orgID string) (string, error)
+	SelectCollectionRole(ctx context.Context, userID int64, collectionID string) (string, error)
+	InsertInvitation(ctx context.Context, orgID, login, role string, invitedBy int64) (*uuid.UUID, error)
+	SelectInvitations(context.Context, int64) ([]*rpc.Invitation, error)
+	AnswerInvitation(ctx context.Context, userID int64, id string, accept bool) error
+	SelectMembers(context.Context, string) ([]*rpc.OrgMember, error)
+	UpdateMember(ctx context.Context, orgID string, userID int64, role string) error
+	DeleteMember(ctx context.Context, orgID string, userID int64) error
+	InsertCollection(ctx context.Context, orgID, name string) (*uuid.UUID, error)
+	SelectCollections(ctx context.Context, userID int64, orgID string) ([]*rpc.Collection, error)
+	SelectCollectionOrg(context.Context, string) (string, error)
+	DeleteCollection(context.Context, string) error
+	SyncCollections(context.Context, int64, []*rpc.Collection) ([]string, error)
+	InsertEmergencyAccess(ctx context.Context, grantorID int64, login, accessType string, waitDays int64) (*uuid.UUID, error)
+	SelectEmergencyAccess(context.Context, int64) ([]*rpc.EmergencyAccess, error)
+	SelectEmergency(context.Context, string) (*rpc.EmergencyAccess, error)
+	SetEmergencyStatus(ctx context.Context, id, from, to string) error
+	ApproveExpiredEmergency(ctx context.Context, id string) (bool, error)
+	ConfirmEmergencyAccess(ctx context.Context, id string, wrappedKey []byte, keyVersion int64) error
+	DeleteEmergencyAccess(context.Context, string) error
+	SetPassword(ctx context.Context, userID int64, password string, vaultKey, vaultSalt []byte) error
+	DeleteAccount(ctx context.Context, userID int64) error
+	SetRecoveryKey(ctx context.Context, userID int64, wrapped, hash []byte, version int64) error
+	SelectRecoveryKey(ctx context.Context, user *rpc.User) ([]byte, []byte, int64, error)
+	RecoverAccount(ctx context.Context, userID int64, password string, vaultKey, vaultSalt, recoveryKey, recoveryHash []byte) error
+	InsertAuditEvent(context.Context, *rpc.AuditEvent) error
+	SelectAuditEvents(ctx context.Context, f *rpc.ListAuditEventsRequest, since, until time.Time) ([]*rpc.AuditEvent, error)
+}
+
+// ErrVaultKey is returned when vault key is set already or rotated from a version that is not current
+var ErrVaultKey = fmt.Errorf("vault key version does not match")
+
+// ErrAmbiguous is returned when the name matches more than one secret
+var ErrAmbiguous = fmt.Errorf("name matches several secrets")
+
+// sortColumns are sql expressions of sort options, cursor values are cast to the type of expression
+var sortColumns = map[string]struct{ expr, cast string }{
+	paging.SortTitle:      {"lower(coalesce(metadata, ''))", "text"},
+	paging.SortChangeDate: {"change_date", "timestamp"},
+	paging.SortType: {`CASE WHEN data ? 'AuthData' THEN 'login' WHEN data ? 'BankCard' THEN 'card' ` +
+		`WHEN data ? 'Binary' THEN 'binary' ELSE 'text' END`, "text"},
+}
+
+// tokenRe matches blind index tokens, malformed tokens are rejected before they are bound as query parameters
+var tokenRe = regexp.MustCompile(`^[0-9]+:[a-z]+\^?:[0-9a-f]+$`)
+
+// accessFilter is the condition of secrets the user may access for the action: personal secrets of the user
+// and secrets in collections of organisations where role of the user allows the action
+func accessFilter(userID int64, action string) string {
+	return fmt.Sprintf(`(user_id=%d AND collection_id IS NULL OR collection_id IN (SELECT c.id FROM collections c `+
+		`JOIN org_members m ON m.org_id=c.org_id WHERE m.user_id=%d AND m.role IN ('%s')))`,
+		userID, userID, strings.Join(rbac.Roles(action), "','"))
 }
 
 // PGDB type for postgres
@@ -120,27 +205,190 @@ func (db *PGDB) SelectPass(ctx context.Context, user *rpc.User) (*string, *int64
 	return &val, &user.ID, nil
 }
 
+// SelectTokenVersion gets version of tokens of the user, tokens of other versions are revoked
+func (db *PGDB) SelectTokenVersion(ctx context.Context, id int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var version int64
+	err := db.Conn.QueryRow(ctx, "SELECT token_version FROM users WHERE id=$1", id).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%w: user %d", ErrNotFound, id)
+	} else if err != nil {
+		return 0, fmt.Errorf("select from users failed: %v", err)
+	}
+	return version, nil
+}
+
+// SelectLogin gets login of a user by id
+func (db *PGDB) SelectLogin(ctx context.Context, id int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var login string
+	err := db.Conn.QueryRow(ctx, "SELECT login FROM users WHERE id=$1", id).Scan(&login)
+	if err != nil {
+		return "", fmt.Errorf("select from users failed: %v", err)
+	}
+	return login, nil
+}
+
+// SelectVaultKey gets wrapped vault key, salt of master key and vault key version, key is nil if not set yet
+func (db *PGDB) SelectVaultKey(ctx context.Context, userID int64) ([]byte, []byte, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var key, salt []byte
+	var version int64
+	err := db.Conn.QueryRow(ctx, "SELECT vault_key, vault_salt, vault_key_version FROM users WHERE id=$1", userID).Scan(&key, &salt, &version)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("select from users failed: %v", err)
+	}
+	return key, salt, version, nil
+}
+
+// SetVaultKey sets the first vault key of the user, existing key is never replaced
+func (db *PGDB) SetVaultKey(ctx context.Context, userID int64, key, salt []byte, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `UPDATE users SET vault_key=$2, vault_salt=$3, vault_key_version=$4 WHERE id=$1 AND vault_key IS NULL`,
+		userID, key, salt, version)
+	if err != nil {
+		return fmt.Errorf("update users failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVaultKey
+	}
+	return nil
+}
+
+// RotateVaultKey replaces vault key of the previous version with the new one, blind indexes of secrets and private key
+// wrapped with the vault key in one transaction, so indexes never mix tokens of different keys. Private key is kept if nil,
+// emergency contacts lose the old key and have to be confirmed again, recovery key is dropped
+func (db *PGDB) RotateVaultKey(ctx context.Context, userID int64, key, salt []byte, version int64, updates []*rpc.IndexUpdate, privateKey []byte) (int64, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET vault_key=$2, vault_salt=$3, vault_key_version=$4, private_key=COALESCE($6, private_key),
+	recovery_key=NULL, recovery_hash=NULL
+	WHERE id=$1 AND vault_key_version=$5`,
+		userID, key, salt, version, version-1, privateKey)
+	if err != nil {
+		return 0, fmt.Errorf("update users failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, ErrVaultKey
+	}
+
+	// vault key sealed to emergency contacts is of the old version, grantor has to confirm them again
+	_, err = tx.Exec(ctx, `UPDATE emergency_access SET status=$2, wrapped_key=NULL, change_date=current_timestamp
+	WHERE grantor_id=$1 AND wrapped_key IS NOT NULL`, userID, emergency.StatusAccepted)
+	if err != nil {
+		return 0, fmt.Errorf("update emergency_access failed: %v", err)
+	}
+
+	n, err := updateBlindIndexes(ctx, tx, userID, updates)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit failed: %v", err)
+	}
+	return n, nil
+}
+
+// UpdateBlindIndexes replaces blind indexes of secrets, it is used by client storage after rotation
+func (db *PGDB) UpdateBlindIndexes(ctx context.Context, userID int64, updates []*rpc.IndexUpdate) (int64, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	n, err := updateBlindIndexes(ctx, tx, userID, updates)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit failed: %v", err)
+	}
+	return n, nil
+}
+
+func updateBlindIndexes(ctx context.Context, tx pgx.Tx, userID int64, updates []*rpc.IndexUpdate) (int64, error) {
+	var n int64
+	for _, u := range updates {
+		tag, err := tx.Exec(ctx, `UPDATE secrets SET blind_index=$3 WHERE user_id=$1 AND blind_index=$2`, userID, u.Old, u.New)
+		if err != nil {
+			return 0, fmt.Errorf("update blind index failed: %v", err)
+		}
+		n += tag.RowsAffected()
+	}
+	return n, nil
+}
+
 // InsertData appends new data to existing secrets
 func (db *PGDB) InsertData(ctx context.Context, data *rpc.Data, synchronized bool) (*uuid.UUID, error) {
+	expiresAt, err := app.ExpiryFromData(data)
+	if err != nil {
+		return nil, err
+	}
 	id := uuid.Generate()
-	_, err := db.Conn.Exec(ctx, `
-	INSERT INTO secrets (id, user_id, data, metadata,synchronized) VALUES ($1,$2,$3,$4,$5)`,
-		id, data.UserID, data.Data, data.Metadata, synchronized)
+	_, err = db.Conn.Exec(ctx, `
+	INSERT INTO secrets (id, user_id, data, metadata, synchronized, expires_at, blind_index, collection_id)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8, '')::uuid)`,
+		id, data.UserID, data.Data, data.Metadata, synchronized, expiresAt, data.BlindIndex, data.CollectionID)
 	if err != nil {
 		return nil, err
 	}
 	return &id, nil
 }
 
+// InsertBatch inserts several secrets in one transaction, either all of them are saved or none
+func (db *PGDB) InsertBatch(ctx context.Context, data []*rpc.Data, synchronized bool) ([]uuid.UUID, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]uuid.UUID, 0, len(data))
+	for _, d := range data {
+		expiresAt, err := app.ExpiryFromData(d)
+		if err != nil {
+			return nil, err
+		}
+		id := uuid.Generate()
+		_, err = tx.Exec(ctx, `
+		INSERT INTO secrets (id, user_id, data, metadata, synchronized, expires_at, blind_index, collection_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8, '')::uuid)`,
+			id, d.UserID, d.Data, d.Metadata, synchronized, expiresAt, d.BlindIndex, d.CollectionID)
+		if err != nil {
+			return nil, fmt.Errorf("insert into secrets failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit failed: %v", err)
+	}
+	return ids, nil
+}
+
 // SearchData gets all secrets that match search criteria
 func (db *PGDB) SearchData(ctx context.Context, in *rpc.Data) ([]*rpc.Data, error) {
+	return db.searchData(ctx, in, rbac.ActionRead)
+}
+
+// searchData gets secrets that match search criteria among secrets the user may access for the action
+func (db *PGDB) searchData(ctx context.Context, in *rpc.Data, action string) ([]*rpc.Data, error) {
 	var out []*rpc.Data
-	query, err := db.buildQuery(in, "id, data, metadata")
+	query, args, err := db.buildQuery(in, "id, data, metadata, expires_at, change_date, coalesce(collection_id::text, '')", action)
 	if err != nil {
 		return nil, err
 	}
 
-	row, err := db.Conn.Query(context.Background(), query)
+	row, err := db.Conn.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("select from secrets failed: %v", err)
 	}
@@ -149,8 +397,12 @@ func (db *PGDB) SearchData(ctx context.Context, in *rpc.Data) ([]*rpc.Data, erro
 	for row.Next() {
 		var o rpc.Data
 		var g rpc.KeepData
-		err := row.Scan(&o.ID, &g, &o.Metadata)
+		var expiresAt *time.Time
+		var changeDate time.Time
+		err := row.Scan(&o.ID, &g, &o.Metadata, &expiresAt, &changeDate, &o.CollectionID)
 		o.Data = &g
+		o.ExpiresAt = formatExpiry(expiresAt)
+		o.ChangeDate = changeDate.Format(app.ChangeDateLayout)
 		if err != nil {
 			db.log.Error("select from secrets failed:", zap.Error(err))
 		}
@@ -164,55 +416,273 @@ func (db *PGDB) SearchData(ctx context.Context, in *rpc.Data) ([]*rpc.Data, erro
 	return out, nil
 }
 
+// SearchText runs ranked full-text search with typos allowed, words of the query are matched by tsvector,
+// parts of words by LIKE and misspelled words by trigram similarity, all backed by GIN indexes.
+// Total number of hits on all pages is returned too
+func (db *PGDB) SearchText(ctx context.Context, userID int64, query string, limit, offset int64) ([]*rpc.SearchHit, int64, error) {
+	limit, offset = search.Page(limit, offset)
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil, 0, fmt.Errorf("search query is empty")
+	}
+
+	row, err := db.Conn.Query(ctx, `
+	SELECT id, data, metadata, expires_at, change_date, coalesce(collection_id::text, ''),
+		ts_rank(search_tsv, websearch_to_tsquery('simple', $1)) + word_similarity($1, search_text) AS rank,
+		count(*) OVER () AS total
+	FROM secrets
+	WHERE `+accessFilter(userID, rbac.ActionRead)+` AND (search_tsv @@ websearch_to_tsquery('simple', $1) OR search_text LIKE $2 OR $1 <% search_text)
+	ORDER BY rank DESC, change_date DESC
+	LIMIT $3 OFFSET $4`, q, "%"+search.EscapeLike(q)+"%", limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search in secrets failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.SearchHit
+	var total int64
+	for row.Next() {
+		var o rpc.Data
+		var g rpc.KeepData
+		var expiresAt *time.Time
+		var changeDate time.Time
+		var rank float64
+		if err := row.Scan(&o.ID, &g, &o.Metadata, &expiresAt, &changeDate, &o.CollectionID, &rank, &total); err != nil {
+			return nil, 0, fmt.Errorf("search in secrets failed: %v", err)
+		}
+		o.Data = &g
+		o.ExpiresAt = formatExpiry(expiresAt)
+		o.ChangeDate = changeDate.Format(app.ChangeDateLayout)
+		out = append(out, &rpc.SearchHit{Data: &o, Rank: rank, Highlights: search.Highlight(&o, q)})
+	}
+	return out, total, row.Err()
+}
+
+// SearchDataPage gets one page of secrets that match search criteria and token of the next page
+func (db *PGDB) SearchDataPage(ctx context.Context, in *rpc.Data, page *rpc.PageRequest) ([]*rpc.Data, string, error) {
+	query, args, err := db.buildQuery(in, pageColumns(page), rbac.ActionRead)
+	if err != nil {
+		return nil, "", err
+	}
+	return db.selectPage(ctx, query, args, page)
+}
+
+// SelectDataPage gets one page of all secrets of the user changed after since and token of the next page
+func (db *PGDB) SelectDataPage(ctx context.Context, userID int64, since string, page *rpc.PageRequest) ([]*rpc.Data, string, error) {
+	if since == "" {
+		since = "-infinity"
+	}
+	query := fmt.Sprintf("SELECT %s FROM secrets WHERE %s AND change_date>$1::timestamp", pageColumns(page), accessFilter(userID, rbac.ActionRead))
+	return db.selectPage(ctx, query, []interface{}{since}, page)
+}
+
+// pageColumns are columns read by selectPage, contents of secrets are not read if the field mask does not need them
+func pageColumns(page *rpc.PageRequest) string {
+	body := "data"
+	if !paging.WithBody(page.GetFields()) {
+		body = "'{}'::jsonb"
+	}
+	sort, _ := paging.Sort(page)
+	return fmt.Sprintf("id::text, user_id, %s, metadata, expires_at, change_date, blind_index, coalesce(collection_id::text, ''), (%s)::text",
+		body, sortColumns[sort].expr)
+}
+
+// selectPage continues query with keyset pagination: secrets after the cursor in sort order, id breaks ties
+func (db *PGDB) selectPage(ctx context.Context, query string, args []interface{}, page *rpc.PageRequest) ([]*rpc.Data, string, error) {
+	if err := paging.Validate(page); err != nil {
+		return nil, "", err
+	}
+	sort, _ := paging.Sort(page)
+	cursor, _ := paging.Parse(page)
+	col := sortColumns[sort]
+	dir, cmp := "ASC", ">"
+	if page.GetDescending() {
+		dir, cmp = "DESC", "<"
+	}
+
+	if cursor != nil {
+		query += fmt.Sprintf(" AND (%s, id) %s ($%d::%s, $%d::uuid)", col.expr, cmp, len(args)+1, col.cast, len(args)+2)
+		args = append(args, cursor.Value, cursor.ID)
+	}
+	size := paging.Size(page)
+	// one more row tells if there is the next page
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", col.expr, dir, dir, size+1)
+
+	row, err := db.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("select from secrets failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.Data
+	var last paging.Cursor
+	for row.Next() {
+		if int64(len(out)) == size {
+			return out, last.Token(), nil
+		}
+		var o rpc.Data
+		var g rpc.KeepData
+		var expiresAt *time.Time
+		var changeDate time.Time
+		var id, value string
+		if err := row.Scan(&id, &o.UserID, &g, &o.Metadata, &expiresAt, &changeDate, &o.BlindIndex, &o.CollectionID, &value); err != nil {
+			return nil, "", fmt.Errorf("select from secrets failed: %v", err)
+		}
+		o.Data = &g
+		o.ExpiresAt = formatExpiry(expiresAt)
+		o.ChangeDate = changeDate.Format(app.ChangeDateLayout)
+		out = append(out, paging.Project(&o, page.GetFields()))
+		last = paging.Cursor{Sort: sort, Desc: page.GetDescending(), Value: value, ID: id}
+	}
+	return out, "", row.Err()
+}
+
+// InserDataForUser saves secrets pulled from the server, secrets that are kept already are replaced with them,
+// so edits made on the server reach local db
 func (db *PGDB) InserDataForUser(ctx context.Context, d []*rpc.Data, id int64) error {
 	tx, err := db.Conn.Begin(ctx)
 	if err != nil {
 		db.log.Error("starting connection failed: ", zap.Error(err))
+		return err
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Prepare(ctx, "batch insert data", `INSERT INTO secrets (id, user_id, data, metadata)
-													VALUES($1,$2,$3,$4);`) // TODO: ON CONFLICT...
+	_, err = tx.Prepare(ctx, "batch insert data", `INSERT INTO secrets (id, user_id, data, metadata, expires_at, change_date, blind_index, collection_id, synchronized)
+													VALUES($1,$2,$3,$4,$5,COALESCE($6, current_timestamp),$7,NULLIF($8, '')::uuid,true)
+													ON CONFLICT (id) DO UPDATE SET user_id=EXCLUDED.user_id, data=EXCLUDED.data, metadata=EXCLUDED.metadata,
+													expires_at=EXCLUDED.expires_at, change_date=EXCLUDED.change_date, blind_index=EXCLUDED.blind_index,
+													collection_id=EXCLUDED.collection_id;`)
 	if err != nil {
 		db.log.Error("prep transaction failed: ", zap.Error(err))
 		return err
 	}
 	for _, v := range d {
+		expiresAt, err := app.ExpiryFromData(v)
+		if err != nil {
+			db.log.Error("wrong expiry date: ", zap.Error(err))
+			return err
+		}
+
+		var changeDate *time.Time
+		if t, err := time.Parse(app.ChangeDateLayout, v.ChangeDate); err == nil {
+			changeDate = &t
+		}
 
-		if _, err = tx.Exec(ctx, "batch insert data", v.ID, id, v.Data, v.Metadata); err != nil {
+		if _, err = tx.Exec(ctx, "batch insert data", v.ID, id, v.Data, v.Metadata, expiresAt, changeDate, v.BlindIndex, v.CollectionID); err != nil {
 			db.log.Error("Insert data failed: ", zap.Error(err))
 			return err
 		}
-		if err = tx.Commit(ctx); err != nil {
-			db.log.Error("Commit failed: ", zap.Error(err))
+	}
+	if err = tx.Commit(ctx); err != nil {
+		db.log.Error("Commit failed: ", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// InsertSyncData saves secrets pushed by the client on the server. A secret that is kept already is replaced only
+// if the user can write it where it is now, it keeps its owner and cannot be moved to another collection this way
+func (db *PGDB) InsertSyncData(ctx context.Context, d []*rpc.Data, userID int64) error {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, v := range d {
+		expiresAt, err := app.ExpiryFromData(v)
+		if err != nil {
 			return err
 		}
+		var changeDate *time.Time
+		if t, err := time.Parse(app.ChangeDateLayout, v.ChangeDate); err == nil {
+			changeDate = &t
+		}
+
+		tag, err := tx.Exec(ctx, `
+		INSERT INTO secrets (id, user_id, data, metadata, expires_at, change_date, blind_index, collection_id, synchronized)
+		VALUES($1,$2,$3,$4,$5,COALESCE($6, current_timestamp),$7,NULLIF($8, '')::uuid,true)
+		ON CONFLICT (id) DO UPDATE SET data=EXCLUDED.data, metadata=EXCLUDED.metadata, expires_at=EXCLUDED.expires_at,
+		change_date=EXCLUDED.change_date, blind_index=EXCLUDED.blind_index
+		WHERE secrets.collection_id IS NOT DISTINCT FROM EXCLUDED.collection_id
+		AND secrets.id IN (SELECT id FROM secrets WHERE `+accessFilter(userID, rbac.ActionWrite)+`)`,
+			v.ID, userID, v.Data, v.Metadata, expiresAt, changeDate, v.BlindIndex, v.CollectionID)
+		if err != nil {
+			return fmt.Errorf("insert into secrets failed: %v", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%w: secret %s", ErrNotFound, v.ID)
+		}
+	}
+	return tx.Commit(ctx)
+}
 
+// PushUnsynchronized gives secrets of the user that are not on the server yet to push, they are marked synchronized
+// in the same transaction only when push succeeds, so they are pushed again after a failed push
+func (db *PGDB) PushUnsynchronized(ctx context.Context, userID int64, push func([]*rpc.Data) error) error {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %v", err)
 	}
-	return nil
+	defer tx.Rollback(ctx)
+
+	row, err := tx.Query(ctx, `UPDATE secrets SET synchronized=true WHERE synchronized=false AND user_id=$1
+	RETURNING id, user_id, data, metadata, expires_at, change_date, blind_index, coalesce(collection_id::text, '')`, userID)
+	if err != nil {
+		return fmt.Errorf("update secrets failed: %v", err)
+	}
+	data := db.scanSecrets(row)
+	if len(data) == 0 {
+		return nil
+	}
+	if err := push(data); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (db *PGDB) SelectAllDataForUser(ctx context.Context, id int64, t string, synchronized bool) ([]*rpc.Data, error) {
-	var out []*rpc.Data
-	var query string
+// SelectAllDataForUser gets secrets the user can read changed after time t, all of them if t is empty
+func (db *PGDB) SelectAllDataForUser(ctx context.Context, id int64, t string) ([]*rpc.Data, error) {
+	if t == "" {
+		t = "-infinity"
+	}
+	return db.selectSecrets(ctx, `SELECT id, user_id, data, metadata, expires_at, change_date, blind_index, coalesce(collection_id::text, '') FROM secrets
+	WHERE `+accessFilter(id, rbac.ActionRead)+` AND change_date>$1::timestamp`, t)
+}
 
-	if synchronized {
-		query = fmt.Sprintf(`SELECT id, user_id, data, metadata FROM secrets where user_id=%d AND change_date>'%s'`, id, t)
-	} else {
-		query = fmt.Sprintf(`UPDATE secrets 
-    SET synchronized=true WHERE synchronized=false AND user_id=%d RETURNING id, user_id, data, metadata`, id)
+// SelectCollectionData gets all secrets of the collections the user may read
+func (db *PGDB) SelectCollectionData(ctx context.Context, userID int64, collectionIDs []string) ([]*rpc.Data, error) {
+	for _, id := range collectionIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("collection id is not valid: %v", err)
+		}
 	}
-	row, err := db.Conn.Query(context.Background(), query)
+	return db.selectSecrets(ctx, `SELECT id, user_id, data, metadata, expires_at, change_date, blind_index, coalesce(collection_id::text, '') FROM secrets
+	WHERE `+accessFilter(userID, rbac.ActionRead)+` AND collection_id = ANY($1::uuid[])`, collectionIDs)
+}
+
+// selectSecrets runs query that returns secrets with their user, blind index and collection
+func (db *PGDB) selectSecrets(ctx context.Context, query string, args ...interface{}) ([]*rpc.Data, error) {
+	row, err := db.Conn.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("select from secrets failed: %v", err)
 	}
+	return db.scanSecrets(row), nil
+}
+
+func (db *PGDB) scanSecrets(row pgx.Rows) []*rpc.Data {
 	defer row.Close()
 
+	var out []*rpc.Data
 	for row.Next() {
 		var o rpc.Data
 		var g rpc.KeepData
-		err := row.Scan(&o.ID, &o.UserID, &g, &o.Metadata)
+		var expiresAt *time.Time
+		var changeDate time.Time
+		err := row.Scan(&o.ID, &o.UserID, &g, &o.Metadata, &expiresAt, &changeDate, &o.BlindIndex, &o.CollectionID)
 		o.Data = &g
+		o.ExpiresAt = formatExpiry(expiresAt)
+		o.ChangeDate = changeDate.Format(app.ChangeDateLayout)
 		if err != nil {
 			db.log.Error("select from secrets failed:", zap.Error(err))
 		}
@@ -222,13 +692,90 @@ func (db *PGDB) SelectAllDataForUser(ctx context.Context, id int64, t string, sy
 		}
 		out = append(out, &o)
 	}
+	return out
+}
+
+// SelectExpiring gets secrets that expire before given time, including already expired ones.
+// If user id is 0 secrets of all users are returned.
+func (db *PGDB) SelectExpiring(ctx context.Context, userID int64, before time.Time) ([]*rpc.Data, error) {
+	var out []*rpc.Data
+	query := `SELECT id, user_id, data, metadata, expires_at, change_date FROM secrets WHERE expires_at IS NOT NULL AND expires_at<=$1`
+	args := []interface{}{before}
+	if userID != 0 {
+		query += ` AND ` + accessFilter(userID, rbac.ActionRead)
+	}
+
+	row, err := db.Conn.Query(ctx, query+` ORDER BY user_id, expires_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select from secrets failed: %v", err)
+	}
+	defer row.Close()
+
+	for row.Next() {
+		var o rpc.Data
+		var g rpc.KeepData
+		var expiresAt *time.Time
+		var changeDate time.Time
+		if err := row.Scan(&o.ID, &o.UserID, &g, &o.Metadata, &expiresAt, &changeDate); err != nil {
+			db.log.Error("select from secrets failed:", zap.Error(err))
+			continue
+		}
+		o.Data = &g
+		o.ExpiresAt = formatExpiry(expiresAt)
+		o.ChangeDate = changeDate.Format(app.ChangeDateLayout)
+		out = append(out, &o)
+	}
 	return out, nil
 }
 
-// DeleteData gets all secrets that match search criteria
+// formatExpiry converts nullable expiry column to the format used in rpc
+func formatExpiry(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(app.ExpiryLayout)
+}
+
+// UpdateData replaces data, metadata, expiry and blind index of the secret with id, the one with metadata equal
+// to name if id is empty. The secret stays in its collection, role in it must allow writes
+func (db *PGDB) UpdateData(ctx context.Context, userID int64, id, name string, data *rpc.Data) error {
+	expiresAt, err := app.ExpiryFromData(data)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	where, key := "metadata=$1", name
+	if id != "" {
+		if _, err = uuid.Parse(id); err != nil {
+			return fmt.Errorf("secret id is not valid: %v", err)
+		}
+		where, key, name = "id=$1::uuid", id, id
+	}
+	tag, err := tx.Exec(ctx, `
+	UPDATE secrets SET data=$2, metadata=$3, expires_at=$4, blind_index=$5, change_date=current_timestamp
+	WHERE `+accessFilter(userID, rbac.ActionWrite)+` AND `+where,
+		key, data.Data, data.Metadata, expiresAt, data.BlindIndex)
+	if err != nil {
+		return fmt.Errorf("update secrets failed: %v", err)
+	}
+	switch n := tag.RowsAffected(); {
+	case n == 0:
+		return fmt.Errorf("%w: secret %s", ErrNotFound, name)
+	case n > 1:
+		return fmt.Errorf("%w: %d secrets are named %s", ErrAmbiguous, n, name)
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteData deletes all secrets that match search criteria, secrets of collections are deleted only if role allows it
 func (db *PGDB) DeleteData(ctx context.Context, in *rpc.Data) ([]*rpc.Data, error) {
 	var ids []string
-	data, err := db.SearchData(ctx, in)
+	data, err := db.searchData(ctx, in, rbac.ActionDelete)
 	if err != nil {
 		return nil, err
 	}
@@ -246,55 +793,67 @@ func (db *PGDB) DeleteData(ctx context.Context, in *rpc.Data) ([]*rpc.Data, erro
 	return data, nil
 }
 
-func (db *PGDB) buildQuery(data *rpc.Data, cols string) (string, error) {
-	query := fmt.Sprintf("SELECT %s FROM secrets WHERE user_id=%d AND ", cols, data.UserID)
-	if data.ID != 0 {
-		return query + fmt.Sprintf("id=%d", data.ID), nil
+// buildQuery builds select of secrets that match search criteria, all values of the criteria are bind parameters
+func (db *PGDB) buildQuery(data *rpc.Data, cols string, action string) (string, []interface{}, error) {
+	query := fmt.Sprintf("SELECT %s FROM secrets WHERE %s AND ", cols, accessFilter(data.UserID, action))
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if data.ID != "" {
+		if _, err := uuid.Parse(data.ID); err != nil {
+			return "", nil, fmt.Errorf("secret id is not valid: %v", err)
+		}
+		return query + "id=" + arg(data.ID) + "::uuid", args, nil
 	}
 
 	var filter []string
+	if data.CollectionID != "" {
+		if _, err := uuid.Parse(data.CollectionID); err != nil {
+			return "", nil, fmt.Errorf("collection id is not valid: %v", err)
+		}
+		filter = append(filter, "collection_id="+arg(data.CollectionID)+"::uuid")
+	}
 	if data.Metadata != "" {
-		filter = append(filter, fmt.Sprintf("metadata LIKE '%%%s%%'", data.Metadata))
+		filter = append(filter, "metadata LIKE "+arg("%"+search.EscapeLike(data.Metadata)+"%"))
 	}
 
-	if data.Data.GetAuthData() != nil && data.Data.AuthData.Login != "" {
-		filter = append(filter, fmt.Sprintf(`data #> '{AuthData}' @? '$.login ? (@ == "%s")'`, data.Data.AuthData.Login))
+	if data.Data.GetAuthData().GetLogin() != "" {
+		filter = append(filter, "data->'AuthData'->>'login' = "+arg(data.Data.AuthData.Login))
 	}
 
 	if data.Data.GetText() != "" {
-		filter = append(filter, fmt.Sprintf(`data @? '$.Text ? (@ == "%s")'`, data.Data.Text))
+		filter = append(filter, "data->>'Text' = "+arg(data.Data.Text))
 	}
 
-	if data.Data.GetBinary() != nil {
-		b := string(data.Data.Binary[:])
-		filter = append(filter, fmt.Sprintf(`data @? '$.Binary ? (@ == "%s")'`, b))
+	if len(data.Data.GetBinary()) > 0 {
+		// bytes are kept in json as base64
+		filter = append(filter, "data->>'Binary' = "+arg(base64.StdEncoding.EncodeToString(data.Data.Binary)))
 	}
 
-	if data.Data.GetBankCard() != nil {
-		if data.Data.BankCard.Address != "" {
-			filter = append(filter, fmt.Sprintf(`data #> '{BankCard}' @? '$.Address ? (@ == "%s")'`, data.Data.BankCard.Address))
-		}
-
-		if data.Data.BankCard.BankName != "" {
-			filter = append(filter, fmt.Sprintf(`data #> '{BankCard}' @? '$.BankName ? (@ == "%s")'`, data.Data.BankCard.BankName))
-		}
-
-		if data.Data.BankCard.Expiry != "" {
-			filter = append(filter, fmt.Sprintf(`data #> '{BankCard}' @? '$.Expiry ? (@ == "%s")'`, data.Data.BankCard.Expiry))
-		}
-
-		if data.Data.BankCard.HolderName != "" {
-			filter = append(filter, fmt.Sprintf(`data #> '{BankCard}' @? '$.HolderName ? (@ == "%s")'`, data.Data.BankCard.HolderName))
+	if card := data.Data.GetBankCard(); card != nil {
+		for _, f := range []struct{ key, value string }{
+			{"Address", card.Address}, {"BankName", card.BankName}, {"Expiry", card.Expiry},
+			{"HolderName", card.HolderName}, {"CardNumber", card.CardNumber}, {"Brand", card.Brand},
+		} {
+			if f.value != "" {
+				filter = append(filter, fmt.Sprintf("data->'BankCard'->>'%s' = %s", f.key, arg(f.value)))
+			}
 		}
+	}
 
-		if data.Data.BankCard.CardNumber != 0 {
-			filter = append(filter, fmt.Sprintf(`data #> '{BankCard}' @? '$.CardNumber ? (@ == "%d")'`, data.Data.BankCard.CardNumber))
+	if len(data.BlindIndex) > 0 {
+		for _, t := range data.BlindIndex {
+			if !tokenRe.MatchString(t) {
+				return "", nil, fmt.Errorf("malformed blind index token %q", t)
+			}
 		}
+		filter = append(filter, "blind_index @> "+arg(data.BlindIndex)+"::text[]")
 	}
 
 	if len(filter) > 0 {
-		query += strings.Join(filter[:], " AND ")
-		return query, nil
+		return query + strings.Join(filter, " AND "), args, nil
 	}
-	return "", fmt.Errorf("could not construct any filter, please add more data")
+	return "", nil, fmt.Errorf("could not construct any filter, please add more data")
 }
@@ -2,35 +2,138 @@ package storage
 
 import (
 	"testing"
+	"time"
 
 	rpc "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/rbac"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestPGDB_buildQuery(t *testing.T) {
 	db := &PGDB{}
+	access := accessFilter(0, rbac.ActionRead)
 
 	tests := []struct {
-		name string
-		data *rpc.Data
-		want string
+		name     string
+		data     *rpc.Data
+		want     string
+		wantArgs []interface{}
 	}{
 		{name: "with id",
-			data: &rpc.Data{ID: 1, Data: &rpc.KeepData{AuthData: &rpc.AuthData{Login: "name"}}},
-			want: "SELECT * FROM secrets WHERE user_id=0 AND id=1",
+			data:     &rpc.Data{ID: "2d0c6a1e-7f4b-4e8a-9c3d-5b1a8e6f0c42", Data: &rpc.KeepData{AuthData: &rpc.AuthData{Login: "name"}}},
+			want:     "SELECT * FROM secrets WHERE " + access + " AND id=$1::uuid",
+			wantArgs: []interface{}{"2d0c6a1e-7f4b-4e8a-9c3d-5b1a8e6f0c42"},
+		},
+
+		{name: "with malformed id",
+			data: &rpc.Data{ID: "1 OR true"},
+			want: "",
 		},
 
 		{name: "with meta and login",
-			data: &rpc.Data{Data: &rpc.KeepData{AuthData: &rpc.AuthData{Login: "name"}}, Metadata: "some data"},
-			want: `SELECT * FROM secrets WHERE user_id=0 AND metadata LIKE '%some data%' AND data #> '{AuthData}' @? '$.login ? (@ == "name")'`,
+			data:     &rpc.Data{Data: &rpc.KeepData{AuthData: &rpc.AuthData{Login: "name"}}, Metadata: "some_data"},
+			want:     "SELECT * FROM secrets WHERE " + access + ` AND metadata LIKE $1 AND data->'AuthData'->>'login' = $2`,
+			wantArgs: []interface{}{`%some\_data%`, "name"},
+		},
+
+		{name: "with card",
+			data: &rpc.Data{Data: &rpc.KeepData{BankCard: &rpc.BankCard{CardNumber: "4111' OR true --", Brand: "visa"}}},
+			want: "SELECT * FROM secrets WHERE " + access +
+				` AND data->'BankCard'->>'CardNumber' = $1 AND data->'BankCard'->>'Brand' = $2`,
+			wantArgs: []interface{}{"4111' OR true --", "visa"},
+		},
+
+		{name: "with binary",
+			data:     &rpc.Data{Data: &rpc.KeepData{Binary: []byte{0, 1, 2, 255}}},
+			want:     "SELECT * FROM secrets WHERE " + access + ` AND data->>'Binary' = $1`,
+			wantArgs: []interface{}{"AAEC/w=="},
+		},
+
+		{name: "with blind index",
+			data:     &rpc.Data{Data: &rpc.KeepData{AuthData: &rpc.AuthData{}}, BlindIndex: []string{"1:login:0a1b", "1:bank^:ff"}},
+			want:     "SELECT * FROM secrets WHERE " + access + ` AND blind_index @> $1::text[]`,
+			wantArgs: []interface{}{[]string{"1:login:0a1b", "1:bank^:ff"}},
+		},
+
+		{name: "with collection",
+			data:     &rpc.Data{CollectionID: "8f5c2a8e-3b1d-4c55-9a51-0d9a3e0c7b21"},
+			want:     "SELECT * FROM secrets WHERE " + access + " AND collection_id=$1::uuid",
+			wantArgs: []interface{}{"8f5c2a8e-3b1d-4c55-9a51-0d9a3e0c7b21"},
+		},
+
+		{name: "with malformed collection",
+			data: &rpc.Data{CollectionID: "x' OR true --"},
+			want: "",
+		},
+
+		{name: "with malformed blind index",
+			data: &rpc.Data{Data: &rpc.KeepData{}, BlindIndex: []string{"1:login:0a1b']::text[] OR true --"}},
+			want: "",
+		},
+
+		{name: "without filter",
+			data: &rpc.Data{Data: &rpc.KeepData{}},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, args, err := db.buildQuery(tt.data, "*", rbac.ActionRead)
+
+			assert.Equal(t, tt.want, s)
+			assert.Equal(t, tt.wantArgs, args)
+			assert.Equal(t, tt.want == "", err != nil)
+		})
+	}
+}
+
+func Test_pageColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		page *rpc.PageRequest
+		want string
+	}{
+		{name: "default",
+			want: "id::text, user_id, data, metadata, expires_at, change_date, blind_index, coalesce(collection_id::text, ''), (change_date)::text",
+		},
+		{name: "without body",
+			page: &rpc.PageRequest{SortBy: "title", Fields: &fieldmaskpb.FieldMask{Paths: []string{"Metadata"}}},
+			want: "id::text, user_id, '{}'::jsonb, metadata, expires_at, change_date, blind_index, coalesce(collection_id::text, ''), (lower(coalesce(metadata, '')))::text",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pageColumns(tt.page))
+		})
+	}
+}
 
-			s, _ := db.buildQuery(tt.data, "*")
+func Test_accessFilter(t *testing.T) {
+	assert.Equal(t, `(user_id=7 AND collection_id IS NULL OR collection_id IN (SELECT c.id FROM collections c `+
+		`JOIN org_members m ON m.org_id=c.org_id WHERE m.user_id=7 AND m.role IN ('owner','admin','manager')))`,
+		accessFilter(7, rbac.ActionDelete))
+}
 
-			assert.Equal(t, s, tt.want)
+func Test_auditFilter(t *testing.T) {
+	since := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		f     *rpc.ListAuditEventsRequest
+		since time.Time
+		want  string
+		args  []interface{}
+	}{
+		{name: "all", f: &rpc.ListAuditEventsRequest{}, want: "id>$1", args: []interface{}{int64(0)}},
+		{name: "user and action since", f: &rpc.ListAuditEventsRequest{UserID: 11, Action: "login", AfterID: 5}, since: since,
+			want: "id>$1 AND user_id=$2 AND action=$3 AND created_at>=$4", args: []interface{}{int64(5), int64(11), "login", since}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := auditFilter(tt.f, tt.since, time.Time{})
+			assert.Equal(t, tt.want, where)
+			assert.Equal(t, tt.args, args)
 		})
 	}
 }
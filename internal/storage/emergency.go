@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/distribution/uuid"
+	"github.com/jackc/pgx/v4"
+	rpc "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/emergency"
+)
+
+var (
+	// ErrEmergencyExists is returned when the user is emergency contact of the grantor already
+	ErrEmergencyExists = errors.New("user is emergency contact already")
+	// ErrEmergencyStatus is returned when the step is not possible in the current status of emergency access
+	ErrEmergencyStatus = errors.New("emergency access is not in the right status")
+)
+
+const emergencyColumns = `e.id::text, e.grantor_id, g.login, e.grantee_id, c.login, e.access_type, e.wait_days, e.status,
+	e.recovery_initiated_at, c.public_key, e.wrapped_key, e.key_version`
+
+// InsertEmergencyAccess invites the user by login to become emergency contact of the grantor
+func (db *PGDB) InsertEmergencyAccess(ctx context.Context, grantorID int64, login, accessType string, waitDays int64) (*uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var granteeID int64
+	err := db.Conn.QueryRow(ctx, "SELECT id FROM users WHERE login=$1", login).Scan(&granteeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: user %s", ErrNotFound, login)
+	} else if err != nil {
+		return nil, fmt.Errorf("select from users failed: %v", err)
+	}
+	if granteeID == grantorID {
+		return nil, fmt.Errorf("%w: users cannot be their own emergency contact", ErrEmergencyStatus)
+	}
+
+	id := uuid.Generate()
+	tag, err := db.Conn.Exec(ctx, `
+	INSERT INTO emergency_access (id, grantor_id, grantee_id, access_type, wait_days, status) VALUES ($1,$2,$3,$4,$5,$6)
+	ON CONFLICT (grantor_id, grantee_id) DO NOTHING`, id, grantorID, granteeID, accessType, waitDays, emergency.StatusInvited)
+	if err != nil {
+		return nil, fmt.Errorf("insert into emergency_access failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrEmergencyExists
+	}
+	return &id, nil
+}
+
+// SelectEmergencyAccess gets emergency access where the user is the grantor or the contact
+func (db *PGDB) SelectEmergencyAccess(ctx context.Context, userID int64) ([]*rpc.EmergencyAccess, error) {
+	row, err := db.Conn.Query(ctx, `SELECT `+emergencyColumns+`
+	FROM emergency_access e JOIN users g ON g.id=e.grantor_id JOIN users c ON c.id=e.grantee_id
+	WHERE e.grantor_id=$1 OR e.grantee_id=$1 ORDER BY e.change_date DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select from emergency_access failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.EmergencyAccess
+	for row.Next() {
+		e, err := scanEmergency(row)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, row.Err()
+}
+
+// SelectEmergency gets emergency access by id
+func (db *PGDB) SelectEmergency(ctx context.Context, id string) (*rpc.EmergencyAccess, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	e, err := scanEmergency(db.Conn.QueryRow(ctx, `SELECT `+emergencyColumns+`
+	FROM emergency_access e JOIN users g ON g.id=e.grantor_id JOIN users c ON c.id=e.grantee_id
+	WHERE e.id=$1::uuid`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: emergency access %s", ErrNotFound, id)
+	}
+	return e, err
+}
+
+// ApproveExpiredEmergency approves requested access whose waiting period is over, false if it is not requested or still waits
+func (db *PGDB) ApproveExpiredEmergency(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `
+	UPDATE emergency_access SET status=$2, change_date=current_timestamp
+	WHERE id=$1::uuid AND status=$3 AND recovery_initiated_at + make_interval(days => wait_days::int) <= current_timestamp`,
+		id, emergency.StatusRecoveryApproved, emergency.StatusRecoveryInitiated)
+	if err != nil {
+		return false, fmt.Errorf("update emergency_access failed: %v", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanEmergency(row pgx.Row) (*rpc.EmergencyAccess, error) {
+	var e rpc.EmergencyAccess
+	var initiatedAt *time.Time
+	err := row.Scan(&e.ID, &e.GrantorID, &e.Grantor, &e.GranteeID, &e.Grantee, &e.AccessType, &e.WaitDays, &e.Status,
+		&initiatedAt, &e.PublicKey, &e.WrappedKey, &e.KeyVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("select from emergency_access failed: %v", err)
+	}
+	if initiatedAt != nil {
+		e.RecoveryInitiatedAt = initiatedAt.Format(app.ChangeDateLayout)
+	}
+	return &e, nil
+}
+
+// SetEmergencyStatus moves emergency access from one status to another, initiating recovery starts the waiting period
+func (db *PGDB) SetEmergencyStatus(ctx context.Context, id, from, to string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `
+	UPDATE emergency_access SET status=$3, change_date=current_timestamp,
+		recovery_initiated_at=CASE WHEN $3=$4 THEN current_timestamp ELSE recovery_initiated_at END
+	WHERE id=$1::uuid AND status=$2`, id, from, to, emergency.StatusRecoveryInitiated)
+	if err != nil {
+		return fmt.Errorf("update emergency_access failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEmergencyStatus
+	}
+	return nil
+}
+
+// ConfirmEmergencyAccess saves vault key of the grantor sealed to the contact, the contact must have accepted the invitation
+func (db *PGDB) ConfirmEmergencyAccess(ctx context.Context, id string, wrappedKey []byte, keyVersion int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `
+	UPDATE emergency_access SET status=$2, wrapped_key=$3, key_version=$4, change_date=current_timestamp
+	WHERE id=$1::uuid AND status=$5`, id, emergency.StatusConfirmed, wrappedKey, keyVersion, emergency.StatusAccepted)
+	if err != nil {
+		return fmt.Errorf("update emergency_access failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEmergencyStatus
+	}
+	return nil
+}
+
+// DeleteEmergencyAccess removes emergency access
+func (db *PGDB) DeleteEmergencyAccess(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := db.Conn.Exec(ctx, `DELETE FROM emergency_access WHERE id=$1::uuid`, id); err != nil {
+		return fmt.Errorf("delete from emergency_access failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/distribution/uuid"
+	"github.com/jackc/pgx/v4"
+	rpc "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/rbac"
+)
+
+var (
+	// ErrNotMember is returned when the user is not a member of the organisation
+	ErrNotMember = errors.New("not a member of the organisation")
+	// ErrMember is returned when the user is a member or is invited already
+	ErrMember = errors.New("user is a member or is invited already")
+	// ErrLastOwner is returned when the change would leave the organisation without owners
+	ErrLastOwner = errors.New("organisation must keep at least one owner")
+	// ErrNotFound is returned for organisations, collections, invitations and users that do not exist
+	ErrNotFound = errors.New("not found")
+)
+
+// InsertOrg creates organisation, the user becomes its owner
+func (db *PGDB) InsertOrg(ctx context.Context, ownerID int64, name string) (*uuid.UUID, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id := uuid.Generate()
+	if _, err = tx.Exec(ctx, `INSERT INTO orgs (id, name) VALUES ($1,$2)`, id, name); err != nil {
+		return nil, fmt.Errorf("insert into orgs failed: %v", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO org_members (org_id, user_id, role) VALUES ($1,$2,$3)`, id, ownerID, rbac.RoleOwner)
+	if err != nil {
+		return nil, fmt.Errorf("insert into org_members failed: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit failed: %v", err)
+	}
+	return &id, nil
+}
+
+// SelectOrgs gets organisations of the user with the role of the user
+func (db *PGDB) SelectOrgs(ctx context.Context, userID int64) ([]*rpc.Org, error) {
+	row, err := db.Conn.Query(ctx, `
+	SELECT o.id::text, o.name, m.role FROM orgs o JOIN org_members m ON m.org_id=o.id
+	WHERE m.user_id=$1 ORDER BY o.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select from orgs failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.Org
+	for row.Next() {
+		var o rpc.Org
+		if err := row.Scan(&o.ID, &o.Name, &o.Role); err != nil {
+			return nil, fmt.Errorf("select from orgs failed: %v", err)
+		}
+		out = append(out, &o)
+	}
+	return out, row.Err()
+}
+
+// DeleteOrg deletes organisation with its members, invitations, collections and their secrets
+func (db *PGDB) DeleteOrg(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `DELETE FROM orgs WHERE id=$1::uuid`, id)
+	if err != nil {
+		return fmt.Errorf("delete from orgs failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SelectOrgRole gets role of the user in the organisation
+func (db *PGDB) SelectOrgRole(ctx context.Context, userID int64, orgID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var role string
+	err := db.Conn.QueryRow(ctx, `SELECT role FROM org_members WHERE org_id=$1::uuid AND user_id=$2`, orgID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotMember
+	} else if err != nil {
+		return "", fmt.Errorf("select from org_members failed: %v", err)
+	}
+	return role, nil
+}
+
+// SelectCollectionRole gets role of the user in the organisation of the collection
+func (db *PGDB) SelectCollectionRole(ctx context.Context, userID int64, collectionID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var role string
+	err := db.Conn.QueryRow(ctx, `
+	SELECT m.role FROM collections c JOIN org_members m ON m.org_id=c.org_id
+	WHERE c.id=$1::uuid AND m.user_id=$2`, collectionID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotMember
+	} else if err != nil {
+		return "", fmt.Errorf("select from collections failed: %v", err)
+	}
+	return role, nil
+}
+
+// InsertInvitation invites the user by login to join the organisation with the role
+func (db *PGDB) InsertInvitation(ctx context.Context, orgID, login, role string, invitedBy int64) (*uuid.UUID, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	var member bool
+	err = tx.QueryRow(ctx, `
+	SELECT u.id, EXISTS (SELECT 1 FROM org_members m WHERE m.org_id=$2::uuid AND m.user_id=u.id)
+	FROM users u WHERE u.login=$1`, login, orgID).Scan(&userID, &member)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: user %s", ErrNotFound, login)
+	} else if err != nil {
+		return nil, fmt.Errorf("select from users failed: %v", err)
+	}
+	if member {
+		return nil, ErrMember
+	}
+
+	id := uuid.Generate()
+	tag, err := tx.Exec(ctx, `
+	INSERT INTO org_invitations (id, org_id, user_id, role, invited_by) VALUES ($1,$2::uuid,$3,$4,$5)
+	ON CONFLICT (org_id, user_id) DO NOTHING`, id, orgID, userID, role, invitedBy)
+	if err != nil {
+		return nil, fmt.Errorf("insert into org_invitations failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrMember
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit failed: %v", err)
+	}
+	return &id, nil
+}
+
+// SelectInvitations gets invitations of the user
+func (db *PGDB) SelectInvitations(ctx context.Context, userID int64) ([]*rpc.Invitation, error) {
+	row, err := db.Conn.Query(ctx, `
+	SELECT i.id::text, i.org_id::text, o.name, u.login, i.role, b.login
+	FROM org_invitations i JOIN orgs o ON o.id=i.org_id JOIN users u ON u.id=i.user_id JOIN users b ON b.id=i.invited_by
+	WHERE i.user_id=$1 ORDER BY i.created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select from org_invitations failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.Invitation
+	for row.Next() {
+		var i rpc.Invitation
+		if err := row.Scan(&i.ID, &i.OrgID, &i.OrgName, &i.Login, &i.Role, &i.InvitedBy); err != nil {
+			return nil, fmt.Errorf("select from org_invitations failed: %v", err)
+		}
+		out = append(out, &i)
+	}
+	return out, row.Err()
+}
+
+// AnswerInvitation removes invitation of the user, if it is accepted the user becomes a member with the invited role
+func (db *PGDB) AnswerInvitation(ctx context.Context, userID int64, id string, accept bool) error {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var orgID, role string
+	err = tx.QueryRow(ctx, `DELETE FROM org_invitations WHERE id=$1::uuid AND user_id=$2 RETURNING org_id::text, role`,
+		id, userID).Scan(&orgID, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: invitation %s", ErrNotFound, id)
+	} else if err != nil {
+		return fmt.Errorf("delete from org_invitations failed: %v", err)
+	}
+	if accept {
+		_, err = tx.Exec(ctx, `INSERT INTO org_members (org_id, user_id, role) VALUES ($1::uuid,$2,$3)
+		ON CONFLICT (org_id, user_id) DO NOTHING`, orgID, userID, role)
+		if err != nil {
+			return fmt.Errorf("insert into org_members failed: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit failed: %v", err)
+	}
+	return nil
+}
+
+// SelectMembers gets members of the organisation
+func (db *PGDB) SelectMembers(ctx context.Context, orgID string) ([]*rpc.OrgMember, error) {
+	row, err := db.Conn.Query(ctx, `
+	SELECT m.user_id, u.login, m.role FROM org_members m JOIN users u ON u.id=m.user_id
+	WHERE m.org_id=$1::uuid ORDER BY u.login`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("select from org_members failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.OrgMember
+	for row.Next() {
+		var m rpc.OrgMember
+		if err := row.Scan(&m.UserID, &m.Login, &m.Role); err != nil {
+			return nil, fmt.Errorf("select from org_members failed: %v", err)
+		}
+		out = append(out, &m)
+	}
+	return out, row.Err()
+}
+
+// UpdateMember changes role of the member, the last owner cannot be demoted
+func (db *PGDB) UpdateMember(ctx context.Context, orgID string, userID int64, role string) error {
+	return db.changeMember(ctx, orgID, userID, role,
+		`UPDATE org_members SET role=$3 WHERE org_id=$1::uuid AND user_id=$2`, role)
+}
+
+// DeleteMember removes the member from the organisation, the last owner cannot be removed
+func (db *PGDB) DeleteMember(ctx context.Context, orgID string, userID int64) error {
+	return db.changeMember(ctx, orgID, userID, "", `DELETE FROM org_members WHERE org_id=$1::uuid AND user_id=$2`)
+}
+
+// changeMember runs query giving the member the new role, empty if the member is removed, in transaction with members
+// of the organisation locked, query gets org id and user id followed by args. If the member stops being an owner,
+// another owner must stay
+func (db *PGDB) changeMember(ctx context.Context, orgID string, userID int64, newRole string, query string, args ...interface{}) error {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row, err := tx.Query(ctx, `SELECT user_id, role FROM org_members WHERE org_id=$1::uuid FOR UPDATE`, orgID)
+	if err != nil {
+		return fmt.Errorf("select from org_members failed: %v", err)
+	}
+	roles := map[int64]string{}
+	owners := 0
+	for row.Next() {
+		var id int64
+		var role string
+		if err := row.Scan(&id, &role); err != nil {
+			row.Close()
+			return fmt.Errorf("select from org_members failed: %v", err)
+		}
+		roles[id] = role
+		if role == rbac.RoleOwner {
+			owners++
+		}
+	}
+	row.Close()
+
+	role, ok := roles[userID]
+	if !ok {
+		return ErrNotMember
+	}
+	if !rbac.KeepsOwner(owners, role, newRole) {
+		return ErrLastOwner
+	}
+	if _, err := tx.Exec(ctx, query, append([]interface{}{orgID, userID}, args...)...); err != nil {
+		return fmt.Errorf("update org_members failed: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit failed: %v", err)
+	}
+	return nil
+}
+
+// InsertCollection creates collection in the organisation
+func (db *PGDB) InsertCollection(ctx context.Context, orgID, name string) (*uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	id := uuid.Generate()
+	if _, err := db.Conn.Exec(ctx, `INSERT INTO collections (id, org_id, name) VALUES ($1,$2::uuid,$3)`, id, orgID, name); err != nil {
+		return nil, fmt.Errorf("insert into collections failed: %v", err)
+	}
+	return &id, nil
+}
+
+// SelectCollections gets collections of the organisation or of all organisations of the user if orgID is empty,
+// only organisations where the user is a member are looked at
+func (db *PGDB) SelectCollections(ctx context.Context, userID int64, orgID string) ([]*rpc.Collection, error) {
+	query := `
+	SELECT c.id::text, c.org_id::text, o.name, c.name, m.role
+	FROM collections c JOIN orgs o ON o.id=c.org_id JOIN org_members m ON m.org_id=c.org_id
+	WHERE m.user_id=$1`
+	args := []interface{}{userID}
+	if orgID != "" {
+		query += ` AND c.org_id=$2::uuid`
+		args = append(args, orgID)
+	}
+	row, err := db.Conn.Query(ctx, query+` ORDER BY o.name, c.name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select from collections failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.Collection
+	for row.Next() {
+		var c rpc.Collection
+		if err := row.Scan(&c.ID, &c.OrgID, &c.OrgName, &c.Name, &c.Role); err != nil {
+			return nil, fmt.Errorf("select from collections failed: %v", err)
+		}
+		out = append(out, &c)
+	}
+	return out, row.Err()
+}
+
+// SelectCollectionOrg gets id of the organisation of the collection
+func (db *PGDB) SelectCollectionOrg(ctx context.Context, id string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var orgID string
+	err := db.Conn.QueryRow(ctx, `SELECT org_id::text FROM collections WHERE id=$1::uuid`, id).Scan(&orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: collection %s", ErrNotFound, id)
+	} else if err != nil {
+		return "", fmt.Errorf("select from collections failed: %v", err)
+	}
+	return orgID, nil
+}
+
+// DeleteCollection deletes collection with its secrets
+func (db *PGDB) DeleteCollection(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `DELETE FROM collections WHERE id=$1::uuid`, id)
+	if err != nil {
+		return fmt.Errorf("delete from collections failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: collection %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// SyncCollections replaces organisations, collections and roles of the user in client storage with ones from the server,
+// so collection secrets pulled by sync are accessible offline with the same roles.
+// Collections the user could not access before are returned, their secrets have to be pulled in full
+func (db *PGDB) SyncCollections(ctx context.Context, userID int64, collections []*rpc.Collection) ([]string, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT c.id::text FROM collections c JOIN org_members m ON m.org_id=c.org_id WHERE m.user_id=$1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select from collections failed: %v", err)
+	}
+	known := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("select from collections failed: %v", err)
+		}
+		known[id] = true
+	}
+	rows.Close()
+
+	if _, err = tx.Exec(ctx, `DELETE FROM org_members WHERE user_id=$1`, userID); err != nil {
+		return nil, fmt.Errorf("delete from org_members failed: %v", err)
+	}
+	var added []string
+	for _, c := range collections {
+		_, err = tx.Exec(ctx, `INSERT INTO orgs (id, name) VALUES ($1::uuid,$2) ON CONFLICT (id) DO UPDATE SET name=$2`, c.OrgID, c.OrgName)
+		if err != nil {
+			return nil, fmt.Errorf("insert into orgs failed: %v", err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO collections (id, org_id, name) VALUES ($1::uuid,$2::uuid,$3) ON CONFLICT (id) DO UPDATE SET name=$3`,
+			c.ID, c.OrgID, c.Name)
+		if err != nil {
+			return nil, fmt.Errorf("insert into collections failed: %v", err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO org_members (org_id, user_id, role) VALUES ($1::uuid,$2,$3) ON CONFLICT (org_id, user_id) DO NOTHING`,
+			c.OrgID, userID, c.Role)
+		if err != nil {
+			return nil, fmt.Errorf("insert into org_members failed: %v", err)
+		}
+		if !known[c.ID] {
+			added = append(added, c.ID)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit failed: %v", err)
+	}
+	return added, nil
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/maffka123/GophKeeper/internal/ratelimit"
+)
+
+// RateStore keeps state of the login rate limiter in postgres, so that all server replicas share it
+type RateStore struct {
+	Conn PGinterface
+}
+
+// NewRateStore creates rate store on the connection of the db
+func NewRateStore(db *PGDB) *RateStore {
+	return &RateStore{Conn: db.Conn}
+}
+
+// Take takes a token from the bucket of the key, the bucket row is locked while it is updated
+func (s *RateStore) Take(ctx context.Context, key string, rate ratelimit.Rate, now time.Time) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO rate_buckets (key, tokens, updated_at) VALUES ($1,$2,$3) ON CONFLICT (key) DO NOTHING`,
+		key, float64(rate.Burst), now.UTC()); err != nil {
+		return 0, fmt.Errorf("insert into rate_buckets failed: %v", err)
+	}
+	var b ratelimit.Bucket
+	if err := tx.QueryRow(ctx, `SELECT tokens, updated_at FROM rate_buckets WHERE key=$1 FOR UPDATE`, key).Scan(&b.Tokens, &b.Updated); err != nil {
+		return 0, fmt.Errorf("select from rate_buckets failed: %v", err)
+	}
+	wait := rate.Take(&b, now.UTC())
+	if _, err := tx.Exec(ctx, `UPDATE rate_buckets SET tokens=$2, updated_at=$3 WHERE key=$1`, key, b.Tokens, b.Updated); err != nil {
+		return 0, fmt.Errorf("update rate_buckets failed: %v", err)
+	}
+	return wait, tx.Commit(ctx)
+}
+
+// Fail counts failure of the login and sets end of its lockout
+func (s *RateStore) Fail(ctx context.Context, login string, lockout ratelimit.Lockout, now time.Time) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var failures int
+	err = tx.QueryRow(ctx, `
+	INSERT INTO login_failures (login, failures) VALUES ($1, 1)
+	ON CONFLICT (login) DO UPDATE SET failures=login_failures.failures+1 RETURNING failures`, login).Scan(&failures)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("insert into login_failures failed: %v", err)
+	}
+	until := lockout.Until(failures, now.UTC())
+	if !until.IsZero() {
+		if _, err := tx.Exec(ctx, `UPDATE login_failures SET locked_until=$2 WHERE login=$1`, login, until); err != nil {
+			return time.Time{}, fmt.Errorf("update login_failures failed: %v", err)
+		}
+	}
+	return until, tx.Commit(ctx)
+}
+
+// Locked returns end of the lockout of the login
+func (s *RateStore) Locked(ctx context.Context, login string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var until *time.Time
+	err := s.Conn.QueryRow(ctx, `SELECT locked_until FROM login_failures WHERE login=$1`, login).Scan(&until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, fmt.Errorf("select from login_failures failed: %v", err)
+	} else if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+// Reset forgets failures and lockout of the login
+func (s *RateStore) Reset(ctx context.Context, login string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.Conn.Exec(ctx, `DELETE FROM login_failures WHERE login=$1`, login); err != nil {
+		return fmt.Errorf("delete from login_failures failed: %v", err)
+	}
+	return nil
+}
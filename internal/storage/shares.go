@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/distribution/uuid"
+	"github.com/jackc/pgx/v4"
+	rpc "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+	"github.com/maffka123/GophKeeper/internal/sharing"
+)
+
+var (
+	// ErrKeyPair is returned when key pair of the user is set already
+	ErrKeyPair = errors.New("key pair is set already")
+	// ErrShareNotFound is returned for shares that do not exist or are not shared with the user
+	ErrShareNotFound = errors.New("share not found")
+	// ErrSharePermission is returned when the user may not change the share
+	ErrSharePermission = errors.New("not allowed to change the share")
+	// ErrShareKeyVersion is returned when share is encrypted with other key version than expected
+	ErrShareKeyVersion = errors.New("share key version does not match")
+	// ErrShareRecipients is returned when revoke does not keep the owner or adds recipients
+	ErrShareRecipients = errors.New("recipients are not valid")
+)
+
+// SelectKeyPair gets public key and wrapped private key of the user, they are nil if not set yet
+func (db *PGDB) SelectKeyPair(ctx context.Context, userID int64) ([]byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var public, private []byte
+	err := db.Conn.QueryRow(ctx, "SELECT public_key, private_key FROM users WHERE id=$1", userID).Scan(&public, &private)
+	if err != nil {
+		return nil, nil, fmt.Errorf("select from users failed: %v", err)
+	}
+	return public, private, nil
+}
+
+// SetKeyPair sets key pair of the user, existing key pair is never replaced
+func (db *PGDB) SetKeyPair(ctx context.Context, userID int64, public, private []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tag, err := db.Conn.Exec(ctx, `UPDATE users SET public_key=$2, private_key=$3 WHERE id=$1 AND public_key IS NULL`,
+		userID, public, private)
+	if err != nil {
+		return fmt.Errorf("update users failed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrKeyPair
+	}
+	return nil
+}
+
+// SelectPublicKey gets id and public key of the user by login, public key is nil if the user has not logged in since sharing was added
+func (db *PGDB) SelectPublicKey(ctx context.Context, login string) (int64, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var id int64
+	var public []byte
+	err := db.Conn.QueryRow(ctx, "SELECT id, public_key FROM users WHERE login=$1", login).Scan(&id, &public)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, fmt.Errorf("user %s not found", login)
+	} else if err != nil {
+		return 0, nil, fmt.Errorf("select from users failed: %v", err)
+	}
+	return id, public, nil
+}
+
+// InsertShare saves encrypted secret and its key wrapped for every recipient
+func (db *PGDB) InsertShare(ctx context.Context, ownerID int64, ciphertext []byte, recipients []*rpc.ShareRecipient) (*uuid.UUID, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id := uuid.Generate()
+	if _, err = tx.Exec(ctx, `INSERT INTO shares (id, owner_id, ciphertext) VALUES ($1,$2,$3)`, id, ownerID, ciphertext); err != nil {
+		return nil, fmt.Errorf("insert into shares failed: %v", err)
+	}
+	if err = insertRecipients(ctx, tx, id.String(), recipients); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit failed: %v", err)
+	}
+	return &id, nil
+}
+
+func insertRecipients(ctx context.Context, tx pgx.Tx, shareID string, recipients []*rpc.ShareRecipient) error {
+	for _, r := range recipients {
+		_, err := tx.Exec(ctx, `INSERT INTO share_recipients (share_id, user_id, wrapped_key, permission) VALUES ($1::uuid,$2,$3,$4)`,
+			shareID, r.UserID, r.WrappedKey, r.Permission)
+		if err != nil {
+			return fmt.Errorf("insert into share_recipients failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// SelectShares gets shares owned by the user and shared with the user with the key wrapped for the user,
+// owner gets also the list of recipients
+func (db *PGDB) SelectShares(ctx context.Context, userID int64) ([]*rpc.Share, error) {
+	row, err := db.Conn.Query(ctx, `
+	SELECT s.id::text, s.owner_id, u.login, s.ciphertext, s.key_version, r.wrapped_key, r.permission, s.change_date
+	FROM share_recipients r JOIN shares s ON s.id=r.share_id JOIN users u ON u.id=s.owner_id
+	WHERE r.user_id=$1
+	ORDER BY s.change_date DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select from shares failed: %v", err)
+	}
+	defer row.Close()
+
+	var out []*rpc.Share
+	owned := map[string]*rpc.Share{}
+	for row.Next() {
+		var s rpc.Share
+		var changeDate time.Time
+		if err := row.Scan(&s.ID, &s.OwnerID, &s.Owner, &s.Ciphertext, &s.KeyVersion, &s.WrappedKey, &s.Permission, &changeDate); err != nil {
+			return nil, fmt.Errorf("select from shares failed: %v", err)
+		}
+		s.ChangeDate = changeDate.Format(app.ChangeDateLayout)
+		if s.OwnerID == userID {
+			owned[s.ID] = &s
+		}
+		out = append(out, &s)
+	}
+	if err := row.Err(); err != nil {
+		return nil, fmt.Errorf("select from shares failed: %v", err)
+	}
+	if len(owned) == 0 {
+		return out, nil
+	}
+
+	rec, err := db.Conn.Query(ctx, `
+	SELECT r.share_id::text, r.user_id, u.login, u.public_key, r.permission
+	FROM share_recipients r JOIN shares s ON s.id=r.share_id JOIN users u ON u.id=r.user_id
+	WHERE s.owner_id=$1
+	ORDER BY u.login`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select from share_recipients failed: %v", err)
+	}
+	defer rec.Close()
+	for rec.Next() {
+		var id string
+		var r rpc.ShareRecipient
+		if err := rec.Scan(&id, &r.UserID, &r.Login, &r.PublicKey, &r.Permission); err != nil {
+			return nil, fmt.Errorf("select from share_recipients failed: %v", err)
+		}
+		if s, ok := owned[id]; ok {
+			s.Recipients = append(s.Recipients, &r)
+		}
+	}
+	return out, rec.Err()
+}
+
+// UpdateShare replaces content of the share, the user must be its owner or have write permission,
+// the content must be encrypted with the current key version
+func (db *PGDB) UpdateShare(ctx context.Context, userID int64, id string, ciphertext []byte, keyVersion int64) error {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var version int64
+	var permission string
+	err = tx.QueryRow(ctx, `
+	SELECT s.key_version, r.permission FROM shares s JOIN share_recipients r ON r.share_id=s.id
+	WHERE s.id=$1::uuid AND r.user_id=$2 FOR UPDATE OF s`, id, userID).Scan(&version, &permission)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrShareNotFound
+	} else if err != nil {
+		return fmt.Errorf("select from shares failed: %v", err)
+	}
+	if permission != sharing.PermissionOwner && permission != sharing.PermissionWrite {
+		return ErrSharePermission
+	}
+	if version != keyVersion {
+		return ErrShareKeyVersion
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE shares SET ciphertext=$2, change_date=current_timestamp WHERE id=$1::uuid`, id, ciphertext); err != nil {
+		return fmt.Errorf("update shares failed: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit failed: %v", err)
+	}
+	return nil
+}
+
+// RevokeShare replaces content of the share encrypted with a new key of the next version and keeps only given recipients,
+// their permissions are not changed. Only the owner can revoke and recipients cannot be added this way
+func (db *PGDB) RevokeShare(ctx context.Context, ownerID int64, id string, ciphertext []byte, keyVersion int64, recipients []*rpc.ShareRecipient) error {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var version, owner int64
+	err = tx.QueryRow(ctx, `SELECT key_version, owner_id FROM shares WHERE id=$1::uuid FOR UPDATE`, id).Scan(&version, &owner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrShareNotFound
+	} else if err != nil {
+		return fmt.Errorf("select from shares failed: %v", err)
+	}
+	if owner != ownerID {
+		return ErrSharePermission
+	}
+	if keyVersion != version+1 {
+		return ErrShareKeyVersion
+	}
+
+	rows, err := tx.Query(ctx, `SELECT user_id, permission FROM share_recipients WHERE share_id=$1::uuid`, id)
+	if err != nil {
+		return fmt.Errorf("select from share_recipients failed: %v", err)
+	}
+	existing := map[int64]string{}
+	for rows.Next() {
+		var userID int64
+		var permission string
+		if err := rows.Scan(&userID, &permission); err != nil {
+			rows.Close()
+			return fmt.Errorf("select from share_recipients failed: %v", err)
+		}
+		existing[userID] = permission
+	}
+	rows.Close()
+
+	keep := make([]*rpc.ShareRecipient, 0, len(recipients))
+	hasOwner := false
+	for _, r := range recipients {
+		permission, ok := existing[r.UserID]
+		if !ok {
+			return fmt.Errorf("%w: user %d is not a recipient of the share, revoke cannot add recipients", ErrShareRecipients, r.UserID)
+		}
+		hasOwner = hasOwner || r.UserID == ownerID
+		keep = append(keep, &rpc.ShareRecipient{UserID: r.UserID, WrappedKey: r.WrappedKey, Permission: permission})
+	}
+	if !hasOwner {
+		return fmt.Errorf("%w: owner must keep access to the share", ErrShareRecipients)
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM share_recipients WHERE share_id=$1::uuid`, id); err != nil {
+		return fmt.Errorf("delete from share_recipients failed: %v", err)
+	}
+	if err = insertRecipients(ctx, tx, id, keep); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE shares SET ciphertext=$2, key_version=$3, change_date=current_timestamp WHERE id=$1::uuid`,
+		id, ciphertext, keyVersion)
+	if err != nil {
+		return fmt.Errorf("update shares failed: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package syncdb
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
+)
+
+// ErrUnavailable is returned by sync when the server cannot be reached, local db keeps working
+var ErrUnavailable = errors.New("server is not available")
+
+// State tells how the last sync went, it is shared by the sync routine and the api
+type State struct {
+	mu       sync.Mutex
+	syncing  bool
+	offline  bool
+	lastSync time.Time
+	err      error
+	revision int64
+}
+
+// NewState creates state of sync that has not run yet
+func NewState() *State {
+	return &State{}
+}
+
+// begin marks sync as running
+func (s *State) begin() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncing = true
+}
+
+// finish records result of sync, unavailable server is not an error but offline mode
+func (s *State) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncing = false
+	s.offline = errors.Is(err, ErrUnavailable)
+	if err == nil {
+		s.lastSync = time.Now()
+	}
+	if s.offline {
+		err = nil
+	}
+	s.err = err
+}
+
+// pulled records that sync got n secrets from the server
+func (s *State) pulled(n int) {
+	if s == nil || n == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revision++
+}
+
+// Fill writes sync state to the vault status
+func (s *State) Fill(st *pb.VaultStatus) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.Syncing, st.Offline, st.Revision = s.syncing, s.offline, s.revision
+	if !s.lastSync.IsZero() {
+		st.LastSync = s.lastSync.Format(app.ChangeDateLayout)
+	}
+	if s.err != nil {
+		st.SyncError = s.err.Error()
+	}
+}
@@ -0,0 +1,56 @@
+package syncdb
+
+import (
+	"errors"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *pb.VaultStatus
+	}{
+		{name: "ok", want: &pb.VaultStatus{}},
+		{name: "offline", err: ErrUnavailable, want: &pb.VaultStatus{Offline: true}},
+		{name: "failed", err: errors.New("db is gone"), want: &pb.VaultStatus{SyncError: "db is gone"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewState()
+			st := &pb.VaultStatus{}
+			s.Fill(st)
+			assert.Empty(t, st.LastSync)
+
+			s.begin()
+			st = &pb.VaultStatus{}
+			s.Fill(st)
+			assert.True(t, st.Syncing)
+
+			s.finish(tt.err)
+			st = &pb.VaultStatus{}
+			s.Fill(st)
+			assert.Equal(t, tt.err == nil, st.LastSync != "")
+			st.LastSync = ""
+			assert.Equal(t, tt.want.Offline, st.Offline)
+			assert.Equal(t, tt.want.SyncError, st.SyncError)
+			assert.False(t, st.Syncing)
+		})
+	}
+
+	s := NewState()
+	s.pulled(0)
+	s.pulled(3)
+	st := &pb.VaultStatus{}
+	s.Fill(st)
+	assert.Equal(t, int64(1), st.Revision)
+
+	var none *State
+	st = &pb.VaultStatus{}
+	none.pulled(1)
+	none.Fill(st)
+	assert.False(t, st.Syncing)
+}
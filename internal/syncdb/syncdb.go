@@ -6,6 +6,7 @@ import (
 	"time"
 
 	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/app"
 	"github.com/maffka123/GophKeeper/internal/storage"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -21,6 +22,9 @@ type SyncDB struct {
 	c        pb.GophKeeperClient
 	ctx      context.Context
 	logger   *zap.Logger
+	state    *State
+	// newCollections are collections the user joined, whose secrets are not pulled yet
+	newCollections []string
 }
 
 // NewSyncDB returns new sync db object
@@ -30,83 +34,127 @@ func NewSyncDB(
 	token string,
 	db storage.StoregeInterface,
 	client pb.GophKeeperClient,
-	log *zap.Logger) SyncDB {
+	log *zap.Logger,
+	state *State) SyncDB {
 	return SyncDB{
-		lastSync: time.Now().Format("2006-01-02 15:04:05"), // TODO: implement proper time extraction (last synced time from local db)
+		lastSync: time.Now().Format(app.ChangeDateLayout), // TODO: implement proper time extraction (last synced time from local db)
 		UserID:   userID,
 		Token:    token,
 		db:       db,
 		c:        client,
 		ctx:      ctx,
 		logger:   log,
+		state:    state,
 	}
 }
 
-// Sync synchronizes dbs
+// Sync synchronizes dbs, collections of organisations go first so that their secrets can be saved,
+// ErrUnavailable is returned if the server cannot be reached. Changes are pulled since the last successful pull,
+// secrets of collections the user has just joined are pulled in full, as they may have changed long before
 // TODO: sync users tables
-func (s SyncDB) Sync() error {
-	s.ctx = metadata.AppendToOutgoingContext(s.ctx, "token", s.Token)
-	resp, err := s.c.GetAllDataForUser(s.ctx, &pb.GetAllDataForUserRequest{UserID: s.UserID, Time: s.lastSync})
+func (s *SyncDB) Sync() error {
+	ctx := metadata.AppendToOutgoingContext(s.ctx, "token", s.Token)
+	added, err := s.syncCollections(ctx)
 	if err != nil {
-		if e, ok := status.FromError(err); ok {
-			switch e.Code() {
-			case codes.Code(codes.Unavailable):
-				s.logger.Warn("server is not available.....")
-				return nil
-			}
-		} else {
-			s.logger.Error(fmt.Sprintf("data select from server failed: %s", err.Error()))
+		return err
+	}
+	s.newCollections = append(s.newCollections, added...)
+
+	var n int
+	if len(s.newCollections) > 0 {
+		if n, err = s.pull(ctx, &pb.GetAllDataForUserRequest{UserID: s.UserID, CollectionIDs: s.newCollections, Reveal: true}); err != nil {
 			return err
 		}
+		s.newCollections = nil
 	}
 
-	err = s.db.InserDataForUser(s.ctx, resp.Data, s.UserID)
-	s.lastSync = time.Now().Format("2006-01-02 15:04:05")
-
-	data, err := s.db.SelectAllDataForUser(s.ctx, s.UserID, s.lastSync, false)
+	// changes made during the pull are pulled next time
+	started := time.Now().Format(app.ChangeDateLayout)
+	changed, err := s.pull(ctx, &pb.GetAllDataForUserRequest{UserID: s.UserID, Time: s.lastSync, Reveal: true})
+	s.state.pulled(n + changed)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("data select from client failed: %s", err.Error()))
 		return err
 	}
-	if len(data) != 0 {
-		_, err = s.c.InsertSyncData(s.ctx, &pb.InsertSyncDataRequest{Data: data})
-		if err != nil {
-			if e, ok := status.FromError(err); ok {
-				switch e.Code() {
-				case codes.Code(codes.Unavailable):
-					s.logger.Warn("server is not available.....")
-					return nil
-				}
-			} else {
-				s.logger.Error(fmt.Sprintf("data insert to server failed: %s", err.Error()))
-				return err
+	s.lastSync = started
+
+	// local changes stay unsynchronized until the server has them
+	return s.db.PushUnsynchronized(ctx, s.UserID, func(data []*pb.Data) error {
+		if _, err := s.c.InsertSyncData(ctx, &pb.InsertSyncDataRequest{Data: data}); err != nil {
+			if e, ok := status.FromError(err); ok && e.Code() == codes.Unavailable {
+				s.logger.Warn("server is not available.....")
+				return ErrUnavailable
 			}
+			s.logger.Error(fmt.Sprintf("data insert to server failed: %s", err.Error()))
+			return err
 		}
+		return nil
+	})
+}
+
+// pull saves secrets the server gives for the request in local db and returns how many were saved
+func (s *SyncDB) pull(ctx context.Context, req *pb.GetAllDataForUserRequest) (int, error) {
+	resp, err := s.c.GetAllDataForUser(ctx, req)
+	if err != nil {
+		if e, ok := status.FromError(err); ok && e.Code() == codes.Unavailable {
+			s.logger.Warn("server is not available.....")
+			return 0, ErrUnavailable
+		}
+		s.logger.Error(fmt.Sprintf("data select from server failed: %s", err.Error()))
+		return 0, err
 	}
 
-	return nil
+	if err = s.db.InserDataForUser(ctx, resp.Data, s.UserID); err != nil {
+		s.logger.Error(fmt.Sprintf("data insert to client failed: %s", err.Error()))
+		return 0, err
+	}
+	return len(resp.Data), nil
+}
+
+// syncCollections replaces collections of the user and roles in them in local db with ones from the server,
+// collections the user has just joined are returned
+func (s *SyncDB) syncCollections(ctx context.Context) ([]string, error) {
+	resp, err := s.c.ListCollections(ctx, &pb.ListCollectionsRequest{})
+	if err != nil {
+		if e, ok := status.FromError(err); ok && e.Code() == codes.Unavailable {
+			s.logger.Warn("server is not available.....")
+			return nil, ErrUnavailable
+		}
+		s.logger.Error(fmt.Sprintf("collections select from server failed: %s", err.Error()))
+		return nil, err
+	}
+	added, err := s.db.SyncCollections(ctx, s.UserID, resp.Collections)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("collections insert to client failed: %s", err.Error()))
+		return nil, err
+	}
+	return added, nil
 }
 
-// InitSync starts synchronizing dbs as soon as it has user id and token
+// InitSync starts synchronizing dbs as soon as it has user id and token, result of every sync is kept in state
 // TODO: problem with multiple users
 func InitSync(ctx context.Context, tokenChan chan string, userID chan int64,
-	db storage.StoregeInterface, client pb.GophKeeperClient, log *zap.Logger, tsync <-chan time.Time) {
+	db storage.StoregeInterface, client pb.GophKeeperClient, log *zap.Logger, tsync <-chan time.Time, state *State) {
 
 	token := <-tokenChan
 	id := <-userID
-	s := NewSyncDB(ctx, id, token, db, client, log)
-	go s.syncRoutine(tsync)
+	s := NewSyncDB(ctx, id, token, db, client, log, state)
+	go s.syncRoutine(tsync, tokenChan)
 }
 
-// syncRoutine runs periodic sync of dbs as go routine
-func (s SyncDB) syncRoutine(t <-chan time.Time) {
+// syncRoutine runs periodic sync of dbs as go routine, tokens given later replace the token of sync,
+// as changing the password revokes the old one
+func (s *SyncDB) syncRoutine(t <-chan time.Time, tokens <-chan string) {
 	for {
 		select {
+		case token := <-tokens:
+			s.Token = token
 		case <-t:
 			s.logger.Info("synchronizing dbs")
-			s.Sync()
+			s.state.begin()
+			s.state.finish(s.Sync())
 		case <-s.ctx.Done():
 			s.logger.Info("context canceled")
+			return
 		}
 	}
 }
@@ -0,0 +1,156 @@
+package syncdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/maffka123/GophKeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClient gives a new secret at every pull and records times pulls were asked since,
+// collections pulled in full and how many secrets were pushed
+type fakeClient struct {
+	pb.GophKeeperClient
+	since       []string
+	collections [][]string
+	pullErr     error
+	pushErr     error
+	pushed      int
+}
+
+func (c *fakeClient) ListCollections(context.Context, *pb.ListCollectionsRequest, ...grpc.CallOption) (*pb.ListCollectionsResp, error) {
+	return &pb.ListCollectionsResp{}, nil
+}
+
+func (c *fakeClient) GetAllDataForUser(_ context.Context, req *pb.GetAllDataForUserRequest, _ ...grpc.CallOption) (*pb.GetAllDataForUserResp, error) {
+	if len(req.CollectionIDs) > 0 {
+		c.collections = append(c.collections, req.CollectionIDs)
+		if c.pullErr != nil {
+			return nil, c.pullErr
+		}
+		return &pb.GetAllDataForUserResp{Data: []*pb.Data{{Metadata: "shared", CollectionID: req.CollectionIDs[0]}}}, nil
+	}
+	c.since = append(c.since, req.Time)
+	return &pb.GetAllDataForUserResp{Data: []*pb.Data{{Metadata: "pulled"}}}, nil
+}
+
+func (c *fakeClient) InsertSyncData(_ context.Context, req *pb.InsertSyncDataRequest, _ ...grpc.CallOption) (*pb.InsertSyncDataResp, error) {
+	if c.pushErr != nil {
+		return nil, c.pushErr
+	}
+	c.pushed += len(req.Data)
+	return &pb.InsertSyncDataResp{}, nil
+}
+
+type fakeDB struct {
+	storage.StoregeInterface
+	insertErr error
+	inserted  int
+	joined    []string
+	// local are changes not pushed to the server yet
+	local []*pb.Data
+}
+
+func (db *fakeDB) SyncCollections(context.Context, int64, []*pb.Collection) ([]string, error) {
+	joined := db.joined
+	db.joined = nil
+	return joined, nil
+}
+
+func (db *fakeDB) InserDataForUser(_ context.Context, d []*pb.Data, _ int64) error {
+	if db.insertErr != nil {
+		return db.insertErr
+	}
+	db.inserted += len(d)
+	return nil
+}
+
+// PushUnsynchronized marks local changes synchronized only if push succeeds
+func (db *fakeDB) PushUnsynchronized(_ context.Context, _ int64, push func([]*pb.Data) error) error {
+	if len(db.local) == 0 {
+		return nil
+	}
+	if err := push(db.local); err != nil {
+		return err
+	}
+	db.local = nil
+	return nil
+}
+
+func TestSyncDB_Sync(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	c, db, state := &fakeClient{}, &fakeDB{}, NewState()
+	s := NewSyncDB(context.Background(), 1, "token", db, c, logger, state)
+	s.lastSync = "2022-10-01 10:00:00"
+
+	// every pull moves the revision and the time pulls are asked since
+	for i := 1; i <= 2; i++ {
+		state.begin()
+		state.finish(s.Sync())
+		st := &pb.VaultStatus{}
+		state.Fill(st)
+		assert.Empty(t, st.SyncError)
+		assert.Equal(t, int64(i), st.Revision)
+	}
+	assert.Equal(t, 2, db.inserted)
+	require.Len(t, c.since, 2)
+	assert.Greater(t, c.since[1], c.since[0])
+
+	// failed insert is reported and the same changes are pulled again
+	db.insertErr = errors.New("duplicate key")
+	state.begin()
+	state.finish(s.Sync())
+	st := &pb.VaultStatus{}
+	state.Fill(st)
+	assert.Equal(t, "duplicate key", st.SyncError)
+	assert.Equal(t, int64(2), st.Revision)
+	db.insertErr = nil
+	require.NoError(t, s.Sync())
+	assert.Equal(t, c.since[2], c.since[3])
+}
+
+func TestSyncDB_SyncNewCollections(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	c, db, state := &fakeClient{}, &fakeDB{joined: []string{"c1"}}, NewState()
+	s := NewSyncDB(context.Background(), 1, "token", db, c, logger, state)
+
+	// failed pull of a joined collection is retried at the next sync
+	c.pullErr = errors.New("internal")
+	assert.EqualError(t, s.Sync(), "internal")
+	assert.Empty(t, c.since)
+
+	c.pullErr = nil
+	db.joined = []string{"c2"}
+	require.NoError(t, s.Sync())
+	assert.Equal(t, [][]string{{"c1"}, {"c1", "c2"}}, c.collections)
+	assert.Equal(t, 2, db.inserted)
+
+	// collections are pulled in full only once
+	require.NoError(t, s.Sync())
+	assert.Len(t, c.collections, 2)
+	assert.Len(t, c.since, 2)
+}
+
+func TestSyncDB_SyncPush(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	c, db := &fakeClient{}, &fakeDB{local: []*pb.Data{{Metadata: "edited"}}}
+	s := NewSyncDB(context.Background(), 1, "token", db, c, logger, NewState())
+
+	// changes that did not reach the server are pushed at the next sync
+	c.pushErr = status.Error(codes.Unavailable, "no connection")
+	assert.ErrorIs(t, s.Sync(), ErrUnavailable)
+	assert.Len(t, db.local, 1)
+
+	c.pushErr = nil
+	require.NoError(t, s.Sync())
+	assert.Equal(t, 1, c.pushed)
+	assert.Empty(t, db.local)
+}
@@ -0,0 +1,141 @@
+// Package urlmatch finds logins that belong to a website.
+package urlmatch
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"golang.org/x/net/publicsuffix"
+)
+
+// scores rank matches, more specific match modes win
+var scores = map[pb.MatchMode]int64{
+	pb.MatchMode_EXACT:       50,
+	pb.MatchMode_STARTS_WITH: 40,
+	pb.MatchMode_REGEX:       30,
+	pb.MatchMode_HOST:        20,
+	pb.MatchMode_BASE_DOMAIN: 10,
+}
+
+// parse parses url, urls without scheme are treated as https
+func parse(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("url has no host: %s", raw)
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u, nil
+}
+
+// normalize brings url to the form used for exact and starts with comparison
+func normalize(u *url.URL) string {
+	s := u.Scheme + "://" + u.Host + u.EscapedPath()
+	if u.RawQuery != "" {
+		s += "?" + u.RawQuery
+	}
+	return strings.TrimSuffix(s, "/")
+}
+
+// baseDomain gets registrable domain of the host, ips and single label hosts are returned as is
+func baseDomain(host string) string {
+	if net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return host
+	}
+	d, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return d
+}
+
+// Validate checks that all uris of the login can be used for matching
+func Validate(auth *pb.AuthData) error {
+	for _, u := range auth.GetURIs() {
+		if u.Match == pb.MatchMode_REGEX {
+			if _, err := regexp.Compile(u.URI); err != nil {
+				return fmt.Errorf("regex %s is not valid: %v", u.URI, err)
+			}
+			continue
+		}
+		if _, err := parse(u.URI); err != nil {
+			return fmt.Errorf("uri %s is not valid: %v", u.URI, err)
+		}
+	}
+	return nil
+}
+
+// Match checks if the login uri matches website
+func Match(uri *pb.LoginURI, target *url.URL) bool {
+	if uri.Match == pb.MatchMode_REGEX {
+		re, err := regexp.Compile(uri.URI)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(target.String())
+	}
+
+	u, err := parse(uri.URI)
+	if err != nil {
+		return false
+	}
+
+	switch uri.Match {
+	case pb.MatchMode_EXACT:
+		return normalize(u) == normalize(target)
+	case pb.MatchMode_STARTS_WITH:
+		return strings.HasPrefix(normalize(target), normalize(u))
+	case pb.MatchMode_HOST:
+		return u.Host == target.Host
+	case pb.MatchMode_BASE_DOMAIN:
+		return baseDomain(u.Hostname()) == baseDomain(target.Hostname())
+	}
+	return false
+}
+
+// Rank finds logins matching the website and sorts them from the best match.
+// For every login only its best matching uri is returned.
+func Rank(data []*pb.Data, website string) ([]*pb.LoginMatch, error) {
+	target, err := parse(website)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*pb.LoginMatch
+	for _, d := range data {
+		var best *pb.LoginMatch
+		for _, uri := range d.GetData().GetAuthData().GetURIs() {
+			if !Match(uri, target) {
+				continue
+			}
+			// longer uris are more specific within the same mode
+			bonus := int64(len(uri.URI)) / 10
+			if bonus > 9 {
+				bonus = 9
+			}
+			score := scores[uri.Match] + bonus
+			if best == nil || score > best.Score {
+				best = &pb.LoginMatch{Data: d, URI: uri.URI, Match: uri.Match, Score: score}
+			}
+		}
+		if best != nil {
+			out = append(out, best)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Score > out[j].Score
+	})
+	return out, nil
+}
@@ -0,0 +1,62 @@
+package urlmatch
+
+import (
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     *pb.LoginURI
+		website string
+		want    bool
+	}{
+		{name: "base domain subdomain", uri: &pb.LoginURI{URI: "example.com"}, website: "https://login.example.com/auth", want: true},
+		{name: "base domain public suffix", uri: &pb.LoginURI{URI: "alice.github.io"}, website: "https://bob.github.io", want: false},
+		{name: "base domain co.uk", uri: &pb.LoginURI{URI: "https://shop.example.co.uk"}, website: "https://www.example.co.uk", want: true},
+		{name: "host", uri: &pb.LoginURI{URI: "login.example.com", Match: pb.MatchMode_HOST}, website: "https://login.example.com/x", want: true},
+		{name: "host other subdomain", uri: &pb.LoginURI{URI: "login.example.com", Match: pb.MatchMode_HOST}, website: "https://www.example.com", want: false},
+		{name: "host with port", uri: &pb.LoginURI{URI: "localhost:8080", Match: pb.MatchMode_HOST}, website: "http://localhost:8081", want: false},
+		{name: "exact", uri: &pb.LoginURI{URI: "https://example.com/login/", Match: pb.MatchMode_EXACT}, website: "https://EXAMPLE.com/login", want: true},
+		{name: "exact other path", uri: &pb.LoginURI{URI: "https://example.com/login", Match: pb.MatchMode_EXACT}, website: "https://example.com/", want: false},
+		{name: "starts with", uri: &pb.LoginURI{URI: "https://example.com/app", Match: pb.MatchMode_STARTS_WITH}, website: "https://example.com/app/settings", want: true},
+		{name: "starts with other scheme", uri: &pb.LoginURI{URI: "https://example.com/app", Match: pb.MatchMode_STARTS_WITH}, website: "http://example.com/app", want: false},
+		{name: "regex", uri: &pb.LoginURI{URI: `^https://(www\.)?example\.(com|org)/`, Match: pb.MatchMode_REGEX}, website: "https://example.org/", want: true},
+		{name: "broken regex", uri: &pb.LoginURI{URI: `(`, Match: pb.MatchMode_REGEX}, website: "https://example.org/", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := parse(tt.website)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, Match(tt.uri, target))
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	data := []*pb.Data{
+		{ID: "1", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "domain", URIs: []*pb.LoginURI{{URI: "example.com"}}}}},
+		{ID: "2", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "exact", URIs: []*pb.LoginURI{
+			{URI: "example.com"},
+			{URI: "https://app.example.com/login", Match: pb.MatchMode_EXACT}}}}},
+		{ID: "3", Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "other", URIs: []*pb.LoginURI{{URI: "example.org"}}}}},
+		{ID: "4", Data: &pb.KeepData{Text: "no login"}},
+	}
+
+	got, err := Rank(data, "https://app.example.com/login")
+
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "2", got[0].Data.ID)
+	assert.Equal(t, pb.MatchMode_EXACT, got[0].Match)
+	assert.Equal(t, "1", got[1].Data.ID)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(&pb.AuthData{URIs: []*pb.LoginURI{{URI: "example.com"}, {URI: ".*", Match: pb.MatchMode_REGEX}}}))
+	assert.Error(t, Validate(&pb.AuthData{URIs: []*pb.LoginURI{{URI: "[", Match: pb.MatchMode_REGEX}}}))
+	assert.Error(t, Validate(&pb.AuthData{URIs: []*pb.LoginURI{{URI: "https://"}}}))
+}
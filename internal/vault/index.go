@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+)
+
+const (
+	// MinPrefix is the shortest prefix that can be looked up, shorter ones would match too much
+	MinPrefix = 3
+	// MaxPrefix is the longest indexed prefix, longer prefixes are looked up by their first MaxPrefix characters
+	MaxPrefix = 16
+	// tokenLen is the length of truncated HMAC in bytes
+	tokenLen = 16
+)
+
+// ErrPrefix is returned for prefix lookups shorter than MinPrefix
+var ErrPrefix = fmt.Errorf("prefix must have at least %d characters", MinPrefix)
+
+// indexed returns values of the fields that have blind index
+func indexed(d *pb.Data) map[string]string {
+	kd := d.GetData()
+	return map[string]string{
+		"login":  kd.GetAuthData().GetLogin(),
+		"bank":   kd.GetBankCard().GetBankName(),
+		"holder": kd.GetBankCard().GetHolderName(),
+	}
+}
+
+// Normalize makes values that differ only in case or spaces equal
+func Normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// token is keyed HMAC of the normalized value, the key version and field are kept in clear so tokens of
+// different keys and fields never collide and the server can tell which tokens are left after rotation
+func (k *Key) token(field, value string) string {
+	sum := mac(k.derive("blind index"), field+"\x00"+value)
+	return fmt.Sprintf("%d:%s:%s", k.Version, field, hex.EncodeToString(sum[:tokenLen]))
+}
+
+// Tokens computes blind index of the secret: exact token of every indexed field
+// and tokens of its prefixes from MinPrefix to MaxPrefix characters
+func (k *Key) Tokens(d *pb.Data) []string {
+	var out []string
+	for _, field := range []string{"login", "bank", "holder"} {
+		v := []rune(Normalize(indexed(d)[field]))
+		if len(v) == 0 {
+			continue
+		}
+		out = append(out, k.token(field, string(v)))
+		for n := MinPrefix; n <= MaxPrefix && n <= len(v); n++ {
+			out = append(out, k.token(field+"^", string(v[:n])))
+		}
+	}
+	return out
+}
+
+// QueryTokens computes tokens to look up secrets by indexed fields given in d, with prefix the fields are
+// treated as prefixes. Plain values of indexed fields are removed from d, so they are never sent to the server.
+// If d has no indexed fields nil is returned
+func (k *Key) QueryTokens(d *pb.Data, prefix bool) ([]string, error) {
+	var out []string
+	for _, field := range []string{"login", "bank", "holder"} {
+		v := []rune(Normalize(indexed(d)[field]))
+		if len(v) == 0 {
+			continue
+		}
+		switch {
+		case !prefix:
+			out = append(out, k.token(field, string(v)))
+		case len(v) < MinPrefix:
+			return nil, ErrPrefix
+		case len(v) > MaxPrefix:
+			out = append(out, k.token(field+"^", string(v[:MaxPrefix])))
+		default:
+			out = append(out, k.token(field+"^", string(v)))
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	if a := d.GetData().GetAuthData(); a != nil {
+		a.Login = ""
+	}
+	if c := d.GetData().GetBankCard(); c != nil {
+		c.BankName, c.HolderName = "", ""
+	}
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// checksumLen bytes of sha256 of the secret are appended to the recovery key, so typos are found on the client
+	checksumLen = 2
+	// groupLen is the number of characters in a group of printed recovery key
+	groupLen = 5
+)
+
+// ErrRecoveryKey is returned when recovery key is mistyped
+var ErrRecoveryKey = errors.New("recovery key is not valid, check it for typos")
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// RecoveryKey is a random key given to the user once to keep offline. It wraps the vault key besides the master key,
+// so the vault can be opened when the password is forgotten. It has full strength, no kdf is needed to derive keys from it
+type RecoveryKey struct {
+	secret []byte
+}
+
+// NewRecoveryKey generates random recovery key
+func NewRecoveryKey() (*RecoveryKey, error) {
+	r := &RecoveryKey{secret: make([]byte, keyLen)}
+	if _, err := rand.Read(r.secret); err != nil {
+		return nil, fmt.Errorf("random generator failed: %v", err)
+	}
+	return r, nil
+}
+
+// ParseRecoveryKey reads recovery key as it is printed, case, spaces and dashes do not matter
+func ParseRecoveryKey(s string) (*RecoveryKey, error) {
+	s = strings.ToUpper(strings.NewReplacer("-", "", " ", "", "\t", "", "\n", "").Replace(s))
+	b, err := recoveryEncoding.DecodeString(s)
+	if err != nil || len(b) != keyLen+checksumLen {
+		return nil, ErrRecoveryKey
+	}
+	secret := b[:keyLen]
+	if sum := sha256.Sum256(secret); !bytes.Equal(sum[:checksumLen], b[keyLen:]) {
+		return nil, ErrRecoveryKey
+	}
+	return &RecoveryKey{secret: secret}, nil
+}
+
+// String returns recovery key with checksum in base32 groups separated by dashes
+func (r *RecoveryKey) String() string {
+	sum := sha256.Sum256(r.secret)
+	s := recoveryEncoding.EncodeToString(append(append([]byte{}, r.secret...), sum[:checksumLen]...))
+	var groups []string
+	for len(s) > groupLen {
+		groups = append(groups, s[:groupLen])
+		s = s[groupLen:]
+	}
+	return strings.Join(append(groups, s), "-")
+}
+
+func (r *RecoveryKey) derive(purpose string) []byte {
+	out := make([]byte, keyLen)
+	io.ReadFull(hkdf.New(sha256.New, r.secret, nil, []byte("gophkeeper recovery "+purpose)), out)
+	return out
+}
+
+// MasterKey returns the key that wraps the vault key, it has no salt
+func (r *RecoveryKey) MasterKey() *MasterKey {
+	return &MasterKey{key: r.derive("wrap")}
+}
+
+// Verifier proves to the server that the user has the recovery key, it tells nothing about the wrapping key
+func (r *RecoveryKey) Verifier() []byte {
+	return r.derive("verifier")
+}
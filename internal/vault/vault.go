@@ -0,0 +1,176 @@
+// Package vault manages keys of the vault on the client side.
+//
+// Every user has a random vault key, all client-side keys (like the blind index key) are derived from it.
+// The server keeps the vault key only wrapped with master key, which is derived from the user's password,
+// so the vault key can be rotated without changing the password and vice versa.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	keyLen  = 32
+	saltLen = 16
+	// argon2id parameters of master key
+	kdfTime    = 3
+	kdfMemory  = 64 * 1024
+	kdfThreads = 4
+)
+
+// ErrUnwrap is returned when vault key cannot be unwrapped, usually the password is wrong
+var ErrUnwrap = errors.New("vault key cannot be unwrapped")
+
+// Key is the vault key, Version grows with every rotation
+type Key struct {
+	Version int64
+	secret  []byte
+}
+
+// NewKey generates random vault key of the version
+func NewKey(version int64) (*Key, error) {
+	k := &Key{Version: version, secret: make([]byte, keyLen)}
+	if _, err := rand.Read(k.secret); err != nil {
+		return nil, fmt.Errorf("random generator failed: %v", err)
+	}
+	return k, nil
+}
+
+// Bytes returns the secret of the vault key, it is only sealed to other keys and never leaves the client as is
+func (k *Key) Bytes() []byte {
+	return k.secret
+}
+
+// FromBytes restores vault key of the version from its secret
+func FromBytes(version int64, secret []byte) (*Key, error) {
+	if len(secret) != keyLen {
+		return nil, ErrUnwrap
+	}
+	return &Key{Version: version, secret: secret}, nil
+}
+
+// Wipe overwrites the secret of the vault key, the key cannot be used after it
+func (k *Key) Wipe() {
+	wipe(k.secret)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// derive derives subkey for the purpose from the vault key
+func (k *Key) derive(purpose string) []byte {
+	out := make([]byte, keyLen)
+	io.ReadFull(hkdf.New(sha256.New, k.secret, nil, []byte("gophkeeper "+purpose)), out)
+	return out
+}
+
+// MasterKey is derived from the password, it only wraps the vault key
+type MasterKey struct {
+	Salt []byte
+	key  []byte
+}
+
+// DeriveMasterKey derives master key from the password, new random salt is generated if salt is empty
+func DeriveMasterKey(password string, salt []byte) (*MasterKey, error) {
+	if len(salt) == 0 {
+		salt = make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("random generator failed: %v", err)
+		}
+	}
+	return &MasterKey{Salt: salt, key: argon2.IDKey([]byte(password), salt, kdfTime, kdfMemory, kdfThreads, keyLen)}, nil
+}
+
+// Wipe overwrites the master key, salt is kept
+func (m *MasterKey) Wipe() {
+	wipe(m.key)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with AES-GCM, nonce is prepended to the result
+func seal(key, plain, ad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("random generator failed: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plain, ad), nil
+}
+
+// open decrypts result of seal
+func open(key, sealed, ad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("message is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], ad)
+}
+
+// Seal encrypts data with subkey of the vault key derived for the purpose
+func (k *Key) Seal(purpose string, plain []byte) ([]byte, error) {
+	return seal(k.derive(purpose), plain, versionAD(k.Version))
+}
+
+// Open decrypts data sealed by Seal with the same purpose
+func (k *Key) Open(purpose string, sealed []byte) ([]byte, error) {
+	plain, err := open(k.derive(purpose), sealed, versionAD(k.Version))
+	if err != nil {
+		return nil, ErrUnwrap
+	}
+	return plain, nil
+}
+
+// version is authenticated with the wrapped key, so old key cannot be passed off as the new one
+func versionAD(version int64) []byte {
+	ad := make([]byte, 8)
+	binary.BigEndian.PutUint64(ad, uint64(version))
+	return ad
+}
+
+// Wrap encrypts vault key with the master key, nonce is prepended to the result
+func (m *MasterKey) Wrap(k *Key) ([]byte, error) {
+	return seal(m.key, k.secret, versionAD(k.Version))
+}
+
+// Unwrap decrypts vault key of the version wrapped by Wrap
+func (m *MasterKey) Unwrap(wrapped []byte, version int64) (*Key, error) {
+	secret, err := open(m.key, wrapped, versionAD(version))
+	if err != nil {
+		return nil, ErrUnwrap
+	}
+	return &Key{Version: version, secret: secret}, nil
+}
+
+// mac computes HMAC-SHA256 of the message with the key
+func mac(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
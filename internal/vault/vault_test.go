@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/maffka123/GophKeeper/api/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapUnwrap(t *testing.T) {
+	k, err := NewKey(1)
+	require.NoError(t, err)
+	m, err := DeriveMasterKey("pass", nil)
+	require.NoError(t, err)
+
+	wrapped, err := m.Wrap(k)
+	require.NoError(t, err)
+
+	same, err := DeriveMasterKey("pass", m.Salt)
+	require.NoError(t, err)
+	got, err := same.Unwrap(wrapped, 1)
+	require.NoError(t, err)
+	assert.Equal(t, k, got)
+
+	_, err = same.Unwrap(wrapped, 2)
+	assert.ErrorIs(t, err, ErrUnwrap)
+
+	wrong, _ := DeriveMasterKey("wrong", m.Salt)
+	_, err = wrong.Unwrap(wrapped, 1)
+	assert.ErrorIs(t, err, ErrUnwrap)
+
+	same.Wipe()
+	_, err = same.Unwrap(wrapped, 1)
+	assert.ErrorIs(t, err, ErrUnwrap)
+	got.Wipe()
+	assert.Equal(t, make([]byte, keyLen), got.Bytes())
+}
+
+func TestSealOpen(t *testing.T) {
+	k, err := NewKey(1)
+	require.NoError(t, err)
+
+	sealed, err := k.Seal("private key", []byte("secret"))
+	require.NoError(t, err)
+	got, err := k.Open("private key", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), got)
+
+	_, err = k.Open("other purpose", sealed)
+	assert.ErrorIs(t, err, ErrUnwrap)
+	other, _ := NewKey(2)
+	_, err = other.Open("private key", sealed)
+	assert.ErrorIs(t, err, ErrUnwrap)
+	_, err = k.Open("private key", sealed[:4])
+	assert.ErrorIs(t, err, ErrUnwrap)
+}
+
+func contains(tokens []string, want []string) bool {
+	set := map[string]bool{}
+	for _, t := range tokens {
+		set[t] = true
+	}
+	for _, w := range want {
+		if !set[w] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTokens(t *testing.T) {
+	k, _ := NewKey(3)
+	d := &pb.Data{Data: &pb.KeepData{AuthData: &pb.AuthData{Login: "Alice.Smith@Example.com", Password: "pw"}}}
+	tokens := k.Tokens(d)
+	assert.Len(t, tokens, 1+MaxPrefix-MinPrefix+1)
+	for _, tok := range tokens {
+		assert.NotContains(t, tok, "alice")
+		assert.Regexp(t, `^3:login\^?:[0-9a-f]{32}$`, tok)
+	}
+
+	tests := []struct {
+		name    string
+		login   string
+		prefix  bool
+		found   bool
+		wantErr bool
+	}{
+		{name: "exact", login: "alice.smith@example.com", found: true},
+		{name: "exact_case_and_spaces", login: " ALICE.SMITH@example.com ", found: true},
+		{name: "exact_partial", login: "alice", found: false},
+		{name: "prefix", login: "Alice", prefix: true, found: true},
+		{name: "prefix_longer_than_indexed", login: "alice.smith@exam", prefix: true, found: true},
+		{name: "prefix_whole_value", login: "alice.smith@example.com", prefix: true, found: true},
+		{name: "prefix_other", login: "bob", prefix: true, found: false},
+		{name: "prefix_too_short", login: "al", prefix: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &pb.Data{Data: &pb.KeepData{AuthData: &pb.AuthData{Login: tt.login}}}
+			got, err := k.QueryTokens(q, tt.prefix)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.found, contains(tokens, got))
+			assert.Empty(t, q.Data.AuthData.Login)
+		})
+	}
+
+	rotated, _ := NewKey(4)
+	assert.False(t, contains(rotated.Tokens(d), tokens[:1]))
+
+	card := &pb.Data{Data: &pb.KeepData{BankCard: &pb.BankCard{BankName: "Big Bank", HolderName: "Alice Smith"}}}
+	q := &pb.Data{Data: &pb.KeepData{BankCard: &pb.BankCard{BankName: "big  bank", HolderName: "alice smith"}}}
+	got, err := k.QueryTokens(q, false)
+	require.NoError(t, err)
+	assert.True(t, contains(k.Tokens(card), got))
+	assert.Empty(t, q.Data.BankCard.BankName)
+
+	got, err = k.QueryTokens(&pb.Data{Metadata: "bank"}, true)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestRecoveryKey(t *testing.T) {
+	r, err := NewRecoveryKey()
+	require.NoError(t, err)
+	k, _ := NewKey(3)
+	wrapped, err := r.MasterKey().Wrap(k)
+	require.NoError(t, err)
+
+	printed := r.String()
+	assert.Len(t, printed, 65)
+	same, err := ParseRecoveryKey(" " + strings.ToLower(strings.ReplaceAll(printed, "-", " ")) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, r.Verifier(), same.Verifier())
+	assert.NotEqual(t, r.Verifier(), r.MasterKey().key)
+	got, err := same.MasterKey().Unwrap(wrapped, 3)
+	require.NoError(t, err)
+	assert.Equal(t, k, got)
+
+	typo := []byte(printed)
+	if typo[0] == 'A' {
+		typo[0] = 'B'
+	} else {
+		typo[0] = 'A'
+	}
+	_, err = ParseRecoveryKey(string(typo))
+	assert.ErrorIs(t, err, ErrRecoveryKey)
+	_, err = ParseRecoveryKey(printed[:60])
+	assert.ErrorIs(t, err, ErrRecoveryKey)
+}